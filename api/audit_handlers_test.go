@@ -0,0 +1,144 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"ledgerproject/audit"
+	"ledgerproject/auth"
+	"ledgerproject/config"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// setupAuditTest returns a server whose trail holds the creation of ACC001
+// by the admin "tester" and its freeze by the operator "backoffice".
+func setupAuditTest(t *testing.T) *Server {
+	setupTestLogger(t)
+	mockLedger := new(MockLedger)
+	// httptest requests come from 192.0.2.1, taken for a proxy
+	cfg := &config.Config{TrustedProxies: []string{"192.0.2.0/24"}}
+	server := NewServer(ServerParams{Ledger: mockLedger, Config: cfg, Auth: testAuthenticator(t), Audit: audit.NewMemoryLog()})
+	mockLedger.On("CreateAccount", mock.Anything).Return(nil)
+	mockLedger.On("FreezeAccount", "ACC001").Return(nil)
+
+	for _, step := range []struct{ key, method, path, body string }{
+		{testAPIKey, "POST", "/accounts", `{"id":"ACC001","name":"Cash","currency":"USD","balance":{"amount":"0","currency":"USD"}}`},
+		{"operator-key", "POST", "/accounts/ACC001/freeze", ""},
+	} {
+		req := httptest.NewRequest(step.method, step.path, bytes.NewBufferString(step.body))
+		req.Header.Set(auth.APIKeyHeader, step.key)
+		req.Header.Set("X-Request-ID", "req-"+step.key)
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		require.Less(t, rr.Code, 300, rr.Body.String())
+	}
+	return server
+}
+
+func auditRequest(server *Server, key, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", path, nil)
+	req.Header.Set(auth.APIKeyHeader, key)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestQueryAuditHandler(t *testing.T) {
+	server := setupAuditTest(t)
+
+	rr := auditRequest(server, "auditor-key", "/audit")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var entries []audit.Entry
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&entries))
+	require.Len(t, entries, 2)
+
+	created := entries[0]
+	assert.Equal(t, "tester", created.Actor)
+	assert.Equal(t, auth.RoleAdmin, created.Role)
+	assert.Equal(t, actionAccountCreate, created.Action)
+	assert.Equal(t, "ACC001", created.Target)
+	assert.Equal(t, "req-"+testAPIKey, created.RequestID)
+	assert.Equal(t, "203.0.113.7", created.ClientIP)
+	assert.Contains(t, created.Changes, audit.Change{Field: "name", To: "Cash"})
+
+	frozen := entries[1]
+	assert.Equal(t, "backoffice", frozen.Actor)
+	assert.Equal(t, []audit.Change{{Field: "frozen", From: false, To: true}}, frozen.Changes)
+	assert.NoError(t, audit.VerifyChain(entries))
+
+	tests := []struct {
+		name       string
+		key        string
+		query      string
+		wantStatus int
+		wantCount  int
+	}{
+		{name: "filter by actor", key: "auditor-key", query: "?actor=backoffice", wantStatus: http.StatusOK, wantCount: 1},
+		{name: "filter by action", key: "auditor-key", query: "?action=account.create", wantStatus: http.StatusOK, wantCount: 1},
+		{name: "after and limit", key: "auditor-key", query: "?after=0&limit=1", wantStatus: http.StatusOK, wantCount: 1},
+		{name: "invalid time", key: "auditor-key", query: "?from=yesterday", wantStatus: http.StatusBadRequest},
+		{name: "invalid limit", key: "auditor-key", query: "?limit=0", wantStatus: http.StatusBadRequest},
+		{name: "admin is not an auditor", key: testAPIKey, wantStatus: http.StatusForbidden},
+		{name: "operator is not an auditor", key: "operator-key", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := auditRequest(server, tt.key, "/audit"+tt.query)
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			var entries []audit.Entry
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&entries))
+			assert.Len(t, entries, tt.wantCount)
+		})
+	}
+}
+
+func TestExportAuditHandler(t *testing.T) {
+	server := setupAuditTest(t)
+
+	t.Run("json lines", func(t *testing.T) {
+		rr := auditRequest(server, "auditor-key", "/audit/export")
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/x-ndjson", rr.Header().Get("Content-Type"))
+		assert.Contains(t, rr.Header().Get("Content-Disposition"), "attachment;")
+
+		var entries []audit.Entry
+		scanner := bufio.NewScanner(rr.Body)
+		for scanner.Scan() {
+			var entry audit.Entry
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+			entries = append(entries, entry)
+		}
+		require.Len(t, entries, 2)
+		assert.NoError(t, audit.VerifyChain(entries))
+	})
+
+	t.Run("csv", func(t *testing.T) {
+		rr := auditRequest(server, "auditor-key", "/audit/export?format=csv&actor=backoffice")
+		require.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "text/csv", rr.Header().Get("Content-Type"))
+
+		records, err := csv.NewReader(rr.Body).ReadAll()
+		require.NoError(t, err)
+		require.Len(t, records, 2)
+		assert.Equal(t, "actor", records[0][2])
+		assert.Equal(t, []string{"2", "backoffice", "account.freeze", "ACC001"},
+			[]string{records[1][0], records[1][2], records[1][4], records[1][5]})
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		rr := auditRequest(server, "auditor-key", "/audit/export?format=xml")
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
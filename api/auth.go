@@ -0,0 +1,87 @@
+package api
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"ledgerproject/auth"
+	"ledgerproject/logger"
+	"net/http"
+)
+
+// authenticate rejects requests without valid credentials and attaches the
+// principal to the context of the others.
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isPublic(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		principal, err := s.auth.Authenticate(r)
+		if err != nil {
+			requestLogger(r).Warn("Request authentication failed",
+				zap.Error(err),
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path),
+				zap.String("remote_addr", clientIP(r)))
+
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ledger"`)
+			writeProblem(w, r, http.StatusUnauthorized, "authentication required")
+			return
+		}
+
+		r = withLogFields(r, zap.String("principal", principal.ID), zap.String("auth_method", principal.Method))
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// requestLogger returns the logger for a request, annotated with its request
+// ID, the authenticated principal and the tenant serving it.
+func requestLogger(r *http.Request) *zap.Logger {
+	return logger.FromContext(r.Context())
+}
+
+// authorize rejects principals whose role lacks the permission.
+func (s *Server) authorize(permission auth.Permission, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.PrincipalFrom(r.Context())
+		if !ok {
+			writeProblem(w, r, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		if !principal.Can(permission) {
+			requestLogger(r).Warn("Request not permitted",
+				zap.String("role", principal.Role),
+				zap.String("permission", string(permission)),
+				zap.String("method", r.Method),
+				zap.String("path", r.URL.Path))
+			writeProblem(w, r, http.StatusForbidden, "permission denied")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowAccount checks that the principal may use the account and answers
+// 403 when it may not. Requests reach the handlers only through
+// authenticate, so a missing principal means the handler was called
+// directly.
+func allowAccount(w http.ResponseWriter, r *http.Request, accountID string) bool {
+	principal, ok := auth.PrincipalFrom(r.Context())
+	if !ok || principal.CanAccessAccount(accountID) {
+		return true
+	}
+	requestLogger(r).Warn("Account access denied", zap.String("account_id", accountID))
+	writeProblem(w, r, http.StatusForbidden, fmt.Sprintf("access to account %s denied", accountID))
+	return false
+}
+
+// accountFilter returns whether the principal may see each account, for
+// trimming list responses.
+func accountFilter(r *http.Request) func(accountID string) bool {
+	principal, ok := auth.PrincipalFrom(r.Context())
+	if !ok {
+		return func(string) bool { return true }
+	}
+	return principal.CanAccessAccount
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"ledgerproject/audit"
+	"ledgerproject/auth"
+	"ledgerproject/config"
+	"ledgerproject/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+const testAPIKey = "test-api-key"
+
+func keyHash(key string) string {
+	digest := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(digest[:])
+}
+
+// testAuthenticator accepts testAPIKey for an admin, plus "auditor-key",
+// "operator-key", "client-key" for a client owning ACC001 and "retail-key"
+// for an admin of the retail tenant.
+func testAuthenticator(t *testing.T) *auth.Authenticator {
+	a, err := auth.NewAuthenticator(&config.Config{
+		APIKeys: []config.APIKey{
+			{Principal: "tester", KeyHash: keyHash(testAPIKey), Role: auth.RoleAdmin},
+			{Principal: "support", KeyHash: keyHash("auditor-key"), Role: auth.RoleAuditor},
+			{Principal: "backoffice", KeyHash: keyHash("operator-key"), Role: auth.RoleOperator},
+			{Principal: "merchant", KeyHash: keyHash("client-key"), Role: auth.RoleClient, Accounts: []string{"ACC001"}},
+			{Principal: "retail-admin", KeyHash: keyHash("retail-key"), Role: auth.RoleAdmin, Tenant: "retail"},
+		},
+	})
+	require.NoError(t, err)
+	return a
+}
+
+func TestAuthenticate(t *testing.T) {
+	setupTestLogger(t)
+	server := &Server{router: mux.NewRouter(), auth: testAuthenticator(t)}
+
+	var principal auth.Principal
+	var authenticated bool
+	handler := server.authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, authenticated = auth.PrincipalFrom(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name       string
+		header     string
+		value      string
+		wantStatus int
+	}{
+		{name: "api key header", header: auth.APIKeyHeader, value: testAPIKey, wantStatus: http.StatusOK},
+		{name: "api key as bearer token", header: "Authorization", value: "Bearer " + testAPIKey, wantStatus: http.StatusOK},
+		{name: "unknown api key", header: auth.APIKeyHeader, value: "guess", wantStatus: http.StatusUnauthorized},
+		{name: "invalid jwt", header: "Authorization", value: "Bearer a.b.c", wantStatus: http.StatusUnauthorized},
+		{name: "basic auth", header: "Authorization", value: "Basic dGVzdDp0ZXN0", wantStatus: http.StatusUnauthorized},
+		{name: "no credentials", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			principal, authenticated = auth.Principal{}, false
+
+			req := httptest.NewRequest("GET", "/accounts", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			rr := httptest.NewRecorder()
+
+			handler.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus != http.StatusOK {
+				assert.False(t, authenticated)
+				assert.Equal(t, `Bearer realm="ledger"`, rr.Header().Get("WWW-Authenticate"))
+				return
+			}
+			assert.True(t, authenticated)
+			assert.Equal(t, auth.Principal{ID: "tester", Method: auth.MethodAPIKey, Role: auth.RoleAdmin}, principal)
+		})
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	usd := models.Money{Amount: decimal.NewFromInt(10), Currency: "USD"}
+
+	tests := []struct {
+		name       string
+		key        string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+	}{
+		{name: "auditor reads balance", key: "auditor-key", method: "GET", path: "/accounts/ACC002/balance", wantStatus: http.StatusOK},
+		{name: "auditor cannot post", key: "auditor-key", method: "POST", path: "/transactions",
+			body:       `{"id":"TX1","debit_account":"ACC002","credit_account":"ACC001","amount":{"amount":"10","currency":"USD"}}`,
+			wantStatus: http.StatusForbidden},
+		{name: "operator posts", key: "operator-key", method: "POST", path: "/transactions",
+			body:       `{"id":"TX1","debit_account":"ACC002","credit_account":"ACC001","amount":{"amount":"10","currency":"USD"}}`,
+			wantStatus: http.StatusCreated},
+		{name: "operator cannot change fee rules", key: "operator-key", method: "PUT", path: "/fee-rules", body: `[]`, wantStatus: http.StatusForbidden},
+		{name: "client reads own balance", key: "client-key", method: "GET", path: "/accounts/ACC001/balance", wantStatus: http.StatusOK},
+		{name: "client cannot read other balance", key: "client-key", method: "GET", path: "/accounts/ACC002/balance", wantStatus: http.StatusForbidden},
+		{name: "client cannot read other history", key: "client-key", method: "GET", path: "/accounts/ACC002/history", wantStatus: http.StatusForbidden},
+		{name: "client debits own account", key: "client-key", method: "POST", path: "/transactions",
+			body:       `{"id":"TX1","debit_account":"ACC001","credit_account":"ACC002","amount":{"amount":"10","currency":"USD"}}`,
+			wantStatus: http.StatusCreated},
+		{name: "client cannot debit other account", key: "client-key", method: "POST", path: "/transactions",
+			body:       `{"id":"TX1","debit_account":"ACC002","credit_account":"ACC001","amount":{"amount":"10","currency":"USD"}}`,
+			wantStatus: http.StatusForbidden},
+		{name: "client cannot debit other account through postings", key: "client-key", method: "POST", path: "/transactions",
+			body:       `{"id":"TX1","postings":[{"account":"ACC002","amount":{"amount":"-10","currency":"USD"}},{"account":"ACC001","amount":{"amount":"10","currency":"USD"}}]}`,
+			wantStatus: http.StatusForbidden},
+		{name: "client cannot create accounts", key: "client-key", method: "POST", path: "/accounts", body: `{}`, wantStatus: http.StatusForbidden},
+		{name: "client cannot read the change feed", key: "client-key", method: "GET", path: "/changes", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setupTestLogger(t)
+			mockLedger := new(MockLedger)
+			server := NewServer(ServerParams{Ledger: mockLedger, Config: &config.Config{}, Auth: testAuthenticator(t), Audit: audit.NewMemoryLog()})
+			mockLedger.On("GetAccountBalance", mock.Anything).Return(usd, nil)
+			mockLedger.On("GetTransactionHistory", mock.Anything).Return([]models.Transaction{})
+			mockLedger.On("RecordTransaction", mock.Anything).Return(nil)
+
+			req := httptest.NewRequest(tt.method, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set(auth.APIKeyHeader, tt.key)
+			rr := httptest.NewRecorder()
+
+			server.router.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code, rr.Body.String())
+			if tt.wantStatus == http.StatusForbidden {
+				mockLedger.AssertNotCalled(t, "RecordTransaction", mock.Anything)
+			}
+		})
+	}
+}
+
+func TestListAccountsForClient(t *testing.T) {
+	setupTestLogger(t)
+	mockLedger := new(MockLedger)
+	server := NewServer(ServerParams{Ledger: mockLedger, Config: &config.Config{}, Auth: testAuthenticator(t), Audit: audit.NewMemoryLog()})
+	usd := models.Money{Amount: decimal.NewFromInt(10), Currency: "USD"}
+	mockLedger.On("FindAccounts", models.MetadataFilter{}).Return([]models.Account{
+		{ID: "ACC001", Currency: "USD", Balance: usd},
+		{ID: "ACC002", Currency: "USD", Balance: usd},
+	})
+	mockLedger.On("FindTransactions", models.MetadataFilter{}).Return([]models.Transaction{
+		{ID: "TX1", DebitAccount: "ACC002", CreditAccount: "ACC001", Amount: usd},
+		{ID: "TX2", DebitAccount: "ACC002", CreditAccount: "ACC003", Amount: usd},
+	})
+
+	for path, want := range map[string]string{
+		"/accounts":     `"id":"ACC001"`,
+		"/transactions": `"id":"TX1"`,
+	} {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(auth.APIKeyHeader, "client-key")
+		rr := httptest.NewRecorder()
+
+		server.router.ServeHTTP(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Contains(t, rr.Body.String(), want)
+		assert.Equal(t, 1, bytes.Count(rr.Body.Bytes(), []byte(`"id"`)), path)
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"go.uber.org/zap"
+	"ledgerproject/models"
+	"net/http"
+	"strconv"
+)
+
+// Page sizes of the change feed.
+const (
+	defaultChangesLimit = 100
+	maxChangesLimit     = 1000
+)
+
+// changesPage is one page of the change feed. Next is the cursor to pass as
+// after for the following page; it stays at the request's cursor when there
+// is nothing new yet.
+type changesPage struct {
+	Changes      []models.Event `json:"changes"`
+	Next         uint64         `json:"next"`
+	LastSequence uint64         `json:"last_sequence"`
+	HasMore      bool           `json:"has_more"`
+}
+
+// ListChangesHandler serves every ledger change in commit order, starting
+// after the sequence given in the after parameter.
+func (s *Server) ListChangesHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+	query := r.URL.Query()
+
+	var after uint64
+	if value := query.Get("after"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, fmt.Sprintf("invalid after %q", value))
+			return
+		}
+		after = parsed
+	}
+
+	limit := defaultChangesLimit
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 || parsed > maxChangesLimit {
+			writeProblem(w, r, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxChangesLimit))
+			return
+		}
+		limit = parsed
+	}
+
+	last := s.ledger.LastSequence()
+	if after > last {
+		// The consumer is ahead of the log, which means it was reset
+		log.Error("Change feed cursor is ahead of the log",
+			zap.Uint64("after", after),
+			zap.Uint64("last_sequence", last))
+		writeProblem(w, r, http.StatusBadRequest, fmt.Sprintf("cursor %d is ahead of the last sequence %d", after, last))
+		return
+	}
+
+	page := changesPage{
+		Changes:      s.ledger.Events(after, limit),
+		Next:         after,
+		LastSequence: last,
+	}
+	if n := len(page.Changes); n > 0 {
+		page.Next = page.Changes[n-1].Sequence
+	}
+	if page.Next > page.LastSequence {
+		// Changes committed after LastSequence was read
+		page.LastSequence = page.Next
+	}
+	page.HasMore = page.Next < page.LastSequence
+
+	writeJSON(w, http.StatusOK, page)
+}
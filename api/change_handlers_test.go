@@ -0,0 +1,115 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ledgerproject/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func testEvents(from, to uint64) []models.Event {
+	events := []models.Event{}
+	for seq := from; seq <= to; seq++ {
+		events = append(events, models.Event{
+			Sequence: seq,
+			ID:       fmt.Sprintf("evt-%d", seq),
+			Type:     models.EventAccountCreated,
+		})
+	}
+	return events
+}
+
+func TestListChangesHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		after      uint64
+		limit      int
+		events     []models.Event
+		wantStatus int
+		want       changesPage
+	}{
+		{
+			name:       "first page",
+			query:      "?limit=2",
+			after:      0,
+			limit:      2,
+			events:     testEvents(1, 2),
+			wantStatus: http.StatusOK,
+			want:       changesPage{Changes: testEvents(1, 2), Next: 2, LastSequence: 5, HasMore: true},
+		},
+		{
+			name:       "last page",
+			query:      "?after=3",
+			after:      3,
+			limit:      defaultChangesLimit,
+			events:     testEvents(4, 5),
+			wantStatus: http.StatusOK,
+			want:       changesPage{Changes: testEvents(4, 5), Next: 5, LastSequence: 5},
+		},
+		{
+			name:       "caught up",
+			query:      "?after=5&limit=10",
+			after:      5,
+			limit:      10,
+			events:     []models.Event{},
+			wantStatus: http.StatusOK,
+			want:       changesPage{Changes: []models.Event{}, Next: 5, LastSequence: 5},
+		},
+		{
+			name:       "cursor ahead of the log",
+			query:      "?after=9",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid cursor",
+			query:      "?after=-1",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "limit too large",
+			query:      "?limit=5000",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "zero limit",
+			query:      "?limit=0",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, mockLedger := setupTest(t)
+			mockLedger.On("LastSequence").Return(uint64(5))
+			if tt.events != nil {
+				mockLedger.On("Events", tt.after, tt.limit).Return(tt.events)
+			}
+
+			req := httptest.NewRequest("GET", "/changes"+tt.query, nil)
+			rr := httptest.NewRecorder()
+
+			server.ListChangesHandler(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var page changesPage
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&page))
+			assert.Equal(t, tt.want.Next, page.Next)
+			assert.Equal(t, tt.want.LastSequence, page.LastSequence)
+			assert.Equal(t, tt.want.HasMore, page.HasMore)
+			require.Len(t, page.Changes, len(tt.want.Changes))
+			for i, change := range page.Changes {
+				assert.Equal(t, tt.want.Changes[i].Sequence, change.Sequence)
+			}
+			mockLedger.AssertExpectations(t)
+		})
+	}
+}
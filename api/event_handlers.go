@@ -0,0 +1,235 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+	"ledgerproject/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// eventStreamBatch is the number of events read from the ledger at a time.
+const eventStreamBatch = 500
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+// eventFilter selects the events a stream client asked for. Empty sets match
+// everything.
+type eventFilter struct {
+	accounts map[string]bool
+	types    map[string]bool
+}
+
+func (f eventFilter) matches(event models.Event) bool {
+	if len(f.types) > 0 && !f.types[event.Type] {
+		return false
+	}
+	if len(f.accounts) == 0 {
+		return true
+	}
+	for _, id := range event.Accounts {
+		if f.accounts[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// StreamEventsHandler pushes ledger events as they are committed. Requests
+// that ask for a WebSocket upgrade receive one JSON message per event; all
+// others get a Server-Sent Events stream. The account and type query
+// parameters filter the stream and may be repeated or comma-separated.
+// Streams start after the sequence given in the after parameter or, for SSE
+// reconnects, the Last-Event-ID header; without either they start with new
+// events only.
+func (s *Server) StreamEventsHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+
+	filter, err := parseEventFilter(r)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+	cursor, err := s.eventCursor(r)
+	if err != nil {
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	if websocket.IsWebSocketUpgrade(r) {
+		err = s.streamWebSocket(w, r, filter, cursor)
+	} else {
+		err = s.streamSSE(w, r, filter, cursor)
+	}
+	if err != nil {
+		log.Warn("Event stream closed", zap.Error(err), zap.Uint64("cursor", cursor))
+	}
+}
+
+func (s *Server) streamSSE(w http.ResponseWriter, r *http.Request, filter eventFilter, cursor uint64) error {
+	// Streams outlive the server's write timeout
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		return err
+	}
+
+	send := func(event models.Event) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("error encoding event: %v", err)
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Sequence, event.Type, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	keepAlive := func() error {
+		if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	return s.followEvents(r.Context(), filter, cursor, send, keepAlive)
+}
+
+func (s *Server) streamWebSocket(w http.ResponseWriter, r *http.Request, filter eventFilter, cursor uint64) error {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already replied with an error
+		return err
+	}
+	defer conn.Close()
+
+	// The stream is one-way; reading only processes control frames and
+	// notices when the client goes away
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+	go func() {
+		defer cancel()
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	timeout := s.config.EventStreamHeartbeat
+	send := func(event models.Event) error {
+		if err := conn.SetWriteDeadline(time.Now().Add(timeout)); err != nil {
+			return err
+		}
+		return conn.WriteJSON(event)
+	}
+	ping := func() error {
+		return conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(timeout))
+	}
+
+	err = s.followEvents(ctx, filter, cursor, send, ping)
+	if err == nil {
+		_ = conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	}
+	return err
+}
+
+// followEvents sends every matching event after cursor and then waits for
+// new ones until the context ends, the server shuts down or sending fails.
+// keepAlive runs whenever the stream has been idle for a heartbeat interval.
+func (s *Server) followEvents(ctx context.Context, filter eventFilter, cursor uint64,
+	send func(models.Event) error, keepAlive func() error) error {
+	heartbeat := time.NewTicker(s.config.EventStreamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		changed := s.ledger.EventsChanged()
+		events := s.ledger.Events(cursor, eventStreamBatch)
+		for _, event := range events {
+			cursor = event.Sequence
+			if !filter.matches(event) {
+				continue
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+		}
+		if len(events) == eventStreamBatch {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-s.shutdown:
+			return nil
+		case <-changed:
+		case <-heartbeat.C:
+			if err := keepAlive(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func parseEventFilter(r *http.Request) (eventFilter, error) {
+	filter := eventFilter{accounts: make(map[string]bool), types: make(map[string]bool)}
+	query := r.URL.Query()
+
+	for _, id := range splitParams(query["account"]) {
+		filter.accounts[id] = true
+	}
+	for _, t := range splitParams(query["type"]) {
+		if !models.KnownEventType(t) {
+			return filter, fmt.Errorf("unknown event type %q", t)
+		}
+		filter.types[t] = true
+	}
+	return filter, nil
+}
+
+// eventCursor returns the sequence the stream resumes after. Without an
+// explicit cursor the stream starts at the current end of the log.
+func (s *Server) eventCursor(r *http.Request) (uint64, error) {
+	value := r.URL.Query().Get("after")
+	if value == "" {
+		value = r.Header.Get("Last-Event-ID")
+	}
+	if value == "" {
+		return s.ledger.LastSequence(), nil
+	}
+
+	cursor, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid event cursor %q", value)
+	}
+	return cursor, nil
+}
+
+func splitParams(values []string) []string {
+	var params []string
+	for _, value := range values {
+		for _, param := range strings.Split(value, ",") {
+			if param = strings.TrimSpace(param); param != "" {
+				params = append(params, param)
+			}
+		}
+	}
+	return params
+}
@@ -0,0 +1,225 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"ledgerproject/auth"
+	"ledgerproject/config"
+	"ledgerproject/ledger"
+	"ledgerproject/models"
+	"ledgerproject/services"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupEventStreamTest serves the router over a real ledger holding ACC001
+// (sequence 1), ACC002 (2) and a transfer between them (3).
+func setupEventStreamTest(t *testing.T) (*httptest.Server, ledger.LedgerService) {
+	setupTestLogger(t)
+
+	validator, err := services.NewCurrencyValidator(&config.Config{
+		CurrencyFile: "../data/iso4217_currency_test.json",
+	})
+	require.NoError(t, err)
+
+	l := ledger.NewDetachedLedger(validator)
+	usd := func(amount int64) models.Money {
+		return models.Money{Amount: decimal.NewFromInt(amount), Currency: "USD"}
+	}
+	require.NoError(t, l.CreateAccount(context.Background(), models.Account{ID: "ACC001", Name: "Account 1", Currency: "USD", Balance: usd(100)}))
+	require.NoError(t, l.CreateAccount(context.Background(), models.Account{ID: "ACC002", Name: "Account 2", Currency: "USD"}))
+	require.NoError(t, l.RecordTransaction(context.Background(), models.Transaction{
+		ID: "TX001", DebitAccount: "ACC001", CreditAccount: "ACC002", Amount: usd(10),
+	}))
+
+	server := &Server{
+		router: mux.NewRouter(),
+		ledger: l,
+		config: &config.Config{EventStreamHeartbeat: time.Second},
+		auth:   testAuthenticator(t),
+	}
+	server.setupRoutes()
+
+	ts := httptest.NewServer(server.router)
+	t.Cleanup(ts.Close)
+	return ts, l
+}
+
+// readSSE returns the next n events of the stream as "id type" pairs.
+func readSSE(t *testing.T, scanner *bufio.Scanner, n int) []string {
+	var events []string
+	var id string
+	for len(events) < n && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			events = append(events, id+" "+strings.TrimPrefix(line, "event: "))
+		}
+	}
+	require.Len(t, events, n)
+	return events
+}
+
+func TestStreamEventsSSE(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		header string
+		want   []string
+	}{
+		{
+			name:  "all events from the start",
+			query: "?after=0",
+			want:  []string{"1 account.created", "2 account.created", "3 transaction.recorded", "4 account.frozen"},
+		},
+		{
+			name:  "account filter",
+			query: "?after=0&account=ACC002",
+			want:  []string{"2 account.created", "3 transaction.recorded", "4 account.frozen"},
+		},
+		{
+			name:  "type filter",
+			query: "?after=0&type=account.created,account.frozen",
+			want:  []string{"1 account.created", "2 account.created", "4 account.frozen"},
+		},
+		{
+			name:   "resume from Last-Event-ID",
+			header: "2",
+			want:   []string{"3 transaction.recorded", "4 account.frozen"},
+		},
+		{
+			name: "new events only",
+			want: []string{"4 account.frozen"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts, l := setupEventStreamTest(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, "GET", ts.URL+"/events"+tt.query, nil)
+			require.NoError(t, err)
+			req.Header.Set(auth.APIKeyHeader, testAPIKey)
+			if tt.header != "" {
+				req.Header.Set("Last-Event-ID", tt.header)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+			// Committed while the stream is open
+			require.NoError(t, l.FreezeAccount(context.Background(), "ACC002"))
+
+			scanner := bufio.NewScanner(resp.Body)
+			assert.Equal(t, tt.want, readSSE(t, scanner, len(tt.want)))
+		})
+	}
+}
+
+func TestStreamEventsWebSocket(t *testing.T) {
+	ts, l := setupEventStreamTest(t)
+
+	url := "ws" + strings.TrimPrefix(ts.URL, "http") + "/events?after=1&account=ACC001"
+	conn, resp, err := websocket.DefaultDialer.Dial(url, http.Header{auth.APIKeyHeader: {testAPIKey}})
+	require.NoError(t, err)
+	defer conn.Close()
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+
+	require.NoError(t, l.FreezeAccount(context.Background(), "ACC002"))
+	require.NoError(t, l.FreezeAccount(context.Background(), "ACC001"))
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	var received []models.Event
+	for len(received) < 2 {
+		var event models.Event
+		require.NoError(t, conn.ReadJSON(&event))
+		received = append(received, event)
+	}
+
+	assert.Equal(t, uint64(3), received[0].Sequence)
+	assert.Equal(t, models.EventTransactionRecorded, received[0].Type)
+	assert.Equal(t, uint64(5), received[1].Sequence)
+	assert.Equal(t, models.EventAccountFrozen, received[1].Type)
+	assert.Equal(t, []string{"ACC001"}, received[1].Accounts)
+}
+
+func TestStreamEventsInvalidRequest(t *testing.T) {
+	ts, _ := setupEventStreamTest(t)
+
+	for _, query := range []string{"?type=account.deleted", "?after=-1", "?after=abc"} {
+		req, err := http.NewRequest("GET", ts.URL+"/events"+query, nil)
+		require.NoError(t, err)
+		req.Header.Set(auth.APIKeyHeader, testAPIKey)
+
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, query)
+	}
+}
+
+func TestStreamEventsEndOnShutdown(t *testing.T) {
+	setupTestLogger(t)
+	validator, err := services.NewCurrencyValidator(&config.Config{
+		CurrencyFile: "../data/iso4217_currency_test.json",
+	})
+	require.NoError(t, err)
+
+	server := &Server{
+		router: mux.NewRouter(),
+		ledger: ledger.NewDetachedLedger(validator),
+		config: &config.Config{EventStreamHeartbeat: time.Minute},
+		auth:   testAuthenticator(t),
+	}
+	server.server = &http.Server{Handler: server.router}
+	server.endStreamsOnShutdown()
+	server.setupRoutes()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.Serve(lis) }()
+	base := "http://" + lis.Addr().String()
+
+	req, err := http.NewRequest("GET", base+"/events", nil)
+	require.NoError(t, err)
+	req.Header.Set(auth.APIKeyHeader, testAPIKey)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws://"+lis.Addr().String()+"/events",
+		http.Header{auth.APIKeyHeader: {testAPIKey}})
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	started := time.Now()
+	require.NoError(t, server.Shutdown(ctx))
+	assert.Less(t, time.Since(started), 2*time.Second)
+
+	// Both streams end instead of waiting for their clients
+	_, err = io.ReadAll(resp.Body)
+	assert.NoError(t, err)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseNormalClosure), "got %v", err)
+}
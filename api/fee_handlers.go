@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"ledgerproject/fees"
+	"ledgerproject/models"
+	"net/http"
+)
+
+// feePreview is the fee a transaction would be charged and the legs of the
+// entry that would be recorded.
+type feePreview struct {
+	Fee      models.Money     `json:"fee"`
+	Postings []models.Posting `json:"postings"`
+}
+
+func (s *Server) GetFeeRulesHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.fees.Rules())
+}
+
+// SetFeeRulesHandler replaces the whole fee rule set.
+func (s *Server) SetFeeRulesHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+
+	var rules []fees.Rule
+	if err := json.NewDecoder(r.Body).Decode(&rules); err != nil {
+		log.Error("Failed to decode fee rules", zap.Error(err))
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	before := s.fees.Rules()
+	if err := s.fees.SetRules(rules); err != nil {
+		log.Error("Failed to set fee rules", zap.Error(err))
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	after := s.fees.Rules()
+	s.recordAudit(r, actionFeeRulesUpdate, "fee-rules", before, after)
+	log.Info("Fee rules updated successfully", zap.Int("rule_count", len(rules)))
+	writeJSON(w, http.StatusOK, after)
+}
+
+// PreviewFeesHandler shows the fee a transaction would be charged without
+// recording it.
+func (s *Server) PreviewFeesHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+
+	var tx models.Transaction
+	if err := json.NewDecoder(r.Body).Decode(&tx); err != nil {
+		log.Error("Failed to decode fee preview request", zap.Error(err))
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	legs, err := s.ledger.PreviewFees(r.Context(), tx)
+	if err != nil {
+		log.Error("Failed to preview fees",
+			zap.Error(err),
+			zap.String("transaction_id", tx.ID))
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	preview := feePreview{
+		Fee:      models.Money{Amount: decimal.Zero, Currency: tx.Amount.Currency},
+		Postings: append(tx.Legs(), legs...),
+	}
+	for _, leg := range legs {
+		if leg.Amount.Amount.IsPositive() {
+			preview.Fee.Amount = preview.Fee.Amount.Add(leg.Amount.Amount)
+		}
+	}
+	writeJSON(w, http.StatusOK, preview)
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"ledgerproject/config"
+	"ledgerproject/fees"
+	"ledgerproject/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func setupFeeTest(t *testing.T) (*Server, *MockLedger) {
+	server, mockLedger := setupTest(t)
+	engine, err := fees.NewEngine(nil, &config.Config{})
+	require.NoError(t, err)
+	server.fees = engine
+	return server, mockLedger
+}
+
+func TestSetFeeRulesHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantRules  int
+	}{
+		{
+			name: "valid rules",
+			body: `[
+				{"id": "small", "account_type": "asset", "max_amount": "1000", "kind": "flat", "flat": "1.50", "revenue_account": "FEES"},
+				{"id": "large", "kind": "percentage", "rate": "0.001", "min_fee": "2", "max_fee": "50", "revenue_account": "FEES"}
+			]`,
+			wantStatus: http.StatusOK,
+			wantRules:  2,
+		},
+		{
+			name:       "invalid rule",
+			body:       `[{"id": "broken", "kind": "tiered", "revenue_account": "FEES"}]`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid json",
+			body:       `[{"id":`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := setupFeeTest(t)
+
+			rr := httptest.NewRecorder()
+			server.SetFeeRulesHandler(rr, httptest.NewRequest("PUT", "/fee-rules", bytes.NewBufferString(tt.body)))
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+
+			rr = httptest.NewRecorder()
+			server.GetFeeRulesHandler(rr, httptest.NewRequest("GET", "/fee-rules", nil))
+			var rules []fees.Rule
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&rules))
+			assert.Len(t, rules, tt.wantRules)
+		})
+	}
+}
+
+func TestPreviewFeesHandler(t *testing.T) {
+	usd := func(amount string) models.Money {
+		return models.Money{Amount: decimal.RequireFromString(amount), Currency: "USD"}
+	}
+
+	t.Run("transfer with fee", func(t *testing.T) {
+		server, mockLedger := setupFeeTest(t)
+		mockLedger.On("PreviewFees", mock.AnythingOfType("models.Transaction")).Return([]models.Posting{
+			{Account: "1001", Amount: usd("-2.50")},
+			{Account: "FEES", Amount: usd("2.50")},
+		}, nil)
+
+		body := `{"id":"tx1","debit_account":"1001","credit_account":"2001","amount":{"amount":"100","currency":"USD"}}`
+		rr := httptest.NewRecorder()
+		server.PreviewFeesHandler(rr, httptest.NewRequest("POST", "/fees/preview", bytes.NewBufferString(body)))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var preview feePreview
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&preview))
+		assert.Equal(t, "2.5", preview.Fee.Amount.String())
+		assert.Equal(t, "USD", preview.Fee.Currency)
+		assert.Len(t, preview.Postings, 4)
+	})
+
+	t.Run("transfer without fee", func(t *testing.T) {
+		server, mockLedger := setupFeeTest(t)
+		mockLedger.On("PreviewFees", mock.AnythingOfType("models.Transaction")).Return(nil, nil)
+
+		body := `{"id":"tx1","debit_account":"1001","credit_account":"2001","amount":{"amount":"100","currency":"USD"}}`
+		rr := httptest.NewRecorder()
+		server.PreviewFeesHandler(rr, httptest.NewRequest("POST", "/fees/preview", bytes.NewBufferString(body)))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var preview feePreview
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&preview))
+		assert.True(t, preview.Fee.Amount.IsZero())
+		assert.Len(t, preview.Postings, 2)
+	})
+
+	t.Run("unknown account", func(t *testing.T) {
+		server, mockLedger := setupFeeTest(t)
+		mockLedger.On("PreviewFees", mock.AnythingOfType("models.Transaction")).
+			Return(nil, fmt.Errorf("debit account NONEXISTENT does not exist"))
+
+		body := `{"id":"tx1","debit_account":"NONEXISTENT","credit_account":"2001","amount":{"amount":"100","currency":"USD"}}`
+		rr := httptest.NewRecorder()
+		server.PreviewFeesHandler(rr, httptest.NewRequest("POST", "/fees/preview", bytes.NewBufferString(body)))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
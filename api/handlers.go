@@ -4,82 +4,86 @@ import (
 	"encoding/json"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
+	"ledgerproject/importer"
 	"ledgerproject/logger"
 	"ledgerproject/models"
 	"net/http"
+	"time"
 )
 
 func (s *Server) CreateAccountHandler(w http.ResponseWriter, r *http.Request) {
-	log := logger.Get()
+	log := requestLogger(r)
 	var account models.Account
 	if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
-		// X-Forwarded-For header
-		clientIP := r.Header.Get("X-Forwarded-For")
-		// If not present, fallback to RemoteAddr
-		if clientIP == "" {
-			clientIP = r.RemoteAddr
-		}
-
 		log.Error("Failed to decode account creation request",
 			zap.Error(err),
-			zap.String("remote_addr", clientIP))
+			zap.String("remote_addr", clientIP(r)))
 
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	if err := s.ledger.CreateAccount(account); err != nil {
+	if err := s.ledger.CreateAccount(r.Context(), account); err != nil {
 		log.Error("Failed to create account",
 			zap.Error(err),
 			zap.String("account_id", account.ID))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
+	account.Frozen = false
+	s.recordAudit(r, actionAccountCreate, account.ID, nil, account)
 	log.Info("Account created successfully", zap.String("account_id", account.ID))
 	w.WriteHeader(http.StatusCreated)
 }
 
 func (s *Server) RecordTransactionHandler(w http.ResponseWriter, r *http.Request) {
-	log := logger.Get()
+	log := requestLogger(r)
 	var tx models.Transaction
 	if err := json.NewDecoder(r.Body).Decode(&tx); err != nil {
-		clientIP := r.Header.Get("X-Forwarded-For")
-		if clientIP == "" {
-			clientIP = r.RemoteAddr
-		}
-
 		log.Error("Failed to decode record transaction request",
 			zap.Error(err),
-			zap.String("remote_addr", clientIP))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+			zap.String("remote_addr", clientIP(r)))
+		writeError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
-	if err := s.ledger.RecordTransaction(tx); err != nil {
+	// Only the owners of the debited accounts may move money out of them
+	for _, leg := range tx.Legs() {
+		if leg.Amount.Amount.IsNegative() && !allowAccount(w, r, leg.Account) {
+			return
+		}
+	}
+
+	if err := s.ledger.RecordTransaction(r.Context(), tx); err != nil {
 		log.Error("Failed to record transaction",
 			zap.Error(err),
 			zap.String("transaction_id", tx.ID))
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeError(w, r, err, http.StatusBadRequest)
 		return
 	}
 
+	s.recordAudit(r, actionTransactionRecord, tx.ID, nil, tx)
 	log.Info("Transaction recorded successfully", zap.String("transaction_id", tx.ID))
 	w.WriteHeader(http.StatusCreated)
 }
 
 func (s *Server) GetBalanceHandler(w http.ResponseWriter, r *http.Request) {
-	log := logger.Get()
+	log := requestLogger(r)
 	vars := mux.Vars(r)
 	accountID := vars["accountId"]
 
-	balance, err := s.ledger.GetAccountBalance(accountID)
+	if !allowAccount(w, r, accountID) {
+		return
+	}
+
+	balance, err := s.ledger.GetAccountBalance(r.Context(), accountID)
 	if err != nil {
 		log.Error("Failed to get account balance",
 			zap.Error(err),
 			zap.String("account_id", accountID),
 		)
-		http.Error(w, err.Error(), http.StatusNotFound)
+		writeError(w, r, err, http.StatusNotFound)
 		return
 	}
 
@@ -90,18 +94,22 @@ func (s *Server) GetBalanceHandler(w http.ResponseWriter, r *http.Request) {
 		log.Error("Failed to encode balance response",
 			zap.Error(err),
 			zap.String("account_id", accountID))
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, "Failed to encode response")
 		return
 	}
 
 }
 
 func (s *Server) GetTransactionHistoryHandler(w http.ResponseWriter, r *http.Request) {
-	log := logger.Get()
+	log := requestLogger(r)
 	vars := mux.Vars(r)
 	accountID := vars["accountId"]
 
-	history := s.ledger.GetTransactionHistory(accountID)
+	if !allowAccount(w, r, accountID) {
+		return
+	}
+
+	history := s.ledger.GetTransactionHistory(r.Context(), accountID)
 	log.Info("Successfully generated transaction history", zap.String("account_id", accountID))
 
 	// Set content type header
@@ -112,7 +120,196 @@ func (s *Server) GetTransactionHistoryHandler(w http.ResponseWriter, r *http.Req
 		log.Error("Failed to encode transaction history",
 			zap.Error(err),
 			zap.String("account_id", accountID))
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		writeProblem(w, r, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+}
+
+// FreezeAccountHandler blocks all postings to and from an account.
+func (s *Server) FreezeAccountHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+	accountID := mux.Vars(r)["accountId"]
+
+	if err := s.ledger.FreezeAccount(r.Context(), accountID); err != nil {
+		log.Error("Failed to freeze account",
+			zap.Error(err),
+			zap.String("account_id", accountID))
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	s.recordAudit(r, actionAccountFreeze, accountID, map[string]bool{"frozen": false}, map[string]bool{"frozen": true})
+	log.Info("Account frozen successfully", zap.String("account_id", accountID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) UnfreezeAccountHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+	accountID := mux.Vars(r)["accountId"]
+
+	if err := s.ledger.UnfreezeAccount(r.Context(), accountID); err != nil {
+		log.Error("Failed to unfreeze account",
+			zap.Error(err),
+			zap.String("account_id", accountID))
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	s.recordAudit(r, actionAccountUnfreeze, accountID, map[string]bool{"frozen": true}, map[string]bool{"frozen": false})
+	log.Info("Account unfrozen successfully", zap.String("account_id", accountID))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListAccountsHandler returns the accounts, optionally filtered by the
+// metadata_key, metadata_value and tag query parameters. Clients only see
+// their own accounts.
+func (s *Server) ListAccountsHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+	filter := metadataFilter(r)
+	allowed := accountFilter(r)
+
+	accounts := []models.Account{}
+	for _, account := range s.ledger.FindAccounts(r.Context(), filter) {
+		if allowed(account.ID) {
+			accounts = append(accounts, account)
+		}
+	}
+	log.Info("Successfully listed accounts", zap.Int("count", len(accounts)))
+	writeJSON(w, http.StatusOK, accounts)
+}
+
+// ListTransactionsHandler returns the transactions, optionally filtered by
+// the metadata_key, metadata_value and tag query parameters. Clients only see
+// transactions that touch one of their accounts.
+func (s *Server) ListTransactionsHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+	filter := metadataFilter(r)
+	allowed := accountFilter(r)
+
+	transactions := []models.Transaction{}
+	for _, tx := range s.ledger.FindTransactions(r.Context(), filter) {
+		for _, leg := range tx.Legs() {
+			if allowed(leg.Account) {
+				transactions = append(transactions, tx)
+				break
+			}
+		}
+	}
+	log.Info("Successfully listed transactions", zap.Int("count", len(transactions)))
+	writeJSON(w, http.StatusOK, transactions)
+}
+
+func metadataFilter(r *http.Request) models.MetadataFilter {
+	query := r.URL.Query()
+	return models.MetadataFilter{
+		Key:   query.Get("metadata_key"),
+		Value: query.Get("metadata_value"),
+		Tag:   query.Get("tag"),
+	}
+}
+
+// ImportJournalHandler loads an hledger or beancount journal sent as the
+// request body. Pass dry_run=true to only validate it.
+func (s *Server) ImportJournalHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+	format := importer.Format(r.URL.Query().Get("format"))
+	if format == "" {
+		format = importer.FormatHledger
+	}
+	dryRun := r.URL.Query().Get("dry_run") == "true"
+
+	report := s.importer.Import(r.Context(), r.Body, format, dryRun)
+
+	status := http.StatusOK
+	switch {
+	case !report.OK():
+		log.Error("Journal import failed",
+			zap.String("format", string(format)),
+			zap.Bool("dry_run", dryRun))
+		status = http.StatusBadRequest
+	case report.Committed:
+		s.recordAudit(r, actionJournalImport, string(format), nil,
+			map[string]int{"transactions": report.Transactions})
+		log.Info("Journal imported successfully",
+			zap.String("format", string(format)),
+			zap.Int("transactions", report.Transactions))
+		status = http.StatusCreated
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		log.Error("Failed to encode import report", zap.Error(err))
+	}
+}
+
+// GetCamt053StatementHandler serves an ISO 20022 camt.053 statement. The
+// period is given as from/to dates (YYYY-MM-DD, end exclusive) and defaults
+// to the current month up to now.
+func (s *Server) GetCamt053StatementHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+	vars := mux.Vars(r)
+	accountID := vars["accountId"]
+
+	if !allowAccount(w, r, accountID) {
+		return
+	}
+
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := now
+
+	for param, target := range map[string]*time.Time{"from": &from, "to": &to} {
+		value := r.URL.Query().Get(param)
+		if value == "" {
+			continue
+		}
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			log.Error("Invalid statement period",
+				zap.Error(err),
+				zap.String("account_id", accountID))
+			writeProblem(w, r, http.StatusBadRequest, "invalid "+param+" date, expected YYYY-MM-DD")
+			return
+		}
+		*target = parsed
+	}
+
+	if !from.Before(to) {
+		writeProblem(w, r, http.StatusBadRequest, "statement period start must be before its end")
+		return
+	}
+
+	doc, err := s.statements.Camt053(r.Context(), accountID, from, to)
+	if err != nil {
+		log.Error("Failed to generate statement",
+			zap.Error(err),
+			zap.String("account_id", accountID))
+		writeError(w, r, err, http.StatusNotFound)
 		return
 	}
+
+	body, err := doc.Marshal()
+	if err != nil {
+		log.Error("Failed to encode statement",
+			zap.Error(err),
+			zap.String("account_id", accountID))
+		writeProblem(w, r, http.StatusInternalServerError, "Failed to encode response")
+		return
+	}
+
+	log.Info("Statement generated successfully", zap.String("account_id", accountID))
+	w.Header().Set("Content-Type", "application/xml")
+	if _, err := w.Write(body); err != nil {
+		log.Error("Failed to write statement", zap.Error(err), zap.String("account_id", accountID))
+	}
+}
+
+// writeJSON encodes v as the JSON response body with the given status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Get().Error("Failed to encode response", zap.Error(err))
+	}
 }
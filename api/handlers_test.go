@@ -3,14 +3,22 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"encoding/xml"
 	"fmt"
 	"github.com/gorilla/mux"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest"
+	"ledgerproject/audit"
+	"ledgerproject/config"
+	"ledgerproject/importer"
 	"ledgerproject/logger"
 	"ledgerproject/models"
+	"ledgerproject/services"
+	"ledgerproject/statements"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -42,6 +50,7 @@ func setupTest(t *testing.T) (*Server, *MockLedger) {
 	server := &Server{
 		router: mux.NewRouter(),
 		ledger: mockLedger,
+		audit:  audit.NewMemoryLog(),
 	}
 	return server, mockLedger
 }
@@ -299,3 +308,203 @@ func TestGetTransactionHistoryHandler(t *testing.T) {
 		mockLedger.AssertExpectations(t)
 	})
 }
+
+func TestListAccountsHandler(t *testing.T) {
+	server, mockLedger := setupTest(t)
+
+	filter := models.MetadataFilter{Key: "cost_centre", Value: "CC-42", Tag: "vip"}
+	mockLedger.On("FindAccounts", filter).Return([]models.Account{
+		{
+			ID:       "ACC123",
+			Currency: "USD",
+			Balance:  models.Money{Amount: decimal.Zero, Currency: "USD"},
+			Metadata: map[string]string{"cost_centre": "CC-42"},
+			Tags:     []string{"vip"},
+		},
+	})
+
+	req := httptest.NewRequest("GET", "/accounts?metadata_key=cost_centre&metadata_value=CC-42&tag=vip", nil)
+	rr := httptest.NewRecorder()
+
+	server.ListAccountsHandler(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response []models.Account
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	require.Len(t, response, 1)
+	assert.Equal(t, "CC-42", response[0].Metadata["cost_centre"])
+	assert.Equal(t, []string{"vip"}, response[0].Tags)
+	mockLedger.AssertExpectations(t)
+}
+
+func TestListTransactionsHandler(t *testing.T) {
+	server, mockLedger := setupTest(t)
+
+	mockLedger.On("FindTransactions", models.MetadataFilter{Value: "ORD-1"}).Return([]models.Transaction{
+		{
+			ID:       "TX1",
+			Amount:   models.Money{Amount: decimal.NewFromInt(10), Currency: "USD"},
+			Metadata: map[string]string{"order_id": "ORD-1"},
+		},
+	})
+	mockLedger.On("FindTransactions", models.MetadataFilter{}).Return([]models.Transaction{})
+
+	req := httptest.NewRequest("GET", "/transactions?metadata_value=ORD-1", nil)
+	rr := httptest.NewRecorder()
+	server.ListTransactionsHandler(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response []models.Transaction
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	require.Len(t, response, 1)
+	assert.Equal(t, "ORD-1", response[0].Metadata["order_id"])
+
+	rr = httptest.NewRecorder()
+	server.ListTransactionsHandler(rr, httptest.NewRequest("GET", "/transactions", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, "[]", rr.Body.String())
+	mockLedger.AssertExpectations(t)
+}
+
+// ImportJournalHandler tests
+func TestImportJournalHandler(t *testing.T) {
+	setupImport := func(t *testing.T) (*Server, *MockLedger) {
+		server, mockLedger := setupTest(t)
+		validator, err := services.NewCurrencyValidator(&config.Config{
+			CurrencyFile: "../data/iso4217_currency_test.json",
+		})
+		if err != nil {
+			t.Fatalf("Failed to load currencies: %v", err)
+		}
+		server.importer = importer.NewImporter(mockLedger, validator)
+		return server, mockLedger
+	}
+
+	journal := "2024-01-01 Opening\n    assets:bank  0 USD\n    equity:opening\n"
+
+	t.Run("dry run", func(t *testing.T) {
+		server, mockLedger := setupImport(t)
+		mockLedger.On("GetAccountBalance", mock.Anything).Return(models.Money{}, fmt.Errorf("account not found"))
+
+		req := httptest.NewRequest("POST", "/imports?format=hledger&dry_run=true", bytes.NewBufferString(journal))
+		rr := httptest.NewRecorder()
+
+		server.ImportJournalHandler(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+
+		var report importer.Report
+		if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		assert.True(t, report.DryRun)
+		assert.False(t, report.Committed)
+		assert.Equal(t, 1, report.Transactions)
+		mockLedger.AssertNotCalled(t, "ImportBatch", mock.Anything, mock.Anything)
+	})
+
+	t.Run("commit", func(t *testing.T) {
+		server, mockLedger := setupImport(t)
+		mockLedger.On("GetAccountBalance", mock.Anything).Return(models.Money{}, fmt.Errorf("account not found"))
+		mockLedger.On("ImportBatch", mock.Anything, mock.Anything).Return(nil).Once()
+
+		req := httptest.NewRequest("POST", "/imports?format=hledger", bytes.NewBufferString(journal))
+		rr := httptest.NewRecorder()
+
+		server.ImportJournalHandler(rr, req)
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		mockLedger.AssertExpectations(t)
+	})
+
+	t.Run("parse errors", func(t *testing.T) {
+		server, _ := setupImport(t)
+
+		req := httptest.NewRequest("POST", "/imports?format=beancount", bytes.NewBufferString("not a journal\n"))
+		rr := httptest.NewRecorder()
+
+		server.ImportJournalHandler(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+
+		var report importer.Report
+		if err := json.NewDecoder(rr.Body).Decode(&report); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		assert.NotEmpty(t, report.ParseErrors)
+	})
+}
+
+// GetCamt053StatementHandler tests
+func TestGetCamt053StatementHandler(t *testing.T) {
+	t.Run("successful statement", func(t *testing.T) {
+		server, mockLedger := setupTest(t)
+		server.statements = statements.NewGenerator(mockLedger)
+
+		accountID := "ACC123"
+		mockLedger.On("GetAccountBalance", accountID).Return(models.Money{
+			Amount:   decimal.NewFromInt(100),
+			Currency: "USD",
+		}, nil)
+		mockLedger.On("GetTransactionHistory", accountID).Return([]models.Transaction{
+			{
+				ID:            "TX1",
+				DateTime:      time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC),
+				DebitAccount:  "ACC999",
+				CreditAccount: accountID,
+				Amount: models.Money{
+					Amount:   decimal.NewFromInt(40),
+					Currency: "USD",
+				},
+			},
+		})
+
+		req := httptest.NewRequest("GET", "/accounts/"+accountID+"/statements/camt053?from=2024-03-01&to=2024-04-01", nil)
+		req = mux.SetURLVars(req, map[string]string{"accountId": accountID})
+		rr := httptest.NewRecorder()
+
+		server.GetCamt053StatementHandler(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.Equal(t, "application/xml", rr.Header().Get("Content-Type"))
+
+		var doc statements.Document
+		if err := xml.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+			t.Fatalf("Failed to decode response: %v", err)
+		}
+		stmt := doc.Stmt.Statements[0]
+		assert.Equal(t, "60", stmt.Balances[0].Amount.Value)
+		assert.Equal(t, "100", stmt.Balances[1].Amount.Value)
+		assert.Len(t, stmt.Entries, 1)
+		mockLedger.AssertExpectations(t)
+	})
+
+	t.Run("invalid date", func(t *testing.T) {
+		server, mockLedger := setupTest(t)
+		server.statements = statements.NewGenerator(mockLedger)
+
+		req := httptest.NewRequest("GET", "/accounts/ACC123/statements/camt053?from=March", nil)
+		req = mux.SetURLVars(req, map[string]string{"accountId": "ACC123"})
+		rr := httptest.NewRecorder()
+
+		server.GetCamt053StatementHandler(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("account not found", func(t *testing.T) {
+		server, mockLedger := setupTest(t)
+		server.statements = statements.NewGenerator(mockLedger)
+
+		mockLedger.On("GetAccountBalance", "NONEXISTENT").Return(models.Money{}, fmt.Errorf("account not found"))
+
+		req := httptest.NewRequest("GET", "/accounts/NONEXISTENT/statements/camt053?from=2024-03-01&to=2024-04-01", nil)
+		req = mux.SetURLVars(req, map[string]string{"accountId": "NONEXISTENT"})
+		rr := httptest.NewRecorder()
+
+		server.GetCamt053StatementHandler(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+		mockLedger.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"fmt"
+	"ledgerproject/ledger"
+	"ledgerproject/tenants"
+	"net/http"
+	"time"
+)
+
+// BuildInfo identifies the running binary. main fills it in from the
+// version and build time the Makefile links in.
+type BuildInfo struct {
+	Version   string
+	BuildTime string
+}
+
+// publicPaths are served without authentication or rate limiting, for the
+// orchestrator's probes and the API's clients.
+var publicPaths = map[string]bool{
+	"/healthz":      true,
+	"/readyz":       true,
+	"/openapi.json": true,
+}
+
+func isPublic(r *http.Request) bool {
+	return publicPaths[routeTemplate(r)]
+}
+
+// SetReady marks the server ready to take traffic once every service has
+// been built and started, and not ready again when it starts shutting down.
+func (s *Server) SetReady(ready bool) {
+	s.ready.Store(ready)
+}
+
+type readinessCheck struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+type readinessResponse struct {
+	Status string           `json:"status"`
+	Checks []readinessCheck `json:"checks"`
+}
+
+type balanceCheckStatus struct {
+	At       time.Time `json:"at"`
+	Duration string    `json:"duration"`
+	Balanced bool      `json:"balanced"`
+	Error    string    `json:"error,omitempty"`
+}
+
+type tenantStatus struct {
+	ID               string              `json:"id"`
+	Accounts         int                 `json:"accounts"`
+	Transactions     int                 `json:"transactions"`
+	Events           uint64              `json:"events"`
+	LastBalanceCheck *balanceCheckStatus `json:"last_balance_check,omitempty"`
+}
+
+type statusResponse struct {
+	Version       string         `json:"version"`
+	BuildTime     string         `json:"build_time"`
+	StartedAt     time.Time      `json:"started_at"`
+	Uptime        string         `json:"uptime"`
+	UptimeSeconds int64          `json:"uptime_seconds"`
+	Ready         bool           `json:"ready"`
+	Balanced      bool           `json:"balanced"`
+	Tenants       []tenantStatus `json:"tenants"`
+}
+
+// HealthzHandler reports that the process is alive. It checks nothing else,
+// so that a struggling ledger is taken out of rotation rather than
+// restarted.
+func (s *Server) HealthzHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// ReadyzHandler reports whether the server should receive traffic: it has
+// finished starting, which includes loading the currency data and replaying
+// the stored audit trails and reconciliations, and no tenant's last balance
+// check failed.
+func (s *Server) ReadyzHandler(w http.ResponseWriter, r *http.Request) {
+	checks := s.readinessChecks()
+	resp := readinessResponse{Status: "ready", Checks: checks}
+	status := http.StatusOK
+	for _, check := range checks {
+		if !check.OK {
+			resp.Status = "not ready"
+			status = http.StatusServiceUnavailable
+		}
+	}
+	if status != http.StatusOK {
+		requestLogger(r).Warn("Readiness check failed")
+	}
+	writeJSON(w, status, resp)
+}
+
+// StatusHandler reports the version, uptime and size of every tenant's
+// ledger, and whether every tenant's last balance check passed.
+func (s *Server) StatusHandler(w http.ResponseWriter, r *http.Request) {
+	uptime := time.Since(s.startedAt)
+	resp := statusResponse{
+		Version:       s.build.Version,
+		BuildTime:     s.build.BuildTime,
+		StartedAt:     s.startedAt,
+		Uptime:        uptime.Round(time.Second).String(),
+		UptimeSeconds: int64(uptime.Seconds()),
+		Ready:         s.ready.Load(),
+		Balanced:      true,
+		Tenants:       []tenantStatus{},
+	}
+	for _, tl := range s.tenantLedgers() {
+		stats := tl.ledger.Stats()
+		ts := tenantStatus{
+			ID:           tl.id,
+			Accounts:     stats.Accounts,
+			Transactions: stats.Transactions,
+			Events:       stats.Events,
+		}
+		if check := stats.LastBalanceCheck; check != nil {
+			ts.LastBalanceCheck = &balanceCheckStatus{
+				At:       check.At,
+				Duration: check.Duration.String(),
+				Balanced: check.Err == nil,
+			}
+			if check.Err != nil {
+				ts.LastBalanceCheck.Error = check.Err.Error()
+				resp.Balanced = false
+			}
+		}
+		resp.Tenants = append(resp.Tenants, ts)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) readinessChecks() []readinessCheck {
+	started := readinessCheck{Name: "started", OK: s.ready.Load()}
+	if !started.OK {
+		started.Error = "server is starting or shutting down"
+	}
+
+	currencies := readinessCheck{Name: "currencies", OK: s.currencies != nil && s.currencies.Len() > 0}
+	if !currencies.OK {
+		currencies.Error = "currency data is not loaded"
+	}
+
+	balance := readinessCheck{Name: "balance", OK: true}
+	for _, tl := range s.tenantLedgers() {
+		if check := tl.ledger.Stats().LastBalanceCheck; check != nil && check.Err != nil {
+			balance.OK = false
+			balance.Error = fmt.Sprintf("tenant %s: %v", tl.id, check.Err)
+			break
+		}
+	}
+	return []readinessCheck{started, currencies, balance}
+}
+
+type tenantLedger struct {
+	id     string
+	ledger ledger.LedgerService
+}
+
+// tenantLedgers returns the ledger of every tenant, ordered by tenant ID.
+func (s *Server) tenantLedgers() []tenantLedger {
+	if s.tenants == nil {
+		return []tenantLedger{{id: tenants.DefaultID, ledger: s.ledger}}
+	}
+	var ledgers []tenantLedger
+	for _, t := range s.tenants.List() {
+		ledgers = append(ledgers, tenantLedger{id: t.ID, ledger: t.Ledger})
+	}
+	return ledgers
+}
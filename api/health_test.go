@@ -0,0 +1,183 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ledgerproject/audit"
+	"ledgerproject/auth"
+	"ledgerproject/config"
+	"ledgerproject/ledger"
+	"ledgerproject/services"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func setupHealthTest(t *testing.T, stats ledger.Stats) *Server {
+	setupTestLogger(t)
+	cfg := &config.Config{
+		CurrencyFile:  "../data/iso4217_currency_test.json",
+		ReadRateLimit: config.RateLimit{Rate: 0.01, Burst: 1},
+	}
+	validator, err := services.NewCurrencyValidator(cfg)
+	require.NoError(t, err)
+
+	mockLedger := new(MockLedger)
+	mockLedger.On("Stats").Return(stats)
+	return NewServer(ServerParams{
+		Ledger:     mockLedger,
+		Config:     cfg,
+		Auth:       testAuthenticator(t),
+		Audit:      audit.NewMemoryLog(),
+		Currencies: validator,
+		Build:      BuildInfo{Version: "abc1234", BuildTime: "2026-10-18_12:00:00"},
+	})
+}
+
+func TestHealthz(t *testing.T) {
+	server := setupHealthTest(t, ledger.Stats{})
+
+	// Probes need no credentials and are not rate limited
+	for i := 0; i < 3; i++ {
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, httptest.NewRequest("GET", "/healthz", nil))
+		assert.Equal(t, http.StatusOK, rr.Code)
+		assert.JSONEq(t, `{"status":"ok"}`, rr.Body.String())
+		assert.Empty(t, rr.Header().Get("RateLimit-Limit"))
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	unbalanced := errors.New("ledger is unbalanced for USD: total balance is 10")
+
+	tests := []struct {
+		name       string
+		ready      bool
+		stats      ledger.Stats
+		wantStatus int
+		wantFailed string
+	}{
+		{
+			name:       "starting",
+			ready:      false,
+			wantStatus: http.StatusServiceUnavailable,
+			wantFailed: "started",
+		},
+		{
+			name:       "ready before the first balance check",
+			ready:      true,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "ready after a passing balance check",
+			ready:      true,
+			stats:      ledger.Stats{LastBalanceCheck: &ledger.BalanceCheck{At: time.Now()}},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "failed balance check",
+			ready:      true,
+			stats:      ledger.Stats{LastBalanceCheck: &ledger.BalanceCheck{At: time.Now(), Err: unbalanced}},
+			wantStatus: http.StatusServiceUnavailable,
+			wantFailed: "balance",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := setupHealthTest(t, tt.stats)
+			server.SetReady(tt.ready)
+
+			rr := httptest.NewRecorder()
+			server.router.ServeHTTP(rr, httptest.NewRequest("GET", "/readyz", nil))
+			assert.Equal(t, tt.wantStatus, rr.Code)
+
+			var resp readinessResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			for _, check := range resp.Checks {
+				assert.Equal(t, check.Name != tt.wantFailed, check.OK, check.Name)
+			}
+			if tt.wantFailed == "balance" {
+				assert.Contains(t, rr.Body.String(), "tenant default: ledger is unbalanced")
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	server := setupHealthTest(t, ledger.Stats{
+		Accounts:     2,
+		Transactions: 5,
+		Events:       7,
+		LastBalanceCheck: &ledger.BalanceCheck{
+			At:       time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+			Duration: 3 * time.Millisecond,
+		},
+	})
+	server.SetReady(true)
+
+	send := func(key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/status", nil)
+		if key != "" {
+			req.Header.Set(auth.APIKeyHeader, key)
+		}
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, send("").Code)
+	assert.Equal(t, http.StatusForbidden, send("client-key").Code)
+
+	rr := send(testAPIKey)
+	require.Equal(t, http.StatusOK, rr.Code)
+	var resp statusResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "abc1234", resp.Version)
+	assert.Equal(t, "2026-10-18_12:00:00", resp.BuildTime)
+	assert.True(t, resp.Ready)
+	assert.True(t, resp.Balanced)
+	assert.GreaterOrEqual(t, resp.UptimeSeconds, int64(0))
+	assert.Equal(t, []tenantStatus{{
+		ID:           "default",
+		Accounts:     2,
+		Transactions: 5,
+		Events:       7,
+		LastBalanceCheck: &balanceCheckStatus{
+			At:       time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC),
+			Duration: "3ms",
+			Balanced: true,
+		},
+	}}, resp.Tenants)
+}
+
+func TestStatusUnbalanced(t *testing.T) {
+	server := setupHealthTest(t, ledger.Stats{LastBalanceCheck: &ledger.BalanceCheck{
+		At:  time.Now(),
+		Err: errors.New("ledger is unbalanced for USD: total balance is 10"),
+	}})
+	server.SetReady(true)
+
+	// The failure takes the server out of rotation and is detailed in /status
+	req := httptest.NewRequest("GET", "/readyz", nil)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusServiceUnavailable, rr.Code)
+
+	req = httptest.NewRequest("GET", "/status", nil)
+	req.Header.Set(auth.APIKeyHeader, testAPIKey)
+	rr = httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var resp statusResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.True(t, resp.Ready)
+	assert.False(t, resp.Balanced)
+	require.Len(t, resp.Tenants, 1)
+	assert.False(t, resp.Tenants[0].LastBalanceCheck.Balanced)
+	assert.Contains(t, resp.Tenants[0].LastBalanceCheck.Error, "ledger is unbalanced")
+}
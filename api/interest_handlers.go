@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"ledgerproject/interest"
+	"ledgerproject/logger"
+	"net/http"
+)
+
+// ConfigureInterestHandler sets the interest rate, day-count convention and
+// counter account of an account.
+func (s *Server) ConfigureInterestHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+	accountID := mux.Vars(r)["accountId"]
+
+	var terms interest.Terms
+	if err := json.NewDecoder(r.Body).Decode(&terms); err != nil {
+		log.Error("Failed to decode interest terms",
+			zap.Error(err),
+			zap.String("account_id", accountID))
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	var before *interest.Terms
+	if previous, err := s.interest.Get(accountID); err == nil {
+		before = &previous.Terms
+	}
+
+	accrual, err := s.interest.Configure(r.Context(), accountID, terms)
+	if err != nil {
+		log.Error("Failed to configure interest",
+			zap.Error(err),
+			zap.String("account_id", accountID))
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	s.recordAudit(r, actionInterestConfigure, accountID, before, accrual.Terms)
+	log.Info("Interest configured successfully", zap.String("account_id", accountID))
+	s.writeAccrual(w, accrual)
+}
+
+func (s *Server) GetInterestHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+	accountID := mux.Vars(r)["accountId"]
+
+	if !allowAccount(w, r, accountID) {
+		return
+	}
+
+	accrual, err := s.interest.Get(accountID)
+	if err != nil {
+		log.Error("Failed to get interest",
+			zap.Error(err),
+			zap.String("account_id", accountID))
+		status := http.StatusBadRequest
+		if errors.Is(err, interest.ErrNotConfigured) {
+			status = http.StatusNotFound
+		}
+		writeError(w, r, err, status)
+		return
+	}
+
+	s.writeAccrual(w, accrual)
+}
+
+func (s *Server) writeAccrual(w http.ResponseWriter, accrual *interest.Accrual) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(accrual); err != nil {
+		logger.Get().Error("Failed to encode interest accrual",
+			zap.Error(err),
+			zap.String("account_id", accrual.AccountID))
+	}
+}
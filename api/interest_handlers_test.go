@@ -0,0 +1,113 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ledgerproject/config"
+	"ledgerproject/interest"
+	"ledgerproject/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func setupInterestTest(t *testing.T) *Server {
+	server, mockLedger := setupTest(t)
+	server.interest = interest.NewEngine(mockLedger, nil, &config.Config{InterestAccrualInterval: time.Hour})
+
+	usd := models.Money{Amount: decimal.NewFromInt(1000), Currency: "USD"}
+	mockLedger.On("GetAccountBalance", "SAVINGS").Return(usd, nil)
+	mockLedger.On("GetAccountBalance", "EXPENSE").Return(usd, nil)
+	mockLedger.On("GetAccountBalance", "NONEXISTENT").Return(models.Money{}, fmt.Errorf("account NONEXISTENT does not exist"))
+	return server
+}
+
+func TestConfigureInterestHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		accountID  string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "valid terms",
+			accountID:  "SAVINGS",
+			body:       `{"rate":"0.045","day_count":"ACT/365","counter_account":"EXPENSE"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unsupported day count",
+			accountID:  "SAVINGS",
+			body:       `{"rate":"0.045","day_count":"ACT/ACT","counter_account":"EXPENSE"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown counter account",
+			accountID:  "SAVINGS",
+			body:       `{"rate":"0.045","day_count":"ACT/360","counter_account":"NONEXISTENT"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid json",
+			accountID:  "SAVINGS",
+			body:       `{"rate":`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := setupInterestTest(t)
+
+			req := httptest.NewRequest("PUT", "/accounts/"+tt.accountID+"/interest", bytes.NewBufferString(tt.body))
+			req = mux.SetURLVars(req, map[string]string{"accountId": tt.accountID})
+			rr := httptest.NewRecorder()
+
+			server.ConfigureInterestHandler(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus == http.StatusOK {
+				var accrual interest.Accrual
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&accrual))
+				assert.Equal(t, "0.045", accrual.Rate.String())
+				assert.Equal(t, interest.Actual365, accrual.DayCount)
+			}
+		})
+	}
+}
+
+func TestGetInterestHandler(t *testing.T) {
+	server := setupInterestTest(t)
+	_, err := server.interest.Configure(context.Background(), "SAVINGS", interest.Terms{
+		Rate: decimal.RequireFromString("0.02"), DayCount: interest.Thirty360, CounterAccount: "EXPENSE",
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		accountID  string
+		wantStatus int
+	}{
+		{name: "configured account", accountID: "SAVINGS", wantStatus: http.StatusOK},
+		{name: "account without interest", accountID: "EXPENSE", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/accounts/"+tt.accountID+"/interest", nil)
+			req = mux.SetURLVars(req, map[string]string{"accountId": tt.accountID})
+			rr := httptest.NewRecorder()
+
+			server.GetInterestHandler(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+		})
+	}
+}
@@ -4,35 +4,87 @@ package api
 import (
 	"context"
 	"github.com/stretchr/testify/mock"
+	"ledgerproject/ledger"
 	"ledgerproject/models"
 )
 
-// MockLedger implements the LedgerService interface for testing
+// MockLedger implements the LedgerService interface for testing. The
+// context is not part of the recorded calls.
 type MockLedger struct {
 	mock.Mock
 }
 
-func (m *MockLedger) CreateAccount(account models.Account) error {
+func (m *MockLedger) CreateAccount(ctx context.Context, account models.Account) error {
 	args := m.Called(account)
 	return args.Error(0)
 }
 
-func (m *MockLedger) RecordTransaction(tx models.Transaction) error {
+func (m *MockLedger) RecordTransaction(ctx context.Context, tx models.Transaction) error {
 	args := m.Called(tx)
 	return args.Error(0)
 }
 
-func (m *MockLedger) GetAccountBalance(accountID string) (models.Money, error) {
+func (m *MockLedger) RecordSystemTransaction(ctx context.Context, tx models.Transaction) error {
+	args := m.Called(tx)
+	return args.Error(0)
+}
+
+func (m *MockLedger) ImportTransaction(ctx context.Context, tx models.Transaction) error {
+	args := m.Called(tx)
+	return args.Error(0)
+}
+
+func (m *MockLedger) ImportBatch(ctx context.Context, accounts []models.Account, txs []models.Transaction) error {
+	args := m.Called(accounts, txs)
+	return args.Error(0)
+}
+
+func (m *MockLedger) GetAccountBalance(ctx context.Context, accountID string) (models.Money, error) {
 	args := m.Called(accountID)
 	return args.Get(0).(models.Money), args.Error(1)
 }
 
-func (m *MockLedger) GetTransactionHistory(accountID string) []models.Transaction {
+func (m *MockLedger) GetTransactionHistory(ctx context.Context, accountID string) []models.Transaction {
 	args := m.Called(accountID)
 	return args.Get(0).([]models.Transaction)
 }
 
-func (m *MockLedger) VerifyLedgerBalance() error {
+func (m *MockLedger) FindAccounts(ctx context.Context, filter models.MetadataFilter) []models.Account {
+	args := m.Called(filter)
+	return args.Get(0).([]models.Account)
+}
+
+func (m *MockLedger) FindTransactions(ctx context.Context, filter models.MetadataFilter) []models.Transaction {
+	args := m.Called(filter)
+	return args.Get(0).([]models.Transaction)
+}
+
+func (m *MockLedger) FreezeAccount(ctx context.Context, accountID string) error {
+	args := m.Called(accountID)
+	return args.Error(0)
+}
+
+func (m *MockLedger) UnfreezeAccount(ctx context.Context, accountID string) error {
+	args := m.Called(accountID)
+	return args.Error(0)
+}
+
+func (m *MockLedger) Events(after uint64, limit int) []models.Event {
+	args := m.Called(after, limit)
+	return args.Get(0).([]models.Event)
+}
+
+func (m *MockLedger) EventsChanged() <-chan struct{} {
+	args := m.Called()
+	return args.Get(0).(<-chan struct{})
+}
+
+func (m *MockLedger) LastSequence() uint64 {
+	args := m.Called()
+	return args.Get(0).(uint64)
+}
+
+func (m *MockLedger) VerifyLedgerBalance(ctx context.Context) error {
 	args := m.Called()
 	return args.Error(0)
 }
@@ -40,3 +92,14 @@ func (m *MockLedger) VerifyLedgerBalance() error {
 func (m *MockLedger) PerformPeriodicBalanceCheck(ctx context.Context) {
 	m.Called(ctx)
 }
+
+func (m *MockLedger) PreviewFees(ctx context.Context, tx models.Transaction) ([]models.Posting, error) {
+	args := m.Called(tx)
+	postings, _ := args.Get(0).([]models.Posting)
+	return postings, args.Error(1)
+}
+
+func (m *MockLedger) Stats() ledger.Stats {
+	args := m.Called()
+	return args.Get(0).(ledger.Stats)
+}
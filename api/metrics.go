@@ -0,0 +1,88 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/gorilla/mux"
+	"net"
+	"net/http"
+	"time"
+)
+
+// instrument counts every routed request and times it by route template,
+// method and status code.
+func (s *Server) instrument(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.metrics == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+
+		s.metrics.ObserveRequest(routeTemplate(r), r.Method, sw.status, time.Since(start))
+	})
+}
+
+// routeTemplate returns the path template of the route serving r, such as
+// /accounts/{accountId}/balance, or the path itself outside of a route.
+func routeTemplate(r *http.Request) string {
+	if current := mux.CurrentRoute(r); current != nil {
+		if tmpl, err := current.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return r.URL.Path
+}
+
+// MetricsHandler serves the platform metrics in the Prometheus text format.
+func (s *Server) MetricsHandler(w http.ResponseWriter, r *http.Request) {
+	if s.metrics == nil {
+		writeProblem(w, r, http.StatusNotFound, "metrics are not enabled")
+		return
+	}
+	s.metrics.Handler().ServeHTTP(w, r)
+}
+
+// statusWriter remembers the status code of a response. It passes flushes
+// and hijacks through for the event stream.
+type statusWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (w *statusWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response does not support hijacking")
+	}
+	w.status = http.StatusSwitchingProtocols
+	w.wroteHeader = true
+	return h.Hijack()
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
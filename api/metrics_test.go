@@ -0,0 +1,61 @@
+package api
+
+import (
+	"github.com/stretchr/testify/assert"
+	"ledgerproject/audit"
+	"ledgerproject/auth"
+	"ledgerproject/config"
+	"ledgerproject/metrics"
+	"ledgerproject/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestMetricsHandler(t *testing.T) {
+	setupTestLogger(t)
+	mockLedger := new(MockLedger)
+	mockLedger.On("GetAccountBalance", "ACC001").Return(models.Money{}, nil)
+	server := NewServer(ServerParams{
+		Ledger:  mockLedger,
+		Config:  &config.Config{},
+		Auth:    testAuthenticator(t),
+		Audit:   audit.NewMemoryLog(),
+		Metrics: metrics.New(),
+	})
+
+	send := func(path, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.Header.Set(auth.APIKeyHeader, key)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+	send("/accounts/ACC001/balance", testAPIKey)
+	send("/accounts/ACC001/balance", "nobody")
+
+	tests := []struct {
+		name       string
+		key        string
+		wantStatus int
+	}{
+		{name: "admin", key: testAPIKey, wantStatus: http.StatusOK},
+		{name: "operator", key: "operator-key", wantStatus: http.StatusOK},
+		{name: "client", key: "client-key", wantStatus: http.StatusForbidden},
+		{name: "tenant admin", key: "retail-key", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := send("/metrics", tt.key)
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			body := rr.Body.String()
+			assert.Contains(t, body, `ledger_http_requests_total{method="GET",route="/accounts/{accountId}/balance",status="200"} 1`)
+			assert.Contains(t, body, `ledger_http_requests_total{method="GET",route="/accounts/{accountId}/balance",status="401"} 1`)
+			assert.Contains(t, body, `ledger_http_request_duration_seconds_bucket{method="GET",route="/accounts/{accountId}/balance",status="200"`)
+		})
+	}
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// tenantPrefix is the path under which every tenant-scoped route is also
+// served for an explicit tenant.
+const tenantPrefix = "/tenants/{tenantId}"
+
+//go:embed openapi.yaml
+var specYAML []byte
+
+// apiSpec is the OpenAPI document of the routes in setupRoutes, and
+// apiSpecJSON its JSON encoding. TestSpecMatchesRoutes fails when the
+// document and the routes diverge.
+var apiSpec, apiSpecJSON = mustLoadSpec()
+
+// mustLoadSpec parses the embedded document and adds the tenant-prefixed
+// copy of every path marked x-tenant-scoped. The document is compiled into
+// the binary, so an invalid one is a programming error.
+func mustLoadSpec() (*openapi3.T, []byte) {
+	spec, err := openapi3.NewLoader().LoadFromData(specYAML)
+	if err != nil {
+		panic(fmt.Sprintf("error loading OpenAPI spec: %v", err))
+	}
+	addTenantPaths(spec)
+	if err := spec.Validate(context.Background()); err != nil {
+		panic(fmt.Sprintf("invalid OpenAPI spec: %v", err))
+	}
+	data, err := json.Marshal(spec)
+	if err != nil {
+		panic(fmt.Sprintf("error encoding OpenAPI spec: %v", err))
+	}
+	return spec, data
+}
+
+func addTenantPaths(spec *openapi3.T) {
+	tenantID := &openapi3.ParameterRef{Value: openapi3.NewPathParameter("tenantId").
+		WithDescription("Tenant to address instead of the caller's own").
+		WithSchema(openapi3.NewStringSchema())}
+
+	for path, item := range spec.Paths.Map() {
+		if scoped, _ := item.Extensions["x-tenant-scoped"].(bool); !scoped {
+			continue
+		}
+		tenantItem := *item
+		tenantItem.Extensions = nil
+		tenantItem.Parameters = append(openapi3.Parameters{tenantID}, item.Parameters...)
+		for method, op := range item.Operations() {
+			tenantOp := *op
+			tenantOp.OperationID = op.OperationID + "ForTenant"
+			tenantItem.SetOperation(method, &tenantOp)
+		}
+		spec.Paths.Set(tenantPrefix+path, &tenantItem)
+	}
+}
+
+// OpenAPIHandler serves the OpenAPI document of the API.
+func (s *Server) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(apiSpecJSON)
+}
+
+// fieldError is one way in which a request does not match the API schema.
+// Field is the dotted path of a body field or the name of a parameter.
+type fieldError struct {
+	Field   string `json:"field"`
+	In      string `json:"in"`
+	Message string `json:"message"`
+}
+
+// limitBody caps the size of request bodies at maxBody bytes, when set.
+// Reading past it fails, and validateRequest, which reads every body of the
+// API, answers 413 Content Too Large.
+func (s *Server) limitBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.maxBody > 0 && r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, s.maxBody)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// validateRequest rejects requests whose parameters or JSON body do not
+// match the operation in the OpenAPI document, listing every offending
+// field. Other bodies, such as journals and CSV statements, are left to
+// the parsers of their handlers.
+func validateRequest(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		template := routeTemplate(r)
+		item := apiSpec.Paths.Find(template)
+		var op *openapi3.Operation
+		if item != nil {
+			op = item.GetOperation(r.Method)
+		}
+		if op == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// The validator consumes the body, so it reads a copy
+		body, err := io.ReadAll(r.Body)
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			requestLogger(r).Warn("Request body too large",
+				zap.String("method", r.Method),
+				zap.String("route", template),
+				zap.Int64("limit", tooLarge.Limit))
+			writeProblem(w, r, http.StatusRequestEntityTooLarge,
+				fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		if err != nil {
+			writeProblem(w, r, http.StatusBadRequest, "error reading request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		checked := r.Clone(r.Context())
+		checked.Body = io.NopCloser(bytes.NewReader(body))
+
+		// Malformed JSON has no fields to report; the handler's decoder
+		// answers it
+		validateBody := hasJSONBody(op) && isJSON(checked.Header.Get("Content-Type")) &&
+			(len(body) == 0 || json.Valid(body))
+		if validateBody && checked.Header.Get("Content-Type") == "" {
+			checked.Header.Set("Content-Type", "application/json")
+		}
+
+		err = openapi3filter.ValidateRequest(r.Context(), &openapi3filter.RequestValidationInput{
+			Request:    checked,
+			PathParams: mux.Vars(r),
+			Route: &routers.Route{
+				Spec:      apiSpec,
+				Path:      template,
+				PathItem:  item,
+				Method:    r.Method,
+				Operation: op,
+			},
+			Options: &openapi3filter.Options{
+				ExcludeRequestBody:  !validateBody,
+				MultiError:          true,
+				AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc,
+				SkipSettingDefaults: true,
+			},
+		})
+		if err != nil {
+			fields := fieldErrors(err)
+			requestLogger(r).Warn("Request does not match the API schema",
+				zap.String("method", r.Method),
+				zap.String("route", template),
+				zap.Int("errors", len(fields)))
+			p := newProblem(r, http.StatusBadRequest, "validation_failed", "Invalid request",
+				"the request does not match the API schema")
+			p.Errors = fields
+			sendProblem(w, p)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func hasJSONBody(op *openapi3.Operation) bool {
+	return op.RequestBody != nil && op.RequestBody.Value.GetMediaType("application/json") != nil
+}
+
+// isJSON reports whether a body of the content type is decoded as JSON.
+// Handlers treat a body without a content type as JSON.
+func isJSON(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// fieldErrors flattens the errors of the validator into one entry per
+// offending field.
+func fieldErrors(err error) []fieldError {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		var fields []fieldError
+		for _, nested := range e {
+			fields = append(fields, fieldErrors(nested)...)
+		}
+		return fields
+	case *openapi3filter.RequestError:
+		in, field := "body", ""
+		if e.Parameter != nil {
+			in, field = e.Parameter.In, e.Parameter.Name
+		}
+		switch cause := e.Err.(type) {
+		case nil:
+			return []fieldError{{Field: field, In: in, Message: e.Reason}}
+		case openapi3.MultiError:
+			var fields []fieldError
+			for _, nested := range cause {
+				fields = append(fields, schemaFieldError(in, field, nested))
+			}
+			return fields
+		default:
+			return []fieldError{schemaFieldError(in, field, cause)}
+		}
+	default:
+		return []fieldError{{In: "body", Message: err.Error()}}
+	}
+}
+
+// schemaFieldError names the field a schema error is about. Errors that are
+// not about the schema, such as a parameter that is not a number, are
+// reported on the parameter.
+func schemaFieldError(in, field string, err error) fieldError {
+	schemaErr, ok := err.(*openapi3.SchemaError)
+	if !ok {
+		return fieldError{Field: field, In: in, Message: err.Error()}
+	}
+
+	path := schemaErr.JSONPointer()
+	if field != "" {
+		path = append([]string{field}, path...)
+	}
+	message := schemaErr.Reason
+	// An unknown property is reported on its object; name the property itself
+	var property string
+	if _, scanErr := fmt.Sscanf(message, "property %q is unsupported", &property); scanErr == nil {
+		path = append(path, property)
+		message = "unknown field"
+	}
+	return fieldError{Field: strings.Join(path, "."), In: in, Message: message}
+}
@@ -0,0 +1,205 @@
+package api
+
+import (
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ledgerproject/audit"
+	"ledgerproject/auth"
+	"ledgerproject/config"
+	"ledgerproject/ledger"
+	"ledgerproject/services"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSpecMatchesRoutes(t *testing.T) {
+	server := NewServer(ServerParams{Ledger: new(MockLedger), Config: &config.Config{ServerPort: ":8080"}})
+
+	var routes []string
+	err := server.router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			// Subrouter prefixes carry no handler of their own
+			return nil
+		}
+		for _, method := range methods {
+			routes = append(routes, method+" "+template)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	var operations []string
+	for path, item := range apiSpec.Paths.Map() {
+		for method := range item.Operations() {
+			operations = append(operations, method+" "+path)
+		}
+	}
+
+	sort.Strings(routes)
+	sort.Strings(operations)
+	assert.Equal(t, routes, operations, "routes in setupRoutes and operations in openapi.yaml must match")
+}
+
+func TestOpenAPIHandler(t *testing.T) {
+	setupTestLogger(t)
+	server := NewServer(ServerParams{Ledger: new(MockLedger), Config: &config.Config{ServerPort: ":8080"}})
+
+	// The document is public, like the probes
+	req := httptest.NewRequest("GET", "/openapi.json", nil)
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var doc struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Contains(t, doc.Paths["/accounts"], "post")
+	assert.Contains(t, doc.Paths["/tenants/{tenantId}/accounts"], "post")
+	assert.Contains(t, doc.Paths["/tenants"], "post")
+	assert.NotContains(t, doc.Paths, "/tenants/{tenantId}/tenants")
+}
+
+func TestValidateRequest(t *testing.T) {
+	setupTestLogger(t)
+	validator, err := services.NewCurrencyValidator(&config.Config{
+		CurrencyFile: "../data/iso4217_currency_test.json",
+	})
+	require.NoError(t, err)
+	server := &Server{
+		router: mux.NewRouter(),
+		ledger: ledger.NewDetachedLedger(validator),
+		auth:   testAuthenticator(t),
+		audit:  audit.NewMemoryLog(),
+		// Large enough for every body below but the last
+		maxBody: 256,
+	}
+	server.setupRoutes()
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(auth.APIKeyHeader, testAPIKey)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+	require.Equal(t, http.StatusCreated, send("POST", "/accounts",
+		`{"id":"ACC001","name":"Cash","currency":"USD","type":"asset","balance":{"amount":"100","currency":"USD"}}`).Code)
+	require.Equal(t, http.StatusCreated, send("POST", "/accounts",
+		`{"id":"ACC002","name":"Revenue","currency":"USD","type":"revenue"}`).Code)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		wantErrors []fieldError
+	}{
+		{
+			name:       "valid transaction",
+			method:     "POST",
+			path:       "/transactions",
+			body:       `{"id":"TX001","debit_account":"ACC001","credit_account":"ACC002","amount":{"amount":"10","currency":"USD"}}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "unknown field",
+			method:     "POST",
+			path:       "/transactions",
+			body:       `{"id":"TX002","debit_account":"ACC001","credit_account":"ACC002","money":{"amount":"10","currency":"USD"}}`,
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []fieldError{{Field: "money", In: "body", Message: "unknown field"}},
+		},
+		{
+			name:       "wrong type of nested field",
+			method:     "POST",
+			path:       "/transactions",
+			body:       `{"id":"TX002","debit_account":"ACC001","credit_account":"ACC002","amount":{"amount":10,"currency":"USD"}}`,
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []fieldError{{Field: "amount.amount", In: "body", Message: `value must be a string`}},
+		},
+		{
+			name:       "every offending field is listed",
+			method:     "POST",
+			path:       "/tenants/default/accounts",
+			body:       `{"id":3,"name":"Cash","tags":"vip","colour":"red"}`,
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []fieldError{
+				{Field: "colour", In: "body", Message: "unknown field"},
+				{Field: "id", In: "body", Message: "value must be a string"},
+				{Field: "tags", In: "body", Message: "value must be an array"},
+			},
+		},
+		{
+			name:       "unknown field in an array item",
+			method:     "POST",
+			path:       "/transactions",
+			body:       `{"id":"TX003","postings":[{"account":"ACC001","amount":{"amount":"-1","currency":"USD"},"memo":"x"}]}`,
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []fieldError{{Field: "postings.0.memo", In: "body", Message: "unknown field"}},
+		},
+		{
+			name:       "wrong type of query parameter",
+			method:     "GET",
+			path:       "/changes?limit=ten",
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []fieldError{{Field: "limit", In: "query", Message: `value ten: an invalid integer: invalid syntax`}},
+		},
+		{
+			name:       "query parameter out of range",
+			method:     "GET",
+			path:       "/changes?after=-1",
+			wantStatus: http.StatusBadRequest,
+			wantErrors: []fieldError{{Field: "after", In: "query", Message: "number must be at least 0"}},
+		},
+		{
+			name:       "malformed JSON is left to the handler",
+			method:     "POST",
+			path:       "/transactions",
+			body:       `{"id":`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "body too large",
+			method:     "POST",
+			path:       "/transactions",
+			body:       `{"id":"TX004","description":"` + strings.Repeat("x", 300) + `"}`,
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := send(tt.method, tt.path, tt.body)
+			require.Equal(t, tt.wantStatus, rr.Code, rr.Body.String())
+			if rr.Code < 400 {
+				return
+			}
+
+			var got problem
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+			if tt.wantErrors == nil {
+				assert.NotEqual(t, "validation_failed", got.Code)
+				return
+			}
+			assert.Equal(t, "validation_failed", got.Code)
+			sort.Slice(got.Errors, func(i, j int) bool { return got.Errors[i].Field < got.Errors[j].Field })
+			assert.Equal(t, tt.wantErrors, got.Errors)
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"ledgerproject/ledger"
+	"net/http"
+	"strings"
+)
+
+// problemTypeBase prefixes the type URI of every problem; the code follows.
+const problemTypeBase = "urn:ledger:problem:"
+
+// problem is an RFC 7807 problem details body. Code is stable and meant for
+// programs; Title and Detail are for people. The account, transaction and
+// currency involved are included when known, and requests that do not match
+// the API schema list their offending fields in Errors.
+type problem struct {
+	Type          string `json:"type"`
+	Title         string `json:"title"`
+	Status        int    `json:"status"`
+	Detail        string `json:"detail,omitempty"`
+	Instance      string `json:"instance,omitempty"`
+	Code          string `json:"code"`
+	RequestID     string `json:"request_id,omitempty"`
+	AccountID     string `json:"account_id,omitempty"`
+	TransactionID string `json:"transaction_id,omitempty"`
+	Currency      string `json:"currency,omitempty"`
+
+	Errors []fieldError `json:"errors,omitempty"`
+}
+
+// ledgerProblems maps each kind of ledger error to its status and code.
+var ledgerProblems = []struct {
+	kind   error
+	status int
+	code   string
+	title  string
+}{
+	{ledger.ErrAccountNotFound, http.StatusNotFound, "account_not_found", "Account not found"},
+	{ledger.ErrDuplicate, http.StatusConflict, "duplicate", "Already exists"},
+	{ledger.ErrAccountFrozen, http.StatusConflict, "account_frozen", "Account is frozen"},
+	{ledger.ErrAccountNotFrozen, http.StatusConflict, "account_not_frozen", "Account is not frozen"},
+	{ledger.ErrInvalidCurrency, http.StatusUnprocessableEntity, "invalid_currency", "Invalid currency"},
+	{ledger.ErrCurrencyMismatch, http.StatusUnprocessableEntity, "currency_mismatch", "Currency mismatch"},
+	{ledger.ErrInsufficientFunds, http.StatusUnprocessableEntity, "insufficient_funds", "Insufficient funds"},
+	{ledger.ErrUnbalanced, http.StatusUnprocessableEntity, "unbalanced", "Books would be unbalanced"},
+	{ledger.ErrInvalid, http.StatusUnprocessableEntity, "invalid", "Invalid request"},
+}
+
+// writeError answers with the problem for err. Ledger errors get the status
+// and code of their kind; any other error gets the fallback status.
+func writeError(w http.ResponseWriter, r *http.Request, err error, fallback int) {
+	for _, lp := range ledgerProblems {
+		if !errors.Is(err, lp.kind) {
+			continue
+		}
+		p := newProblem(r, lp.status, lp.code, lp.title, err.Error())
+		var ledgerErr *ledger.Error
+		if errors.As(err, &ledgerErr) {
+			p.AccountID = ledgerErr.AccountID
+			p.TransactionID = ledgerErr.TransactionID
+			p.Currency = ledgerErr.Currency
+		}
+		sendProblem(w, p)
+		return
+	}
+	writeProblem(w, r, fallback, err.Error())
+}
+
+// writeProblem answers with a problem whose code is derived from the
+// status, such as bad_request or forbidden.
+func writeProblem(w http.ResponseWriter, r *http.Request, status int, detail string) {
+	title := http.StatusText(status)
+	code := strings.ReplaceAll(strings.ToLower(title), " ", "_")
+	sendProblem(w, newProblem(r, status, code, title, detail))
+}
+
+func newProblem(r *http.Request, status int, code, title, detail string) problem {
+	return problem{
+		Type:      problemTypeBase + code,
+		Title:     title,
+		Status:    status,
+		Detail:    detail,
+		Instance:  r.URL.Path,
+		Code:      code,
+		RequestID: requestIDFrom(r.Context()),
+	}
+}
+
+func sendProblem(w http.ResponseWriter, p problem) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
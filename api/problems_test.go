@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ledgerproject/audit"
+	"ledgerproject/auth"
+	"ledgerproject/config"
+	"ledgerproject/ledger"
+	"ledgerproject/services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLedgerProblems(t *testing.T) {
+	setupTestLogger(t)
+	validator, err := services.NewCurrencyValidator(&config.Config{
+		CurrencyFile: "../data/iso4217_currency_test.json",
+	})
+	require.NoError(t, err)
+	server := &Server{
+		router: mux.NewRouter(),
+		ledger: ledger.NewDetachedLedger(validator),
+		auth:   testAuthenticator(t),
+		audit:  audit.NewMemoryLog(),
+	}
+	server.setupRoutes()
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(auth.APIKeyHeader, testAPIKey)
+		req.Header.Set(RequestIDHeader, "problem-1")
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+	for _, account := range []string{
+		`{"id":"ACC001","name":"Cash","currency":"USD","type":"asset","balance":{"amount":"100","currency":"USD"}}`,
+		`{"id":"ACC002","name":"Revenue","currency":"USD","type":"revenue"}`,
+		`{"id":"ACC003","name":"Euro","currency":"EUR","type":"asset"}`,
+	} {
+		require.Equal(t, http.StatusCreated, send("POST", "/accounts", account).Code)
+	}
+	transfer := func(debit, credit, amount string) string {
+		return fmt.Sprintf(`{"id":"TX001","debit_account":"%s","credit_account":"%s","amount":{"amount":"%s","currency":"USD"}}`,
+			debit, credit, amount)
+	}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		want   problem
+	}{
+		{
+			name: "insufficient funds", method: "POST", path: "/transactions", body: transfer("ACC001", "ACC002", "500"),
+			want: problem{Status: http.StatusUnprocessableEntity, Code: "insufficient_funds", Title: "Insufficient funds",
+				Detail: "insufficient funds in debit account ACC001", AccountID: "ACC001", TransactionID: "TX001", Currency: "USD"},
+		},
+		{
+			name: "currency mismatch", method: "POST", path: "/transactions", body: transfer("ACC001", "ACC003", "10"),
+			want: problem{Status: http.StatusUnprocessableEntity, Code: "currency_mismatch", Title: "Currency mismatch",
+				Detail: "currency mismatch between accounts and transaction", TransactionID: "TX001", Currency: "USD"},
+		},
+		{
+			name: "unknown account", method: "POST", path: "/transactions", body: transfer("ACC001", "ACC999", "10"),
+			want: problem{Status: http.StatusNotFound, Code: "account_not_found", Title: "Account not found",
+				Detail: "credit account ACC999 does not exist", AccountID: "ACC999", TransactionID: "TX001"},
+		},
+		{
+			name: "duplicate account", method: "POST", path: "/accounts", body: `{"id":"ACC002","name":"Again","currency":"USD"}`,
+			want: problem{Status: http.StatusConflict, Code: "duplicate", Title: "Already exists",
+				Detail: "account ACC002 already exists", AccountID: "ACC002"},
+		},
+		{
+			name: "balance of unknown account", method: "GET", path: "/accounts/ACC999/balance",
+			want: problem{Status: http.StatusNotFound, Code: "account_not_found", Title: "Account not found",
+				Detail: "account ACC999 does not exist", AccountID: "ACC999"},
+		},
+		{
+			name: "unfreeze active account", method: "POST", path: "/accounts/ACC001/unfreeze",
+			want: problem{Status: http.StatusConflict, Code: "account_not_frozen", Title: "Account is not frozen",
+				Detail: "account ACC001 is not frozen", AccountID: "ACC001"},
+		},
+		{
+			name: "malformed body", method: "POST", path: "/transactions", body: `{"id":`,
+			want: problem{Status: http.StatusBadRequest, Code: "bad_request", Title: "Bad Request",
+				Detail: "unexpected EOF"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := send(tt.method, tt.path, tt.body)
+			assert.Equal(t, tt.want.Status, rr.Code)
+			assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+
+			var got problem
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &got))
+			tt.want.Type = "urn:ledger:problem:" + tt.want.Code
+			tt.want.Instance = tt.path
+			tt.want.RequestID = "problem-1"
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
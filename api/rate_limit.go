@@ -0,0 +1,81 @@
+package api
+
+import (
+	"go.uber.org/zap"
+	"ledgerproject/auth"
+	"ledgerproject/ratelimit"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// limitIP admits requests within the budget of their client's IP address
+// before they are authenticated, so that floods of requests with bad
+// credentials are limited too, and rejects the others with 429 Too Many
+// Requests.
+func (s *Server) limitIP(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.ipLimit == nil || isPublic(r) || admit(w, r, s.ipLimit, "ip:"+clientIP(r)) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// rateLimit admits requests within the client's read or write budget and
+// rejects the others with 429 Too Many Requests.
+func (s *Server) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limiter := s.writeLimit
+		if isRead(r.Method) {
+			limiter = s.readLimit
+		}
+		if limiter == nil || isPublic(r) || admit(w, r, limiter, rateLimitKey(r)) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// admit counts the request against the key's budget and reports whether it
+// is within it, answering 429 when it is not. Every limited response carries
+// the RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers of
+// the last budget checked; rejections add Retry-After.
+func admit(w http.ResponseWriter, r *http.Request, limiter *ratelimit.Limiter, key string) bool {
+	d := limiter.Allow(key)
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	w.Header().Set("RateLimit-Reset", seconds(d.Reset))
+	if d.Allowed {
+		return true
+	}
+
+	requestLogger(r).Warn("Request rate limited",
+		zap.String("method", r.Method),
+		zap.String("path", r.URL.Path),
+		zap.String("key", key),
+		zap.Duration("retry_after", d.RetryAfter))
+	w.Header().Set("Retry-After", seconds(d.RetryAfter))
+	writeProblem(w, r, http.StatusTooManyRequests, "rate limit exceeded")
+	return false
+}
+
+// rateLimitKey identifies the client a request is counted against: its
+// principal, or its IP address when it has none.
+func rateLimitKey(r *http.Request) string {
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+		if principal.Tenant != "" {
+			return "principal:" + principal.Tenant + "/" + principal.ID
+		}
+		return "principal:" + principal.ID
+	}
+	return "ip:" + clientIP(r)
+}
+
+func isRead(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// seconds formats a duration as whole seconds, rounded up.
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ledgerproject/audit"
+	"ledgerproject/auth"
+	"ledgerproject/config"
+	"ledgerproject/ledger"
+	"ledgerproject/ratelimit"
+	"ledgerproject/services"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRateLimit(t *testing.T) {
+	setupTestLogger(t)
+	validator, err := services.NewCurrencyValidator(&config.Config{
+		CurrencyFile: "../data/iso4217_currency_test.json",
+	})
+	require.NoError(t, err)
+
+	server := &Server{
+		router:     mux.NewRouter(),
+		ledger:     ledger.NewDetachedLedger(validator),
+		auth:       testAuthenticator(t),
+		audit:      audit.NewMemoryLog(),
+		readLimit:  ratelimit.New(config.RateLimit{Rate: 0.01, Burst: 2}),
+		writeLimit: ratelimit.New(config.RateLimit{Rate: 0.01, Burst: 1}),
+	}
+	server.setupRoutes()
+
+	send := func(method, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/accounts", nil)
+		if method == "POST" {
+			req = httptest.NewRequest(method, "/accounts",
+				strings.NewReader(`{"id":"ACC001","name":"Cash","currency":"USD","type":"asset"}`))
+		}
+		req.Header.Set(auth.APIKeyHeader, key)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	tests := []struct {
+		name          string
+		method        string
+		key           string
+		wantStatus    int
+		wantRemaining string
+	}{
+		{name: "first read", method: "GET", key: testAPIKey, wantStatus: http.StatusOK, wantRemaining: "1"},
+		{name: "second read", method: "GET", key: testAPIKey, wantStatus: http.StatusOK, wantRemaining: "0"},
+		{name: "read budget spent", method: "GET", key: testAPIKey, wantStatus: http.StatusTooManyRequests, wantRemaining: "0"},
+		{name: "writes have their own budget", method: "POST", key: testAPIKey, wantStatus: http.StatusCreated, wantRemaining: "0"},
+		{name: "write budget spent", method: "POST", key: testAPIKey, wantStatus: http.StatusTooManyRequests, wantRemaining: "0"},
+		{name: "other clients have their own budget", method: "GET", key: "operator-key", wantStatus: http.StatusOK, wantRemaining: "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := send(tt.method, tt.key)
+			assert.Equal(t, tt.wantStatus, rr.Code, rr.Body.String())
+			assert.Equal(t, tt.wantRemaining, rr.Header().Get("RateLimit-Remaining"))
+			assert.NotEmpty(t, rr.Header().Get("RateLimit-Limit"))
+			assert.NotEmpty(t, rr.Header().Get("RateLimit-Reset"))
+			if tt.wantStatus == http.StatusTooManyRequests {
+				assert.Equal(t, "100", rr.Header().Get("Retry-After"))
+			} else {
+				assert.Empty(t, rr.Header().Get("Retry-After"))
+			}
+		})
+	}
+}
+
+func TestIPRateLimit(t *testing.T) {
+	setupTestLogger(t)
+	server := &Server{
+		router:  mux.NewRouter(),
+		auth:    testAuthenticator(t),
+		ipLimit: ratelimit.New(config.RateLimit{Rate: 0.01, Burst: 2}),
+	}
+	server.setupRoutes()
+
+	send := func(remoteAddr, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/accounts", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(auth.APIKeyHeader, key)
+		rr := httptest.NewRecorder()
+		server.router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Bad credentials are counted before they are checked
+	assert.Equal(t, http.StatusUnauthorized, send("198.51.100.1:4000", "wrong-key").Code)
+	assert.Equal(t, http.StatusUnauthorized, send("198.51.100.1:4001", "wrong-key").Code)
+	rr := send("198.51.100.1:4002", "wrong-key")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "100", rr.Header().Get("Retry-After"))
+
+	assert.Equal(t, http.StatusUnauthorized, send("198.51.100.2:4000", "wrong-key").Code, "other addresses have their own budget")
+}
+
+func TestRateLimitKey(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  *auth.Principal
+		remoteAddr string
+		want       string
+	}{
+		{name: "principal", principal: &auth.Principal{ID: "tester"}, remoteAddr: "10.0.0.1:5000", want: "principal:tester"},
+		{name: "tenant principal", principal: &auth.Principal{ID: "tester", Tenant: "retail"}, want: "principal:retail/tester"},
+		{name: "remote address", remoteAddr: "10.0.0.1:5000", want: "ip:10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/accounts", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.principal != nil {
+				req = req.WithContext(auth.WithPrincipal(req.Context(), *tt.principal))
+			}
+			assert.Equal(t, tt.want, rateLimitKey(req))
+		})
+	}
+}
+
+func TestForwardedClient(t *testing.T) {
+	trusted := parseProxies([]string{"10.0.0.0/24", "192.0.2.9", "2001:db8::/32"})
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.7:5000", want: "203.0.113.7"},
+		{name: "forwarded for is ignored from untrusted peers", remoteAddr: "203.0.113.7:5000",
+			forwarded: []string{"198.51.100.1"}, want: "203.0.113.7"},
+		{name: "trusted proxy", remoteAddr: "10.0.0.1:5000", forwarded: []string{"203.0.113.7"}, want: "203.0.113.7"},
+		{name: "chain of trusted proxies", remoteAddr: "192.0.2.9:5000",
+			forwarded: []string{"203.0.113.7, 10.0.0.2"}, want: "203.0.113.7"},
+		{name: "addresses claimed by the client are ignored", remoteAddr: "10.0.0.1:5000",
+			forwarded: []string{"198.51.100.1, 203.0.113.7"}, want: "203.0.113.7"},
+		{name: "repeated headers", remoteAddr: "10.0.0.1:5000",
+			forwarded: []string{"198.51.100.1", "203.0.113.7, 10.0.0.2"}, want: "203.0.113.7"},
+		{name: "only proxies", remoteAddr: "10.0.0.1:5000", forwarded: []string{"10.0.0.3, 10.0.0.2"}, want: "10.0.0.3"},
+		{name: "malformed hop", remoteAddr: "10.0.0.1:5000", forwarded: []string{"203.0.113.7, unknown"}, want: "10.0.0.1"},
+		{name: "ipv6 proxy", remoteAddr: "[2001:db8::1]:5000", forwarded: []string{"2001:db8:1::5, 203.0.113.7"}, want: "203.0.113.7"},
+		{name: "no forwarded for", remoteAddr: "10.0.0.1:5000", want: "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/accounts", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			assert.Equal(t, tt.want, forwardedClient(req, trusted))
+		})
+	}
+}
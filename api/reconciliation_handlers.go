@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"ledgerproject/logger"
+	"ledgerproject/reconciliation"
+	"net/http"
+	"strings"
+)
+
+type matchRequest struct {
+	LineID        string `json:"line_id"`
+	TransactionID string `json:"transaction_id"`
+}
+
+// CreateReconciliationHandler uploads a bank statement for an account. The
+// body is CSV when sent as text/csv and a JSON array of lines otherwise.
+func (s *Server) CreateReconciliationHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+	accountID := mux.Vars(r)["accountId"]
+
+	var lines []reconciliation.Line
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "text/csv") {
+		lines, err = reconciliation.ParseCSV(r.Body)
+	} else {
+		lines, err = reconciliation.ParseJSON(r.Body)
+	}
+	if err != nil {
+		log.Error("Failed to parse bank statement",
+			zap.Error(err),
+			zap.String("account_id", accountID))
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	report, err := s.reconciler.Create(r.Context(), accountID, lines)
+	if err != nil {
+		log.Error("Failed to create reconciliation",
+			zap.Error(err),
+			zap.String("account_id", accountID))
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	s.recordAudit(r, actionReconciliationCreate, report.ID, nil, report.Reconciliation)
+	log.Info("Reconciliation created successfully",
+		zap.String("account_id", accountID),
+		zap.String("reconciliation_id", report.ID))
+	s.writeReconciliation(w, http.StatusCreated, report)
+}
+
+func (s *Server) GetReconciliationHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+	id := mux.Vars(r)["reconciliationId"]
+
+	report, err := s.reconciler.Get(r.Context(), id)
+	if err != nil {
+		log.Error("Failed to get reconciliation",
+			zap.Error(err),
+			zap.String("reconciliation_id", id))
+		writeError(w, r, err, reconciliationStatus(err))
+		return
+	}
+
+	s.writeReconciliation(w, http.StatusOK, report)
+}
+
+// MatchStatementLineHandler manually matches a statement line to a ledger
+// transaction.
+func (s *Server) MatchStatementLineHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+	id := mux.Vars(r)["reconciliationId"]
+
+	var req matchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("Failed to decode match request",
+			zap.Error(err),
+			zap.String("reconciliation_id", id))
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	report, err := s.reconciler.Match(r.Context(), id, req.LineID, req.TransactionID)
+	if err != nil {
+		log.Error("Failed to match statement line",
+			zap.Error(err),
+			zap.String("reconciliation_id", id),
+			zap.String("line_id", req.LineID))
+		writeError(w, r, err, reconciliationStatus(err))
+		return
+	}
+
+	s.recordAudit(r, actionReconciliationMatch, id, nil, req)
+	s.writeReconciliation(w, http.StatusOK, report)
+}
+
+// UnmatchStatementLineHandler removes the match from a statement line.
+func (s *Server) UnmatchStatementLineHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+	vars := mux.Vars(r)
+	id := vars["reconciliationId"]
+	lineID := vars["lineId"]
+
+	report, err := s.reconciler.Unmatch(r.Context(), id, lineID)
+	if err != nil {
+		log.Error("Failed to unmatch statement line",
+			zap.Error(err),
+			zap.String("reconciliation_id", id),
+			zap.String("line_id", lineID))
+		writeError(w, r, err, reconciliationStatus(err))
+		return
+	}
+
+	s.recordAudit(r, actionReconciliationUnmatch, id, map[string]string{"line_id": lineID}, nil)
+	s.writeReconciliation(w, http.StatusOK, report)
+}
+
+func (s *Server) writeReconciliation(w http.ResponseWriter, status int, report *reconciliation.Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.Get().Error("Failed to encode reconciliation",
+			zap.Error(err),
+			zap.String("reconciliation_id", report.ID))
+	}
+}
+
+func reconciliationStatus(err error) int {
+	if errors.Is(err, reconciliation.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
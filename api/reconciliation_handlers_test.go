@@ -0,0 +1,150 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ledgerproject/config"
+	"ledgerproject/models"
+	"ledgerproject/reconciliation"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func setupReconciliationTest(t *testing.T) (*Server, *MockLedger) {
+	server, mockLedger := setupTest(t)
+	server.reconciler = reconciliation.NewService(mockLedger, reconciliation.NewMemoryStore(), &config.Config{
+		ReconciliationWindow: 24 * time.Hour,
+	})
+
+	mockLedger.On("GetAccountBalance", "BANK").Return(models.Money{
+		Amount:   decimal.NewFromInt(900),
+		Currency: "USD",
+	}, nil)
+	mockLedger.On("GetAccountBalance", "NONEXISTENT").Return(models.Money{}, fmt.Errorf("account NONEXISTENT does not exist"))
+	mockLedger.On("GetTransactionHistory", "BANK").Return([]models.Transaction{
+		{
+			ID:            "TX1",
+			DateTime:      time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+			DebitAccount:  "BANK",
+			CreditAccount: "VENDOR",
+			Amount:        models.Money{Amount: decimal.NewFromInt(100), Currency: "USD"},
+		},
+	})
+	return server, mockLedger
+}
+
+func createReconciliation(t *testing.T, server *Server, body, contentType string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/accounts/BANK/reconciliations", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", contentType)
+	req = mux.SetURLVars(req, map[string]string{"accountId": "BANK"})
+	rr := httptest.NewRecorder()
+	server.CreateReconciliationHandler(rr, req)
+	return rr
+}
+
+func TestCreateReconciliationHandler(t *testing.T) {
+	t.Run("csv statement", func(t *testing.T) {
+		server, _ := setupReconciliationTest(t)
+
+		rr := createReconciliation(t, server, "date,amount,reference\n2024-03-01,-100,TX1\n2024-03-02,-5,FEE\n", "text/csv")
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+		var report reconciliation.Report
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+		assert.Equal(t, "TX1", report.Lines[0].TransactionID)
+		assert.Equal(t, []string{"L2"}, report.UnmatchedLines)
+	})
+
+	t.Run("json statement", func(t *testing.T) {
+		server, _ := setupReconciliationTest(t)
+
+		rr := createReconciliation(t, server, `[{"date":"2024-03-01","amount":"-100"}]`, "application/json")
+
+		assert.Equal(t, http.StatusCreated, rr.Code)
+	})
+
+	t.Run("invalid statement", func(t *testing.T) {
+		server, _ := setupReconciliationTest(t)
+
+		rr := createReconciliation(t, server, "reference\nTX1\n", "text/csv")
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+
+	t.Run("unknown account", func(t *testing.T) {
+		server, _ := setupReconciliationTest(t)
+
+		req := httptest.NewRequest("POST", "/accounts/NONEXISTENT/reconciliations", bytes.NewBufferString(`[{"date":"2024-03-01","amount":"1"}]`))
+		req = mux.SetURLVars(req, map[string]string{"accountId": "NONEXISTENT"})
+		rr := httptest.NewRecorder()
+		server.CreateReconciliationHandler(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code)
+	})
+}
+
+func TestMatchAndUnmatchHandlers(t *testing.T) {
+	server, _ := setupReconciliationTest(t)
+
+	rr := createReconciliation(t, server, `[{"date":"2024-03-03","amount":"-100"}]`, "application/json")
+	require.Equal(t, http.StatusCreated, rr.Code)
+	var created reconciliation.Report
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&created))
+	require.Equal(t, []string{"L1"}, created.UnmatchedLines)
+
+	vars := map[string]string{"reconciliationId": created.ID}
+
+	t.Run("manual match", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/reconciliations/"+created.ID+"/matches",
+			bytes.NewBufferString(`{"line_id":"L1","transaction_id":"TX1"}`))
+		req = mux.SetURLVars(req, vars)
+		rr := httptest.NewRecorder()
+
+		server.MatchStatementLineHandler(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var report reconciliation.Report
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+		assert.Equal(t, reconciliation.MatchManual, report.Lines[0].Match)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/reconciliations/"+created.ID, nil)
+		req = mux.SetURLVars(req, vars)
+		rr := httptest.NewRecorder()
+
+		server.GetReconciliationHandler(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var report reconciliation.Report
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&report))
+		assert.Equal(t, "TX1", report.Lines[0].TransactionID)
+	})
+
+	t.Run("unmatch", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/reconciliations/"+created.ID+"/matches/L1", nil)
+		req = mux.SetURLVars(req, map[string]string{"reconciliationId": created.ID, "lineId": "L1"})
+		rr := httptest.NewRecorder()
+
+		server.UnmatchStatementLineHandler(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("unknown reconciliation", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/reconciliations/rec-missing", nil)
+		req = mux.SetURLVars(req, map[string]string{"reconciliationId": "rec-missing"})
+		rr := httptest.NewRecorder()
+
+		server.GetReconciliationHandler(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"go.uber.org/zap"
+	"ledgerproject/logger"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// RequestIDHeader carries the ID that ties together every log line, audit
+// entry and response of a request.
+const RequestIDHeader = "X-Request-ID"
+
+// validRequestID bounds the IDs accepted from clients, since they end up in
+// logs and the audit trail.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+type requestIDKey struct{}
+
+// requestID adopts the client's X-Request-ID, or generates one when it is
+// missing or malformed, and returns it in the response. The request context
+// carries a logger annotated with the ID, which the ledger and services log
+// with.
+func (s *Server) requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID.MatchString(id) {
+			id = newRequestID()
+		}
+		w.Header().Set(RequestIDHeader, id)
+
+		ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+		ctx = logger.WithContext(ctx, logger.FromContext(ctx).With(zap.String("request_id", id)))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// requestIDFrom returns the ID of the request ctx belongs to.
+func requestIDFrom(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// withLogFields returns a copy of r whose context logger carries the fields.
+func withLogFields(r *http.Request, fields ...zap.Field) *http.Request {
+	log := logger.FromContext(r.Context()).With(fields...)
+	return r.WithContext(logger.WithContext(r.Context(), log))
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("req-%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
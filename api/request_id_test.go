@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+	"ledgerproject/audit"
+	"ledgerproject/auth"
+	"ledgerproject/config"
+	"ledgerproject/ledger"
+	"ledgerproject/logger"
+	"ledgerproject/services"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestRequestID(t *testing.T) {
+	setupTestLogger(t)
+	server := &Server{router: mux.NewRouter(), ledger: new(MockLedger), auth: testAuthenticator(t)}
+
+	var seen string
+	handler := server.requestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = requestIDFrom(r.Context())
+	}))
+
+	tests := []struct {
+		name    string
+		header  string
+		want    string
+		pattern string
+	}{
+		{name: "adopted from the client", header: "checkout-42", want: "checkout-42"},
+		{name: "generated when missing", pattern: "^[0-9a-f]{32}$"},
+		{name: "replaced when malformed", header: "bad id\nwith newline", pattern: "^[0-9a-f]{32}$"},
+		{name: "replaced when too long", header: strings.Repeat("a", 129), pattern: "^[0-9a-f]{32}$"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/accounts", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			id := rr.Header().Get(RequestIDHeader)
+			assert.Equal(t, id, seen)
+			if tt.want != "" {
+				assert.Equal(t, tt.want, id)
+			} else {
+				assert.Regexp(t, regexp.MustCompile(tt.pattern), id)
+			}
+		})
+	}
+}
+
+// TestRequestLogCorrelation checks that the handler and the ledger log the
+// same request ID and principal.
+func TestRequestLogCorrelation(t *testing.T) {
+	setupTestLogger(t)
+	validator, err := services.NewCurrencyValidator(&config.Config{
+		CurrencyFile: "../data/iso4217_currency_test.json",
+	})
+	require.NoError(t, err)
+
+	server := &Server{
+		router: mux.NewRouter(),
+		ledger: ledger.NewDetachedLedger(validator),
+		auth:   testAuthenticator(t),
+		audit:  audit.NewMemoryLog(),
+	}
+	server.setupRoutes()
+
+	core, logs := observer.New(zap.InfoLevel)
+	body := `{"id":"ACC001","name":"Cash","currency":"XXX","type":"asset"}`
+	req := httptest.NewRequest("POST", "/accounts", strings.NewReader(body))
+	req = req.WithContext(logger.WithContext(context.Background(), zap.New(core)))
+	req.Header.Set(auth.APIKeyHeader, testAPIKey)
+	req.Header.Set(RequestIDHeader, "transfer-7")
+	rr := httptest.NewRecorder()
+	server.router.ServeHTTP(rr, req)
+	require.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	// The ledger rejects the currency and the handler reports the failure
+	messages := []string{}
+	for _, entry := range logs.All() {
+		fields := entry.ContextMap()
+		assert.Equal(t, "transfer-7", fields["request_id"], entry.Message)
+		assert.Equal(t, "tester", fields["principal"], entry.Message)
+		assert.Equal(t, "default", fields["tenant"], entry.Message)
+		messages = append(messages, entry.Message)
+	}
+	assert.Contains(t, messages, "Currency is not valid")
+	assert.Contains(t, messages, "Failed to create account")
+}
@@ -3,24 +3,105 @@ package api
 import (
 	"context"
 	"github.com/gorilla/mux"
+	"go.uber.org/fx"
+	"ledgerproject/audit"
+	"ledgerproject/auth"
 	"ledgerproject/config"
+	"ledgerproject/fees"
+	"ledgerproject/importer"
+	"ledgerproject/interest"
 	"ledgerproject/ledger"
+	"ledgerproject/metrics"
+	"ledgerproject/ratelimit"
+	"ledgerproject/reconciliation"
+	"ledgerproject/scheduler"
+	"ledgerproject/services"
+	"ledgerproject/statements"
+	"ledgerproject/tenants"
+	"ledgerproject/tlsconfig"
+	"ledgerproject/webhooks"
+	"net"
 	"net/http"
+	"sync/atomic"
+	"time"
 )
 
 type Server struct {
-	router *mux.Router
-	ledger ledger.LedgerService
-	config *config.Config
-	server *http.Server
+	router         *mux.Router
+	ledger         ledger.LedgerService
+	importer       *importer.Importer
+	statements     *statements.Generator
+	reconciler     *reconciliation.Service
+	interest       *interest.Engine
+	scheduler      *scheduler.Scheduler
+	fees           *fees.Engine
+	webhooks       *webhooks.Dispatcher
+	auth           *auth.Authenticator
+	audit          *audit.Log
+	tenants        *tenants.Registry
+	metrics        *metrics.Metrics
+	currencies     *services.CurrencyValidator
+	ipLimit        *ratelimit.Limiter
+	readLimit      *ratelimit.Limiter
+	writeLimit     *ratelimit.Limiter
+	trustedProxies []*net.IPNet
+	maxBody        int64
+	config         *config.Config
+	build          BuildInfo
+	startedAt      time.Time
+	ready          *atomic.Bool
+	server         *http.Server
+	shutdown       <-chan struct{}
 }
 
-func NewServer(l ledger.LedgerService, c *config.Config) *Server {
+// ServerParams lists the dependencies fx injects into NewServer.
+type ServerParams struct {
+	fx.In
+
+	Ledger     ledger.LedgerService
+	Config     *config.Config
+	Importer   *importer.Importer
+	Reconciler *reconciliation.Service
+	Interest   *interest.Engine
+	Scheduler  *scheduler.Scheduler
+	Fees       *fees.Engine
+	Webhooks   *webhooks.Dispatcher
+	Auth       *auth.Authenticator
+	Audit      *audit.Log
+	Tenants    *tenants.Registry
+	Metrics    *metrics.Metrics
+	Currencies *services.CurrencyValidator
+	TLS        *tlsconfig.Reloader
+	Build      BuildInfo
+}
+
+func NewServer(p ServerParams) *Server {
 	r := mux.NewRouter()
+	c := p.Config
 	s := &Server{
-		router: r,
-		ledger: l,
-		config: c,
+		router:         r,
+		ledger:         p.Ledger,
+		importer:       p.Importer,
+		statements:     statements.NewGenerator(p.Ledger),
+		reconciler:     p.Reconciler,
+		interest:       p.Interest,
+		scheduler:      p.Scheduler,
+		fees:           p.Fees,
+		webhooks:       p.Webhooks,
+		auth:           p.Auth,
+		audit:          p.Audit,
+		tenants:        p.Tenants,
+		metrics:        p.Metrics,
+		currencies:     p.Currencies,
+		ipLimit:        ratelimit.New(c.IPRateLimit),
+		readLimit:      ratelimit.New(c.ReadRateLimit),
+		writeLimit:     ratelimit.New(c.WriteRateLimit),
+		trustedProxies: parseProxies(c.TrustedProxies),
+		maxBody:        int64(c.MaxRequestBytes),
+		config:         c,
+		build:          p.Build,
+		startedAt:      time.Now().UTC(),
+		ready:          new(atomic.Bool),
 		server: &http.Server{
 			Addr:              c.ServerPort,
 			Handler:           r,
@@ -31,19 +112,117 @@ func NewServer(l ledger.LedgerService, c *config.Config) *Server {
 			MaxHeaderBytes:    c.MaxHeaderBytes,
 		},
 	}
+	if p.TLS != nil {
+		s.server.TLSConfig = p.TLS.ServerConfig()
+	}
+	s.endStreamsOnShutdown()
 	s.setupRoutes()
 	return s
 }
 
 func (s *Server) setupRoutes() {
-	s.router.HandleFunc("/accounts", s.CreateAccountHandler).Methods("POST")
-	s.router.HandleFunc("/transactions", s.RecordTransactionHandler).Methods("POST")
-	s.router.HandleFunc("/accounts/{accountId}/balance", s.GetBalanceHandler).Methods("GET")
-	s.router.HandleFunc("/accounts/{accountId}/history", s.GetTransactionHistoryHandler).Methods("GET")
+	s.router.Use(s.requestID, s.resolveClientIP, s.limitBody, s.traceRequest, s.instrument, s.limitIP, s.authenticate, s.rateLimit)
+
+	s.router.HandleFunc("/healthz", s.HealthzHandler).Methods("GET")
+	s.router.HandleFunc("/readyz", s.ReadyzHandler).Methods("GET")
+	s.router.HandleFunc("/openapi.json", s.OpenAPIHandler).Methods("GET")
+	s.route("/status", auth.PermMetricsRead, s.StatusHandler).Methods("GET")
+	s.route("/metrics", auth.PermMetricsRead, s.MetricsHandler).Methods("GET")
+
+	s.route("/tenants", auth.PermTenantsManage, s.CreateTenantHandler).Methods("POST")
+	s.route("/tenants", auth.PermTenantsManage, s.ListTenantsHandler).Methods("GET")
+
+	// The ledger API is served for the principal's tenant at the root and for
+	// a named tenant under its prefix
+	s.tenantRoutes(s.router.PathPrefix(tenantPrefix).Subrouter())
+	s.tenantRoutes(s.router)
+}
+
+// tenantRoutes registers the tenant-scoped API on r.
+func (s *Server) tenantRoutes(r *mux.Router) {
+	s.tenantRoute(r, "/accounts", auth.PermAccountsManage, (*Server).CreateAccountHandler).Methods("POST")
+	s.tenantRoute(r, "/accounts", auth.PermAccountsRead, (*Server).ListAccountsHandler).Methods("GET")
+	s.tenantRoute(r, "/transactions", auth.PermTransactionsPost, (*Server).RecordTransactionHandler).Methods("POST")
+	s.tenantRoute(r, "/transactions", auth.PermAccountsRead, (*Server).ListTransactionsHandler).Methods("GET")
+	s.tenantRoute(r, "/accounts/{accountId}/balance", auth.PermAccountsRead, (*Server).GetBalanceHandler).Methods("GET")
+	s.tenantRoute(r, "/accounts/{accountId}/history", auth.PermAccountsRead, (*Server).GetTransactionHistoryHandler).Methods("GET")
+	s.tenantRoute(r, "/accounts/{accountId}/freeze", auth.PermAccountsManage, (*Server).FreezeAccountHandler).Methods("POST")
+	s.tenantRoute(r, "/accounts/{accountId}/unfreeze", auth.PermAccountsManage, (*Server).UnfreezeAccountHandler).Methods("POST")
+	s.tenantRoute(r, "/accounts/{accountId}/statements/camt053", auth.PermAccountsRead, (*Server).GetCamt053StatementHandler).Methods("GET")
+	s.tenantRoute(r, "/imports", auth.PermOperationsManage, (*Server).ImportJournalHandler).Methods("POST")
+	s.tenantRoute(r, "/accounts/{accountId}/reconciliations", auth.PermOperationsManage, (*Server).CreateReconciliationHandler).Methods("POST")
+	s.tenantRoute(r, "/reconciliations/{reconciliationId}", auth.PermOperationsRead, (*Server).GetReconciliationHandler).Methods("GET")
+	s.tenantRoute(r, "/reconciliations/{reconciliationId}/matches", auth.PermOperationsManage, (*Server).MatchStatementLineHandler).Methods("POST")
+	s.tenantRoute(r, "/reconciliations/{reconciliationId}/matches/{lineId}", auth.PermOperationsManage, (*Server).UnmatchStatementLineHandler).Methods("DELETE")
+	s.tenantRoute(r, "/accounts/{accountId}/interest", auth.PermAccountsManage, (*Server).ConfigureInterestHandler).Methods("PUT")
+	s.tenantRoute(r, "/accounts/{accountId}/interest", auth.PermAccountsRead, (*Server).GetInterestHandler).Methods("GET")
+	s.tenantRoute(r, "/standing-orders", auth.PermOperationsManage, (*Server).CreateStandingOrderHandler).Methods("POST")
+	s.tenantRoute(r, "/standing-orders", auth.PermOperationsRead, (*Server).ListStandingOrdersHandler).Methods("GET")
+	s.tenantRoute(r, "/standing-orders/{orderId}", auth.PermOperationsRead, (*Server).GetStandingOrderHandler).Methods("GET")
+	s.tenantRoute(r, "/standing-orders/{orderId}", auth.PermOperationsManage, (*Server).CancelStandingOrderHandler).Methods("DELETE")
+	s.tenantRoute(r, "/fee-rules", auth.PermSettingsRead, (*Server).GetFeeRulesHandler).Methods("GET")
+	s.tenantRoute(r, "/fee-rules", auth.PermSettingsManage, (*Server).SetFeeRulesHandler).Methods("PUT")
+	s.tenantRoute(r, "/fees/preview", auth.PermTransactionsPost, (*Server).PreviewFeesHandler).Methods("POST")
+	s.tenantRoute(r, "/webhooks", auth.PermSettingsManage, (*Server).RegisterWebhookHandler).Methods("POST")
+	s.tenantRoute(r, "/webhooks", auth.PermSettingsRead, (*Server).ListWebhooksHandler).Methods("GET")
+	s.tenantRoute(r, "/webhooks/dead-letters", auth.PermSettingsRead, (*Server).ListDeadLettersHandler).Methods("GET")
+	s.tenantRoute(r, "/webhooks/dead-letters/{deliveryId}/redeliver", auth.PermSettingsManage, (*Server).RedeliverDeadLetterHandler).Methods("POST")
+	s.tenantRoute(r, "/webhooks/{endpointId}", auth.PermSettingsManage, (*Server).DeleteWebhookHandler).Methods("DELETE")
+	s.tenantRoute(r, "/events", auth.PermEventsRead, (*Server).StreamEventsHandler).Methods("GET")
+	s.tenantRoute(r, "/changes", auth.PermEventsRead, (*Server).ListChangesHandler).Methods("GET")
+	s.tenantRoute(r, "/audit", auth.PermAuditRead, (*Server).QueryAuditHandler).Methods("GET")
+	s.tenantRoute(r, "/audit/export", auth.PermAuditRead, (*Server).ExportAuditHandler).Methods("GET")
+}
+
+// route registers a handler that requires the given permission and a
+// request matching the API schema.
+func (s *Server) route(path string, permission auth.Permission, handler http.HandlerFunc) *mux.Route {
+	return s.router.Handle(path, s.authorize(permission, validateRequest(handler)))
 }
 
+// tenantHandler is a handler method served by the Server of the request's
+// tenant.
+type tenantHandler func(*Server, http.ResponseWriter, *http.Request)
+
+// tenantRoute registers a tenant-scoped handler that requires the given
+// permission and a request matching the API schema.
+func (s *Server) tenantRoute(r *mux.Router, path string, permission auth.Permission, handler tenantHandler) *mux.Route {
+	return r.Handle(path, s.authorize(permission, validateRequest(s.inTenant(handler))))
+}
+
+// Start listens on the configured port and serves the API until the server
+// is shut down.
 func (s *Server) Start() error {
-	return s.server.ListenAndServe()
+	lis, err := s.Listen()
+	if err != nil {
+		return err
+	}
+	return s.Serve(lis)
+}
+
+// Listen binds the configured port, so that callers know the API accepts
+// connections before they report the server ready.
+func (s *Server) Listen() (net.Listener, error) {
+	return net.Listen("tcp", s.server.Addr)
+}
+
+// Serve serves the API on lis until the server is shut down: over TLS, with
+// HTTP/2 negotiated, when TLS is configured, and in plain HTTP/1.1 otherwise.
+func (s *Server) Serve(lis net.Listener) error {
+	if s.server.TLSConfig != nil {
+		return s.server.ServeTLS(lis, "", "")
+	}
+	return s.server.Serve(lis)
+}
+
+// endStreamsOnShutdown closes s.shutdown when the HTTP server shuts down.
+// Shutdown neither waits for nor closes hijacked connections, and waits for
+// long-lived responses until its deadline, so event streams watch s.shutdown
+// to end by themselves.
+func (s *Server) endStreamsOnShutdown() {
+	streams, stop := context.WithCancel(context.Background())
+	s.shutdown = streams.Done()
+	s.server.RegisterOnShutdown(stop)
 }
 
 // Graceful shutdown
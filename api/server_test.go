@@ -2,6 +2,9 @@ package api
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
 	"fmt"
 	"github.com/gorilla/mux"
@@ -9,12 +12,18 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"ledgerproject/audit"
+	"ledgerproject/auth"
 	"ledgerproject/config"
 	"ledgerproject/models"
+	"ledgerproject/tlsconfig"
+	"ledgerproject/tlsconfig/tlstest"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewServer(t *testing.T) {
@@ -25,7 +34,7 @@ func TestNewServer(t *testing.T) {
 			CurrencyFile: "test_currency.json",
 		}
 
-		server := NewServer(mockLedger, cfg)
+		server := NewServer(ServerParams{Ledger: mockLedger, Config: cfg})
 
 		assert.NotNil(t, server)
 		assert.NotNil(t, server.router)
@@ -37,7 +46,7 @@ func TestNewServer(t *testing.T) {
 func TestSetupRoutes(t *testing.T) {
 	mockLedger := new(MockLedger)
 	cfg := &config.Config{ServerPort: ":8080"}
-	server := NewServer(mockLedger, cfg)
+	server := NewServer(ServerParams{Ledger: mockLedger, Config: cfg, Auth: testAuthenticator(t), Audit: audit.NewMemoryLog()})
 
 	// Helper function to test route existence and method
 	testRoute := func(path, method string) {
@@ -50,12 +59,49 @@ func TestSetupRoutes(t *testing.T) {
 	testRoute("/transactions", "POST")
 	testRoute("/accounts/{accountId}/balance", "GET")
 	testRoute("/accounts/{accountId}/history", "GET")
+	testRoute("/accounts/{accountId}/statements/camt053", "GET")
+	testRoute("/imports", "POST")
+	testRoute("/accounts/{accountId}/reconciliations", "POST")
+	testRoute("/reconciliations/{reconciliationId}", "GET")
+	testRoute("/reconciliations/{reconciliationId}/matches", "POST")
+	testRoute("/reconciliations/{reconciliationId}/matches/{lineId}", "DELETE")
+	testRoute("/accounts/{accountId}/interest", "PUT")
+	testRoute("/accounts/{accountId}/interest", "GET")
+	testRoute("/standing-orders", "POST")
+	testRoute("/standing-orders", "GET")
+	testRoute("/standing-orders/{orderId}", "GET")
+	testRoute("/standing-orders/{orderId}", "DELETE")
+	testRoute("/fee-rules", "GET")
+	testRoute("/fee-rules", "PUT")
+	testRoute("/fees/preview", "POST")
+	testRoute("/accounts", "GET")
+	testRoute("/transactions", "GET")
+	testRoute("/accounts/{accountId}/freeze", "POST")
+	testRoute("/accounts/{accountId}/unfreeze", "POST")
+	testRoute("/webhooks", "POST")
+	testRoute("/webhooks", "GET")
+	testRoute("/webhooks/dead-letters", "GET")
+	testRoute("/webhooks/dead-letters/{deliveryId}/redeliver", "POST")
+	testRoute("/webhooks/{endpointId}", "DELETE")
+	testRoute("/events", "GET")
+	testRoute("/changes", "GET")
+	testRoute("/audit", "GET")
+	testRoute("/audit/export", "GET")
+	testRoute("/tenants", "POST")
+	testRoute("/tenants", "GET")
+	testRoute("/tenants/{tenantId}/accounts", "POST")
+	testRoute("/tenants/{tenantId}/accounts/{accountId}/balance", "GET")
+	testRoute("/metrics", "GET")
+	testRoute("/healthz", "GET")
+	testRoute("/readyz", "GET")
+	testRoute("/status", "GET")
+	testRoute("/openapi.json", "GET")
 }
 
 func TestRouteHandlers(t *testing.T) {
 	mockLedger := new(MockLedger)
 	cfg := &config.Config{ServerPort: ":8080"}
-	server := NewServer(mockLedger, cfg)
+	server := NewServer(ServerParams{Ledger: mockLedger, Config: cfg, Auth: testAuthenticator(t), Audit: audit.NewMemoryLog()})
 
 	t.Run("create account route", func(t *testing.T) {
 		account := models.Account{
@@ -75,6 +121,7 @@ func TestRouteHandlers(t *testing.T) {
 
 		body, _ := json.Marshal(account)
 		req := httptest.NewRequest("POST", "/accounts", strings.NewReader(string(body)))
+		req.Header.Set(auth.APIKeyHeader, testAPIKey)
 		rr := httptest.NewRecorder()
 
 		server.router.ServeHTTP(rr, req)
@@ -100,6 +147,7 @@ func TestRouteHandlers(t *testing.T) {
 
 		body, _ := json.Marshal(tx)
 		req := httptest.NewRequest("POST", "/transactions", strings.NewReader(string(body)))
+		req.Header.Set(auth.APIKeyHeader, testAPIKey)
 		rr := httptest.NewRecorder()
 
 		server.router.ServeHTTP(rr, req)
@@ -118,6 +166,7 @@ func TestRouteHandlers(t *testing.T) {
 		mockLedger.On("GetAccountBalance", accountID).Return(balance, nil)
 
 		req := httptest.NewRequest("GET", fmt.Sprintf("/accounts/%s/balance", accountID), nil)
+		req.Header.Set(auth.APIKeyHeader, testAPIKey)
 		rr := httptest.NewRecorder()
 
 		server.router.ServeHTTP(rr, req)
@@ -143,6 +192,7 @@ func TestRouteHandlers(t *testing.T) {
 		mockLedger.On("GetTransactionHistory", accountID).Return(history)
 
 		req := httptest.NewRequest("GET", fmt.Sprintf("/accounts/%s/history", accountID), nil)
+		req.Header.Set(auth.APIKeyHeader, testAPIKey)
 		rr := httptest.NewRecorder()
 
 		server.router.ServeHTTP(rr, req)
@@ -156,7 +206,7 @@ func TestServerStart(t *testing.T) {
 	t.Run("server start with valid port", func(t *testing.T) {
 		mockLedger := new(MockLedger)
 		cfg := &config.Config{ServerPort: ":0"} // Use port 0 for testing (OS will assign free port)
-		server := NewServer(mockLedger, cfg)
+		server := NewServer(ServerParams{Ledger: mockLedger, Config: cfg})
 
 		// Start server in goroutine
 		go func() {
@@ -175,13 +225,89 @@ func TestServerStart(t *testing.T) {
 	t.Run("server start with invalid port", func(t *testing.T) {
 		mockLedger := new(MockLedger)
 		cfg := &config.Config{ServerPort: "invalid-port"}
-		server := NewServer(mockLedger, cfg)
+		server := NewServer(ServerParams{Ledger: mockLedger, Config: cfg})
 
 		err := server.Start()
 		assert.Error(t, err)
 	})
 }
 
+func TestServerTLS(t *testing.T) {
+	setupTestLogger(t)
+	dir := t.TempDir()
+	ca := tlstest.NewCA(t, dir, "ca")
+	_, certFile, keyFile := ca.Issue(t, dir, "localhost")
+	_, clientCert, clientKey := ca.Issue(t, dir, "payments")
+
+	// Reserve a free port for the server
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := ln.Addr().String()
+	require.NoError(t, ln.Close())
+
+	cfg := &config.Config{
+		ServerPort: addr,
+		TLS: config.TLS{
+			CertFile: certFile, KeyFile: keyFile, ClientCAFile: ca.CertFile, ReloadInterval: time.Minute,
+		},
+		ClientCertificates: []config.ClientCertificate{{CommonName: "payments", Role: auth.RoleClient, Accounts: []string{"ACC001"}}},
+	}
+	authenticator, err := auth.NewAuthenticator(cfg)
+	require.NoError(t, err)
+	reloader, err := tlsconfig.New(cfg)
+	require.NoError(t, err)
+
+	mockLedger := new(MockLedger)
+	mockLedger.On("FindAccounts", models.MetadataFilter{}).Return([]models.Account{
+		{ID: "ACC001", Currency: "USD", Balance: models.Money{Amount: decimal.Zero, Currency: "USD"}},
+		{ID: "ACC002", Currency: "USD", Balance: models.Money{Amount: decimal.Zero, Currency: "USD"}},
+	})
+	server := NewServer(ServerParams{Ledger: mockLedger, Config: cfg, Auth: authenticator, Audit: audit.NewMemoryLog(), TLS: reloader})
+	go server.Start()
+	t.Cleanup(func() { server.Shutdown(context.Background()) })
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+	cert, err := tls.LoadX509KeyPair(clientCert, clientKey)
+	require.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: []tls.Certificate{cert}},
+		ForceAttemptHTTP2: true,
+	}}
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// The certificate authenticates the client, who sees only its account
+	resp, err := client.Get("https://" + addr + "/accounts")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, 2, resp.ProtoMajor)
+	var accounts []models.Account
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&accounts))
+	require.Len(t, accounts, 1)
+	assert.Equal(t, "ACC001", accounts[0].ID)
+
+	// Without a certificate or other credentials the request is rejected
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	resp, err = anonymous.Get("https://" + addr + "/accounts")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	// Plain HTTP is not served
+	resp, err = http.Get("http://" + addr + "/healthz")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
 // Helper function to find a route in the router
 func findRoute(router *mux.Router, path, method string) *mux.Route {
 	var foundRoute *mux.Route
@@ -226,7 +352,7 @@ func TestServerIntegration(t *testing.T) {
 
 	mockLedger := new(MockLedger)
 	cfg := &config.Config{ServerPort: ":8081"}
-	server := NewServer(mockLedger, cfg)
+	server := NewServer(ServerParams{Ledger: mockLedger, Config: cfg, Auth: testAuthenticator(t), Audit: audit.NewMemoryLog()})
 
 	// Start server in goroutine
 	go func() {
@@ -235,6 +361,16 @@ func TestServerIntegration(t *testing.T) {
 		assert.NotNil(t, err)
 	}()
 
+	// Wait until the server accepts connections
+	require.Eventually(t, func() bool {
+		conn, err := net.Dial("tcp", "localhost"+cfg.ServerPort)
+		if err != nil {
+			return false
+		}
+		conn.Close()
+		return true
+	}, 2*time.Second, 10*time.Millisecond)
+
 	// Test complete flow: Create account -> Record transaction -> Get balance -> Get history
 	t.Run("complete flow", func(t *testing.T) {
 		// Setup mock expectations
@@ -299,6 +435,7 @@ func TestServerIntegration(t *testing.T) {
 
 		req, err := http.NewRequest("POST", baseURL+"/accounts", bytes.NewBuffer(accountBody1))
 		require.NoError(t, err)
+		req.Header.Set(auth.APIKeyHeader, testAPIKey)
 		req.Header.Set("Content-Type", "application/json")
 
 		resp, err := client.Do(req)
@@ -313,6 +450,7 @@ func TestServerIntegration(t *testing.T) {
 
 		req, err = http.NewRequest("POST", baseURL+"/accounts", bytes.NewBuffer(accountBody2))
 		require.NoError(t, err)
+		req.Header.Set(auth.APIKeyHeader, testAPIKey)
 		req.Header.Set("Content-Type", "application/json")
 
 		resp, err = client.Do(req)
@@ -327,6 +465,7 @@ func TestServerIntegration(t *testing.T) {
 
 		req, err = http.NewRequest("POST", baseURL+"/transactions", bytes.NewBuffer(txBody))
 		require.NoError(t, err)
+		req.Header.Set(auth.APIKeyHeader, testAPIKey)
 		req.Header.Set("Content-Type", "application/json")
 
 		resp, err = client.Do(req)
@@ -338,6 +477,7 @@ func TestServerIntegration(t *testing.T) {
 		t.Log("Getting account balance...")
 		req, err = http.NewRequest("GET", fmt.Sprintf("%s/accounts/%s/balance", baseURL, account1.ID), nil)
 		require.NoError(t, err)
+		req.Header.Set(auth.APIKeyHeader, testAPIKey)
 
 		resp, err = client.Do(req)
 		require.NoError(t, err)
@@ -353,6 +493,7 @@ func TestServerIntegration(t *testing.T) {
 		t.Log("Getting transaction history...")
 		req, err = http.NewRequest("GET", fmt.Sprintf("%s/accounts/%s/history", baseURL, account1.ID), nil)
 		require.NoError(t, err)
+		req.Header.Set(auth.APIKeyHeader, testAPIKey)
 
 		resp, err = client.Do(req)
 		require.NoError(t, err)
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"ledgerproject/scheduler"
+	"net/http"
+)
+
+func (s *Server) CreateStandingOrderHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+
+	var order scheduler.StandingOrder
+	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+		log.Error("Failed to decode standing order request", zap.Error(err))
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	created, err := s.scheduler.Create(r.Context(), order)
+	if err != nil {
+		log.Error("Failed to create standing order",
+			zap.Error(err),
+			zap.String("order_id", order.ID))
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	s.recordAudit(r, actionStandingOrderCreate, created.ID, nil, created)
+	log.Info("Standing order created successfully", zap.String("order_id", created.ID))
+	writeJSON(w, http.StatusCreated, created)
+}
+
+func (s *Server) ListStandingOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.scheduler.List())
+}
+
+func (s *Server) GetStandingOrderHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+	id := mux.Vars(r)["orderId"]
+
+	order, err := s.scheduler.Get(id)
+	if err != nil {
+		log.Error("Failed to get standing order",
+			zap.Error(err),
+			zap.String("order_id", id))
+		writeError(w, r, err, standingOrderStatus(err))
+		return
+	}
+
+	writeJSON(w, http.StatusOK, order)
+}
+
+// CancelStandingOrderHandler stops future occurrences of a standing order.
+func (s *Server) CancelStandingOrderHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+	id := mux.Vars(r)["orderId"]
+
+	before, _ := s.scheduler.Get(id)
+	order, err := s.scheduler.Cancel(id)
+	if err != nil {
+		log.Error("Failed to cancel standing order",
+			zap.Error(err),
+			zap.String("order_id", id))
+		writeError(w, r, err, standingOrderStatus(err))
+		return
+	}
+
+	s.recordAudit(r, actionStandingOrderCancel, id, before, order)
+	log.Info("Standing order cancelled successfully", zap.String("order_id", id))
+	writeJSON(w, http.StatusOK, order)
+}
+
+func standingOrderStatus(err error) int {
+	if errors.Is(err, scheduler.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ledgerproject/config"
+	"ledgerproject/models"
+	"ledgerproject/scheduler"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func setupStandingOrderTest(t *testing.T) *Server {
+	server, mockLedger := setupTest(t)
+	server.scheduler = scheduler.NewScheduler(mockLedger, &config.Config{
+		SchedulerInterval:    time.Minute,
+		SchedulerMaxAttempts: 3,
+		SchedulerMaxCatchUp:  100,
+	})
+
+	usd := models.Money{Amount: decimal.NewFromInt(1000), Currency: "USD"}
+	mockLedger.On("GetAccountBalance", "TENANT").Return(usd, nil)
+	mockLedger.On("GetAccountBalance", "LANDLORD").Return(usd, nil)
+	mockLedger.On("GetAccountBalance", "NONEXISTENT").Return(models.Money{}, fmt.Errorf("account NONEXISTENT does not exist"))
+	return server
+}
+
+const rentOrder = `{
+	"id": "RENT",
+	"description": "Monthly rent",
+	"debit_account": "TENANT",
+	"credit_account": "LANDLORD",
+	"amount": {"amount": "100.00", "currency": "USD"},
+	"schedule": "FREQ=MONTHLY;BYMONTHDAY=1",
+	"start": "2030-01-01T00:00:00Z",
+	"max_count": 12
+}`
+
+func TestCreateStandingOrderHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "valid order",
+			body:       rentOrder,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "invalid schedule",
+			body:       `{"id":"X","debit_account":"TENANT","credit_account":"LANDLORD","amount":{"amount":"1","currency":"USD"},"schedule":"often"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "unknown account",
+			body:       `{"id":"X","debit_account":"NONEXISTENT","credit_account":"LANDLORD","amount":{"amount":"1","currency":"USD"},"schedule":"@daily"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid json",
+			body:       `{"id":`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := setupStandingOrderTest(t)
+
+			req := httptest.NewRequest("POST", "/standing-orders", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+
+			server.CreateStandingOrderHandler(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus == http.StatusCreated {
+				var order scheduler.StandingOrder
+				require.NoError(t, json.NewDecoder(rr.Body).Decode(&order))
+				assert.Equal(t, scheduler.StatusActive, order.Status)
+				assert.Equal(t, "2030-01-01T00:00:00Z", order.NextRun.Format(time.RFC3339))
+			}
+		})
+	}
+}
+
+func TestStandingOrderLifecycleHandlers(t *testing.T) {
+	server := setupStandingOrderTest(t)
+
+	rr := httptest.NewRecorder()
+	server.CreateStandingOrderHandler(rr, httptest.NewRequest("POST", "/standing-orders", bytes.NewBufferString(rentOrder)))
+	require.Equal(t, http.StatusCreated, rr.Code)
+
+	t.Run("list", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		server.ListStandingOrdersHandler(rr, httptest.NewRequest("GET", "/standing-orders", nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var orders []scheduler.StandingOrder
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&orders))
+		require.Len(t, orders, 1)
+		assert.Equal(t, "RENT", orders[0].ID)
+	})
+
+	t.Run("get", func(t *testing.T) {
+		req := mux.SetURLVars(httptest.NewRequest("GET", "/standing-orders/RENT", nil), map[string]string{"orderId": "RENT"})
+		rr := httptest.NewRecorder()
+		server.GetStandingOrderHandler(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+	})
+
+	t.Run("cancel", func(t *testing.T) {
+		req := mux.SetURLVars(httptest.NewRequest("DELETE", "/standing-orders/RENT", nil), map[string]string{"orderId": "RENT"})
+		rr := httptest.NewRecorder()
+		server.CancelStandingOrderHandler(rr, req)
+
+		assert.Equal(t, http.StatusOK, rr.Code)
+		var order scheduler.StandingOrder
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&order))
+		assert.Equal(t, scheduler.StatusCancelled, order.Status)
+	})
+
+	t.Run("unknown order", func(t *testing.T) {
+		req := mux.SetURLVars(httptest.NewRequest("GET", "/standing-orders/MISSING", nil), map[string]string{"orderId": "MISSING"})
+		rr := httptest.NewRecorder()
+		server.GetStandingOrderHandler(rr, req)
+
+		assert.Equal(t, http.StatusNotFound, rr.Code)
+	})
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"ledgerproject/auth"
+	"ledgerproject/config"
+	"ledgerproject/tenants"
+	"net/http"
+)
+
+// createTenantRequest is the body of POST /tenants. Currencies restricts the
+// tenant to some of the platform currencies.
+type createTenantRequest struct {
+	ID         string   `json:"id"`
+	Name       string   `json:"name"`
+	Currencies []string `json:"currencies"`
+}
+
+// CreateTenantHandler creates a tenant with an empty ledger of its own.
+func (s *Server) CreateTenantHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+
+	if s.tenants == nil {
+		writeProblem(w, r, http.StatusNotFound, "tenants are not enabled")
+		return
+	}
+
+	var req createTenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Error("Failed to decode tenant", zap.Error(err))
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	tenant, err := s.tenants.Create(config.TenantConfig{ID: req.ID, Name: req.Name, Currencies: req.Currencies})
+	if err != nil {
+		log.Error("Failed to create tenant", zap.Error(err), zap.String("tenant", req.ID))
+		status := http.StatusBadRequest
+		if errors.Is(err, tenants.ErrExists) {
+			status = http.StatusConflict
+		}
+		writeError(w, r, err, status)
+		return
+	}
+
+	s.recordAudit(r, actionTenantCreate, tenant.ID, nil, tenant)
+	writeJSON(w, http.StatusCreated, tenant)
+}
+
+func (s *Server) ListTenantsHandler(w http.ResponseWriter, r *http.Request) {
+	if s.tenants == nil {
+		writeJSON(w, http.StatusOK, []*tenants.Tenant{})
+		return
+	}
+	writeJSON(w, http.StatusOK, s.tenants.List())
+}
+
+// inTenant runs the handler on the Server of the request's tenant.
+func (s *Server) inTenant(handler tenantHandler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, err := tenantID(r)
+		if err != nil {
+			requestLogger(r).Warn("Tenant access denied", zap.Error(err))
+			writeError(w, r, err, http.StatusForbidden)
+			return
+		}
+
+		ts, err := s.forTenant(id)
+		if err != nil {
+			writeError(w, r, err, http.StatusNotFound)
+			return
+		}
+		handler(ts, w, withLogFields(r, zap.String("tenant", id)))
+	})
+}
+
+// tenantID resolves the tenant of a request: the one named in the path, or
+// else the principal's own. Principals bound to a tenant cannot name another
+// one, and platform principals default to the default tenant. Only platform
+// principals that manage tenants may name any tenant: the others, clients
+// among them, hold grants such as account IDs that mean nothing outside the
+// default tenant.
+func tenantID(r *http.Request) (string, error) {
+	principal, _ := auth.PrincipalFrom(r.Context())
+	id := mux.Vars(r)["tenantId"]
+
+	switch {
+	case id == "" && principal.Tenant == "":
+		return tenants.DefaultID, nil
+	case id == "":
+		return principal.Tenant, nil
+	case principal.Tenant != "" && principal.Tenant != id:
+		return "", fmt.Errorf("access to tenant %s denied", id)
+	case principal.Tenant == "" && id != tenants.DefaultID && !principal.Can(auth.PermTenantsManage):
+		return "", fmt.Errorf("access to tenant %s denied", id)
+	}
+	return id, nil
+}
+
+// forTenant returns a Server backed by the tenant's services. The default
+// tenant is served by s itself.
+func (s *Server) forTenant(id string) (*Server, error) {
+	if id == tenants.DefaultID {
+		return s, nil
+	}
+	if s.tenants == nil {
+		return nil, fmt.Errorf("%w: %s", tenants.ErrNotFound, id)
+	}
+
+	t, err := s.tenants.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	return &Server{
+		router:     s.router,
+		ledger:     t.Ledger,
+		importer:   t.Importer,
+		statements: t.Statements,
+		reconciler: t.Reconciler,
+		interest:   t.Interest,
+		scheduler:  t.Scheduler,
+		fees:       t.Fees,
+		webhooks:   t.Webhooks,
+		auth:       s.auth,
+		audit:      t.Audit,
+		tenants:    s.tenants,
+		metrics:    s.metrics,
+		currencies: s.currencies,
+		config:     t.Config,
+		build:      s.build,
+		startedAt:  s.startedAt,
+		ready:      s.ready,
+		server:     s.server,
+	}, nil
+}
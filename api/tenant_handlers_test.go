@@ -0,0 +1,204 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ledgerproject/audit"
+	"ledgerproject/auth"
+	"ledgerproject/config"
+	"ledgerproject/ledger"
+	"ledgerproject/models"
+	"ledgerproject/services"
+	"ledgerproject/tenants"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// setupTenantTest serves the router over a real default ledger and a
+// registry holding the retail tenant, which only allows USD.
+func setupTenantTest(t *testing.T) *Server {
+	setupTestLogger(t)
+
+	cfg := &config.Config{
+		CurrencyFile: "../data/iso4217_currency_test.json",
+		Tenants:      []config.TenantConfig{{ID: "retail", Name: "Retail", Currencies: []string{"USD"}}},
+	}
+	validator, err := services.NewCurrencyValidator(cfg)
+	require.NoError(t, err)
+
+	l := ledger.NewDetachedLedger(validator)
+	trail := audit.NewMemoryLog()
+	registry, err := tenants.NewRegistry(tenants.Params{Config: cfg, Currencies: validator, Ledger: l, Audit: trail})
+	require.NoError(t, err)
+
+	server := &Server{
+		router:  mux.NewRouter(),
+		ledger:  l,
+		config:  cfg,
+		auth:    testAuthenticator(t),
+		audit:   trail,
+		tenants: registry,
+	}
+	server.setupRoutes()
+	return server
+}
+
+func serveTenantRequest(s *Server, method, path, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(auth.APIKeyHeader, key)
+	rr := httptest.NewRecorder()
+	s.router.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestCreateTenantHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        string
+		body       string
+		wantStatus int
+	}{
+		{name: "created", key: testAPIKey, body: `{"id":"cards","name":"Cards","currencies":["EUR"]}`, wantStatus: http.StatusCreated},
+		{name: "duplicate", key: testAPIKey, body: `{"id":"retail"}`, wantStatus: http.StatusConflict},
+		{name: "invalid id", key: testAPIKey, body: `{"id":"Cards"}`, wantStatus: http.StatusBadRequest},
+		{name: "unknown currency", key: testAPIKey, body: `{"id":"cards","currencies":["XXX"]}`, wantStatus: http.StatusBadRequest},
+		{name: "invalid body", key: testAPIKey, body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "operator", key: "operator-key", body: `{"id":"cards"}`, wantStatus: http.StatusForbidden},
+		{name: "tenant admin", key: "retail-key", body: `{"id":"cards"}`, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := setupTenantTest(t)
+
+			rr := serveTenantRequest(server, "POST", "/tenants", tt.key, tt.body)
+			assert.Equal(t, tt.wantStatus, rr.Code, rr.Body.String())
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+
+			var tenant tenants.Tenant
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&tenant))
+			assert.Equal(t, "cards", tenant.ID)
+			assert.Equal(t, []string{"EUR"}, tenant.Currencies)
+
+			entries := server.audit.Query(audit.Filter{Action: actionTenantCreate})
+			require.Len(t, entries, 1)
+			assert.Equal(t, "cards", entries[0].Target)
+		})
+	}
+}
+
+func TestListTenantsHandler(t *testing.T) {
+	server := setupTenantTest(t)
+
+	rr := serveTenantRequest(server, "GET", "/tenants", testAPIKey, "")
+	require.Equal(t, http.StatusOK, rr.Code)
+
+	var list []tenants.Tenant
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&list))
+	require.Len(t, list, 2)
+	assert.Equal(t, tenants.DefaultID, list[0].ID)
+	assert.Equal(t, "retail", list[1].ID)
+}
+
+func TestTenantRouting(t *testing.T) {
+	server := setupTenantTest(t)
+	account := `{"id":"ACC001","name":"Cash","currency":"USD","type":"asset"}`
+
+	rr := serveTenantRequest(server, "POST", "/tenants/retail/accounts", testAPIKey, account)
+	require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+	// The retail tenant only allows USD
+	rr = serveTenantRequest(server, "POST", "/tenants/retail/accounts", testAPIKey,
+		`{"id":"ACC002","name":"Euro","currency":"EUR","type":"asset"}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+
+	accountIDs := func(rr *httptest.ResponseRecorder) []string {
+		require.Equal(t, http.StatusOK, rr.Code, rr.Body.String())
+		var accounts []models.Account
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&accounts))
+		ids := []string{}
+		for _, account := range accounts {
+			ids = append(ids, account.ID)
+		}
+		return ids
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		key        string
+		wantStatus int
+		wantIDs    []string
+	}{
+		{name: "named tenant", path: "/tenants/retail/accounts", key: testAPIKey, wantStatus: http.StatusOK, wantIDs: []string{"ACC001"}},
+		{name: "platform principal defaults to the default tenant", path: "/accounts", key: testAPIKey, wantStatus: http.StatusOK, wantIDs: []string{}},
+		{name: "default tenant by name", path: "/tenants/default/accounts", key: testAPIKey, wantStatus: http.StatusOK, wantIDs: []string{}},
+		{name: "tenant principal", path: "/accounts", key: "retail-key", wantStatus: http.StatusOK, wantIDs: []string{"ACC001"}},
+		{name: "tenant principal naming its tenant", path: "/tenants/retail/accounts", key: "retail-key", wantStatus: http.StatusOK, wantIDs: []string{"ACC001"}},
+		{name: "tenant principal naming another tenant", path: "/tenants/default/accounts", key: "retail-key", wantStatus: http.StatusForbidden},
+		{name: "unknown tenant", path: "/tenants/cards/accounts", key: testAPIKey, wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := serveTenantRequest(server, "GET", tt.path, tt.key, "")
+			if tt.wantStatus != http.StatusOK {
+				assert.Equal(t, tt.wantStatus, rr.Code)
+				return
+			}
+			assert.Equal(t, tt.wantIDs, accountIDs(rr))
+		})
+	}
+
+	// Changes are audited in the tenant's own trail
+	retail, err := server.tenants.Get("retail")
+	require.NoError(t, err)
+	assert.Len(t, retail.Audit.Query(audit.Filter{Action: actionAccountCreate}), 1)
+	assert.Empty(t, server.audit.Query(audit.Filter{Action: actionAccountCreate}))
+}
+
+func TestTenantIsolation(t *testing.T) {
+	server := setupTenantTest(t)
+	// ACC001 exists in both tenants; the client key owns the default one
+	for _, path := range []string{"/accounts", "/tenants/retail/accounts"} {
+		for _, id := range []string{"ACC001", "ACC002"} {
+			rr := serveTenantRequest(server, "POST", path, testAPIKey,
+				`{"id":"`+id+`","name":"Cash","currency":"USD","type":"asset","balance":{"amount":"100","currency":"USD"}}`)
+			require.Equal(t, http.StatusCreated, rr.Code, rr.Body.String())
+		}
+	}
+	transfer := `{"id":"TX1","debit_account":"ACC001","credit_account":"ACC002","amount":{"amount":"10","currency":"USD"}}`
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		key        string
+		body       string
+		wantStatus int
+	}{
+		{name: "client reads its account", method: "GET", path: "/accounts/ACC001/balance", key: "client-key", wantStatus: http.StatusOK},
+		{name: "client names the default tenant", method: "GET", path: "/tenants/default/accounts/ACC001/balance", key: "client-key", wantStatus: http.StatusOK},
+		{name: "client reads another tenant's account", method: "GET", path: "/tenants/retail/accounts/ACC001/balance", key: "client-key", wantStatus: http.StatusForbidden},
+		{name: "client debits another tenant's account", method: "POST", path: "/tenants/retail/transactions", key: "client-key", body: transfer, wantStatus: http.StatusForbidden},
+		{name: "operator names another tenant", method: "GET", path: "/tenants/retail/accounts", key: "operator-key", wantStatus: http.StatusForbidden},
+		{name: "platform admin names another tenant", method: "GET", path: "/tenants/retail/accounts/ACC001/balance", key: testAPIKey, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := serveTenantRequest(server, tt.method, tt.path, tt.key, tt.body)
+			assert.Equal(t, tt.wantStatus, rr.Code, rr.Body.String())
+		})
+	}
+
+	retail, err := server.tenants.Get("retail")
+	require.NoError(t, err)
+	assert.Empty(t, retail.Ledger.FindTransactions(context.Background(), models.MetadataFilter{}))
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	"ledgerproject/audit"
+	"ledgerproject/auth"
+	"ledgerproject/config"
+	"ledgerproject/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTraceRequest(t *testing.T) {
+	setupTestLogger(t)
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	mockLedger := new(MockLedger)
+	mockLedger.On("GetAccountBalance", "ACC001").Return(models.Money{}, nil)
+	server := NewServer(ServerParams{Ledger: mockLedger, Config: &config.Config{}, Auth: testAuthenticator(t), Audit: audit.NewMemoryLog()})
+
+	tests := []struct {
+		name        string
+		traceparent string
+		wantTraceID string
+		wantParent  string
+	}{
+		{
+			name:        "continues the caller's trace",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			wantTraceID: "4bf92f3577b34da6a3ce929d0e0e4736",
+			wantParent:  "00f067aa0ba902b7",
+		},
+		{
+			name:        "starts a trace without traceparent",
+			traceparent: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(recorder.Ended())
+			req := httptest.NewRequest("GET", "/accounts/ACC001/balance", nil)
+			req.Header.Set(auth.APIKeyHeader, testAPIKey)
+			if tt.traceparent != "" {
+				req.Header.Set("traceparent", tt.traceparent)
+			}
+			rr := httptest.NewRecorder()
+			server.router.ServeHTTP(rr, req)
+			require.Equal(t, http.StatusOK, rr.Code)
+
+			ended := recorder.Ended()
+			require.Len(t, ended, before+1)
+			span := ended[before]
+			assert.Equal(t, "GET /accounts/{accountId}/balance", span.Name())
+			assert.Contains(t, span.Attributes(), attribute.Int("http.response.status_code", http.StatusOK))
+			assert.Contains(t, span.Attributes(), attribute.String("request_id", rr.Header().Get(RequestIDHeader)))
+			if tt.wantTraceID != "" {
+				assert.Equal(t, tt.wantTraceID, span.SpanContext().TraceID().String())
+				assert.Equal(t, tt.wantParent, span.Parent().SpanID().String())
+			} else {
+				assert.False(t, span.Parent().IsValid())
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"ledgerproject/webhooks"
+	"net/http"
+)
+
+// RegisterWebhookHandler registers an endpoint. The response is the only
+// place the signing secret is returned.
+func (s *Server) RegisterWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+
+	var endpoint webhooks.Endpoint
+	if err := json.NewDecoder(r.Body).Decode(&endpoint); err != nil {
+		log.Error("Failed to decode webhook registration", zap.Error(err))
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	registered, err := s.webhooks.Register(endpoint)
+	if err != nil {
+		log.Error("Failed to register webhook",
+			zap.Error(err),
+			zap.String("url", endpoint.URL))
+		writeError(w, r, err, http.StatusBadRequest)
+		return
+	}
+
+	// The secret stays out of the audit trail
+	audited := *registered
+	audited.Secret = ""
+	s.recordAudit(r, actionWebhookRegister, registered.ID, nil, audited)
+	writeJSON(w, http.StatusCreated, registered)
+}
+
+func (s *Server) ListWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.webhooks.Endpoints())
+}
+
+func (s *Server) DeleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+	id := mux.Vars(r)["endpointId"]
+
+	var before *webhooks.Endpoint
+	for _, endpoint := range s.webhooks.Endpoints() {
+		if endpoint.ID == id {
+			before = &endpoint
+			break
+		}
+	}
+
+	if err := s.webhooks.Unregister(id); err != nil {
+		log.Error("Failed to remove webhook",
+			zap.Error(err),
+			zap.String("endpoint_id", id))
+		writeError(w, r, err, webhookStatus(err))
+		return
+	}
+
+	s.recordAudit(r, actionWebhookDelete, id, before, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) ListDeadLettersHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.webhooks.DeadLetters())
+}
+
+// RedeliverDeadLetterHandler queues a dead-lettered delivery again.
+func (s *Server) RedeliverDeadLetterHandler(w http.ResponseWriter, r *http.Request) {
+	log := requestLogger(r)
+	id := mux.Vars(r)["deliveryId"]
+
+	if err := s.webhooks.Redeliver(id); err != nil {
+		log.Error("Failed to redeliver webhook",
+			zap.Error(err),
+			zap.String("delivery_id", id))
+		writeError(w, r, err, webhookStatus(err))
+		return
+	}
+
+	s.recordAudit(r, actionWebhookRedeliver, id, nil, nil)
+	log.Info("Webhook delivery queued again", zap.String("delivery_id", id))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func webhookStatus(err error) int {
+	if errors.Is(err, webhooks.ErrNotFound) {
+		return http.StatusNotFound
+	}
+	return http.StatusBadRequest
+}
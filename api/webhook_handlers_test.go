@@ -0,0 +1,160 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"ledgerproject/config"
+	"ledgerproject/models"
+	"ledgerproject/webhooks"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func setupWebhookTest(t *testing.T) (*Server, *MockLedger) {
+	server, mockLedger := setupTest(t)
+	server.webhooks = webhooks.NewDispatcher(mockLedger, &config.Config{
+		WebhookPollInterval: time.Second,
+		WebhookTimeout:      time.Second,
+		WebhookMaxAttempts:  3,
+		WebhookRetryBase:    time.Second,
+		WebhookRetryMax:     time.Minute,
+	})
+	mockLedger.On("Events", mock.Anything, mock.Anything).Return([]models.Event{})
+	return server, mockLedger
+}
+
+func TestRegisterWebhookHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "valid endpoint",
+			body:       `{"url":"https://example.com/hook","event_types":["account.created"]}`,
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name:       "unknown event type",
+			body:       `{"url":"https://example.com/hook","event_types":["account.deleted"]}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid url",
+			body:       `{"url":"example.com"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid json",
+			body:       `{"url":`,
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, _ := setupWebhookTest(t)
+
+			req := httptest.NewRequest("POST", "/webhooks", bytes.NewBufferString(tt.body))
+			rr := httptest.NewRecorder()
+
+			server.RegisterWebhookHandler(rr, req)
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			if tt.wantStatus != http.StatusCreated {
+				return
+			}
+
+			var endpoint webhooks.Endpoint
+			require.NoError(t, json.NewDecoder(rr.Body).Decode(&endpoint))
+			assert.NotEmpty(t, endpoint.ID)
+			assert.NotEmpty(t, endpoint.Secret)
+
+			// Listing never shows the secret
+			rr = httptest.NewRecorder()
+			server.ListWebhooksHandler(rr, httptest.NewRequest("GET", "/webhooks", nil))
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.NotContains(t, rr.Body.String(), endpoint.Secret)
+			assert.Contains(t, rr.Body.String(), endpoint.ID)
+		})
+	}
+}
+
+func TestDeleteWebhookHandler(t *testing.T) {
+	server, _ := setupWebhookTest(t)
+	endpoint, err := server.webhooks.Register(webhooks.Endpoint{URL: "https://example.com/hook"})
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		id         string
+		wantStatus int
+	}{
+		{id: endpoint.ID, wantStatus: http.StatusNoContent},
+		{id: endpoint.ID, wantStatus: http.StatusNotFound},
+	} {
+		req := httptest.NewRequest("DELETE", "/webhooks/"+tc.id, nil)
+		req = mux.SetURLVars(req, map[string]string{"endpointId": tc.id})
+		rr := httptest.NewRecorder()
+
+		server.DeleteWebhookHandler(rr, req)
+		assert.Equal(t, tc.wantStatus, rr.Code)
+	}
+}
+
+func TestDeadLetterHandlers(t *testing.T) {
+	server, _ := setupWebhookTest(t)
+
+	rr := httptest.NewRecorder()
+	server.ListDeadLettersHandler(rr, httptest.NewRequest("GET", "/webhooks/dead-letters", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `[]`, rr.Body.String())
+
+	req := httptest.NewRequest("POST", "/webhooks/dead-letters/missing/redeliver", nil)
+	req = mux.SetURLVars(req, map[string]string{"deliveryId": "missing"})
+	rr = httptest.NewRecorder()
+	server.RedeliverDeadLetterHandler(rr, req)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestFreezeAccountHandlers(t *testing.T) {
+	tests := []struct {
+		name       string
+		action     string
+		accountID  string
+		err        error
+		wantStatus int
+	}{
+		{name: "freeze", action: "freeze", accountID: "ACC001", wantStatus: http.StatusNoContent},
+		{name: "freeze twice", action: "freeze", accountID: "ACC002", err: fmt.Errorf("account ACC002 is already frozen"), wantStatus: http.StatusBadRequest},
+		{name: "unfreeze", action: "unfreeze", accountID: "ACC001", wantStatus: http.StatusNoContent},
+		{name: "unfreeze unknown", action: "unfreeze", accountID: "NONEXISTENT", err: fmt.Errorf("account NONEXISTENT does not exist"), wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, mockLedger := setupTest(t)
+
+			req := httptest.NewRequest("POST", "/accounts/"+tt.accountID+"/"+tt.action, nil)
+			req = mux.SetURLVars(req, map[string]string{"accountId": tt.accountID})
+			rr := httptest.NewRecorder()
+
+			if tt.action == "freeze" {
+				mockLedger.On("FreezeAccount", tt.accountID).Return(tt.err)
+				server.FreezeAccountHandler(rr, req)
+			} else {
+				mockLedger.On("UnfreezeAccount", tt.accountID).Return(tt.err)
+				server.UnfreezeAccountHandler(rr, req)
+			}
+
+			assert.Equal(t, tt.wantStatus, rr.Code)
+			mockLedger.AssertExpectations(t)
+		})
+	}
+}
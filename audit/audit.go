@@ -0,0 +1,233 @@
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"ledgerproject/config"
+	"ledgerproject/logger"
+	"os"
+	"sync"
+	"time"
+)
+
+// Entry is one audited action. Entries are chained: Hash covers the entry
+// together with the hash of the one before it, so any edit, removal or
+// reordering of stored entries breaks the chain.
+type Entry struct {
+	Sequence  uint64    `json:"sequence"`
+	Time      time.Time `json:"time"`
+	Actor     string    `json:"actor"`
+	Role      string    `json:"role,omitempty"`
+	Action    string    `json:"action"`
+	Target    string    `json:"target"`
+	RequestID string    `json:"request_id,omitempty"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+	Changes   []Change  `json:"changes"`
+	PrevHash  string    `json:"prev_hash"`
+	Hash      string    `json:"hash"`
+}
+
+// Filter selects entries. Zero fields match everything; From is inclusive
+// and To exclusive.
+type Filter struct {
+	Actor  string
+	Action string
+	Target string
+	From   time.Time
+	To     time.Time
+	After  uint64
+	Limit  int
+}
+
+func (f Filter) matches(e Entry) bool {
+	return (f.Actor == "" || e.Actor == f.Actor) &&
+		(f.Action == "" || e.Action == f.Action) &&
+		(f.Target == "" || e.Target == f.Target) &&
+		(f.From.IsZero() || !e.Time.Before(f.From)) &&
+		(f.To.IsZero() || e.Time.Before(f.To)) &&
+		e.Sequence > f.After
+}
+
+// Log is the append-only audit trail. It offers no way to change or delete
+// an entry. With a file configured every entry is appended and synced to it
+// before Record returns.
+type Log struct {
+	entries []Entry
+	file    *os.File
+	now     func() time.Time
+	mu      sync.RWMutex
+}
+
+// NewLog opens the audit trail in cfg.AuditLogFile, or keeps it in memory
+// when no file is configured. Existing entries are loaded and their chain
+// verified, so a tampered file stops the service from starting.
+func NewLog(cfg *config.Config) (*Log, error) {
+	if cfg.AuditLogFile == "" {
+		return NewMemoryLog(), nil
+	}
+
+	l := NewMemoryLog()
+	if err := l.load(cfg.AuditLogFile); err != nil {
+		return nil, err
+	}
+	if err := l.Verify(); err != nil {
+		return nil, fmt.Errorf("audit log %s is corrupt: %v", cfg.AuditLogFile, err)
+	}
+
+	file, err := os.OpenFile(cfg.AuditLogFile, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+	if err != nil {
+		return nil, fmt.Errorf("error opening audit log: %v", err)
+	}
+	l.file = file
+	return l, nil
+}
+
+func NewMemoryLog() *Log {
+	return &Log{entries: []Entry{}, now: time.Now}
+}
+
+func (l *Log) load(path string) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error opening audit log: %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("error decoding audit entry %d: %v", len(l.entries)+1, err)
+		}
+		l.entries = append(l.entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading audit log: %v", err)
+	}
+	return nil
+}
+
+// Record appends an entry for an action on target that moved it from before
+// to after. Sequence, time and hashes are assigned by the log.
+func (l *Log) Record(entry Entry, before, after interface{}) (Entry, error) {
+	changes, err := Diff(before, after)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.Sequence = uint64(len(l.entries)) + 1
+	entry.Time = l.now().UTC()
+	entry.Changes = changes
+	entry.PrevHash = ""
+	if len(l.entries) > 0 {
+		entry.PrevHash = l.entries[len(l.entries)-1].Hash
+	}
+	entry.Hash, err = hash(entry)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	if l.file != nil {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return Entry{}, fmt.Errorf("error encoding audit entry: %v", err)
+		}
+		if _, err := l.file.Write(append(line, '\n')); err != nil {
+			return Entry{}, fmt.Errorf("error writing audit entry: %v", err)
+		}
+		if err := l.file.Sync(); err != nil {
+			return Entry{}, fmt.Errorf("error syncing audit log: %v", err)
+		}
+	}
+
+	l.entries = append(l.entries, entry)
+	logger.Get().Info("Audit entry recorded",
+		zap.Uint64("sequence", entry.Sequence),
+		zap.String("actor", entry.Actor),
+		zap.String("action", entry.Action),
+		zap.String("target", entry.Target))
+	return entry, nil
+}
+
+// Query returns the matching entries, oldest first.
+func (l *Log) Query(filter Filter) []Entry {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	entries := []Entry{}
+	for _, entry := range l.entries {
+		if !filter.matches(entry) {
+			continue
+		}
+		entries = append(entries, entry)
+		if filter.Limit > 0 && len(entries) == filter.Limit {
+			break
+		}
+	}
+	return entries
+}
+
+// Verify recomputes the hash chain over all entries.
+func (l *Log) Verify() error {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return VerifyChain(l.entries)
+}
+
+// Close closes the audit file.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// VerifyChain checks that entries form an unbroken chain starting at
+// sequence 1, as they do in the log and in a full export.
+func VerifyChain(entries []Entry) error {
+	prev := ""
+	for i, entry := range entries {
+		if entry.Sequence != uint64(i)+1 {
+			return fmt.Errorf("entry %d has sequence %d", i+1, entry.Sequence)
+		}
+		if entry.PrevHash != prev {
+			return fmt.Errorf("entry %d does not follow entry %d", entry.Sequence, entry.Sequence-1)
+		}
+		expected, err := hash(entry)
+		if err != nil {
+			return err
+		}
+		if entry.Hash != expected {
+			return fmt.Errorf("entry %d has been modified", entry.Sequence)
+		}
+		prev = entry.Hash
+	}
+	return nil
+}
+
+func hash(entry Entry) (string, error) {
+	entry.Hash = ""
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", fmt.Errorf("error encoding audit entry: %v", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
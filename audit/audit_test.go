@@ -0,0 +1,124 @@
+package audit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"ledgerproject/config"
+	"ledgerproject/logger"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupTestLogger initializes a test logger
+func setupTestLogger(t *testing.T) *zap.Logger {
+	testLogger := zaptest.NewLogger(t)
+	// Initialize the package-level logger
+	if err := logger.Init(true); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	return testLogger
+}
+
+func record(t *testing.T, l *Log, actor, action, target string, before, after interface{}) Entry {
+	entry, err := l.Record(Entry{Actor: actor, Action: action, Target: target}, before, after)
+	require.NoError(t, err)
+	return entry
+}
+
+func TestRecordAndQuery(t *testing.T) {
+	setupTestLogger(t)
+	l := NewMemoryLog()
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	l.now = func() time.Time { return now }
+
+	first := record(t, l, "alice", "account.create", "ACC001", nil, map[string]string{"id": "ACC001"})
+	now = now.Add(time.Hour)
+	second := record(t, l, "bob", "account.freeze", "ACC001", map[string]bool{"frozen": false}, map[string]bool{"frozen": true})
+	now = now.Add(time.Hour)
+	record(t, l, "alice", "account.create", "ACC002", nil, map[string]string{"id": "ACC002"})
+
+	assert.Equal(t, uint64(1), first.Sequence)
+	assert.Empty(t, first.PrevHash)
+	assert.Equal(t, first.Hash, second.PrevHash)
+	assert.Equal(t, []Change{{Field: "frozen", From: false, To: true}}, second.Changes)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []uint64
+	}{
+		{name: "all", filter: Filter{}, want: []uint64{1, 2, 3}},
+		{name: "actor", filter: Filter{Actor: "alice"}, want: []uint64{1, 3}},
+		{name: "action", filter: Filter{Action: "account.freeze"}, want: []uint64{2}},
+		{name: "target", filter: Filter{Target: "ACC001"}, want: []uint64{1, 2}},
+		{name: "period", filter: Filter{From: start.Add(time.Hour), To: start.Add(2 * time.Hour)}, want: []uint64{2}},
+		{name: "after and limit", filter: Filter{After: 1, Limit: 1}, want: []uint64{2}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sequences []uint64
+			for _, entry := range l.Query(tt.filter) {
+				sequences = append(sequences, entry.Sequence)
+			}
+			assert.Equal(t, tt.want, sequences)
+		})
+	}
+
+	assert.NoError(t, l.Verify())
+}
+
+func TestVerifyChain(t *testing.T) {
+	setupTestLogger(t)
+	l := NewMemoryLog()
+	for _, target := range []string{"ACC001", "ACC002", "ACC003"} {
+		record(t, l, "alice", "account.create", target, nil, map[string]string{"id": target})
+	}
+	entries := l.Query(Filter{})
+	require.NoError(t, VerifyChain(entries))
+
+	edited := append([]Entry(nil), entries...)
+	edited[1].Actor = "mallory"
+	assert.ErrorContains(t, VerifyChain(edited), "entry 2 has been modified")
+
+	removed := []Entry{entries[0], entries[2]}
+	assert.ErrorContains(t, VerifyChain(removed), "has sequence")
+
+	rehashed := append([]Entry(nil), entries...)
+	rehashed[1].Actor = "mallory"
+	rehashed[1].Hash, _ = hash(rehashed[1])
+	assert.ErrorContains(t, VerifyChain(rehashed), "entry 3 does not follow entry 2")
+}
+
+func TestFileLog(t *testing.T) {
+	setupTestLogger(t)
+	cfg := &config.Config{AuditLogFile: filepath.Join(t.TempDir(), "audit.log")}
+
+	l, err := NewLog(cfg)
+	require.NoError(t, err)
+	record(t, l, "alice", "account.create", "ACC001", nil, map[string]string{"id": "ACC001"})
+	record(t, l, "alice", "fee_rules.update", "fee-rules", []string{}, []string{"small"})
+	require.NoError(t, l.Close())
+
+	// Entries survive a restart and new ones continue the chain
+	reopened, err := NewLog(cfg)
+	require.NoError(t, err)
+	third := record(t, reopened, "bob", "account.freeze", "ACC001", nil, nil)
+	assert.Equal(t, uint64(3), third.Sequence)
+	require.NoError(t, reopened.Verify())
+	require.NoError(t, reopened.Close())
+
+	// A tampered file is refused
+	data, err := os.ReadFile(cfg.AuditLogFile)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(cfg.AuditLogFile, []byte(strings.Replace(string(data), "bob", "eve", 1)), 0o640))
+
+	_, err = NewLog(cfg)
+	assert.ErrorContains(t, err, "is corrupt")
+}
@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// Change is one field that differs between the before and after state.
+// Fields are dotted JSON paths; list elements are addressed by index.
+type Change struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// Diff compares two values through their JSON form. A nil before or after
+// stands for a target that did not exist or no longer exists.
+func Diff(before, after interface{}) ([]Change, error) {
+	from, err := flatten(before)
+	if err != nil {
+		return nil, err
+	}
+	to, err := flatten(after)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]bool)
+	for field := range from {
+		fields[field] = true
+	}
+	for field := range to {
+		fields[field] = true
+	}
+
+	changes := []Change{}
+	for field := range fields {
+		if !reflect.DeepEqual(from[field], to[field]) {
+			changes = append(changes, Change{Field: field, From: from[field], To: to[field]})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes, nil
+}
+
+func flatten(v interface{}) (map[string]interface{}, error) {
+	fields := make(map[string]interface{})
+	if v == nil {
+		return fields, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding audit state: %v", err)
+	}
+	var decoded interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, fmt.Errorf("error decoding audit state: %v", err)
+	}
+	walk("", decoded, fields)
+	return fields, nil
+}
+
+func walk(path string, v interface{}, fields map[string]interface{}) {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if len(value) == 0 && path != "" {
+			fields[path] = value
+		}
+		for key, child := range value {
+			walk(join(path, key), child, fields)
+		}
+	case []interface{}:
+		if len(value) == 0 && path != "" {
+			fields[path] = value
+		}
+		for i, child := range value {
+			walk(join(path, fmt.Sprint(i)), child, fields)
+		}
+	default:
+		if path == "" {
+			path = "value"
+		}
+		fields[path] = value
+	}
+}
+
+func join(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
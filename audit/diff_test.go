@@ -0,0 +1,65 @@
+package audit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	type limits struct {
+		Daily  string   `json:"daily"`
+		Frozen bool     `json:"frozen"`
+		Tags   []string `json:"tags,omitempty"`
+	}
+
+	tests := []struct {
+		name   string
+		before interface{}
+		after  interface{}
+		want   []Change
+	}{
+		{
+			name:   "created",
+			before: nil,
+			after:  limits{Daily: "100", Tags: []string{"vip"}},
+			want: []Change{
+				{Field: "daily", To: "100"},
+				{Field: "frozen", To: false},
+				{Field: "tags.0", To: "vip"},
+			},
+		},
+		{
+			name:   "changed field",
+			before: limits{Daily: "100"},
+			after:  limits{Daily: "250"},
+			want:   []Change{{Field: "daily", From: "100", To: "250"}},
+		},
+		{
+			name:   "deleted",
+			before: map[string]bool{"frozen": true},
+			after:  nil,
+			want:   []Change{{Field: "frozen", From: true}},
+		},
+		{
+			name:   "unchanged",
+			before: limits{Daily: "100"},
+			after:  limits{Daily: "100"},
+			want:   []Change{},
+		},
+		{
+			name:   "scalar",
+			before: 1,
+			after:  2,
+			want:   []Change{{Field: "value", From: float64(1), To: float64(2)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := Diff(tt.before, tt.after)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, changes)
+		})
+	}
+}
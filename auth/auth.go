@@ -0,0 +1,212 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"ledgerproject/config"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrUnauthenticated is returned when a request carries no valid credentials.
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// Authentication methods recorded on a principal.
+const (
+	MethodAPIKey      = "api_key"
+	MethodJWT         = "jwt"
+	MethodCertificate = "certificate"
+)
+
+// APIKeyHeader carries a static API key. Keys may also be sent as a bearer
+// token.
+const APIKeyHeader = "X-API-Key"
+
+// Principal is the authenticated caller of a request. Accounts lists the
+// accounts a client owns. Tenant binds the principal to one tenant's ledger;
+// platform principals have none.
+type Principal struct {
+	ID       string   `json:"id"`
+	Method   string   `json:"method"`
+	Role     string   `json:"role"`
+	Accounts []string `json:"accounts,omitempty"`
+	Tenant   string   `json:"tenant,omitempty"`
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal stored in ctx, if any.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
+
+// Authenticator verifies API keys, JWTs and client certificates. API keys are
+// configured by their SHA-256 digest so the configuration never holds a
+// usable key. JWTs are verified locally against the HS256 secret or the
+// Ed25519 public key. Client certificates are verified by the TLS handshake
+// and mapped to principals by their subject common name.
+type Authenticator struct {
+	apiKeys      map[string]Principal
+	certificates map[string]Principal
+	hmacSecret   []byte
+	publicKey    ed25519.PublicKey
+	issuer       string
+	audience     string
+	leeway       time.Duration
+	now          func() time.Time
+}
+
+func NewAuthenticator(cfg *config.Config) (*Authenticator, error) {
+	a := &Authenticator{
+		apiKeys:      make(map[string]Principal),
+		certificates: make(map[string]Principal),
+		hmacSecret:   []byte(cfg.JWTHMACSecret),
+		issuer:       cfg.JWTIssuer,
+		audience:     cfg.JWTAudience,
+		leeway:       cfg.JWTLeeway,
+		now:          time.Now,
+	}
+
+	for _, key := range cfg.APIKeys {
+		digest, err := hex.DecodeString(key.KeyHash)
+		if err != nil || len(digest) != sha256.Size {
+			return nil, fmt.Errorf("api key for %s: key hash must be a hex SHA-256 digest", key.Principal)
+		}
+		if key.Principal == "" {
+			return nil, fmt.Errorf("api key %s has no principal", key.KeyHash)
+		}
+		if !KnownRole(key.Role) {
+			return nil, fmt.Errorf("api key for %s has unknown role %q", key.Principal, key.Role)
+		}
+		a.apiKeys[strings.ToLower(key.KeyHash)] = Principal{
+			ID:       key.Principal,
+			Method:   MethodAPIKey,
+			Role:     key.Role,
+			Accounts: append([]string(nil), key.Accounts...),
+			Tenant:   key.Tenant,
+		}
+	}
+
+	for _, cert := range cfg.ClientCertificates {
+		if cert.CommonName == "" {
+			return nil, errors.New("client certificate has no common name")
+		}
+		if !KnownRole(cert.Role) {
+			return nil, fmt.Errorf("client certificate %s has unknown role %q", cert.CommonName, cert.Role)
+		}
+		a.certificates[cert.CommonName] = Principal{
+			ID:       cert.CommonName,
+			Method:   MethodCertificate,
+			Role:     cert.Role,
+			Accounts: append([]string(nil), cert.Accounts...),
+			Tenant:   cert.Tenant,
+		}
+	}
+
+	if cfg.JWTPublicKeyFile != "" {
+		publicKey, err := loadPublicKey(cfg.JWTPublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		a.publicKey = publicKey
+	}
+	return a, nil
+}
+
+// Authenticate returns the principal of the request. Credentials are read
+// from the X-API-Key header or an "Authorization: Bearer" token, which may
+// hold either a JWT or an API key. Requests without either are authenticated
+// by the client certificate verified in the TLS handshake, if any.
+func (a *Authenticator) Authenticate(r *http.Request) (Principal, error) {
+	apiKey, authorization := r.Header.Get(APIKeyHeader), r.Header.Get("Authorization")
+	if apiKey == "" && authorization == "" && r.TLS != nil && len(r.TLS.VerifiedChains) > 0 {
+		return a.AuthenticateCertificate(r.TLS.VerifiedChains[0][0])
+	}
+	return a.AuthenticateCredentials(apiKey, authorization)
+}
+
+// AuthenticateCertificate returns the principal of a client certificate that
+// has already been verified against the client CAs.
+func (a *Authenticator) AuthenticateCertificate(cert *x509.Certificate) (Principal, error) {
+	principal, exists := a.certificates[cert.Subject.CommonName]
+	if !exists {
+		return Principal{}, fmt.Errorf("%w: unknown client certificate %q", ErrUnauthenticated, cert.Subject.CommonName)
+	}
+	return principal, nil
+}
+
+// AuthenticateCredentials returns the principal of an API key or an
+// Authorization value, for transports other than HTTP. The API key wins
+// when both are given.
+func (a *Authenticator) AuthenticateCredentials(apiKey, authorization string) (Principal, error) {
+	if apiKey != "" {
+		return a.apiKey(apiKey)
+	}
+
+	scheme, token, found := strings.Cut(authorization, " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return Principal{}, fmt.Errorf("%w: missing credentials", ErrUnauthenticated)
+	}
+	if strings.Count(token, ".") == 2 {
+		return a.jwt(token)
+	}
+	return a.apiKey(token)
+}
+
+func (a *Authenticator) apiKey(key string) (Principal, error) {
+	// Looking up the digest keeps the comparison independent of the key
+	digest := sha256.Sum256([]byte(key))
+	principal, exists := a.apiKeys[hex.EncodeToString(digest[:])]
+	if !exists {
+		return Principal{}, fmt.Errorf("%w: unknown api key", ErrUnauthenticated)
+	}
+	return principal, nil
+}
+
+func (a *Authenticator) jwt(token string) (Principal, error) {
+	claims, err := a.verifyJWT(token)
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+	return Principal{
+		ID:       claims.Subject,
+		Method:   MethodJWT,
+		Role:     claims.Role,
+		Accounts: claims.Accounts,
+		Tenant:   claims.Tenant,
+	}, nil
+}
+
+func loadPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading jwt public key: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("jwt public key %s is not PEM encoded", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing jwt public key: %v", err)
+	}
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("jwt public key %s is not an Ed25519 key", path)
+	}
+	return publicKey, nil
+}
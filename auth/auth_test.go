@@ -0,0 +1,252 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ledgerproject/config"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const hmacSecret = "0123456789abcdef0123456789abcdef"
+
+var now = time.Unix(1700000000, 0)
+
+func segment(t *testing.T, v interface{}) string {
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func hs256(t *testing.T, secret string, claims map[string]interface{}) string {
+	unsigned := segment(t, map[string]string{"alg": "HS256", "typ": "JWT"}) + "." + segment(t, claims)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func eddsa(t *testing.T, key ed25519.PrivateKey, claims map[string]interface{}) string {
+	unsigned := segment(t, map[string]string{"alg": "EdDSA", "typ": "JWT"}) + "." + segment(t, claims)
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(key, []byte(unsigned)))
+}
+
+// setupTest returns an authenticator accepting the API key "secret-key",
+// HS256 tokens signed with hmacSecret and EdDSA tokens signed with the
+// returned private key.
+func setupTest(t *testing.T) (*Authenticator, ed25519.PrivateKey) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	require.NoError(t, err)
+	keyFile := filepath.Join(t.TempDir(), "jwt.pem")
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), 0o600))
+
+	digest := sha256.Sum256([]byte("secret-key"))
+	a, err := NewAuthenticator(&config.Config{
+		APIKeys: []config.APIKey{{Principal: "ops", KeyHash: hex.EncodeToString(digest[:]), Role: RoleAuditor}},
+		ClientCertificates: []config.ClientCertificate{
+			{CommonName: "payments", Role: RoleClient, Accounts: []string{"ACC001"}, Tenant: "retail"},
+		},
+		JWTHMACSecret:    hmacSecret,
+		JWTPublicKeyFile: keyFile,
+		JWTIssuer:        "https://id.example.com",
+		JWTAudience:      "ledger",
+		JWTLeeway:        30 * time.Second,
+	})
+	require.NoError(t, err)
+	a.now = func() time.Time { return now }
+	return a, privateKey
+}
+
+func claims(overrides map[string]interface{}) map[string]interface{} {
+	c := map[string]interface{}{
+		"sub":  "alice",
+		"iss":  "https://id.example.com",
+		"aud":  "ledger",
+		"exp":  now.Add(time.Hour).Unix(),
+		"role": RoleOperator,
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(c, k)
+			continue
+		}
+		c[k] = v
+	}
+	return c
+}
+
+func TestAuthenticate(t *testing.T) {
+	a, privateKey := setupTest(t)
+	_, otherKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	none := segment(t, map[string]string{"alg": "none"}) + "." + segment(t, claims(nil)) + "."
+
+	tests := []struct {
+		name    string
+		header  string
+		value   string
+		want    Principal
+		wantErr string
+	}{
+		{name: "api key", header: APIKeyHeader, value: "secret-key", want: Principal{ID: "ops", Method: MethodAPIKey, Role: RoleAuditor}},
+		{name: "api key bearer", header: "Authorization", value: "Bearer secret-key", want: Principal{ID: "ops", Method: MethodAPIKey, Role: RoleAuditor}},
+		{name: "unknown api key", header: APIKeyHeader, value: "other-key", wantErr: "unknown api key"},
+		{name: "hs256", header: "Authorization", value: "Bearer " + hs256(t, hmacSecret, claims(nil)), want: Principal{ID: "alice", Method: MethodJWT, Role: RoleOperator}},
+		{name: "eddsa", header: "Authorization", value: "bearer " + eddsa(t, privateKey, claims(nil)), want: Principal{ID: "alice", Method: MethodJWT, Role: RoleOperator}},
+		{name: "audience list", header: "Authorization", value: "Bearer " + hs256(t, hmacSecret, claims(map[string]interface{}{"aud": []string{"other", "ledger"}})), want: Principal{ID: "alice", Method: MethodJWT, Role: RoleOperator}},
+		{name: "within leeway", header: "Authorization", value: "Bearer " + hs256(t, hmacSecret, claims(map[string]interface{}{"exp": now.Add(-10 * time.Second).Unix()})), want: Principal{ID: "alice", Method: MethodJWT, Role: RoleOperator}},
+		{name: "client claims", header: "Authorization", value: "Bearer " + hs256(t, hmacSecret, claims(map[string]interface{}{"role": RoleClient, "accounts": []string{"ACC001"}})),
+			want: Principal{ID: "alice", Method: MethodJWT, Role: RoleClient, Accounts: []string{"ACC001"}}},
+		{name: "tenant claim", header: "Authorization", value: "Bearer " + hs256(t, hmacSecret, claims(map[string]interface{}{"tenant": "retail"})),
+			want: Principal{ID: "alice", Method: MethodJWT, Role: RoleOperator, Tenant: "retail"}},
+		{name: "unknown role", header: "Authorization", value: "Bearer " + hs256(t, hmacSecret, claims(map[string]interface{}{"role": "root"})), wantErr: "unknown role"},
+		{name: "no role", header: "Authorization", value: "Bearer " + hs256(t, hmacSecret, claims(map[string]interface{}{"role": nil})), wantErr: "unknown role"},
+		{name: "wrong hmac secret", header: "Authorization", value: "Bearer " + hs256(t, "other-secret", claims(nil)), wantErr: "invalid token signature"},
+		{name: "wrong ed25519 key", header: "Authorization", value: "Bearer " + eddsa(t, otherKey, claims(nil)), wantErr: "invalid token signature"},
+		{name: "alg none", header: "Authorization", value: "Bearer " + none, wantErr: "unsupported token algorithm"},
+		{name: "expired", header: "Authorization", value: "Bearer " + hs256(t, hmacSecret, claims(map[string]interface{}{"exp": now.Add(-time.Minute).Unix()})), wantErr: "token expired"},
+		{name: "no expiry", header: "Authorization", value: "Bearer " + hs256(t, hmacSecret, claims(map[string]interface{}{"exp": nil})), wantErr: "no expiry"},
+		{name: "not yet valid", header: "Authorization", value: "Bearer " + hs256(t, hmacSecret, claims(map[string]interface{}{"nbf": now.Add(time.Minute).Unix()})), wantErr: "not valid yet"},
+		{name: "no subject", header: "Authorization", value: "Bearer " + hs256(t, hmacSecret, claims(map[string]interface{}{"sub": nil})), wantErr: "no subject"},
+		{name: "wrong issuer", header: "Authorization", value: "Bearer " + hs256(t, hmacSecret, claims(map[string]interface{}{"iss": "https://evil.example.com"})), wantErr: "unexpected token issuer"},
+		{name: "wrong audience", header: "Authorization", value: "Bearer " + hs256(t, hmacSecret, claims(map[string]interface{}{"aud": "billing"})), wantErr: "audience"},
+		{name: "malformed token", header: "Authorization", value: "Bearer a.b.c", wantErr: "malformed token header"},
+		{name: "missing credentials", wantErr: "missing credentials"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/accounts", nil)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+
+			principal, err := a.Authenticate(req)
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, ErrUnauthenticated)
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, principal)
+		})
+	}
+}
+
+func TestAuthenticateCertificate(t *testing.T) {
+	a, _ := setupTest(t)
+	verified := func(commonName string) *tls.ConnectionState {
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName, Organization: []string{"Example"}}}
+		return &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}, VerifiedChains: [][]*x509.Certificate{{cert}}}
+	}
+
+	tests := []struct {
+		name    string
+		state   *tls.ConnectionState
+		apiKey  string
+		want    Principal
+		wantErr string
+	}{
+		{
+			name:  "mapped subject",
+			state: verified("payments"),
+			want:  Principal{ID: "payments", Method: MethodCertificate, Role: RoleClient, Accounts: []string{"ACC001"}, Tenant: "retail"},
+		},
+		{name: "unknown subject", state: verified("billing"), wantErr: `unknown client certificate "billing"`},
+		{name: "api key wins", state: verified("payments"), apiKey: "secret-key", want: Principal{ID: "ops", Method: MethodAPIKey, Role: RoleAuditor}},
+		{
+			name:    "unverified certificate",
+			state:   &tls.ConnectionState{PeerCertificates: verified("payments").PeerCertificates},
+			wantErr: "missing credentials",
+		},
+		{name: "plain http", wantErr: "missing credentials"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/accounts", nil)
+			req.TLS = tt.state
+			if tt.apiKey != "" {
+				req.Header.Set(APIKeyHeader, tt.apiKey)
+			}
+
+			principal, err := a.Authenticate(req)
+			if tt.wantErr != "" {
+				assert.ErrorIs(t, err, ErrUnauthenticated)
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, principal)
+		})
+	}
+}
+
+func TestAlgorithmRequiresKey(t *testing.T) {
+	a, err := NewAuthenticator(&config.Config{})
+	require.NoError(t, err)
+	a.now = func() time.Time { return now }
+
+	req := httptest.NewRequest("GET", "/accounts", nil)
+	req.Header.Set("Authorization", "Bearer "+hs256(t, "", claims(nil)))
+
+	_, err = a.Authenticate(req)
+	assert.ErrorContains(t, err, "HS256 tokens are not accepted")
+}
+
+func TestNewAuthenticatorErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantErr string
+	}{
+		{
+			name:    "key hash not hex",
+			cfg:     config.Config{APIKeys: []config.APIKey{{Principal: "ops", KeyHash: "secret-key"}}},
+			wantErr: "hex SHA-256 digest",
+		},
+		{
+			name:    "key with unknown role",
+			cfg:     config.Config{APIKeys: []config.APIKey{{Principal: "ops", KeyHash: hex.EncodeToString(make([]byte, 32)), Role: "root"}}},
+			wantErr: "unknown role",
+		},
+		{
+			name:    "key without principal",
+			cfg:     config.Config{APIKeys: []config.APIKey{{KeyHash: hex.EncodeToString(make([]byte, 32))}}},
+			wantErr: "has no principal",
+		},
+		{
+			name:    "certificate with unknown role",
+			cfg:     config.Config{ClientCertificates: []config.ClientCertificate{{CommonName: "payments", Role: "root"}}},
+			wantErr: "unknown role",
+		},
+		{
+			name:    "missing public key file",
+			cfg:     config.Config{JWTPublicKeyFile: filepath.Join(t.TempDir(), "missing.pem")},
+			wantErr: "error reading jwt public key",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewAuthenticator(&tt.cfg)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
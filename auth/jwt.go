@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Claims are the registered JWT claims the ledger checks, plus the role, the
+// owned accounts for clients and the tenant the token is bound to.
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Issuer    string   `json:"iss,omitempty"`
+	Audience  audience `json:"aud,omitempty"`
+	ExpiresAt int64    `json:"exp"`
+	NotBefore int64    `json:"nbf,omitempty"`
+	IssuedAt  int64    `json:"iat,omitempty"`
+	Role      string   `json:"role"`
+	Accounts  []string `json:"accounts,omitempty"`
+	Tenant    string   `json:"tenant,omitempty"`
+}
+
+// audience accepts the aud claim as a single string or a list.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("invalid aud claim")
+	}
+	*a = list
+	return nil
+}
+
+type header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+}
+
+// verifyJWT checks the signature and the time, issuer and audience claims of
+// a compact JWS. Only HS256 and EdDSA are accepted, and only when the
+// matching key is configured, so a token cannot pick its own algorithm.
+func (a *Authenticator) verifyJWT(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+
+	var h header
+	if err := decodeSegment(parts[0], &h); err != nil {
+		return nil, fmt.Errorf("malformed token header")
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("malformed token signature")
+	}
+
+	signed := []byte(parts[0] + "." + parts[1])
+	switch h.Algorithm {
+	case "HS256":
+		if len(a.hmacSecret) == 0 {
+			return nil, fmt.Errorf("HS256 tokens are not accepted")
+		}
+		mac := hmac.New(sha256.New, a.hmacSecret)
+		mac.Write(signed)
+		if !hmac.Equal(mac.Sum(nil), signature) {
+			return nil, fmt.Errorf("invalid token signature")
+		}
+	case "EdDSA":
+		if a.publicKey == nil {
+			return nil, fmt.Errorf("EdDSA tokens are not accepted")
+		}
+		if !ed25519.Verify(a.publicKey, signed, signature) {
+			return nil, fmt.Errorf("invalid token signature")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported token algorithm %q", h.Algorithm)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims")
+	}
+	if err := a.validate(&claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+func (a *Authenticator) validate(claims *Claims) error {
+	now := a.now()
+	if claims.Subject == "" {
+		return fmt.Errorf("token has no subject")
+	}
+	if !KnownRole(claims.Role) {
+		return fmt.Errorf("token has unknown role %q", claims.Role)
+	}
+	if claims.ExpiresAt == 0 {
+		return fmt.Errorf("token has no expiry")
+	}
+	if now.After(time.Unix(claims.ExpiresAt, 0).Add(a.leeway)) {
+		return fmt.Errorf("token expired")
+	}
+	if claims.NotBefore != 0 && now.Add(a.leeway).Before(time.Unix(claims.NotBefore, 0)) {
+		return fmt.Errorf("token not valid yet")
+	}
+	if a.issuer != "" && claims.Issuer != a.issuer {
+		return fmt.Errorf("unexpected token issuer %q", claims.Issuer)
+	}
+	if a.audience != "" {
+		for _, aud := range claims.Audience {
+			if aud == a.audience {
+				return nil
+			}
+		}
+		return fmt.Errorf("token not issued for this audience")
+	}
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,101 @@
+package auth
+
+// Roles a principal can hold.
+const (
+	RoleAdmin    = "admin"
+	RoleOperator = "operator"
+	RoleAuditor  = "auditor"
+	RoleClient   = "client"
+)
+
+// Permission is the right to call a group of routes.
+type Permission string
+
+const (
+	// Reading accounts, balances, history and statements
+	PermAccountsRead Permission = "accounts:read"
+	// Creating and freezing accounts and setting their interest terms
+	PermAccountsManage Permission = "accounts:manage"
+	// Recording transactions and previewing their fees
+	PermTransactionsPost Permission = "transactions:post"
+	// Reading reconciliations and standing orders
+	PermOperationsRead Permission = "operations:read"
+	// Journal imports, reconciliations and standing orders
+	PermOperationsManage Permission = "operations:manage"
+	// Reading fee rules and webhook endpoints
+	PermSettingsRead Permission = "settings:read"
+	// Changing fee rules and webhook endpoints
+	PermSettingsManage Permission = "settings:manage"
+	// Reading the event stream and change feed
+	PermEventsRead Permission = "events:read"
+	// Reading and exporting the audit trail
+	PermAuditRead Permission = "audit:read"
+	// Creating and listing tenants
+	PermTenantsManage Permission = "tenants:manage"
+	// Scraping the metrics of the whole platform
+	PermMetricsRead Permission = "metrics:read"
+)
+
+// platformPermissions span every tenant and are never granted to a
+// principal bound to one.
+var platformPermissions = map[Permission]bool{
+	PermTenantsManage: true,
+	PermMetricsRead:   true,
+}
+
+var rolePermissions = map[string][]Permission{
+	RoleAdmin: {
+		PermAccountsRead, PermAccountsManage, PermTransactionsPost,
+		PermOperationsRead, PermOperationsManage,
+		PermSettingsRead, PermSettingsManage, PermEventsRead,
+		PermTenantsManage, PermMetricsRead,
+	},
+	RoleOperator: {
+		PermAccountsRead, PermAccountsManage, PermTransactionsPost,
+		PermOperationsRead, PermOperationsManage,
+		PermSettingsRead, PermEventsRead, PermMetricsRead,
+	},
+	RoleAuditor: {
+		PermAccountsRead, PermOperationsRead, PermSettingsRead, PermEventsRead,
+		PermAuditRead,
+	},
+	RoleClient: {
+		PermAccountsRead, PermTransactionsPost,
+	},
+}
+
+// KnownRole reports whether role is one of the defined roles.
+func KnownRole(role string) bool {
+	_, exists := rolePermissions[role]
+	return exists
+}
+
+// Can reports whether the principal's role grants the permission. Tenants
+// and metrics are platform concerns, never open to a principal bound to a
+// tenant.
+func (p Principal) Can(permission Permission) bool {
+	if platformPermissions[permission] && p.Tenant != "" {
+		return false
+	}
+	for _, granted := range rolePermissions[p.Role] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// CanAccessAccount reports whether the principal may see and debit the
+// account. Clients are limited to the accounts they own; the staff roles
+// reach every account their permissions allow.
+func (p Principal) CanAccessAccount(accountID string) bool {
+	if p.Role != RoleClient {
+		return true
+	}
+	for _, owned := range p.Accounts {
+		if owned == accountID {
+			return true
+		}
+	}
+	return false
+}
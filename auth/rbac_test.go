@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestPermissions(t *testing.T) {
+	tests := []struct {
+		role       string
+		permission Permission
+		want       bool
+	}{
+		{RoleAdmin, PermSettingsManage, true},
+		{RoleOperator, PermTransactionsPost, true},
+		{RoleOperator, PermSettingsManage, false},
+		{RoleAuditor, PermAccountsRead, true},
+		{RoleAuditor, PermTransactionsPost, false},
+		{RoleAuditor, PermAccountsManage, false},
+		{RoleClient, PermAccountsRead, true},
+		{RoleClient, PermTransactionsPost, true},
+		{RoleClient, PermEventsRead, false},
+		{RoleAuditor, PermAuditRead, true},
+		{RoleAdmin, PermAuditRead, false},
+		{RoleAdmin, PermTenantsManage, true},
+		{RoleOperator, PermTenantsManage, false},
+		{RoleOperator, PermMetricsRead, true},
+		{RoleClient, PermMetricsRead, false},
+		{"", PermAccountsRead, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.role+" "+string(tt.permission), func(t *testing.T) {
+			assert.Equal(t, tt.want, Principal{Role: tt.role}.Can(tt.permission))
+		})
+	}
+}
+
+func TestTenantBoundPrincipal(t *testing.T) {
+	admin := Principal{Role: RoleAdmin, Tenant: "retail"}
+	assert.False(t, admin.Can(PermTenantsManage))
+	assert.False(t, admin.Can(PermMetricsRead))
+	assert.True(t, admin.Can(PermSettingsManage))
+}
+
+func TestCanAccessAccount(t *testing.T) {
+	client := Principal{Role: RoleClient, Accounts: []string{"ACC001"}}
+	assert.True(t, client.CanAccessAccount("ACC001"))
+	assert.False(t, client.CanAccessAccount("ACC002"))
+	assert.False(t, Principal{Role: RoleClient}.CanAccessAccount("ACC001"))
+
+	for _, role := range []string{RoleAdmin, RoleOperator, RoleAuditor} {
+		assert.True(t, Principal{Role: role}.CanAccessAccount("ACC002"), role)
+	}
+}
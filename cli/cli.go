@@ -0,0 +1,240 @@
+// Package cli implements the ledger's command-line subcommands. They talk to
+// a running server through its HTTP API with the credentials in the
+// configuration.
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"ledgerproject/config"
+	"sort"
+	"strings"
+)
+
+// Exit statuses of Run.
+const (
+	exitOK    = 0
+	exitError = 1
+	exitUsage = 2
+)
+
+// errUsage marks errors in how a command was invoked.
+var errUsage = errors.New("usage error")
+
+type command struct {
+	usage   string
+	summary string
+	run     func(r *runner, args []string) error
+}
+
+// commands are the subcommands by name. Groups of commands, such as
+// "accounts", have their own table.
+var commands = map[string]command{
+	"accounts": {"accounts <create|get|list> [flags]", "Manage accounts", (*runner).accounts},
+	"tx":       {"tx <post|reverse> [flags]", "Post and reverse transactions", (*runner).transactions},
+	"balance":  {"balance [flags] <account>", "Show the balance of an account", (*runner).balance},
+	"history":  {"history [flags] <account>", "Show the transactions of an account", (*runner).history},
+	"verify":   {"verify [flags]", "Check that the books balance and the audit trail is intact", (*runner).verify},
+	"export":   {"export [flags] [accounts|transactions|audit]", "Export the book or the audit trail", (*runner).export},
+}
+
+// runner carries what every command needs: the client configuration and
+// where to write.
+type runner struct {
+	ctx    context.Context
+	cfg    config.Client
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Run executes the subcommand named by args[0] and returns the process exit
+// status. The client commands reach the server in the client section of the
+// configuration. "serve" is handled by the caller.
+func Run(args []string, stdout, stderr io.Writer) int {
+	if len(args) > 0 && args[0] == "config" {
+		return printConfig(args[1:], stdout, stderr)
+	}
+	cfg, err := config.FromEnvironment()
+	if err != nil {
+		fmt.Fprintf(stderr, "error: invalid configuration:\n%v\n", err)
+		return exitError
+	}
+	return run(args, cfg.Client, stdout, stderr)
+}
+
+func run(args []string, cfg config.Client, stdout, stderr io.Writer) int {
+	r := &runner{ctx: context.Background(), cfg: cfg, stdout: stdout, stderr: stderr}
+
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		r.usage()
+		return exitOK
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "unknown command %q\n\n", args[0])
+		r.usage()
+		return exitUsage
+	}
+
+	err := cmd.run(r, args[1:])
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, flag.ErrHelp):
+		return exitOK
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(stderr, "%v\nusage: ledger %s\n", err, cmd.usage)
+		return exitUsage
+	}
+	fmt.Fprintf(stderr, "error: %v\n", err)
+	return exitError
+}
+
+func (r *runner) usage() {
+	fmt.Fprintln(r.stderr, "usage: ledger <command> [flags] [arguments]")
+	fmt.Fprintln(r.stderr, "\ncommands:")
+	fmt.Fprintf(r.stderr, "  %-46s %s\n", "serve [flags]", "Run the ledger server (the default)")
+	fmt.Fprintf(r.stderr, "  %-46s %s\n", "config print [flags]", "Show the effective configuration")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(r.stderr, "  %-46s %s\n", commands[name].usage, commands[name].summary)
+	}
+	fmt.Fprintln(r.stderr, "\nThe server and credentials come from the client section of the configuration,")
+	fmt.Fprintln(r.stderr, "such as LEDGER_CLIENT_URL and LEDGER_CLIENT_API_KEY or LEDGER_CLIENT_TOKEN.")
+	fmt.Fprintln(r.stderr, "Run 'ledger <command> -h' for the flags of a command.")
+}
+
+// subcommand dispatches a group of commands, such as "accounts list".
+func (r *runner) subcommand(group string, args []string, subs map[string]func([]string) error) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: %s needs a subcommand", errUsage, group)
+	}
+	run, ok := subs[args[0]]
+	if !ok {
+		return fmt.Errorf("%w: unknown %s subcommand %q", errUsage, group, args[0])
+	}
+	return run(args[1:])
+}
+
+// options are the flags shared by all commands.
+type options struct {
+	format string
+}
+
+// flags returns the flag set of a command with the shared flags: the server,
+// tenant and timeout override the configuration.
+func (r *runner) flags(name string) (*flag.FlagSet, *options) {
+	fs := flag.NewFlagSet("ledger "+name, flag.ContinueOnError)
+	fs.SetOutput(r.stderr)
+	opts := &options{}
+	fs.StringVar(&opts.format, "o", FormatTable, "output format: table, json or csv")
+	fs.StringVar(&r.cfg.URL, "url", r.cfg.URL, "base URL of the server")
+	fs.StringVar(&r.cfg.Tenant, "tenant", r.cfg.Tenant, "tenant to address")
+	fs.DurationVar(&r.cfg.Timeout, "timeout", r.cfg.Timeout, "request timeout")
+	return fs, opts
+}
+
+// parse parses the flags of a command, which may be mixed with its
+// arguments, and checks the number of arguments.
+func (r *runner) parse(fs *flag.FlagSet, opts *options, args []string, min, max int) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: %v", errUsage, err)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if !validFormat(opts.format) {
+		return nil, fmt.Errorf("%w: unknown output format %q", errUsage, opts.format)
+	}
+	if len(positional) < min || len(positional) > max {
+		return nil, fmt.Errorf("%w: wrong number of arguments", errUsage)
+	}
+	return positional, nil
+}
+
+// printConfig runs "config print", which shows the configuration that serve
+// would use given the same flags. Secrets are redacted.
+func printConfig(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(stderr, "usage: ledger config print [-o yaml|toml] [flags]")
+		return exitUsage
+	}
+	fs := flag.NewFlagSet("ledger config print", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("o", "yaml", "output format: yaml or toml")
+	flags := config.RegisterFlags(fs)
+	if err := fs.Parse(args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if fs.NArg() > 0 || (*format != "yaml" && *format != "toml") {
+		fmt.Fprintln(stderr, "usage: ledger config print [-o yaml|toml] [flags]")
+		return exitUsage
+	}
+
+	cfg, err := flags.Load()
+	if err != nil {
+		fmt.Fprintf(stderr, "error: invalid configuration:\n%v\n", err)
+		return exitError
+	}
+	if err := cfg.Write(stdout, *format); err != nil {
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return exitError
+	}
+	return exitOK
+}
+
+func (r *runner) client() (*Client, error) {
+	return NewClient(r.cfg)
+}
+
+// mapFlag collects repeated key=value flags.
+type mapFlag map[string]string
+
+func (m mapFlag) String() string {
+	pairs := make([]string, 0, len(m))
+	for key, value := range m {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func (m mapFlag) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("expected key=value, got %q", value)
+	}
+	m[key] = val
+	return nil
+}
+
+// listFlag collects repeated flags.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
@@ -0,0 +1,398 @@
+package cli
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"ledgerproject/audit"
+	"ledgerproject/config"
+	"ledgerproject/logger"
+	"ledgerproject/tlsconfig/tlstest"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	accountsJSON = `[
+		{"id": "1001", "name": "Cash", "type": "asset", "currency": "USD", "balance": {"amount": "75", "currency": "USD"}, "datetime": "2024-01-02T10:00:00Z", "tags": ["ops"]},
+		{"id": "2001", "name": "Revenue", "type": "revenue", "currency": "USD", "balance": {"amount": "-75", "currency": "USD"}, "datetime": "2024-01-02T10:00:00Z"}
+	]`
+	transactionsJSON = `[
+		{"id": "TX001", "sequence": 3, "datetime": "2024-01-02T11:00:00Z", "description": "Sale", "debit_account": "2001", "credit_account": "1001", "amount": {"amount": "50", "currency": "USD"}},
+		{"id": "TX002", "sequence": 4, "datetime": "2024-01-02T12:00:00Z", "description": "", "debit_account": "", "credit_account": "", "amount": {"amount": "0", "currency": ""},
+		 "postings": [{"account": "2001", "amount": {"amount": "-25", "currency": "USD"}}, {"account": "1001", "amount": {"amount": "25", "currency": "USD"}}]}
+	]`
+)
+
+// fakeServer answers like the HTTP API from canned responses and records the
+// requests it receives.
+type fakeServer struct {
+	*httptest.Server
+	accounts     string
+	transactions string
+	entries      []audit.Entry
+	requests     []*http.Request
+	bodies       []string
+}
+
+func newFakeServer(t *testing.T) *fakeServer {
+	require.NoError(t, logger.Init(true))
+	trail := audit.NewMemoryLog()
+	_, err := trail.Record(audit.Entry{Actor: "dev", Action: "account.create", Target: "1001"}, nil, map[string]string{"id": "1001"})
+	require.NoError(t, err)
+	_, err = trail.Record(audit.Entry{Actor: "dev", Action: "transaction.record", Target: "TX001"}, nil, map[string]string{"id": "TX001"})
+	require.NoError(t, err)
+	f := &fakeServer{accounts: accountsJSON, transactions: transactionsJSON, entries: trail.Query(audit.Filter{})}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /accounts", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, f.accounts) })
+	mux.HandleFunc("GET /transactions", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, f.transactions) })
+	mux.HandleFunc("POST /accounts", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) })
+	mux.HandleFunc("POST /transactions", func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) })
+	mux.HandleFunc("GET /accounts/1001/balance", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"balance": {"amount": "75", "currency": "USD"}}`)
+	})
+	mux.HandleFunc("GET /accounts/1001/history", func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, f.transactions) })
+	mux.HandleFunc("GET /accounts/NOPE/balance", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"title": "Account not found", "status": 404, "detail": "account NOPE does not exist", "code": "account_not_found", "request_id": "req-1"}`)
+	})
+	mux.HandleFunc("GET /audit/export", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "auditor-key" {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, `{"title": "Forbidden", "status": 403, "detail": "permission denied"}`)
+			return
+		}
+		encoder := json.NewEncoder(w)
+		for _, entry := range f.entries {
+			encoder.Encode(entry)
+		}
+	})
+
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		f.requests = append(f.requests, r.Clone(r.Context()))
+		f.bodies = append(f.bodies, string(body))
+		// Tenant requests are answered like default ones
+		r.URL.Path = strings.TrimPrefix(r.URL.Path, "/tenants/retail")
+		mux.ServeHTTP(w, r)
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeServer) run(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := run(args, config.Client{URL: f.URL, APIKey: "dev-api-key"}, &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout []string
+		wantStderr string
+	}{
+		{
+			name:       "accounts list as table",
+			args:       []string{"accounts", "list"},
+			wantStdout: []string{"ID    NAME     TYPE     CURRENCY  BALANCE  FROZEN  TAGS", "1001  Cash     asset    USD       75       false   ops"},
+		},
+		{
+			name:       "accounts get as JSON",
+			args:       []string{"accounts", "get", "2001", "-o", "json"},
+			wantStdout: []string{`"id": "2001"`, `"amount": "-75"`},
+		},
+		{
+			name:       "balance as CSV",
+			args:       []string{"balance", "-o", "csv", "1001"},
+			wantStdout: []string{"ACCOUNT,BALANCE,CURRENCY\n1001,75,USD\n"},
+		},
+		{
+			name: "history has a row per leg",
+			args: []string{"history", "1001", "-o", "csv"},
+			wantStdout: []string{
+				"TX001,3,2024-01-02T11:00:00Z,Sale,2001,-50,USD\nTX001,3,2024-01-02T11:00:00Z,Sale,1001,50,USD\n",
+				"TX002,4,2024-01-02T12:00:00Z,,2001,-25,USD\n",
+			},
+		},
+		{
+			name:       "verify skips the audit trail without audit:read",
+			args:       []string{"verify"},
+			wantStdout: []string{"balance USD  ok       accounts sum to zero", "audit chain  skipped"},
+		},
+		{
+			name:       "problem from the server",
+			args:       []string{"balance", "NOPE"},
+			wantCode:   exitError,
+			wantStderr: "error: Account not found: account NOPE does not exist (request req-1)\n",
+		},
+		{
+			name:       "unknown account",
+			args:       []string{"accounts", "get", "9999"},
+			wantCode:   exitError,
+			wantStderr: "error: account 9999 not found\n",
+		},
+		{
+			name:       "reverse",
+			args:       []string{"tx", "reverse", "TX001"},
+			wantStdout: []string{"TX001-reversal  0                   Reversal of TX001  2001     50"},
+		},
+		{
+			name:       "unknown command",
+			args:       []string{"payments"},
+			wantCode:   exitUsage,
+			wantStderr: `unknown command "payments"`,
+		},
+		{
+			name:       "missing argument",
+			args:       []string{"balance"},
+			wantCode:   exitUsage,
+			wantStderr: "usage error: wrong number of arguments\nusage: ledger balance [flags] <account>\n",
+		},
+		{
+			name:       "unknown output format",
+			args:       []string{"accounts", "list", "-o", "xml"},
+			wantCode:   exitUsage,
+			wantStderr: `unknown output format "xml"`,
+		},
+		{
+			name:       "missing required flag",
+			args:       []string{"tx", "post", "-id", "TX003", "-currency", "USD"},
+			wantCode:   exitUsage,
+			wantStderr: "-debit, -credit and -amount are required without -posting",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeServer(t)
+			code, stdout, stderr := f.run(tt.args...)
+			assert.Equal(t, tt.wantCode, code, stderr)
+			for _, want := range tt.wantStdout {
+				assert.Contains(t, stdout, want)
+			}
+			assert.Contains(t, stderr, tt.wantStderr)
+		})
+	}
+}
+
+func TestRequests(t *testing.T) {
+	t.Run("credentials and tenant", func(t *testing.T) {
+		f := newFakeServer(t)
+		code, _, stderr := f.run("balance", "-tenant", "retail", "1001")
+		require.Equal(t, exitOK, code, stderr)
+		assert.Equal(t, "/tenants/retail/accounts/1001/balance", f.requests[0].URL.Path)
+		assert.Equal(t, "dev-api-key", f.requests[0].Header.Get("X-API-Key"))
+
+		// Run reads the client settings from the environment
+		t.Setenv(config.EnvName, config.EnvDevelopment)
+		t.Setenv(config.EnvFile, "")
+		t.Setenv("LEDGER_CLIENT_URL", f.URL)
+		t.Setenv("LEDGER_CLIENT_TOKEN", "operator-token")
+		var stdout, errOut bytes.Buffer
+		code = Run([]string{"balance", "1001"}, &stdout, &errOut)
+		require.Equal(t, exitOK, code, errOut.String())
+		assert.Equal(t, "Bearer operator-token", f.requests[1].Header.Get("Authorization"))
+		assert.Empty(t, f.requests[1].Header.Get("X-API-Key"))
+	})
+
+	t.Run("create account", func(t *testing.T) {
+		f := newFakeServer(t)
+		code, _, stderr := f.run("accounts", "create", "-id", "1001", "-name", "Cash", "-type", "asset",
+			"-currency", "USD", "-balance", "75", "-meta", "region=eu", "-tag", "ops")
+		require.Equal(t, exitOK, code, stderr)
+		assert.JSONEq(t, `{"id": "1001", "name": "Cash", "type": "asset", "currency": "USD",
+			"balance": {"amount": "75", "currency": "USD"}, "datetime": "0001-01-01T00:00:00Z",
+			"metadata": {"region": "eu"}, "tags": ["ops"]}`, f.bodies[0])
+	})
+
+	t.Run("post transfer", func(t *testing.T) {
+		f := newFakeServer(t)
+		code, _, stderr := f.run("tx", "post", "-id", "TX003", "-debit", "1001", "-credit", "2001",
+			"-amount", "12.50", "-currency", "USD", "-description", "Refund")
+		require.Equal(t, exitOK, code, stderr)
+		assert.JSONEq(t, `{"id": "TX003", "datetime": "0001-01-01T00:00:00Z", "description": "Refund",
+			"debit_account": "1001", "credit_account": "2001", "amount": {"amount": "12.5", "currency": "USD"}}`, f.bodies[0])
+	})
+
+	t.Run("post postings", func(t *testing.T) {
+		f := newFakeServer(t)
+		code, _, stderr := f.run("tx", "post", "-id", "TX003", "-currency", "USD",
+			"-posting", "1001=-10", "-posting", "2001=10")
+		require.Equal(t, exitOK, code, stderr)
+		assert.JSONEq(t, `{"id": "TX003", "datetime": "0001-01-01T00:00:00Z", "description": "",
+			"debit_account": "", "credit_account": "", "postings": [
+				{"account": "1001", "amount": {"amount": "-10", "currency": "USD"}},
+				{"account": "2001", "amount": {"amount": "10", "currency": "USD"}}]}`, f.bodies[0])
+	})
+
+	t.Run("reverse", func(t *testing.T) {
+		f := newFakeServer(t)
+		code, _, stderr := f.run("tx", "reverse", "TX002")
+		require.Equal(t, exitOK, code, stderr)
+		assert.JSONEq(t, `{"id": "TX002-reversal", "datetime": "0001-01-01T00:00:00Z", "description": "Reversal of TX002",
+			"debit_account": "", "credit_account": "", "metadata": {"reverses": "TX002"}, "postings": [
+				{"account": "2001", "amount": {"amount": "25", "currency": "USD"}},
+				{"account": "1001", "amount": {"amount": "-25", "currency": "USD"}}]}`, f.bodies[1])
+
+		f.transactions = strings.Replace(transactionsJSON, `"description": ""`, `"description": "", "metadata": {"reverses": "TX001"}`, 1)
+		code, _, stderr = f.run("tx", "reverse", "TX001")
+		assert.Equal(t, exitError, code)
+		assert.Equal(t, "error: transaction TX001 was already reversed by TX002\n", stderr)
+	})
+
+	t.Run("client certificate", func(t *testing.T) {
+		dir := t.TempDir()
+		ca := tlstest.NewCA(t, dir, "ca")
+		_, serverCert, serverKey := ca.Issue(t, dir, "localhost")
+		_, clientCert, clientKey := ca.Issue(t, dir, "operator")
+		cert, err := tls.LoadX509KeyPair(serverCert, serverKey)
+		require.NoError(t, err)
+		clientCAs := x509.NewCertPool()
+		clientCAs.AddCert(ca.Cert)
+
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "operator", r.TLS.VerifiedChains[0][0].Subject.CommonName)
+			io.WriteString(w, `{"balance": {"amount": "75", "currency": "USD"}}`)
+		}))
+		server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}, ClientCAs: clientCAs, ClientAuth: tls.RequireAndVerifyClientCert}
+		server.StartTLS()
+		t.Cleanup(server.Close)
+
+		cfg := config.Client{URL: server.URL, CAFile: ca.CertFile, CertFile: clientCert, KeyFile: clientKey}
+		var stdout, stderr bytes.Buffer
+		code := run([]string{"balance", "1001"}, cfg, &stdout, &stderr)
+		require.Equal(t, exitOK, code, stderr.String())
+		assert.Contains(t, stdout.String(), "75")
+
+		cfg.CAFile = clientKey
+		stderr.Reset()
+		code = run([]string{"balance", "1001"}, cfg, &stdout, &stderr)
+		assert.Equal(t, exitError, code)
+		assert.Contains(t, stderr.String(), "holds no PEM certificates")
+	})
+}
+
+func TestVerify(t *testing.T) {
+	tests := []struct {
+		name       string
+		accounts   string
+		tamper     bool
+		wantCode   int
+		wantStdout []string
+	}{
+		{
+			name:       "intact",
+			accounts:   accountsJSON,
+			wantStdout: []string{"balance USD  ok      accounts sum to zero", "audit chain  ok      2 entries"},
+		},
+		{
+			name:       "unbalanced",
+			accounts:   strings.Replace(accountsJSON, `"-75"`, `"-70"`, 1),
+			wantCode:   exitError,
+			wantStdout: []string{"balance USD  FAILED  accounts sum to 5"},
+		},
+		{
+			name:       "tampered audit trail",
+			accounts:   accountsJSON,
+			tamper:     true,
+			wantCode:   exitError,
+			wantStdout: []string{"audit chain  FAILED  entry 2 has been modified"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newFakeServer(t)
+			f.accounts = tt.accounts
+			if tt.tamper {
+				f.entries[1].Target = "TX999"
+			}
+
+			var stdout, stderr bytes.Buffer
+			code := run([]string{"verify"}, config.Client{URL: f.URL, APIKey: "auditor-key"}, &stdout, &stderr)
+			assert.Equal(t, tt.wantCode, code, stderr.String())
+			for _, want := range tt.wantStdout {
+				assert.Contains(t, stdout.String(), want)
+			}
+		})
+	}
+}
+
+func TestExport(t *testing.T) {
+	f := newFakeServer(t)
+	file := filepath.Join(t.TempDir(), "accounts.csv")
+	code, stdout, stderr := f.run("export", "accounts", "-o", "csv", "-file", file)
+	require.Equal(t, exitOK, code, stderr)
+	assert.Empty(t, stdout)
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	assert.Equal(t, "ID,NAME,TYPE,CURRENCY,BALANCE,FROZEN,TAGS\n1001,Cash,asset,USD,75,false,ops\n2001,Revenue,revenue,USD,-75,false,\n", string(data))
+
+	code, stdout, stderr = f.run("export", "-o", "json")
+	require.Equal(t, exitOK, code, stderr)
+	var transactions []map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(stdout), &transactions))
+	assert.Len(t, transactions, 2)
+
+	code, _, stderr = f.run("export", "ledgers")
+	assert.Equal(t, exitUsage, code)
+	assert.Contains(t, stderr, `cannot export "ledgers"`)
+}
+
+func TestPrintConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "ledger.yaml")
+	require.NoError(t, os.WriteFile(file, []byte("server_port: \":8443\"\nlog_level: warn\n"), 0o600))
+	t.Setenv(config.EnvName, config.EnvProduction)
+	t.Setenv(config.EnvFile, file)
+	t.Setenv("LEDGER_LOG_LEVEL", "error")
+	t.Setenv("LEDGER_JWT_HMAC_SECRET", "s3cret")
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout []string
+		wantStderr string
+	}{
+		{
+			name:       "yaml",
+			args:       []string{"config", "print", "-log-format", "console"},
+			wantCode:   exitOK,
+			wantStdout: []string{`server_port: :8443`, "log_level: error", "log_format: console", "jwt_hmac_secret: <redacted>"},
+		},
+		{
+			name:       "toml",
+			args:       []string{"config", "print", "-o", "toml"},
+			wantCode:   exitOK,
+			wantStdout: []string{`server_port = ":8443"`, `log_format = "json"`},
+		},
+		{name: "invalid", args: []string{"config", "print", "-log-level", "loud"}, wantCode: exitError, wantStderr: "log_level: must be one of"},
+		{name: "no subcommand", args: []string{"config"}, wantCode: exitUsage, wantStderr: "usage: ledger config print"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			code := Run(tt.args, &stdout, &stderr)
+			require.Equal(t, tt.wantCode, code, stderr.String())
+			for _, want := range tt.wantStdout {
+				assert.Contains(t, stdout.String(), want)
+			}
+			assert.NotContains(t, stdout.String(), "s3cret")
+			assert.Contains(t, stderr.String(), tt.wantStderr)
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"ledgerproject/config"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Client calls the HTTP API of a running server with the configured
+// credentials. Paths are relative to the tenant when one is configured.
+type Client struct {
+	base   string
+	tenant string
+	apiKey string
+	token  string
+	http   *http.Client
+}
+
+func NewClient(cfg config.Client) (*Client, error) {
+	httpClient := &http.Client{Timeout: cfg.Timeout}
+	if cfg.CAFile != "" || cfg.CertFile != "" {
+		tlsConfig, err := clientTLS(cfg)
+		if err != nil {
+			return nil, err
+		}
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		httpClient.Transport = transport
+	}
+
+	return &Client{
+		base:   strings.TrimRight(cfg.URL, "/"),
+		tenant: cfg.Tenant,
+		apiKey: cfg.APIKey,
+		token:  cfg.Token,
+		http:   httpClient,
+	}, nil
+}
+
+// clientTLS trusts the servers signed by the CA file, if any, and presents
+// the client certificate, if any.
+func clientTLS(cfg config.Client) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if cfg.CAFile != "" {
+		data, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA file: %v", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("CA file %s holds no PEM certificates", cfg.CAFile)
+		}
+	}
+	if cfg.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// APIError is a problem details body returned by the server.
+type APIError struct {
+	Status    int    `json:"status"`
+	Code      string `json:"code"`
+	Title     string `json:"title"`
+	Detail    string `json:"detail"`
+	RequestID string `json:"request_id"`
+	Errors    []struct {
+		Field   string `json:"field"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+func (e *APIError) Error() string {
+	msg := e.Title
+	if e.Detail != "" {
+		msg = fmt.Sprintf("%s: %s", msg, e.Detail)
+	}
+	for _, field := range e.Errors {
+		msg += fmt.Sprintf("\n  %s: %s", field.Field, field.Message)
+	}
+	if e.RequestID != "" {
+		msg += fmt.Sprintf(" (request %s)", e.RequestID)
+	}
+	return msg
+}
+
+// get decodes the JSON response to a GET request into out.
+func (c *Client) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	body, err := c.open(ctx, http.MethodGet, path, query, nil)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+	if err := json.NewDecoder(body).Decode(out); err != nil {
+		return fmt.Errorf("error decoding response: %v", err)
+	}
+	return nil
+}
+
+// post sends in as the JSON body of a POST request.
+func (c *Client) post(ctx context.Context, path string, in interface{}) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("error encoding request: %v", err)
+	}
+	body, err := c.open(ctx, http.MethodPost, path, nil, data)
+	if err != nil {
+		return err
+	}
+	return body.Close()
+}
+
+// open sends a request and returns the body of a successful response.
+// Failed responses are returned as an *APIError.
+func (c *Client) open(ctx context.Context, method, path string, query url.Values, data []byte) (io.ReadCloser, error) {
+	if c.tenant != "" {
+		path = "/tenants/" + url.PathEscape(c.tenant) + path
+	}
+	target := c.base + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var body io.Reader
+	if data != nil {
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %v", err)
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	switch {
+	case c.token != "":
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	case c.apiKey != "":
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error calling %s: %v", c.base, err)
+	}
+	if resp.StatusCode < 300 {
+		return resp.Body, nil
+	}
+
+	defer resp.Body.Close()
+	apiErr := &APIError{Status: resp.StatusCode}
+	if err := json.NewDecoder(resp.Body).Decode(apiErr); err != nil || apiErr.Title == "" {
+		apiErr.Title = resp.Status
+	}
+	return nil, apiErr
+}
@@ -0,0 +1,509 @@
+package cli
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"ledgerproject/audit"
+	"ledgerproject/models"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// reversalKey links a reversing transaction to the one it reverses.
+const reversalKey = "reverses"
+
+func (r *runner) accounts(args []string) error {
+	return r.subcommand("accounts", args, map[string]func([]string) error{
+		"create": r.accountsCreate,
+		"get":    r.accountsGet,
+		"list":   r.accountsList,
+	})
+}
+
+func (r *runner) accountsCreate(args []string) error {
+	fs, opts := r.flags("accounts create")
+	account := models.Account{Metadata: mapFlag{}}
+	var balance string
+	var tags listFlag
+	fs.StringVar(&account.ID, "id", "", "account ID (required)")
+	fs.StringVar(&account.Name, "name", "", "account name")
+	fs.StringVar(&account.Type, "type", "", "account type, such as asset or liability")
+	fs.StringVar(&account.Currency, "currency", "", "ISO 4217 currency code (required)")
+	fs.StringVar(&balance, "balance", "0", "opening balance")
+	fs.Var(mapFlag(account.Metadata), "meta", "metadata as key=value (repeatable)")
+	fs.Var(&tags, "tag", "tag (repeatable)")
+	if _, err := r.parse(fs, opts, args, 0, 0); err != nil {
+		return err
+	}
+	if account.ID == "" || account.Currency == "" {
+		return fmt.Errorf("%w: -id and -currency are required", errUsage)
+	}
+	amount, err := decimal.NewFromString(balance)
+	if err != nil {
+		return fmt.Errorf("%w: invalid balance %q", errUsage, balance)
+	}
+	account.Balance = models.Money{Amount: amount, Currency: account.Currency}
+	account.Tags = tags
+
+	client, err := r.client()
+	if err != nil {
+		return err
+	}
+	if err := client.post(r.ctx, "/accounts", account); err != nil {
+		return err
+	}
+	created, err := r.findAccount(client, account.ID)
+	if err != nil {
+		return err
+	}
+	return render(r.stdout, opts.format, created, accountsTable([]models.Account{created}))
+}
+
+// accountsGet shows one account. The API has no endpoint for a single
+// account, so it is picked from the list.
+func (r *runner) accountsGet(args []string) error {
+	fs, opts := r.flags("accounts get")
+	positional, err := r.parse(fs, opts, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	client, err := r.client()
+	if err != nil {
+		return err
+	}
+	account, err := r.findAccount(client, positional[0])
+	if err != nil {
+		return err
+	}
+	return render(r.stdout, opts.format, account, accountsTable([]models.Account{account}))
+}
+
+func (r *runner) findAccount(client *Client, id string) (models.Account, error) {
+	var accounts []models.Account
+	if err := client.get(r.ctx, "/accounts", nil, &accounts); err != nil {
+		return models.Account{}, err
+	}
+	for _, account := range accounts {
+		if account.ID == id {
+			return account, nil
+		}
+	}
+	return models.Account{}, fmt.Errorf("account %s not found", id)
+}
+
+func (r *runner) accountsList(args []string) error {
+	fs, opts := r.flags("accounts list")
+	query := filterFlags(fs)
+	if _, err := r.parse(fs, opts, args, 0, 0); err != nil {
+		return err
+	}
+	client, err := r.client()
+	if err != nil {
+		return err
+	}
+	var accounts []models.Account
+	if err := client.get(r.ctx, "/accounts", query(), &accounts); err != nil {
+		return err
+	}
+	return render(r.stdout, opts.format, accounts, accountsTable(accounts))
+}
+
+// filterFlags adds the metadata and tag filters of the list endpoints and
+// returns a function building their query.
+func filterFlags(fs *flag.FlagSet) func() url.Values {
+	var key, value, tag string
+	fs.StringVar(&key, "metadata-key", "", "only items with this metadata key")
+	fs.StringVar(&value, "metadata-value", "", "only items whose metadata key has this value")
+	fs.StringVar(&tag, "tag", "", "only items with this tag")
+	return func() url.Values {
+		query := url.Values{}
+		for name, v := range map[string]string{"metadata_key": key, "metadata_value": value, "tag": tag} {
+			if v != "" {
+				query.Set(name, v)
+			}
+		}
+		return query
+	}
+}
+
+func (r *runner) transactions(args []string) error {
+	return r.subcommand("tx", args, map[string]func([]string) error{
+		"post":    r.transactionPost,
+		"reverse": r.transactionReverse,
+	})
+}
+
+// transactionPost records a transfer from -debit to -credit, or a
+// multi-posting transaction given as repeated -posting account=amount flags.
+func (r *runner) transactionPost(args []string) error {
+	fs, opts := r.flags("tx post")
+	tx := models.Transaction{Metadata: mapFlag{}}
+	var amount, currency string
+	var postings mapFlag = map[string]string{}
+	var tags listFlag
+	fs.StringVar(&tx.ID, "id", "", "transaction ID (required)")
+	fs.StringVar(&tx.Description, "description", "", "description")
+	fs.StringVar(&tx.DebitAccount, "debit", "", "account to debit")
+	fs.StringVar(&tx.CreditAccount, "credit", "", "account to credit")
+	fs.StringVar(&amount, "amount", "", "amount transferred")
+	fs.StringVar(&currency, "currency", "", "ISO 4217 currency code (required)")
+	fs.Var(postings, "posting", "signed posting as account=amount (repeatable), instead of -debit, -credit and -amount")
+	fs.Var(mapFlag(tx.Metadata), "meta", "metadata as key=value (repeatable)")
+	fs.Var(&tags, "tag", "tag (repeatable)")
+	if _, err := r.parse(fs, opts, args, 0, 0); err != nil {
+		return err
+	}
+	if tx.ID == "" || currency == "" {
+		return fmt.Errorf("%w: -id and -currency are required", errUsage)
+	}
+	tx.Tags = tags
+
+	if len(postings) > 0 {
+		if tx.DebitAccount != "" || tx.CreditAccount != "" || amount != "" {
+			return fmt.Errorf("%w: -posting cannot be combined with -debit, -credit or -amount", errUsage)
+		}
+		accounts := make([]string, 0, len(postings))
+		for account := range postings {
+			accounts = append(accounts, account)
+		}
+		sort.Strings(accounts)
+		for _, account := range accounts {
+			value, err := decimal.NewFromString(postings[account])
+			if err != nil {
+				return fmt.Errorf("%w: invalid amount %q for %s", errUsage, postings[account], account)
+			}
+			tx.Postings = append(tx.Postings, models.Posting{Account: account, Amount: models.Money{Amount: value, Currency: currency}})
+		}
+	} else {
+		if tx.DebitAccount == "" || tx.CreditAccount == "" || amount == "" {
+			return fmt.Errorf("%w: -debit, -credit and -amount are required without -posting", errUsage)
+		}
+		value, err := decimal.NewFromString(amount)
+		if err != nil {
+			return fmt.Errorf("%w: invalid amount %q", errUsage, amount)
+		}
+		tx.Amount = models.Money{Amount: value, Currency: currency}
+	}
+
+	client, err := r.client()
+	if err != nil {
+		return err
+	}
+	if err := r.postTransaction(client, tx); err != nil {
+		return err
+	}
+	return render(r.stdout, opts.format, tx, transactionsTable([]models.Transaction{tx}))
+}
+
+// transactionReverse posts a transaction whose legs undo those of another.
+// The reversal always uses postings, so fees charged on the original are
+// refunded and none are charged on the reversal itself. A transaction is
+// only reversed once.
+func (r *runner) transactionReverse(args []string) error {
+	fs, opts := r.flags("tx reverse")
+	var id, description string
+	fs.StringVar(&id, "id", "", "ID of the reversal (default <id>-reversal)")
+	fs.StringVar(&description, "description", "", "description (default \"Reversal of <id>\")")
+	positional, err := r.parse(fs, opts, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	originalID := positional[0]
+
+	client, err := r.client()
+	if err != nil {
+		return err
+	}
+	transactions, err := r.getTransactions(client, "/transactions")
+	if err != nil {
+		return err
+	}
+	var original *models.Transaction
+	for i, tx := range transactions {
+		if tx.Metadata[reversalKey] == originalID {
+			return fmt.Errorf("transaction %s was already reversed by %s", originalID, tx.ID)
+		}
+		if tx.ID == originalID {
+			original = &transactions[i]
+		}
+	}
+	if original == nil {
+		return fmt.Errorf("transaction %s not found", originalID)
+	}
+
+	if id == "" {
+		id = originalID + "-reversal"
+	}
+	if description == "" {
+		description = "Reversal of " + originalID
+	}
+	reversal := models.Transaction{
+		ID:          id,
+		Description: description,
+		Metadata:    map[string]string{reversalKey: originalID},
+	}
+	for _, leg := range original.Legs() {
+		reversal.Postings = append(reversal.Postings, models.Posting{
+			Account: leg.Account,
+			Amount:  models.Money{Amount: leg.Amount.Amount.Neg(), Currency: leg.Amount.Currency},
+		})
+	}
+
+	if err := r.postTransaction(client, reversal); err != nil {
+		return err
+	}
+	return render(r.stdout, opts.format, reversal, transactionsTable([]models.Transaction{reversal}))
+}
+
+func (r *runner) balance(args []string) error {
+	fs, opts := r.flags("balance")
+	positional, err := r.parse(fs, opts, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	accountID := positional[0]
+	var resp struct {
+		Balance models.Money `json:"balance"`
+	}
+	client, err := r.client()
+	if err != nil {
+		return err
+	}
+	if err := client.get(r.ctx, "/accounts/"+url.PathEscape(accountID)+"/balance", nil, &resp); err != nil {
+		return err
+	}
+	return render(r.stdout, opts.format, resp, balanceTable(accountID, resp.Balance))
+}
+
+func (r *runner) history(args []string) error {
+	fs, opts := r.flags("history")
+	positional, err := r.parse(fs, opts, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	client, err := r.client()
+	if err != nil {
+		return err
+	}
+	transactions, err := r.getTransactions(client, "/accounts/"+url.PathEscape(positional[0])+"/history")
+	if err != nil {
+		return err
+	}
+	return render(r.stdout, opts.format, transactions, transactionsTable(transactions))
+}
+
+// verify checks the server's data independently of it: the balances of all
+// accounts must sum to zero in every currency, and the hash chain of the
+// audit trail must be unbroken. The audit check is skipped for credentials
+// that may not read the trail.
+func (r *runner) verify(args []string) error {
+	fs, opts := r.flags("verify")
+	if _, err := r.parse(fs, opts, args, 0, 0); err != nil {
+		return err
+	}
+	client, err := r.client()
+	if err != nil {
+		return err
+	}
+
+	var accounts []models.Account
+	if err := client.get(r.ctx, "/accounts", nil, &accounts); err != nil {
+		return err
+	}
+	totals := make(map[string]decimal.Decimal)
+	for _, account := range accounts {
+		totals[account.Currency] = totals[account.Currency].Add(account.Balance.Amount)
+	}
+	currencies := make([]string, 0, len(totals))
+	for currency := range totals {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+
+	var checks []check
+	for _, currency := range currencies {
+		total := totals[currency]
+		c := check{Name: "balance " + currency, OK: total.IsZero(), Detail: "accounts sum to zero"}
+		if !c.OK {
+			c.Detail = fmt.Sprintf("accounts sum to %s", total)
+		}
+		checks = append(checks, c)
+	}
+
+	c := check{Name: "audit chain", OK: true}
+	entries, err := r.auditEntries(client)
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.Status == http.StatusForbidden:
+		c.Skipped, c.Detail = true, "the credentials may not read the audit trail"
+	case err != nil:
+		return err
+	default:
+		c.Detail = fmt.Sprintf("%d entries", len(entries))
+		if err := audit.VerifyChain(entries); err != nil {
+			c.OK, c.Detail = false, err.Error()
+		}
+	}
+	checks = append(checks, c)
+
+	if err := render(r.stdout, opts.format, checks, checksTable(checks)); err != nil {
+		return err
+	}
+	for _, c := range checks {
+		if !c.OK {
+			return fmt.Errorf("verification failed")
+		}
+	}
+	return nil
+}
+
+// postTransaction records a transaction. The amount of a multi-posting
+// transaction is left out, as the API rejects an amount without a currency.
+func (r *runner) postTransaction(client *Client, tx models.Transaction) error {
+	if len(tx.Postings) == 0 {
+		return client.post(r.ctx, "/transactions", tx)
+	}
+	data, err := json.Marshal(tx)
+	if err != nil {
+		return fmt.Errorf("error encoding transaction: %v", err)
+	}
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(data, &body); err != nil {
+		return fmt.Errorf("error encoding transaction: %v", err)
+	}
+	delete(body, "amount")
+	return client.post(r.ctx, "/transactions", body)
+}
+
+// getTransactions returns the transactions listed by an endpoint.
+func (r *runner) getTransactions(client *Client, path string) ([]models.Transaction, error) {
+	var listed []transaction
+	if err := client.get(r.ctx, path, nil, &listed); err != nil {
+		return nil, err
+	}
+	transactions := make([]models.Transaction, len(listed))
+	for i, tx := range listed {
+		transactions[i] = tx.Transaction
+	}
+	return transactions, nil
+}
+
+// transaction decodes a transaction listed by the API. Multi-posting
+// transactions are listed with an amount without a currency, which
+// models.Money refuses, so that amount is dropped.
+type transaction struct {
+	models.Transaction
+}
+
+func (t *transaction) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	var amount struct {
+		Currency string `json:"currency"`
+	}
+	if raw, ok := fields["amount"]; ok && json.Unmarshal(raw, &amount) == nil && amount.Currency == "" {
+		delete(fields, "amount")
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &t.Transaction)
+}
+
+// auditEntries downloads the whole audit trail.
+func (r *runner) auditEntries(client *Client) ([]audit.Entry, error) {
+	body, err := client.open(r.ctx, http.MethodGet, "/audit/export", url.Values{"format": {"jsonl"}}, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	entries := []audit.Entry{}
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(strings.TrimSpace(scanner.Text())) == 0 {
+			continue
+		}
+		var entry audit.Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("error decoding audit entry: %v", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading audit trail: %v", err)
+	}
+	return entries, nil
+}
+
+// export writes all accounts, transactions or audit entries to a file or the
+// standard output.
+func (r *runner) export(args []string) error {
+	fs, opts := r.flags("export")
+	var file string
+	fs.StringVar(&file, "file", "", "file to write (default standard output)")
+	positional, err := r.parse(fs, opts, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	what := "transactions"
+	if len(positional) == 1 {
+		what = positional[0]
+	}
+
+	client, err := r.client()
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	var t table
+	switch what {
+	case "accounts":
+		var accounts []models.Account
+		if err := client.get(r.ctx, "/accounts", nil, &accounts); err != nil {
+			return err
+		}
+		value, t = accounts, accountsTable(accounts)
+	case "transactions":
+		transactions, err := r.getTransactions(client, "/transactions")
+		if err != nil {
+			return err
+		}
+		value, t = transactions, transactionsTable(transactions)
+	case "audit":
+		entries, err := r.auditEntries(client)
+		if err != nil {
+			return err
+		}
+		value, t = entries, auditTable(entries)
+	default:
+		return fmt.Errorf("%w: cannot export %q", errUsage, what)
+	}
+
+	if file == "" {
+		return render(r.stdout, opts.format, value, t)
+	}
+	f, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("error creating export file: %v", err)
+	}
+	err = render(f, opts.format, value, t)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing export: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"ledgerproject/audit"
+	"ledgerproject/models"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Output formats. JSON output is the server's representation; table and CSV
+// output share the columns below.
+const (
+	FormatTable = "table"
+	FormatJSON  = "json"
+	FormatCSV   = "csv"
+)
+
+func validFormat(format string) bool {
+	return format == FormatTable || format == FormatJSON || format == FormatCSV
+}
+
+// table is the tabular form of a result.
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+// render writes value as indented JSON, or its table as aligned columns or
+// CSV.
+func render(w io.Writer, format string, value interface{}, t table) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case FormatCSV:
+		out := csv.NewWriter(w)
+		if err := out.Write(t.header); err != nil {
+			return err
+		}
+		if err := out.WriteAll(t.rows); err != nil {
+			return err
+		}
+		return out.Error()
+	}
+
+	out := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(out, strings.Join(t.header, "\t"))
+	for _, row := range t.rows {
+		fmt.Fprintln(out, strings.Join(row, "\t"))
+	}
+	return out.Flush()
+}
+
+func accountsTable(accounts []models.Account) table {
+	t := table{header: []string{"ID", "NAME", "TYPE", "CURRENCY", "BALANCE", "FROZEN", "TAGS"}}
+	for _, a := range accounts {
+		t.rows = append(t.rows, []string{
+			a.ID, a.Name, a.Type, a.Currency, a.Balance.Amount.String(),
+			strconv.FormatBool(a.Frozen), strings.Join(a.Tags, ","),
+		})
+	}
+	return t
+}
+
+// transactionsTable has a row per leg, so that simple transfers and
+// multi-posting transactions read the same.
+func transactionsTable(transactions []models.Transaction) table {
+	t := table{header: []string{"ID", "SEQUENCE", "DATETIME", "DESCRIPTION", "ACCOUNT", "AMOUNT", "CURRENCY"}}
+	for _, tx := range transactions {
+		for _, leg := range tx.Legs() {
+			t.rows = append(t.rows, []string{
+				tx.ID, strconv.FormatUint(tx.Sequence, 10), formatTime(tx.DateTime), tx.Description,
+				leg.Account, leg.Amount.Amount.String(), leg.Amount.Currency,
+			})
+		}
+	}
+	return t
+}
+
+func balanceTable(accountID string, balance models.Money) table {
+	return table{
+		header: []string{"ACCOUNT", "BALANCE", "CURRENCY"},
+		rows:   [][]string{{accountID, balance.Amount.String(), balance.Currency}},
+	}
+}
+
+func auditTable(entries []audit.Entry) table {
+	t := table{header: []string{"SEQUENCE", "TIME", "ACTOR", "ROLE", "ACTION", "TARGET", "REQUEST_ID", "HASH"}}
+	for _, e := range entries {
+		t.rows = append(t.rows, []string{
+			strconv.FormatUint(e.Sequence, 10), formatTime(e.Time), e.Actor, e.Role,
+			e.Action, e.Target, e.RequestID, e.Hash,
+		})
+	}
+	return t
+}
+
+// check is the outcome of one of the verify checks. A skipped check could
+// not be run and does not fail the verification.
+type check struct {
+	Name    string `json:"name"`
+	OK      bool   `json:"ok"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Detail  string `json:"detail"`
+}
+
+func checksTable(checks []check) table {
+	t := table{header: []string{"CHECK", "STATUS", "DETAIL"}}
+	for _, c := range checks {
+		status := "ok"
+		switch {
+		case c.Skipped:
+			status = "skipped"
+		case !c.OK:
+			status = "FAILED"
+		}
+		t.rows = append(t.rows, []string{c.Name, status, c.Detail})
+	}
+	return t
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
@@ -2,24 +2,197 @@ package config
 
 import "time"
 
+// APIKey grants access to the holder of the key whose SHA-256 hex digest is
+// KeyHash. Requests made with it act as Principal with the given role;
+// Accounts lists the accounts a client key owns. A key with a Tenant is bound
+// to that tenant's ledger.
+type APIKey struct {
+	Principal string   `yaml:"principal" toml:"principal"`
+	KeyHash   string   `yaml:"key_hash" toml:"key_hash"`
+	Role      string   `yaml:"role" toml:"role"`
+	Accounts  []string `yaml:"accounts" toml:"accounts"`
+	Tenant    string   `yaml:"tenant" toml:"tenant"`
+}
+
+// RateLimit is a token bucket budget: a client may send Burst requests at
+// once, and the budget refills at Rate requests per second. A zero Rate
+// disables the limit.
+type RateLimit struct {
+	Rate  float64 `yaml:"rate" toml:"rate"`
+	Burst int     `yaml:"burst" toml:"burst"`
+}
+
+// Tracing selects where OpenTelemetry spans are exported. Exporter is
+// "none", "stdout", "file" (JSON lines appended to File) or "otlp" (OTLP over
+// HTTP to Endpoint). SampleRatio is the share of new traces recorded; traces
+// started by a sampled caller are always recorded.
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter"`
+	File        string  `yaml:"file" toml:"file"`
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+// TLS terminates HTTPS in the API server with the certificate chain in
+// CertFile and its key in KeyFile; without them the server speaks plain HTTP.
+// With ClientCAFile the server asks clients for a certificate signed by one
+// of its CAs, and RequireClientCert rejects connections without one. The
+// files are checked every ReloadInterval and reloaded when they change, so
+// certificates can be rotated without a restart.
+type TLS struct {
+	CertFile          string        `yaml:"cert_file" toml:"cert_file"`
+	KeyFile           string        `yaml:"key_file" toml:"key_file"`
+	ClientCAFile      string        `yaml:"client_ca_file" toml:"client_ca_file"`
+	RequireClientCert bool          `yaml:"require_client_cert" toml:"require_client_cert"`
+	ReloadInterval    time.Duration `yaml:"reload_interval" toml:"reload_interval"`
+}
+
+// Enabled reports whether the API server is served over TLS.
+func (t TLS) Enabled() bool {
+	return t.CertFile != ""
+}
+
+// ClientCertificate grants access to clients presenting a certificate signed
+// by a client CA whose subject common name is CommonName. They act as that
+// principal with the role, accounts and tenant given, like API key holders.
+type ClientCertificate struct {
+	CommonName string   `yaml:"common_name" toml:"common_name"`
+	Role       string   `yaml:"role" toml:"role"`
+	Accounts   []string `yaml:"accounts" toml:"accounts"`
+	Tenant     string   `yaml:"tenant" toml:"tenant"`
+}
+
+// TenantConfig defines a tenant created at startup. Currencies restricts the
+// tenant to some of the platform currencies; empty allows all of them.
+type TenantConfig struct {
+	ID           string   `yaml:"id" toml:"id"`
+	Name         string   `yaml:"name" toml:"name"`
+	Currencies   []string `yaml:"currencies" toml:"currencies"`
+	FeeRulesFile string   `yaml:"fee_rules_file" toml:"fee_rules_file"`
+}
+
+// Client is how the command-line tool reaches a running server: the base URL
+// of its HTTP API, the credentials to send (an API key or a bearer token) and
+// the tenant addressed. An empty Tenant addresses the default tenant, or the
+// one the credentials are bound to. CAFile verifies an https server signed by
+// a private CA, and CertFile and KeyFile are the client certificate presented
+// to servers that require one.
+type Client struct {
+	URL      string        `yaml:"url" toml:"url"`
+	APIKey   string        `yaml:"api_key" toml:"api_key" secret:"true"`
+	Token    string        `yaml:"token" toml:"token" secret:"true"`
+	Tenant   string        `yaml:"tenant" toml:"tenant"`
+	Timeout  time.Duration `yaml:"timeout" toml:"timeout"`
+	CAFile   string        `yaml:"ca_file" toml:"ca_file"`
+	CertFile string        `yaml:"cert_file" toml:"cert_file"`
+	KeyFile  string        `yaml:"key_file" toml:"key_file"`
+}
+
+// Config holds every setting of the server and the command-line client.
+// Load assembles it from the defaults of the environment, a YAML or TOML
+// file, LEDGER_* environment variables and flags, in that order; the keys
+// of all three are the yaml tags below.
 type Config struct {
-	ServerPort        string
-	CurrencyFile      string
-	ReadTimeout       time.Duration
-	WriteTimeout      time.Duration
-	IdleTimeout       time.Duration
-	ReadHeaderTimeout time.Duration
-	MaxHeaderBytes    int
+	ServerPort        string        `yaml:"server_port" toml:"server_port"`
+	CurrencyFile      string        `yaml:"currency_file" toml:"currency_file"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" toml:"read_timeout"`
+	WriteTimeout      time.Duration `yaml:"write_timeout" toml:"write_timeout"`
+	IdleTimeout       time.Duration `yaml:"idle_timeout" toml:"idle_timeout"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" toml:"read_header_timeout"`
+	MaxHeaderBytes    int           `yaml:"max_header_bytes" toml:"max_header_bytes"`
+	MaxRequestBytes   int           `yaml:"max_request_bytes" toml:"max_request_bytes"`
+	TLS               TLS           `yaml:"tls" toml:"tls"`
+
+	// Address the gRPC API listens on. Empty disables the gRPC API.
+	GRPCPort string `yaml:"grpc_port" toml:"grpc_port"`
+
+	// Logging. LogLevel is debug, info, warn or error; LogFormat is console
+	// (coloured text for people) or json (for log collectors).
+	LogLevel  string `yaml:"log_level" toml:"log_level"`
+	LogFormat string `yaml:"log_format" toml:"log_format"`
+
+	// How often the ledger checks that its books balance.
+	BalanceCheckInterval time.Duration `yaml:"balance_check_interval" toml:"balance_check_interval"`
+
+	// Reconciliation settings. An empty directory keeps reconciliations in
+	// memory only.
+	ReconciliationDir    string        `yaml:"reconciliation_dir" toml:"reconciliation_dir"`
+	ReconciliationWindow time.Duration `yaml:"reconciliation_window" toml:"reconciliation_window"`
+
+	// How often the interest engine looks for completed days to accrue.
+	InterestAccrualInterval time.Duration `yaml:"interest_accrual_interval" toml:"interest_accrual_interval"`
+
+	// How often standing orders are checked for due occurrences, how many
+	// times a failing occurrence is attempted before it is given up, and how
+	// many missed occurrences of an order are posted in one run.
+	SchedulerInterval    time.Duration `yaml:"scheduler_interval" toml:"scheduler_interval"`
+	SchedulerMaxAttempts int           `yaml:"scheduler_max_attempts" toml:"scheduler_max_attempts"`
+	SchedulerMaxCatchUp  int           `yaml:"scheduler_max_catch_up" toml:"scheduler_max_catch_up"`
+
+	// Optional JSON file with the fee rules loaded at startup. Without it no
+	// fees are charged until rules are set through the API.
+	FeeRulesFile string `yaml:"fee_rules_file" toml:"fee_rules_file"`
+
+	// Webhook delivery: how often the outbox is polled, the timeout per
+	// request, and the retry policy. Retries back off exponentially from
+	// WebhookRetryBase up to WebhookRetryMax; after WebhookMaxAttempts the
+	// delivery goes to the dead-letter list.
+	WebhookPollInterval time.Duration `yaml:"webhook_poll_interval" toml:"webhook_poll_interval"`
+	WebhookTimeout      time.Duration `yaml:"webhook_timeout" toml:"webhook_timeout"`
+	WebhookMaxAttempts  int           `yaml:"webhook_max_attempts" toml:"webhook_max_attempts"`
+	WebhookRetryBase    time.Duration `yaml:"webhook_retry_base" toml:"webhook_retry_base"`
+	WebhookRetryMax     time.Duration `yaml:"webhook_retry_max" toml:"webhook_retry_max"`
+
+	// Interval of the keep-alive sent on idle /events streams.
+	EventStreamHeartbeat time.Duration `yaml:"event_stream_heartbeat" toml:"event_stream_heartbeat"`
+
+	// Authentication. Requests need one of the API keys, a client certificate
+	// or a JWT signed with JWTHMACSecret (HS256) or the Ed25519 key in
+	// JWTPublicKeyFile (EdDSA). Token issuer and audience are checked when
+	// set; JWTLeeway allows for clock skew on the time claims.
+	APIKeys          []APIKey      `yaml:"api_keys" toml:"api_keys"`
+	JWTHMACSecret    string        `yaml:"jwt_hmac_secret" toml:"jwt_hmac_secret" secret:"true"`
+	JWTPublicKeyFile string        `yaml:"jwt_public_key_file" toml:"jwt_public_key_file"`
+	JWTIssuer        string        `yaml:"jwt_issuer" toml:"jwt_issuer"`
+	JWTAudience      string        `yaml:"jwt_audience" toml:"jwt_audience"`
+	JWTLeeway        time.Duration `yaml:"jwt_leeway" toml:"jwt_leeway"`
+
+	// Client certificates accepted as credentials when tls.client_ca_file is
+	// set. Requests carrying an API key or a token are authenticated by it
+	// instead.
+	ClientCertificates []ClientCertificate `yaml:"client_certificates" toml:"client_certificates"`
+
+	// Append-only audit trail of changes made through the API. An empty file
+	// keeps the trail in memory only.
+	AuditLogFile string `yaml:"audit_log_file" toml:"audit_log_file"`
+
+	// Tenants created at startup besides the default one. Each tenant keeps
+	// its reconciliations and audit trail under TenantDataDir/<id>; an empty
+	// directory keeps them in memory only.
+	Tenants       []TenantConfig `yaml:"tenants" toml:"tenants"`
+	TenantDataDir string         `yaml:"tenant_data_dir" toml:"tenant_data_dir"`
+
+	// Request budgets per API client, or per IP address for requests without
+	// a principal. Reads (GET, HEAD and OPTIONS) and writes are limited
+	// separately. IPRateLimit is checked first, per client IP address and
+	// before authentication, for every request.
+	IPRateLimit    RateLimit `yaml:"ip_rate_limit" toml:"ip_rate_limit"`
+	ReadRateLimit  RateLimit `yaml:"read_rate_limit" toml:"read_rate_limit"`
+	WriteRateLimit RateLimit `yaml:"write_rate_limit" toml:"write_rate_limit"`
+
+	// Addresses or CIDR ranges of the reverse proxies in front of the API.
+	// The client address is taken from X-Forwarded-For only on requests
+	// that come through them; otherwise it is the connection's peer.
+	TrustedProxies []string `yaml:"trusted_proxies" toml:"trusted_proxies"`
+
+	// Distributed tracing of requests and ledger operations.
+	Tracing Tracing `yaml:"tracing" toml:"tracing"`
+
+	// Server and credentials used by the command-line subcommands.
+	Client Client `yaml:"client" toml:"client"`
 }
 
+// NewConfig returns the development defaults.
 func NewConfig() *Config {
-	return &Config{
-		ServerPort:        ":8080",
-		CurrencyFile:      "data/iso4217_currency_dev.json",
-		ReadTimeout:       15 * time.Second,
-		WriteTimeout:      15 * time.Second,
-		IdleTimeout:       60 * time.Second,
-		ReadHeaderTimeout: 5 * time.Second,
-		MaxHeaderBytes:    1 << 20, // 1 MB
-	}
+	return Development()
 }
@@ -0,0 +1,255 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables read by Load: APP_ENV selects the defaults,
+// LEDGER_CONFIG names the configuration file, and every setting can be
+// overridden by LEDGER_ and its key in upper case, with dots as
+// underscores (LEDGER_SERVER_PORT, LEDGER_TRACING_EXPORTER).
+const (
+	EnvName   = "APP_ENV"
+	EnvFile   = "LEDGER_CONFIG"
+	envPrefix = "LEDGER_"
+)
+
+// redacted replaces secrets in printed configurations.
+const redacted = "<redacted>"
+
+// Load returns the configuration of the environment env: its defaults,
+// overridden by the YAML or TOML file, if any, then by the variables found
+// by lookup and then by flags, a map from setting keys to values. The result
+// is validated.
+func Load(env, file string, lookup func(string) (string, bool), flags map[string]string) (*Config, error) {
+	cfg := Defaults(env)
+	if file != "" {
+		if err := readFile(cfg, file); err != nil {
+			return nil, err
+		}
+	}
+
+	all := settings(cfg)
+	for _, s := range all {
+		name := envName(s.key)
+		if value, ok := lookup(name); ok {
+			if err := s.set(value); err != nil {
+				return nil, fmt.Errorf("%s: %v", name, err)
+			}
+		}
+	}
+	for key, value := range flags {
+		s, ok := findSetting(all, key)
+		if !ok {
+			return nil, fmt.Errorf("unknown setting %q", key)
+		}
+		if err := s.set(value); err != nil {
+			return nil, fmt.Errorf("-%s: %v", flagName(key), err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Flags are the command-line flags of the settings.
+type Flags struct {
+	file      string
+	overrides map[string]string
+}
+
+// RegisterFlags adds to fs a flag for every setting, named after its key
+// with dashes (-server-port, -tracing-exporter), and -config for the file.
+func RegisterFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{overrides: make(map[string]string)}
+	fs.StringVar(&f.file, "config", os.Getenv(EnvFile), "YAML or TOML configuration `file`")
+	for _, s := range settings(&Config{}) {
+		key := s.key
+		fs.Func(flagName(key), fmt.Sprintf("sets %s (%s)", key, s.kind()), func(value string) error {
+			f.overrides[key] = value
+			return nil
+		})
+	}
+	return f
+}
+
+// Load assembles the configuration of APP_ENV from the file, the process
+// environment and the flags of a parsed flag set.
+func (f *Flags) Load() (*Config, error) {
+	return Load(os.Getenv(EnvName), f.file, os.LookupEnv, f.overrides)
+}
+
+// FromEnvironment assembles the configuration of APP_ENV from the file in
+// LEDGER_CONFIG and the process environment.
+func FromEnvironment() (*Config, error) {
+	return Load(os.Getenv(EnvName), os.Getenv(EnvFile), os.LookupEnv, nil)
+}
+
+func readFile(cfg *Config, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error reading config file: %v", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("error parsing %s: %v", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), cfg)
+		if err != nil {
+			return fmt.Errorf("error parsing %s: %v", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("error parsing %s: unknown setting %q", path, undecoded[0].String())
+		}
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+// Write writes the configuration as YAML or TOML, with secrets redacted.
+func (c *Config) Write(w io.Writer, format string) error {
+	copied := *c
+	for _, s := range settings(&copied) {
+		if s.secret && s.value.String() != "" {
+			s.value.SetString(redacted)
+		}
+	}
+
+	switch format {
+	case "yaml":
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&copied); err != nil {
+			return err
+		}
+		return encoder.Close()
+	case "toml":
+		return toml.NewEncoder(w).Encode(&copied)
+	}
+	return fmt.Errorf("unsupported format %q", format)
+}
+
+// setting is a single value of the configuration, addressed by its key such
+// as "server_port" or "tracing.exporter".
+type setting struct {
+	key    string
+	value  reflect.Value
+	secret bool
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// settings returns the settings of cfg that can be given as text. Lists of
+// API keys and tenants are only read from files.
+func settings(cfg *Config) []setting {
+	var all []setting
+	var walk func(prefix string, v reflect.Value)
+	walk = func(prefix string, v reflect.Value) {
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			key := prefix + field.Tag.Get("yaml")
+			value := v.Field(i)
+			switch {
+			case value.Kind() == reflect.Struct:
+				walk(key+".", value)
+			case value.Kind() == reflect.Slice && value.Type().Elem().Kind() != reflect.String:
+			default:
+				all = append(all, setting{key: key, value: value, secret: field.Tag.Get("secret") == "true"})
+			}
+		}
+	}
+	walk("", reflect.ValueOf(cfg).Elem())
+	return all
+}
+
+func findSetting(all []setting, key string) (setting, bool) {
+	for _, s := range all {
+		if s.key == key {
+			return s, true
+		}
+	}
+	return setting{}, false
+}
+
+func (s setting) kind() string {
+	switch {
+	case s.value.Type() == durationType:
+		return "duration"
+	case s.value.Kind() == reflect.Slice:
+		return "comma-separated list"
+	case s.value.Kind() == reflect.Float64:
+		return "number"
+	}
+	return s.value.Kind().String()
+}
+
+// set parses text into the setting.
+func (s setting) set(text string) error {
+	v := s.value
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", text)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(text)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", text)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", text)
+		}
+		v.SetFloat(f)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", text)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Slice:
+		list := []string{}
+		for _, item := range strings.Split(text, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+		v.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("unsupported setting type %s", v.Type())
+	}
+	return nil
+}
+
+func envName(key string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+func flagName(key string) string {
+	return strings.NewReplacer("_", "-", ".", "-").Replace(key)
+}
@@ -0,0 +1,252 @@
+package config
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDefaults(t *testing.T) {
+	tests := []struct {
+		env        string
+		wantPort   string
+		wantFormat string
+	}{
+		{"", ":8080", "console"},
+		{"development", ":8080", "console"},
+		{"test", ":8081", "console"},
+		{"prod", ":80", "json"},
+		{"Production", ":80", "json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.env, func(t *testing.T) {
+			cfg, err := Load(tt.env, "", noEnv, nil)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantPort, cfg.ServerPort)
+			assert.Equal(t, tt.wantFormat, cfg.LogFormat)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	yamlFile := writeFile(t, dir, "ledger.yaml", `
+server_port: ":9000"
+read_timeout: 5s
+tracing:
+  exporter: stdout
+api_keys:
+  - principal: ops
+    key_hash: "`+hashOf64+`"
+    role: admin
+`)
+	tomlFile := writeFile(t, dir, "ledger.toml", `
+server_port = ":9000"
+read_timeout = "5s"
+
+[tracing]
+exporter = "stdout"
+`)
+
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		flags   map[string]string
+		check   func(t *testing.T, cfg *Config)
+		wantErr string
+	}{
+		{
+			name: "yaml file",
+			file: yamlFile,
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, ":9000", cfg.ServerPort)
+				assert.Equal(t, 5*time.Second, cfg.ReadTimeout)
+				assert.Equal(t, "stdout", cfg.Tracing.Exporter)
+				assert.Equal(t, "ops", cfg.APIKeys[0].Principal)
+				// Settings the file omits keep their defaults
+				assert.Equal(t, "data/iso4217_currency_dev.json", cfg.CurrencyFile)
+			},
+		},
+		{
+			name: "toml file",
+			file: tomlFile,
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, ":9000", cfg.ServerPort)
+				assert.Equal(t, 5*time.Second, cfg.ReadTimeout)
+				assert.Equal(t, "stdout", cfg.Tracing.Exporter)
+			},
+		},
+		{
+			name: "environment overrides file",
+			file: yamlFile,
+			env: map[string]string{
+				"LEDGER_SERVER_PORT":      ":9100",
+				"LEDGER_TRACING_EXPORTER": "none",
+				"LEDGER_TLS_CERT_FILE":    "server.crt",
+				"LEDGER_TLS_KEY_FILE":     "server.key",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, ":9100", cfg.ServerPort)
+				assert.Equal(t, "none", cfg.Tracing.Exporter)
+				assert.Equal(t, TLS{CertFile: "server.crt", KeyFile: "server.key", ReloadInterval: time.Minute}, cfg.TLS)
+				assert.Equal(t, 5*time.Second, cfg.ReadTimeout)
+			},
+		},
+		{
+			name:  "flags override environment",
+			env:   map[string]string{"LEDGER_SERVER_PORT": ":9100", "LEDGER_LOG_LEVEL": "warn"},
+			flags: map[string]string{"server_port": ":9200", "balance_check_interval": "10m"},
+			check: func(t *testing.T, cfg *Config) {
+				assert.Equal(t, ":9200", cfg.ServerPort)
+				assert.Equal(t, "warn", cfg.LogLevel)
+				assert.Equal(t, 10*time.Minute, cfg.BalanceCheckInterval)
+			},
+		},
+		{
+			name:    "unknown yaml key",
+			file:    writeFile(t, dir, "unknown.yaml", "server_prot: \":9000\"\n"),
+			wantErr: "field server_prot not found",
+		},
+		{
+			name:    "unknown toml key",
+			file:    writeFile(t, dir, "unknown.toml", "server_prot = \":9000\"\n"),
+			wantErr: `unknown setting "server_prot"`,
+		},
+		{
+			name:    "unsupported file",
+			file:    writeFile(t, dir, "ledger.json", "{}"),
+			wantErr: "must be .yaml, .yml or .toml",
+		},
+		{
+			name:    "missing file",
+			file:    filepath.Join(dir, "missing.yaml"),
+			wantErr: "error reading config file",
+		},
+		{
+			name:    "malformed environment",
+			env:     map[string]string{"LEDGER_READ_TIMEOUT": "soon"},
+			wantErr: `LEDGER_READ_TIMEOUT: invalid duration "soon"`,
+		},
+		{
+			name:    "malformed flag",
+			flags:   map[string]string{"webhook_max_attempts": "many"},
+			wantErr: `-webhook-max-attempts: invalid integer "many"`,
+		},
+		{
+			name:    "invalid values",
+			flags:   map[string]string{"server_port": "8080", "log_format": "xml", "write_timeout": "0s"},
+			wantErr: "server_port: \"8080\" is not a host:port address\nwrite_timeout: must be positive\nlog_format: must be one of [console json]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lookup := func(name string) (string, bool) {
+				value, ok := tt.env[name]
+				return value, ok
+			}
+			cfg, err := Load(EnvDevelopment, tt.file, lookup, tt.flags)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			tt.check(t, cfg)
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(cfg *Config)
+		wantErr string
+	}{
+		{name: "defaults", modify: func(cfg *Config) {}},
+		{name: "same ports", modify: func(cfg *Config) { cfg.GRPCPort = cfg.ServerPort }, wantErr: "grpc_port: must differ from server_port"},
+		{name: "retry bounds", modify: func(cfg *Config) { cfg.WebhookRetryBase = time.Hour }, wantErr: "webhook_retry_base: must not exceed webhook_retry_max"},
+		{name: "api key hash", modify: func(cfg *Config) { cfg.APIKeys[0].KeyHash = "abc" }, wantErr: "api_keys[0].key_hash: must be a hex SHA-256 digest"},
+		{
+			name:    "duplicate tenant",
+			modify:  func(cfg *Config) { cfg.Tenants = []TenantConfig{{ID: "acme"}, {ID: "acme"}} },
+			wantErr: `tenants[1].id: "acme" is listed twice`,
+		},
+		{name: "catch-up", modify: func(cfg *Config) { cfg.SchedulerMaxCatchUp = 0 }, wantErr: "scheduler_max_catch_up: must be positive"},
+		{
+			name:    "trusted proxies",
+			modify:  func(cfg *Config) { cfg.TrustedProxies = []string{"10.0.0.0/8", "proxy.internal"} },
+			wantErr: `trusted_proxies[1]: "proxy.internal" is not an IP address or CIDR range`,
+		},
+		{name: "rate burst", modify: func(cfg *Config) { cfg.WriteRateLimit = RateLimit{Rate: 5} }, wantErr: "write_rate_limit.burst: must be positive"},
+		{name: "otlp endpoint", modify: func(cfg *Config) { cfg.Tracing.Exporter = "otlp" }, wantErr: "tracing.endpoint: is required"},
+		{name: "tls key", modify: func(cfg *Config) { cfg.TLS.CertFile = "server.crt" }, wantErr: "tls.key_file: must be set together with tls.cert_file"},
+		{name: "tls client ca", modify: func(cfg *Config) { cfg.TLS.ClientCAFile = "ca.crt" }, wantErr: "tls.client_ca_file: requires tls.cert_file"},
+		{name: "required client cert", modify: func(cfg *Config) { cfg.TLS.RequireClientCert = true }, wantErr: "tls.require_client_cert: requires tls.client_ca_file"},
+		{
+			name:    "client certificates",
+			modify:  func(cfg *Config) { cfg.ClientCertificates = []ClientCertificate{{CommonName: "payments"}} },
+			wantErr: "client_certificates: require tls.client_ca_file",
+		},
+		{name: "client url", modify: func(cfg *Config) { cfg.Client.URL = "localhost:8080" }, wantErr: "client.url:"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := Development()
+			tt.modify(cfg)
+			err := cfg.Validate()
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.wantErr)
+		})
+	}
+}
+
+func TestWrite(t *testing.T) {
+	cfg := Development()
+	cfg.JWTHMACSecret = "s3cret"
+
+	for _, format := range []string{"yaml", "toml"} {
+		t.Run(format, func(t *testing.T) {
+			var out bytes.Buffer
+			require.NoError(t, cfg.Write(&out, format))
+			assert.NotContains(t, out.String(), "s3cret")
+			assert.NotContains(t, out.String(), "dev-api-key")
+			assert.Contains(t, out.String(), redacted)
+
+			// The printed configuration loads back to the same settings
+			file := writeFile(t, t.TempDir(), "printed."+format, out.String())
+			loaded, err := Load(EnvProduction, file, noEnv, nil)
+			require.NoError(t, err)
+			assert.Equal(t, cfg.ServerPort, loaded.ServerPort)
+			assert.Equal(t, cfg.ReadTimeout, loaded.ReadTimeout)
+			require.Len(t, loaded.APIKeys, len(cfg.APIKeys))
+			assert.Equal(t, cfg.APIKeys[0].KeyHash, loaded.APIKeys[0].KeyHash)
+		})
+	}
+	assert.Equal(t, "s3cret", cfg.JWTHMACSecret, "Write must not modify the configuration")
+
+	var out bytes.Buffer
+	assert.Error(t, cfg.Write(&out, "ini"))
+}
+
+// hashOf64 is a well-formed key hash.
+const hashOf64 = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+
+func noEnv(string) (string, bool) {
+	return "", false
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
@@ -1,54 +1,205 @@
 package config
 
 import (
-	"go.uber.org/fx"
+	"strings"
 	"time"
 )
 
-func Development() fx.Option {
-	return fx.Options(
-		fx.Provide(func() *Config {
-			return &Config{
-				ServerPort:        ":8080",
-				CurrencyFile:      "data/iso4217_currency_dev.json",
-				ReadTimeout:       15 * time.Second,
-				WriteTimeout:      15 * time.Second,
-				IdleTimeout:       60 * time.Second,
-				ReadHeaderTimeout: 5 * time.Second,
-				MaxHeaderBytes:    1 << 20,
-			}
-		}),
-	)
+// Environments selected by APP_ENV. Each has its own defaults.
+const (
+	EnvDevelopment = "development"
+	EnvTest        = "test"
+	EnvProduction  = "production"
+)
+
+// Environment returns the canonical name of an APP_ENV value. Unknown and
+// empty values select development.
+func Environment(name string) string {
+	switch strings.ToLower(name) {
+	case "test":
+		return EnvTest
+	case "prod", "production":
+		return EnvProduction
+	}
+	return EnvDevelopment
+}
+
+// Defaults returns the defaults of an environment.
+func Defaults(env string) *Config {
+	switch Environment(env) {
+	case EnvTest:
+		return Test()
+	case EnvProduction:
+		return Production()
+	}
+	return Development()
+}
+
+func Development() *Config {
+	return &Config{
+		ServerPort:        ":8080",
+		CurrencyFile:      "data/iso4217_currency_dev.json",
+		ReadTimeout:       15 * time.Second,
+		WriteTimeout:      15 * time.Second,
+		IdleTimeout:       60 * time.Second,
+		ReadHeaderTimeout: 5 * time.Second,
+		MaxHeaderBytes:    1 << 20,
+		MaxRequestBytes:   10 << 20,
+		TLS:               TLS{ReloadInterval: time.Minute},
+
+		GRPCPort: ":9090",
+
+		LogLevel:  "debug",
+		LogFormat: "console",
+
+		BalanceCheckInterval: time.Hour,
+
+		ReconciliationDir:    "data/reconciliations",
+		ReconciliationWindow: 3 * 24 * time.Hour,
+
+		InterestAccrualInterval: time.Hour,
+
+		SchedulerInterval:    time.Minute,
+		SchedulerMaxAttempts: 3,
+		SchedulerMaxCatchUp:  100,
+
+		WebhookPollInterval: time.Second,
+		WebhookTimeout:      10 * time.Second,
+		WebhookMaxAttempts:  8,
+		WebhookRetryBase:    5 * time.Second,
+		WebhookRetryMax:     10 * time.Minute,
+
+		EventStreamHeartbeat: 15 * time.Second,
+
+		// Development key "dev-api-key"
+		APIKeys: []APIKey{
+			{Principal: "dev", KeyHash: "6e1e4e1b8f8b36d08901cdb51b97841dfe20f5efd2fd2fd00768971408c46274", Role: "admin"},
+		},
+		JWTLeeway: 30 * time.Second,
+
+		AuditLogFile: "data/audit.log",
+
+		TenantDataDir: "data/tenants",
+
+		IPRateLimit:    RateLimit{Rate: 50, Burst: 100},
+		ReadRateLimit:  RateLimit{Rate: 20, Burst: 40},
+		WriteRateLimit: RateLimit{Rate: 10, Burst: 20},
+
+		Tracing: Tracing{Exporter: "file", File: "data/traces.jsonl", SampleRatio: 1},
+
+		Client: Client{URL: "http://localhost:8080", APIKey: "dev-api-key", Timeout: 30 * time.Second},
+	}
 }
 
-func Test() fx.Option {
-	return fx.Options(
-		fx.Provide(func() *Config {
-			return &Config{
-				ServerPort:        ":8081",
-				CurrencyFile:      "data/iso4217_currency_test.json",
-				ReadTimeout:       5 * time.Second, // Shorter timeouts for testing
-				WriteTimeout:      5 * time.Second,
-				IdleTimeout:       30 * time.Second,
-				ReadHeaderTimeout: 2 * time.Second,
-				MaxHeaderBytes:    1 << 20,
-			}
-		}),
-	)
+func Test() *Config {
+	return &Config{
+		ServerPort:        ":8081",
+		CurrencyFile:      "data/iso4217_currency_test.json",
+		ReadTimeout:       5 * time.Second, // Shorter timeouts for testing
+		WriteTimeout:      5 * time.Second,
+		IdleTimeout:       30 * time.Second,
+		ReadHeaderTimeout: 2 * time.Second,
+		MaxHeaderBytes:    1 << 20,
+		MaxRequestBytes:   10 << 20,
+		TLS:               TLS{ReloadInterval: time.Minute},
+
+		GRPCPort: ":9091",
+
+		LogLevel:  "debug",
+		LogFormat: "console",
+
+		BalanceCheckInterval: time.Minute,
+
+		ReconciliationDir:    "", // Keep test reconciliations in memory
+		ReconciliationWindow: 3 * 24 * time.Hour,
+
+		InterestAccrualInterval: time.Minute,
+
+		SchedulerInterval:    10 * time.Second,
+		SchedulerMaxAttempts: 3,
+		SchedulerMaxCatchUp:  100,
+
+		WebhookPollInterval: 100 * time.Millisecond,
+		WebhookTimeout:      2 * time.Second,
+		WebhookMaxAttempts:  3,
+		WebhookRetryBase:    100 * time.Millisecond,
+		WebhookRetryMax:     time.Second,
+
+		EventStreamHeartbeat: time.Second,
+
+		// Test key "test-api-key"
+		APIKeys: []APIKey{
+			{Principal: "test", KeyHash: "4c806362b613f7496abf284146efd31da90e4b16169fe001841ca17290f427c4", Role: "admin"},
+		},
+		JWTLeeway: 30 * time.Second,
+
+		AuditLogFile: "", // Keep the test audit trail in memory
+
+		TenantDataDir: "", // Keep tenant data in memory
+
+		// Generous budgets so test runs are not throttled
+		IPRateLimit:    RateLimit{Rate: 2000, Burst: 4000},
+		ReadRateLimit:  RateLimit{Rate: 1000, Burst: 2000},
+		WriteRateLimit: RateLimit{Rate: 500, Burst: 1000},
+
+		Tracing: Tracing{Exporter: "none"},
+
+		Client: Client{URL: "http://localhost:8081", APIKey: "test-api-key", Timeout: 10 * time.Second},
+	}
 }
 
-func Production() fx.Option {
-	return fx.Options(
-		fx.Provide(func() *Config {
-			return &Config{
-				ServerPort:        ":80",
-				CurrencyFile:      "data/iso4217_currency.json",
-				ReadTimeout:       30 * time.Second, // Longer timeouts for production
-				WriteTimeout:      30 * time.Second,
-				IdleTimeout:       120 * time.Second,
-				ReadHeaderTimeout: 10 * time.Second,
-				MaxHeaderBytes:    1 << 20,
-			}
-		}),
-	)
+func Production() *Config {
+	return &Config{
+		ServerPort:        ":80",
+		CurrencyFile:      "data/iso4217_currency.json",
+		ReadTimeout:       30 * time.Second, // Longer timeouts for production
+		WriteTimeout:      30 * time.Second,
+		IdleTimeout:       120 * time.Second,
+		ReadHeaderTimeout: 10 * time.Second,
+		MaxHeaderBytes:    1 << 20,
+		MaxRequestBytes:   10 << 20,
+		TLS:               TLS{ReloadInterval: time.Minute},
+
+		GRPCPort: ":9090",
+
+		LogLevel:  "info",
+		LogFormat: "json",
+
+		BalanceCheckInterval: time.Hour,
+
+		ReconciliationDir:    "/var/lib/ledger/reconciliations",
+		ReconciliationWindow: 5 * 24 * time.Hour,
+
+		InterestAccrualInterval: 15 * time.Minute,
+
+		SchedulerInterval:    time.Minute,
+		SchedulerMaxAttempts: 5,
+		SchedulerMaxCatchUp:  100,
+
+		WebhookPollInterval: time.Second,
+		WebhookTimeout:      10 * time.Second,
+		WebhookMaxAttempts:  12,
+		WebhookRetryBase:    10 * time.Second,
+		WebhookRetryMax:     time.Hour,
+
+		EventStreamHeartbeat: 30 * time.Second,
+
+		// Production accepts tokens from the identity provider only
+		JWTPublicKeyFile: "/etc/ledger/jwt_ed25519.pub.pem",
+		JWTLeeway:        30 * time.Second,
+
+		AuditLogFile: "/var/lib/ledger/audit.log",
+
+		TenantDataDir: "/var/lib/ledger/tenants",
+
+		IPRateLimit:    RateLimit{Rate: 100, Burst: 200},
+		ReadRateLimit:  RateLimit{Rate: 50, Burst: 100},
+		WriteRateLimit: RateLimit{Rate: 20, Burst: 40},
+
+		// Spans go to the collector running next to the service
+		Tracing: Tracing{Exporter: "otlp", Endpoint: "localhost:4318", SampleRatio: 0.1},
+
+		// Operators pass their token in LEDGER_TOKEN
+		Client: Client{URL: "http://localhost:80", Timeout: 30 * time.Second},
+	}
 }
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+// Accepted values of the enumerated settings.
+var (
+	logLevels      = []string{"debug", "info", "warn", "error"}
+	logFormats     = []string{"console", "json"}
+	traceExporters = []string{"none", "stdout", "file", "otlp"}
+)
+
+// Validate reports every invalid setting, naming each by its key.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(validAddress(c.ServerPort), "server_port: %q is not a host:port address", c.ServerPort)
+	check(c.GRPCPort == "" || validAddress(c.GRPCPort), "grpc_port: %q is not a host:port address", c.GRPCPort)
+	check(c.GRPCPort == "" || c.GRPCPort != c.ServerPort, "grpc_port: must differ from server_port")
+	check(c.CurrencyFile != "", "currency_file: is required")
+	check(c.MaxHeaderBytes > 0, "max_header_bytes: must be positive")
+	check(c.MaxRequestBytes >= 0, "max_request_bytes: must not be negative")
+	check((c.TLS.CertFile == "") == (c.TLS.KeyFile == ""), "tls.key_file: must be set together with tls.cert_file")
+	check(c.TLS.ClientCAFile == "" || c.TLS.Enabled(), "tls.client_ca_file: requires tls.cert_file")
+	check(!c.TLS.RequireClientCert || c.TLS.ClientCAFile != "", "tls.require_client_cert: requires tls.client_ca_file")
+	check(!c.TLS.Enabled() || c.TLS.ReloadInterval > 0, "tls.reload_interval: must be positive")
+
+	for _, d := range []struct {
+		key   string
+		value time.Duration
+	}{
+		{"read_timeout", c.ReadTimeout},
+		{"write_timeout", c.WriteTimeout},
+		{"idle_timeout", c.IdleTimeout},
+		{"read_header_timeout", c.ReadHeaderTimeout},
+		{"balance_check_interval", c.BalanceCheckInterval},
+		{"reconciliation_window", c.ReconciliationWindow},
+		{"interest_accrual_interval", c.InterestAccrualInterval},
+		{"scheduler_interval", c.SchedulerInterval},
+		{"webhook_poll_interval", c.WebhookPollInterval},
+		{"webhook_timeout", c.WebhookTimeout},
+		{"webhook_retry_base", c.WebhookRetryBase},
+		{"webhook_retry_max", c.WebhookRetryMax},
+		{"event_stream_heartbeat", c.EventStreamHeartbeat},
+	} {
+		check(d.value > 0, "%s: must be positive", d.key)
+	}
+	check(c.SchedulerMaxAttempts > 0, "scheduler_max_attempts: must be positive")
+	check(c.SchedulerMaxCatchUp > 0, "scheduler_max_catch_up: must be positive")
+	check(c.WebhookMaxAttempts > 0, "webhook_max_attempts: must be positive")
+	check(c.WebhookRetryBase <= c.WebhookRetryMax, "webhook_retry_base: must not exceed webhook_retry_max")
+
+	check(oneOf(c.LogLevel, logLevels), "log_level: must be one of %v", logLevels)
+	check(oneOf(c.LogFormat, logFormats), "log_format: must be one of %v", logFormats)
+
+	for i, key := range c.APIKeys {
+		check(key.Principal != "", "api_keys[%d].principal: is required", i)
+		_, err := hex.DecodeString(key.KeyHash)
+		check(err == nil && len(key.KeyHash) == 64, "api_keys[%d].key_hash: must be a hex SHA-256 digest", i)
+	}
+	check(c.JWTLeeway >= 0, "jwt_leeway: must not be negative")
+
+	commonNames := make(map[string]bool)
+	for i, cert := range c.ClientCertificates {
+		check(cert.CommonName != "", "client_certificates[%d].common_name: is required", i)
+		check(!commonNames[cert.CommonName], "client_certificates[%d].common_name: %q is listed twice", i, cert.CommonName)
+		commonNames[cert.CommonName] = true
+	}
+	check(len(c.ClientCertificates) == 0 || c.TLS.ClientCAFile != "", "client_certificates: require tls.client_ca_file")
+
+	seen := make(map[string]bool)
+	for i, tenant := range c.Tenants {
+		check(tenant.ID != "", "tenants[%d].id: is required", i)
+		check(!seen[tenant.ID], "tenants[%d].id: %q is listed twice", i, tenant.ID)
+		seen[tenant.ID] = true
+	}
+
+	for _, l := range []struct {
+		key   string
+		limit RateLimit
+	}{
+		{"ip_rate_limit", c.IPRateLimit},
+		{"read_rate_limit", c.ReadRateLimit},
+		{"write_rate_limit", c.WriteRateLimit},
+	} {
+		check(l.limit.Rate >= 0, "%s.rate: must not be negative", l.key)
+		check(l.limit.Rate == 0 || l.limit.Burst > 0, "%s.burst: must be positive when a rate is set", l.key)
+	}
+	for i, proxy := range c.TrustedProxies {
+		check(validProxy(proxy), "trusted_proxies[%d]: %q is not an IP address or CIDR range", i, proxy)
+	}
+
+	check(oneOf(c.Tracing.Exporter, traceExporters), "tracing.exporter: must be one of %v", traceExporters)
+	check(c.Tracing.Exporter != "file" || c.Tracing.File != "", "tracing.file: is required by the file exporter")
+	check(c.Tracing.Exporter != "otlp" || c.Tracing.Endpoint != "", "tracing.endpoint: is required by the otlp exporter")
+	check(c.Tracing.SampleRatio >= 0 && c.Tracing.SampleRatio <= 1, "tracing.sample_ratio: must be between 0 and 1")
+
+	if c.Client.URL != "" {
+		u, err := url.Parse(c.Client.URL)
+		check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "",
+			"client.url: %q is not an http or https URL", c.Client.URL)
+	}
+	check(c.Client.Timeout >= 0, "client.timeout: must not be negative")
+	check((c.Client.CertFile == "") == (c.Client.KeyFile == ""), "client.key_file: must be set together with client.cert_file")
+
+	return errors.Join(errs...)
+}
+
+func validAddress(addr string) bool {
+	_, port, err := net.SplitHostPort(addr)
+	return err == nil && port != ""
+}
+
+func validProxy(proxy string) bool {
+	if _, _, err := net.ParseCIDR(proxy); err == nil {
+		return true
+	}
+	return net.ParseIP(proxy) != nil
+}
+
+func oneOf(value string, allowed []string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
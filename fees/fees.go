@@ -0,0 +1,113 @@
+package fees
+
+import (
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"ledgerproject/config"
+	"ledgerproject/logger"
+	"ledgerproject/models"
+	"ledgerproject/services"
+	"os"
+	"sync"
+)
+
+// Engine holds the fee rules and computes the fee legs of a transfer. Rules
+// are evaluated in order and the first match applies.
+type Engine struct {
+	rules             []Rule
+	currencyValidator *services.CurrencyValidator
+	mu                sync.RWMutex
+}
+
+// NewEngine returns an engine loaded from cfg.FeeRulesFile, or one without
+// rules when no file is configured.
+func NewEngine(cv *services.CurrencyValidator, cfg *config.Config) (*Engine, error) {
+	log := logger.Get()
+	e := &Engine{currencyValidator: cv}
+	if cfg.FeeRulesFile == "" {
+		return e, nil
+	}
+
+	data, err := os.ReadFile(cfg.FeeRulesFile)
+	if err != nil {
+		log.Error("Failed to read fee rules file",
+			zap.Error(err),
+			zap.String("file", cfg.FeeRulesFile))
+		return nil, fmt.Errorf("error reading fee rules file: %v", err)
+	}
+	var rules []Rule
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("error unmarshaling fee rules: %v", err)
+	}
+	if err := e.SetRules(rules); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Rules returns the configured rules in evaluation order.
+func (e *Engine) Rules() []Rule {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return append([]Rule{}, e.rules...)
+}
+
+// SetRules validates and replaces the whole rule set.
+func (e *Engine) SetRules(rules []Rule) error {
+	log := logger.Get()
+
+	seen := make(map[string]bool)
+	for _, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return err
+		}
+		if seen[rule.ID] {
+			return fmt.Errorf("duplicate fee rule id %s", rule.ID)
+		}
+		seen[rule.ID] = true
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.rules = append([]Rule{}, rules...)
+	log.Info("Fee rules updated", zap.Int("rule_count", len(rules)))
+	return nil
+}
+
+// Fees returns the legs charging the fee for a simple transfer to the payer,
+// the transaction's debit account: one leg debiting the payer and one
+// crediting the rule's revenue account. No legs are returned when no rule
+// matches or the fee rounds to zero.
+func (e *Engine) Fees(tx models.Transaction, payer models.Account) ([]models.Posting, error) {
+	log := logger.Get()
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	for _, rule := range e.rules {
+		if !rule.matches(payer.Type, tx.Amount.Currency, tx.Amount.Amount) {
+			continue
+		}
+
+		units := e.currencyValidator.MinorUnits(tx.Amount.Currency)
+		fee := rule.fee(tx.Amount.Amount).Round(units)
+		if !fee.IsPositive() {
+			return nil, nil
+		}
+		if rule.RevenueAccount == payer.ID {
+			return nil, fmt.Errorf("fee rule %s would charge its own revenue account %s", rule.ID, payer.ID)
+		}
+
+		log.Info("Fee rule applied",
+			zap.String("tx_id", tx.ID),
+			zap.String("rule_id", rule.ID),
+			zap.String("fee", fee.String()))
+		return []models.Posting{
+			{Account: payer.ID, Amount: models.Money{Amount: fee.Neg(), Currency: tx.Amount.Currency}},
+			{Account: rule.RevenueAccount, Amount: models.Money{Amount: fee, Currency: tx.Amount.Currency}},
+		}, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,128 @@
+package fees
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"ledgerproject/config"
+	"ledgerproject/logger"
+	"ledgerproject/models"
+	"ledgerproject/services"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// setupTestLogger initializes a test logger
+func setupTestLogger(t *testing.T) *zap.Logger {
+	testLogger := zaptest.NewLogger(t)
+	// Initialize the package-level logger
+	if err := logger.Init(true); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	return testLogger
+}
+
+func setupTest(t *testing.T, cfg *config.Config) (*Engine, error) {
+	setupTestLogger(t)
+
+	validator, err := services.NewCurrencyValidator(&config.Config{
+		CurrencyFile: "../data/iso4217_currency_test.json",
+	})
+	require.NoError(t, err)
+	return NewEngine(validator, cfg)
+}
+
+func transfer(amount, currency string) models.Transaction {
+	return models.Transaction{
+		ID:            "TX1",
+		DebitAccount:  "CLIENT",
+		CreditAccount: "MERCHANT",
+		Amount:        models.Money{Amount: dec(amount), Currency: currency},
+	}
+}
+
+func TestNewEngine(t *testing.T) {
+	t.Run("without rules file", func(t *testing.T) {
+		e, err := setupTest(t, &config.Config{})
+		require.NoError(t, err)
+		assert.Empty(t, e.Rules())
+	})
+
+	t.Run("loads rules file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "fees.json")
+		require.NoError(t, os.WriteFile(path, []byte(`[
+			{"id": "transfer", "kind": "flat", "flat": "1.00", "revenue_account": "FEES"}
+		]`), 0o600))
+
+		e, err := setupTest(t, &config.Config{FeeRulesFile: path})
+		require.NoError(t, err)
+		require.Len(t, e.Rules(), 1)
+		assert.Equal(t, "1", e.Rules()[0].Flat.String())
+	})
+
+	t.Run("rejects invalid rules file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "fees.json")
+		require.NoError(t, os.WriteFile(path, []byte(`[{"id": "transfer", "kind": "flat"}]`), 0o600))
+
+		_, err := setupTest(t, &config.Config{FeeRulesFile: path})
+		assert.Error(t, err)
+	})
+
+	t.Run("missing rules file", func(t *testing.T) {
+		_, err := setupTest(t, &config.Config{FeeRulesFile: "nonexistent/fees.json"})
+		assert.Error(t, err)
+	})
+}
+
+func TestFees(t *testing.T) {
+	e, err := setupTest(t, &config.Config{})
+	require.NoError(t, err)
+
+	require.NoError(t, e.SetRules([]Rule{
+		{ID: "large", AccountType: "asset", MinAmount: ptr("10000"), Kind: KindFlat, Flat: dec("25"), RevenueAccount: "FEES"},
+		{ID: "asset", AccountType: "asset", Kind: KindPercentage, Rate: dec("0.0125"), RevenueAccount: "FEES"},
+		{ID: "yen", Currency: "JPY", Kind: KindPercentage, Rate: dec("0.0125"), RevenueAccount: "FEES_JPY"},
+	}))
+	client := models.Account{ID: "CLIENT", Type: "asset"}
+
+	t.Run("first matching rule applies", func(t *testing.T) {
+		legs, err := e.Fees(transfer("20000", "USD"), client)
+		require.NoError(t, err)
+		require.Len(t, legs, 2)
+		assert.Equal(t, "CLIENT", legs[0].Account)
+		assert.Equal(t, "-25", legs[0].Amount.Amount.String())
+		assert.Equal(t, "FEES", legs[1].Account)
+		assert.Equal(t, "25", legs[1].Amount.Amount.String())
+	})
+
+	t.Run("rounds to currency precision", func(t *testing.T) {
+		legs, err := e.Fees(transfer("10.99", "USD"), client)
+		require.NoError(t, err)
+		assert.Equal(t, "0.14", legs[1].Amount.Amount.String())
+
+		legs, err = e.Fees(transfer("1234", "JPY"), models.Account{ID: "CLIENT", Type: "liability"})
+		require.NoError(t, err)
+		assert.Equal(t, "15", legs[1].Amount.Amount.String())
+		assert.Equal(t, "FEES_JPY", legs[1].Account)
+	})
+
+	t.Run("no matching rule", func(t *testing.T) {
+		legs, err := e.Fees(transfer("100", "USD"), models.Account{ID: "CLIENT", Type: "liability"})
+		require.NoError(t, err)
+		assert.Empty(t, legs)
+	})
+
+	t.Run("fee rounding to zero", func(t *testing.T) {
+		legs, err := e.Fees(transfer("0.10", "USD"), client)
+		require.NoError(t, err)
+		assert.Empty(t, legs)
+	})
+
+	t.Run("duplicate rule ids are rejected", func(t *testing.T) {
+		rule := Rule{ID: "dup", Kind: KindFlat, Flat: dec("1"), RevenueAccount: "FEES"}
+		assert.Error(t, e.SetRules([]Rule{rule, rule}))
+		assert.Len(t, e.Rules(), 3)
+	})
+}
@@ -0,0 +1,139 @@
+package fees
+
+import (
+	"fmt"
+	"github.com/shopspring/decimal"
+	"strings"
+)
+
+// Fee kinds.
+const (
+	KindFlat       = "flat"
+	KindPercentage = "percentage"
+	KindTiered     = "tiered"
+)
+
+// Rule charges a fee on transfers whose debit account type, currency and
+// amount match. Empty AccountType or Currency match anything; the amount band
+// includes MinAmount and excludes MaxAmount. Rates are fractions, so 0.01 is
+// 1%. The fee is credited to RevenueAccount.
+type Rule struct {
+	ID             string           `json:"id"`
+	AccountType    string           `json:"account_type,omitempty"`
+	Currency       string           `json:"currency,omitempty"`
+	MinAmount      *decimal.Decimal `json:"min_amount,omitempty"`
+	MaxAmount      *decimal.Decimal `json:"max_amount,omitempty"`
+	Kind           string           `json:"kind"`
+	Flat           decimal.Decimal  `json:"flat"`
+	Rate           decimal.Decimal  `json:"rate"`
+	Tiers          []Tier           `json:"tiers,omitempty"`
+	MinFee         *decimal.Decimal `json:"min_fee,omitempty"`
+	MaxFee         *decimal.Decimal `json:"max_fee,omitempty"`
+	RevenueAccount string           `json:"revenue_account"`
+}
+
+// Tier is one bracket of a tiered fee. The rate applies to the part of the
+// amount between the previous tier's bound and UpTo; the last tier may leave
+// UpTo empty to cover the rest.
+type Tier struct {
+	UpTo *decimal.Decimal `json:"up_to,omitempty"`
+	Rate decimal.Decimal  `json:"rate"`
+}
+
+func (r Rule) validate() error {
+	if r.ID == "" {
+		return fmt.Errorf("fee rule id is required")
+	}
+	if r.RevenueAccount == "" {
+		return fmt.Errorf("fee rule %s has no revenue account", r.ID)
+	}
+	if r.MinAmount != nil && r.MaxAmount != nil && !r.MinAmount.LessThan(*r.MaxAmount) {
+		return fmt.Errorf("fee rule %s amount band is empty", r.ID)
+	}
+	if r.MinFee != nil && r.MaxFee != nil && r.MinFee.GreaterThan(*r.MaxFee) {
+		return fmt.Errorf("fee rule %s minimum fee exceeds maximum fee", r.ID)
+	}
+
+	switch r.Kind {
+	case KindFlat:
+		if r.Flat.IsNegative() {
+			return fmt.Errorf("fee rule %s flat fee must not be negative", r.ID)
+		}
+	case KindPercentage:
+		if r.Rate.IsNegative() {
+			return fmt.Errorf("fee rule %s rate must not be negative", r.ID)
+		}
+	case KindTiered:
+		if len(r.Tiers) == 0 {
+			return fmt.Errorf("fee rule %s has no tiers", r.ID)
+		}
+		previous := decimal.Zero
+		for i, tier := range r.Tiers {
+			if tier.Rate.IsNegative() {
+				return fmt.Errorf("fee rule %s tier %d rate must not be negative", r.ID, i+1)
+			}
+			if tier.UpTo == nil {
+				if i != len(r.Tiers)-1 {
+					return fmt.Errorf("fee rule %s only the last tier may be unbounded", r.ID)
+				}
+				continue
+			}
+			if !tier.UpTo.GreaterThan(previous) {
+				return fmt.Errorf("fee rule %s tiers must be in ascending order", r.ID)
+			}
+			previous = *tier.UpTo
+		}
+	default:
+		return fmt.Errorf("fee rule %s has unsupported kind %q", r.ID, r.Kind)
+	}
+	return nil
+}
+
+func (r Rule) matches(accountType, currency string, amount decimal.Decimal) bool {
+	if r.AccountType != "" && !strings.EqualFold(r.AccountType, accountType) {
+		return false
+	}
+	if r.Currency != "" && r.Currency != currency {
+		return false
+	}
+	if r.MinAmount != nil && amount.LessThan(*r.MinAmount) {
+		return false
+	}
+	if r.MaxAmount != nil && !amount.LessThan(*r.MaxAmount) {
+		return false
+	}
+	return true
+}
+
+// fee computes the unrounded fee on the amount, clamped to the rule's
+// minimum and maximum.
+func (r Rule) fee(amount decimal.Decimal) decimal.Decimal {
+	var fee decimal.Decimal
+	switch r.Kind {
+	case KindFlat:
+		fee = r.Flat
+	case KindPercentage:
+		fee = amount.Mul(r.Rate)
+	case KindTiered:
+		lower := decimal.Zero
+		for _, tier := range r.Tiers {
+			upper := amount
+			if tier.UpTo != nil && tier.UpTo.LessThan(amount) {
+				upper = *tier.UpTo
+			}
+			if !upper.GreaterThan(lower) {
+				break
+			}
+			fee = fee.Add(upper.Sub(lower).Mul(tier.Rate))
+			lower = upper
+		}
+	}
+
+	if r.MinFee != nil && fee.LessThan(*r.MinFee) {
+		fee = *r.MinFee
+	}
+	if r.MaxFee != nil && fee.GreaterThan(*r.MaxFee) {
+		fee = *r.MaxFee
+	}
+	return fee
+}
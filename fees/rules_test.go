@@ -0,0 +1,110 @@
+package fees
+
+import (
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+func ptr(s string) *decimal.Decimal {
+	d := dec(s)
+	return &d
+}
+
+func TestRuleFee(t *testing.T) {
+	tiered := []Tier{
+		{UpTo: ptr("1000"), Rate: dec("0.01")},
+		{UpTo: ptr("10000"), Rate: dec("0.005")},
+		{Rate: dec("0.001")},
+	}
+
+	tests := []struct {
+		name   string
+		rule   Rule
+		amount string
+		want   string
+	}{
+		{"flat", Rule{Kind: KindFlat, Flat: dec("2.50")}, "100", "2.5"},
+		{"percentage", Rule{Kind: KindPercentage, Rate: dec("0.015")}, "200", "3"},
+		{"percentage below minimum", Rule{Kind: KindPercentage, Rate: dec("0.015"), MinFee: ptr("5")}, "200", "5"},
+		{"percentage above maximum", Rule{Kind: KindPercentage, Rate: dec("0.015"), MaxFee: ptr("10")}, "1000", "10"},
+		{"tiered within first tier", Rule{Kind: KindTiered, Tiers: tiered}, "500", "5"},
+		{"tiered across tiers", Rule{Kind: KindTiered, Tiers: tiered}, "5000", "30"},
+		{"tiered into unbounded tier", Rule{Kind: KindTiered, Tiers: tiered}, "20000", "65"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.rule.fee(dec(tt.amount)).String())
+		})
+	}
+}
+
+func TestRuleMatches(t *testing.T) {
+	rule := Rule{AccountType: "asset", Currency: "USD", MinAmount: ptr("100"), MaxAmount: ptr("1000")}
+
+	tests := []struct {
+		name        string
+		accountType string
+		currency    string
+		amount      string
+		want        bool
+	}{
+		{"inside band", "asset", "USD", "500", true},
+		{"account type is case insensitive", "Asset", "USD", "500", true},
+		{"lower bound inclusive", "asset", "USD", "100", true},
+		{"upper bound exclusive", "asset", "USD", "1000", false},
+		{"other account type", "liability", "USD", "500", false},
+		{"other currency", "asset", "EUR", "500", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rule.matches(tt.accountType, tt.currency, dec(tt.amount)))
+		})
+	}
+
+	assert.True(t, Rule{}.matches("anything", "EUR", dec("1")))
+}
+
+func TestRuleValidate(t *testing.T) {
+	valid := Rule{ID: "R1", Kind: KindFlat, Flat: dec("1"), RevenueAccount: "FEES"}
+
+	tests := []struct {
+		name    string
+		modify  func(r *Rule)
+		wantErr bool
+	}{
+		{"valid", func(r *Rule) {}, false},
+		{"missing id", func(r *Rule) { r.ID = "" }, true},
+		{"missing revenue account", func(r *Rule) { r.RevenueAccount = "" }, true},
+		{"unknown kind", func(r *Rule) { r.Kind = "bonus" }, true},
+		{"negative flat fee", func(r *Rule) { r.Flat = dec("-1") }, true},
+		{"empty band", func(r *Rule) { r.MinAmount, r.MaxAmount = ptr("10"), ptr("10") }, true},
+		{"min fee above max fee", func(r *Rule) { r.MinFee, r.MaxFee = ptr("5"), ptr("1") }, true},
+		{"tiered without tiers", func(r *Rule) { r.Kind = KindTiered }, true},
+		{"unbounded tier not last", func(r *Rule) {
+			r.Kind, r.Tiers = KindTiered, []Tier{{Rate: dec("0.01")}, {UpTo: ptr("10"), Rate: dec("0.01")}}
+		}, true},
+		{"tiers out of order", func(r *Rule) {
+			r.Kind, r.Tiers = KindTiered, []Tier{{UpTo: ptr("10"), Rate: dec("0.01")}, {UpTo: ptr("5"), Rate: dec("0.01")}}
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := valid
+			tt.modify(&rule)
+			err := rule.validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package grpcapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"ledgerproject/grpcapi/ledgerpb"
+	"ledgerproject/models"
+	"time"
+)
+
+func moneyFromProto(m *ledgerpb.Money) (models.Money, error) {
+	if m == nil {
+		return models.Money{}, nil
+	}
+	amount, err := decimal.NewFromString(m.Amount)
+	if err != nil {
+		return models.Money{}, fmt.Errorf("invalid amount %q", m.Amount)
+	}
+	if m.Currency == "" {
+		return models.Money{}, fmt.Errorf("currency is required and cannot be empty")
+	}
+	return models.Money{Amount: amount, Currency: m.Currency}, nil
+}
+
+func moneyToProto(m models.Money) *ledgerpb.Money {
+	return &ledgerpb.Money{Amount: m.Amount.String(), Currency: m.Currency}
+}
+
+func accountFromProto(a *ledgerpb.Account) (models.Account, error) {
+	if a == nil {
+		return models.Account{}, fmt.Errorf("account is required")
+	}
+	balance, err := moneyFromProto(a.Balance)
+	if err != nil {
+		return models.Account{}, fmt.Errorf("balance: %v", err)
+	}
+	return models.Account{
+		ID:             a.Id,
+		Name:           a.Name,
+		Balance:        balance,
+		Type:           a.Type,
+		Currency:       a.Currency,
+		CreateDateTime: timeFromProto(a.CreateTime),
+		Metadata:       a.Metadata,
+		Tags:           a.Tags,
+	}, nil
+}
+
+func accountToProto(a models.Account) *ledgerpb.Account {
+	return &ledgerpb.Account{
+		Id:         a.ID,
+		Name:       a.Name,
+		Type:       a.Type,
+		Currency:   a.Currency,
+		Balance:    moneyToProto(a.Balance),
+		CreateTime: timeToProto(a.CreateDateTime),
+		Frozen:     a.Frozen,
+		Metadata:   a.Metadata,
+		Tags:       a.Tags,
+	}
+}
+
+func transactionFromProto(t *ledgerpb.Transaction) (models.Transaction, error) {
+	if t == nil {
+		return models.Transaction{}, fmt.Errorf("transaction is required")
+	}
+	tx := models.Transaction{
+		ID:            t.Id,
+		DateTime:      timeFromProto(t.Time),
+		Description:   t.Description,
+		DebitAccount:  t.DebitAccount,
+		CreditAccount: t.CreditAccount,
+		Metadata:      t.Metadata,
+		Tags:          t.Tags,
+	}
+	var err error
+	if t.Amount != nil {
+		if tx.Amount, err = moneyFromProto(t.Amount); err != nil {
+			return models.Transaction{}, fmt.Errorf("amount: %v", err)
+		}
+	}
+	for i, p := range t.Postings {
+		amount, err := moneyFromProto(p.Amount)
+		if err != nil {
+			return models.Transaction{}, fmt.Errorf("posting %d: %v", i+1, err)
+		}
+		tx.Postings = append(tx.Postings, models.Posting{Account: p.Account, Amount: amount})
+	}
+	return tx, nil
+}
+
+func transactionToProto(tx models.Transaction) *ledgerpb.Transaction {
+	t := &ledgerpb.Transaction{
+		Id:            tx.ID,
+		Sequence:      tx.Sequence,
+		Time:          timeToProto(tx.DateTime),
+		Description:   tx.Description,
+		DebitAccount:  tx.DebitAccount,
+		CreditAccount: tx.CreditAccount,
+		Metadata:      tx.Metadata,
+		Tags:          tx.Tags,
+	}
+	if len(tx.Postings) == 0 {
+		t.Amount = moneyToProto(tx.Amount)
+	}
+	for _, p := range tx.Postings {
+		t.Postings = append(t.Postings, &ledgerpb.Posting{Account: p.Account, Amount: moneyToProto(p.Amount)})
+	}
+	return t
+}
+
+// eventToProto converts an event. The data keeps the JSON shape of the
+// HTTP event stream.
+func eventToProto(e models.Event) (*ledgerpb.Event, error) {
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding event data: %v", err)
+	}
+	value := &structpb.Value{}
+	if err := protojson.Unmarshal(data, value); err != nil {
+		return nil, fmt.Errorf("error converting event data: %v", err)
+	}
+	return &ledgerpb.Event{
+		Sequence: e.Sequence,
+		Id:       e.ID,
+		Type:     e.Type,
+		Time:     timeToProto(e.Time),
+		Accounts: e.Accounts,
+		Data:     value,
+	}, nil
+}
+
+// timeFromProto leaves unset times zero, for the ledger to fill in.
+func timeFromProto(t *timestamppb.Timestamp) time.Time {
+	if t == nil {
+		return time.Time{}
+	}
+	return t.AsTime()
+}
+
+func timeToProto(t time.Time) *timestamppb.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	return timestamppb.New(t)
+}
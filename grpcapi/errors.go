@@ -0,0 +1,60 @@
+package grpcapi
+
+import (
+	"errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"ledgerproject/ledger"
+)
+
+// errorDomain identifies the ledger in the ErrorInfo of failed calls.
+const errorDomain = "ledger"
+
+// ledgerCodes maps each kind of ledger error to its status code. Reasons
+// are the codes of the matching HTTP problems.
+var ledgerCodes = []struct {
+	kind   error
+	code   codes.Code
+	reason string
+}{
+	{ledger.ErrAccountNotFound, codes.NotFound, "account_not_found"},
+	{ledger.ErrDuplicate, codes.AlreadyExists, "duplicate"},
+	{ledger.ErrAccountFrozen, codes.FailedPrecondition, "account_frozen"},
+	{ledger.ErrAccountNotFrozen, codes.FailedPrecondition, "account_not_frozen"},
+	{ledger.ErrInvalidCurrency, codes.InvalidArgument, "invalid_currency"},
+	{ledger.ErrCurrencyMismatch, codes.InvalidArgument, "currency_mismatch"},
+	{ledger.ErrInsufficientFunds, codes.FailedPrecondition, "insufficient_funds"},
+	{ledger.ErrUnbalanced, codes.InvalidArgument, "unbalanced"},
+	{ledger.ErrInvalid, codes.InvalidArgument, "invalid"},
+}
+
+// ledgerStatus returns the status of a failed ledger operation, with an
+// ErrorInfo naming the account, transaction and currency involved. Errors
+// of unknown kind get the fallback code.
+func ledgerStatus(err error, fallback codes.Code) error {
+	for _, lc := range ledgerCodes {
+		if !errors.Is(err, lc.kind) {
+			continue
+		}
+		info := &errdetails.ErrorInfo{Reason: lc.reason, Domain: errorDomain, Metadata: map[string]string{}}
+		var ledgerErr *ledger.Error
+		if errors.As(err, &ledgerErr) {
+			for key, value := range map[string]string{
+				"account_id":     ledgerErr.AccountID,
+				"transaction_id": ledgerErr.TransactionID,
+				"currency":       ledgerErr.Currency,
+			} {
+				if value != "" {
+					info.Metadata[key] = value
+				}
+			}
+		}
+		st, detailErr := status.New(lc.code, err.Error()).WithDetails(info)
+		if detailErr != nil {
+			return status.Error(lc.code, err.Error())
+		}
+		return st.Err()
+	}
+	return status.Error(fallback, err.Error())
+}
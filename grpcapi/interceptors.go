@@ -0,0 +1,242 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"ledgerproject/audit"
+	"ledgerproject/auth"
+	"ledgerproject/grpcapi/ledgerpb"
+	"ledgerproject/ledger"
+	"ledgerproject/logger"
+	"ledgerproject/tenants"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Metadata keys read from calls, matching the HTTP headers of the same
+// names. gRPC metadata keys are lowercase.
+const (
+	requestIDKey     = "x-request-id"
+	apiKeyKey        = "x-api-key"
+	authorizationKey = "authorization"
+	tenantKey        = "x-tenant-id"
+)
+
+// validRequestID bounds the IDs accepted from clients, as the HTTP API does.
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// permissions are required by each method. Methods missing here are refused.
+var permissions = map[string]auth.Permission{
+	ledgerpb.LedgerService_CreateAccount_FullMethodName:            auth.PermAccountsManage,
+	ledgerpb.LedgerService_RecordTransaction_FullMethodName:        auth.PermTransactionsPost,
+	ledgerpb.LedgerService_GetBalance_FullMethodName:               auth.PermAccountsRead,
+	ledgerpb.LedgerService_GetTransactionHistory_FullMethodName:    auth.PermAccountsRead,
+	ledgerpb.LedgerService_StreamTransactionHistory_FullMethodName: auth.PermAccountsRead,
+	ledgerpb.LedgerService_StreamEvents_FullMethodName:             auth.PermEventsRead,
+}
+
+type callKey struct{}
+
+// call is what the interceptors learn about a call: its request ID and the
+// tenant whose book it addresses.
+type call struct {
+	requestID string
+	ledger    ledger.LedgerService
+	audit     *audit.Log
+}
+
+func callFrom(ctx context.Context) *call {
+	c, _ := ctx.Value(callKey{}).(*call)
+	return c
+}
+
+// logUnary gives the call a request ID and a context logger carrying it,
+// and logs the outcome of the call.
+func (s *Server) logUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, start := s.startCall(ctx, info.FullMethod)
+	resp, err := handler(ctx, req)
+	logCall(ctx, start, err)
+	return resp, err
+}
+
+// logStream is logUnary for streaming calls.
+func (s *Server) logStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	ctx, start := s.startCall(ss.Context(), info.FullMethod)
+	err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	logCall(ctx, start, err)
+	return err
+}
+
+func (s *Server) startCall(ctx context.Context, method string) (context.Context, time.Time) {
+	id := firstValue(ctx, requestIDKey)
+	if !validRequestID.MatchString(id) {
+		id = newRequestID()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDKey, id))
+
+	ctx = context.WithValue(ctx, callKey{}, &call{requestID: id})
+	log := logger.FromContext(ctx).With(zap.String("request_id", id), zap.String("grpc_method", method))
+	return logger.WithContext(ctx, log), time.Now()
+}
+
+func logCall(ctx context.Context, start time.Time, err error) {
+	code := status.Code(err)
+	fields := []zap.Field{zap.String("grpc_code", code.String()), zap.Duration("duration", time.Since(start))}
+	if err != nil && code != codes.Canceled {
+		logger.FromContext(ctx).Warn("gRPC call failed", append(fields, zap.Error(err))...)
+		return
+	}
+	logger.FromContext(ctx).Info("gRPC call served", fields...)
+}
+
+// authenticateUnary rejects calls without valid credentials or the
+// permission of their method, and resolves the tenant they address.
+func (s *Server) authenticateUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, err := s.authenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+// authenticateStream is authenticateUnary for streaming calls.
+func (s *Server) authenticateStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	ctx, err := s.authenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+}
+
+// authenticate reads credentials from the metadata like the HTTP API reads
+// them from headers. Calls without any are authenticated by the client
+// certificate verified in the TLS handshake, if any.
+func (s *Server) authenticate(ctx context.Context, method string) (context.Context, error) {
+	log := logger.FromContext(ctx)
+	var principal auth.Principal
+	var err error
+	apiKey, authorization := firstValue(ctx, apiKeyKey), firstValue(ctx, authorizationKey)
+	if cert := peerCertificate(ctx); apiKey == "" && authorization == "" && cert != nil {
+		principal, err = s.auth.AuthenticateCertificate(cert)
+	} else {
+		principal, err = s.auth.AuthenticateCredentials(apiKey, authorization)
+	}
+	if err != nil {
+		log.Warn("Call authentication failed", zap.Error(err), zap.String("peer", peerAddr(ctx)))
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+	log = log.With(zap.String("principal", principal.ID), zap.String("auth_method", principal.Method))
+
+	permission, known := permissions[method]
+	if !known || !principal.Can(permission) {
+		log.Warn("Call not permitted", zap.String("role", principal.Role), zap.String("permission", string(permission)))
+		return nil, status.Error(codes.PermissionDenied, "permission denied")
+	}
+
+	tenant, err := tenantID(principal, firstValue(ctx, tenantKey))
+	if err != nil {
+		log.Warn("Tenant access denied", zap.Error(err))
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	c := callFrom(ctx)
+	if c.ledger, c.audit, err = s.book(tenant); err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+
+	ctx = auth.WithPrincipal(ctx, principal)
+	return logger.WithContext(ctx, log.With(zap.String("tenant", tenant))), nil
+}
+
+// tenantID resolves the tenant of a call like the HTTP API resolves the
+// tenant of a request: the one named in the metadata, or else the
+// principal's own.
+func tenantID(principal auth.Principal, id string) (string, error) {
+	switch {
+	case id == "" && principal.Tenant == "":
+		return tenants.DefaultID, nil
+	case id == "":
+		return principal.Tenant, nil
+	case principal.Tenant != "" && principal.Tenant != id:
+		return "", fmt.Errorf("access to tenant %s denied", id)
+	case principal.Tenant == "" && id != tenants.DefaultID && !principal.Can(auth.PermTenantsManage):
+		return "", fmt.Errorf("access to tenant %s denied", id)
+	}
+	return id, nil
+}
+
+// book returns the ledger and audit trail of a tenant.
+func (s *Server) book(tenant string) (ledger.LedgerService, *audit.Log, error) {
+	if s.tenants == nil {
+		if tenant != tenants.DefaultID {
+			return nil, nil, fmt.Errorf("%w: %s", tenants.ErrNotFound, tenant)
+		}
+		return s.ledger, s.audit, nil
+	}
+	t, err := s.tenants.Get(tenant)
+	if err != nil {
+		return nil, nil, err
+	}
+	return t.Ledger, t.Audit, nil
+}
+
+// contextStream is a server stream with a context of the interceptors'
+// making.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func firstValue(ctx context.Context, key string) string {
+	if values := metadata.ValueFromIncomingContext(ctx, key); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
+
+func peerAddr(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr.String()
+	}
+	return ""
+}
+
+// peerCertificate returns the client certificate verified in the TLS
+// handshake of the call's connection, or nil.
+func peerCertificate(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 {
+		return nil
+	}
+	return info.State.VerifiedChains[0][0]
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("req-%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,110 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/fx"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"ledgerproject/audit"
+	"ledgerproject/auth"
+	"ledgerproject/config"
+	"ledgerproject/grpcapi/ledgerpb"
+	"ledgerproject/ledger"
+	"ledgerproject/tenants"
+	"ledgerproject/tlsconfig"
+	"net"
+)
+
+// Server serves the gRPC API. It shares the ledger, credentials, tenants and
+// audit trail of the HTTP API.
+type Server struct {
+	ledgerpb.UnimplementedLedgerServiceServer
+
+	ledger  ledger.LedgerService
+	auth    *auth.Authenticator
+	audit   *audit.Log
+	tenants *tenants.Registry
+	config  *config.Config
+	server  *grpc.Server
+}
+
+// ServerParams lists the dependencies fx injects into NewServer.
+type ServerParams struct {
+	fx.In
+
+	Ledger  ledger.LedgerService
+	Config  *config.Config
+	Auth    *auth.Authenticator
+	Audit   *audit.Log
+	Tenants *tenants.Registry
+	TLS     *tlsconfig.Reloader
+}
+
+func NewServer(p ServerParams) *Server {
+	s := &Server{
+		ledger:  p.Ledger,
+		auth:    p.Auth,
+		audit:   p.Audit,
+		tenants: p.Tenants,
+		config:  p.Config,
+	}
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(s.logUnary, s.authenticateUnary),
+		grpc.ChainStreamInterceptor(s.logStream, s.authenticateStream),
+	}
+	// The gRPC API is served with the same certificate and client CAs as the
+	// HTTP API
+	if p.TLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(p.TLS.ServerConfig())))
+	}
+	s.server = grpc.NewServer(opts...)
+	ledgerpb.RegisterLedgerServiceServer(s.server, s)
+	return s
+}
+
+// Enabled reports whether the configuration gives the gRPC API a port.
+func (s *Server) Enabled() bool {
+	return s.config.GRPCPort != ""
+}
+
+// Start serves the gRPC API on the configured port until Shutdown.
+func (s *Server) Start() error {
+	lis, err := s.Listen()
+	if err != nil {
+		return err
+	}
+	return s.Serve(lis)
+}
+
+// Listen binds the configured gRPC port.
+func (s *Server) Listen() (net.Listener, error) {
+	lis, err := net.Listen("tcp", s.config.GRPCPort)
+	if err != nil {
+		return nil, fmt.Errorf("error listening on %s: %v", s.config.GRPCPort, err)
+	}
+	return lis, nil
+}
+
+// Serve serves the gRPC API on lis until Shutdown.
+func (s *Server) Serve(lis net.Listener) error {
+	return s.server.Serve(lis)
+}
+
+// Shutdown stops accepting calls and waits for the running ones, including
+// open streams, until ctx ends; the calls still running then are cancelled.
+func (s *Server) Shutdown(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
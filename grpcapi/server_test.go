@@ -0,0 +1,378 @@
+package grpcapi
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"io"
+	"ledgerproject/audit"
+	"ledgerproject/auth"
+	"ledgerproject/config"
+	"ledgerproject/grpcapi/ledgerpb"
+	"ledgerproject/ledger"
+	"ledgerproject/logger"
+	"ledgerproject/models"
+	"ledgerproject/services"
+	"ledgerproject/tlsconfig"
+	"ledgerproject/tlsconfig/tlstest"
+	"net"
+	"testing"
+	"time"
+)
+
+func keyHash(key string) string {
+	digest := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(digest[:])
+}
+
+// setupServer serves a fresh ledger over an in-memory connection and
+// returns a client of it, along with the audit trail.
+func setupServer(t *testing.T) (ledgerpb.LedgerServiceClient, *audit.Log) {
+	server, trail := newServer(t, nil)
+	lis := bufconn.Listen(1 << 20)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return ledgerpb.NewLedgerServiceClient(conn), trail
+}
+
+// newServer returns a server over a fresh ledger, with the given TLS
+// configuration, if any. The client certificate "payments" is an admin.
+func newServer(t *testing.T, reloader *tlsconfig.Reloader) (*Server, *audit.Log) {
+	require.NoError(t, logger.Init(true))
+	validator, err := services.NewCurrencyValidator(&config.Config{
+		CurrencyFile: "../data/iso4217_currency_test.json",
+	})
+	require.NoError(t, err)
+	authenticator, err := auth.NewAuthenticator(&config.Config{
+		APIKeys: []config.APIKey{
+			{Principal: "tester", KeyHash: keyHash("admin-key"), Role: auth.RoleAdmin},
+			{Principal: "support", KeyHash: keyHash("auditor-key"), Role: auth.RoleAuditor},
+			{Principal: "merchant", KeyHash: keyHash("client-key"), Role: auth.RoleClient, Accounts: []string{"ACC001"}},
+			{Principal: "retail-admin", KeyHash: keyHash("retail-key"), Role: auth.RoleAdmin, Tenant: "retail"},
+		},
+		ClientCertificates: []config.ClientCertificate{{CommonName: "payments", Role: auth.RoleAdmin}},
+	})
+	require.NoError(t, err)
+
+	trail := audit.NewMemoryLog()
+	server := NewServer(ServerParams{
+		Ledger: ledger.NewDetachedLedger(validator),
+		Config: &config.Config{},
+		Auth:   authenticator,
+		Audit:  trail,
+		TLS:    reloader,
+	})
+	return server, trail
+}
+
+func TestTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := tlstest.NewCA(t, dir, "ca")
+	_, certFile, keyFile := ca.Issue(t, dir, "localhost")
+	_, clientCert, clientKey := ca.Issue(t, dir, "payments")
+	_, strangerCert, strangerKey := ca.Issue(t, dir, "stranger")
+	reloader, err := tlsconfig.New(&config.Config{TLS: config.TLS{
+		CertFile: certFile, KeyFile: keyFile, ClientCAFile: ca.CertFile, ReloadInterval: time.Minute,
+	}})
+	require.NoError(t, err)
+
+	server, _ := newServer(t, reloader)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = server.Serve(lis) }()
+	t.Cleanup(server.server.Stop)
+
+	dial := func(t *testing.T, creds credentials.TransportCredentials) ledgerpb.LedgerServiceClient {
+		conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(creds))
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return ledgerpb.NewLedgerServiceClient(conn)
+	}
+	withCert := func(t *testing.T, certFile, keyFile string) credentials.TransportCredentials {
+		tlsConfig := &tls.Config{RootCAs: x509.NewCertPool()}
+		tlsConfig.RootCAs.AddCert(ca.Cert)
+		if certFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			require.NoError(t, err)
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+		return credentials.NewTLS(tlsConfig)
+	}
+
+	tests := []struct {
+		name     string
+		creds    func(t *testing.T) credentials.TransportCredentials
+		ctx      context.Context
+		wantCode codes.Code
+	}{
+		{"client certificate", func(t *testing.T) credentials.TransportCredentials { return withCert(t, clientCert, clientKey) },
+			context.Background(), codes.OK},
+		{"unknown client certificate", func(t *testing.T) credentials.TransportCredentials { return withCert(t, strangerCert, strangerKey) },
+			context.Background(), codes.Unauthenticated},
+		{"api key over TLS", func(t *testing.T) credentials.TransportCredentials { return withCert(t, "", "") },
+			withKey("admin-key"), codes.OK},
+		{"no credentials", func(t *testing.T) credentials.TransportCredentials { return withCert(t, "", "") },
+			context.Background(), codes.Unauthenticated},
+		{"plaintext", func(t *testing.T) credentials.TransportCredentials { return insecure.NewCredentials() },
+			withKey("admin-key"), codes.Unavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(tt.ctx, 5*time.Second)
+			defer cancel()
+			_, err := dial(t, tt.creds(t)).CreateAccount(ctx, &ledgerpb.CreateAccountRequest{
+				Account: &ledgerpb.Account{Id: tt.name, Currency: "USD"},
+			})
+			assert.Equal(t, tt.wantCode, status.Code(err), "%v", err)
+		})
+	}
+}
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+}
+
+func createAccounts(t *testing.T, client ledgerpb.LedgerServiceClient) {
+	ctx := withKey("admin-key")
+	for _, account := range []*ledgerpb.Account{
+		{Id: "ACC001", Name: "Cash", Type: "asset", Currency: "USD", Balance: &ledgerpb.Money{Amount: "100", Currency: "USD"}},
+		{Id: "ACC002", Name: "Revenue", Type: "revenue", Currency: "USD"},
+	} {
+		_, err := client.CreateAccount(ctx, &ledgerpb.CreateAccountRequest{Account: account})
+		require.NoError(t, err)
+	}
+}
+
+func transfer(id, amount string) *ledgerpb.RecordTransactionRequest {
+	return &ledgerpb.RecordTransactionRequest{Transaction: &ledgerpb.Transaction{
+		Id:            id,
+		DebitAccount:  "ACC001",
+		CreditAccount: "ACC002",
+		Amount:        &ledgerpb.Money{Amount: amount, Currency: "USD"},
+	}}
+}
+
+func TestAuthentication(t *testing.T) {
+	client, _ := setupServer(t)
+	createAccounts(t, client)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		account  string
+		wantCode codes.Code
+	}{
+		{"no credentials", context.Background(), "ACC001", codes.Unauthenticated},
+		{"unknown key", withKey("wrong-key"), "ACC001", codes.Unauthenticated},
+		{"api key", withKey("admin-key"), "ACC001", codes.OK},
+		{"bearer api key", metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer admin-key"), "ACC001", codes.OK},
+		{"own account", withKey("client-key"), "ACC001", codes.OK},
+		{"account of another client", withKey("client-key"), "ACC002", codes.PermissionDenied},
+		{"tenant-bound principal naming another tenant",
+			metadata.AppendToOutgoingContext(withKey("retail-key"), "x-tenant-id", "default"), "ACC001", codes.PermissionDenied},
+		{"unknown tenant", metadata.AppendToOutgoingContext(withKey("admin-key"), "x-tenant-id", "wholesale"), "ACC001", codes.NotFound},
+		{"client naming another tenant",
+			metadata.AppendToOutgoingContext(withKey("client-key"), "x-tenant-id", "retail"), "ACC001", codes.PermissionDenied},
+		{"client naming the default tenant",
+			metadata.AppendToOutgoingContext(withKey("client-key"), "x-tenant-id", "default"), "ACC001", codes.OK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.GetBalance(tt.ctx, &ledgerpb.GetBalanceRequest{AccountId: tt.account})
+			assert.Equal(t, tt.wantCode, status.Code(err), "%v", err)
+		})
+	}
+
+	t.Run("role without the permission", func(t *testing.T) {
+		_, err := client.CreateAccount(withKey("auditor-key"), &ledgerpb.CreateAccountRequest{
+			Account: &ledgerpb.Account{Id: "ACC003", Currency: "USD"},
+		})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
+
+func TestLedgerCalls(t *testing.T) {
+	client, trail := setupServer(t)
+	createAccounts(t, client)
+	ctx := metadata.AppendToOutgoingContext(withKey("admin-key"), "x-request-id", "grpc-42")
+
+	var header metadata.MD
+	recorded, err := client.RecordTransaction(ctx, transfer("TX001", "40"), grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, "TX001", recorded.Id)
+	assert.Equal(t, []string{"grpc-42"}, header.Get("x-request-id"))
+
+	balance, err := client.GetBalance(ctx, &ledgerpb.GetBalanceRequest{AccountId: "ACC001"})
+	require.NoError(t, err)
+	assert.Equal(t, "60", balance.Amount)
+	assert.Equal(t, "USD", balance.Currency)
+
+	history, err := client.GetTransactionHistory(ctx, &ledgerpb.GetTransactionHistoryRequest{AccountId: "ACC002"})
+	require.NoError(t, err)
+	require.Len(t, history.Transactions, 1)
+	assert.Equal(t, "TX001", history.Transactions[0].Id)
+	assert.NotZero(t, history.Transactions[0].Sequence)
+	assert.Equal(t, "40", history.Transactions[0].Amount.Amount)
+
+	_, err = client.RecordTransaction(ctx, transfer("TX002", "10"))
+	require.NoError(t, err)
+	stream, err := client.StreamTransactionHistory(ctx, &ledgerpb.GetTransactionHistoryRequest{AccountId: "ACC001"})
+	require.NoError(t, err)
+	var ids []string
+	for {
+		tx, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		ids = append(ids, tx.Id)
+	}
+	assert.Equal(t, []string{"TX001", "TX002"}, ids)
+
+	entries := trail.Query(audit.Filter{Action: actionTransactionRecord})
+	require.Len(t, entries, 2)
+	assert.Equal(t, "tester", entries[0].Actor)
+	assert.Equal(t, "grpc-42", entries[0].RequestID)
+}
+
+func TestLedgerErrors(t *testing.T) {
+	client, _ := setupServer(t)
+	createAccounts(t, client)
+	ctx := withKey("admin-key")
+
+	tests := []struct {
+		name       string
+		call       func() error
+		wantCode   codes.Code
+		wantReason string
+		wantMeta   map[string]string
+	}{
+		{
+			name: "insufficient funds",
+			call: func() error {
+				_, err := client.RecordTransaction(ctx, transfer("TX001", "500"))
+				return err
+			},
+			wantCode:   codes.FailedPrecondition,
+			wantReason: "insufficient_funds",
+			wantMeta:   map[string]string{"account_id": "ACC001", "transaction_id": "TX001", "currency": "USD"},
+		},
+		{
+			name: "unknown account",
+			call: func() error {
+				_, err := client.GetBalance(ctx, &ledgerpb.GetBalanceRequest{AccountId: "NOPE"})
+				return err
+			},
+			wantCode:   codes.NotFound,
+			wantReason: "account_not_found",
+			wantMeta:   map[string]string{"account_id": "NOPE"},
+		},
+		{
+			name: "duplicate account",
+			call: func() error {
+				_, err := client.CreateAccount(ctx, &ledgerpb.CreateAccountRequest{
+					Account: &ledgerpb.Account{Id: "ACC001", Currency: "USD"},
+				})
+				return err
+			},
+			wantCode:   codes.AlreadyExists,
+			wantReason: "duplicate",
+			wantMeta:   map[string]string{"account_id": "ACC001"},
+		},
+		{
+			name: "malformed amount",
+			call: func() error {
+				_, err := client.RecordTransaction(ctx, transfer("TX002", "ten"))
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+		},
+		{
+			name: "missing transaction",
+			call: func() error {
+				_, err := client.RecordTransaction(ctx, &ledgerpb.RecordTransactionRequest{})
+				return err
+			},
+			wantCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := status.Convert(tt.call())
+			assert.Equal(t, tt.wantCode, st.Code(), st.Message())
+			if tt.wantReason == "" {
+				return
+			}
+			require.Len(t, st.Details(), 1)
+			info, ok := st.Details()[0].(*errdetails.ErrorInfo)
+			require.True(t, ok)
+			assert.Equal(t, tt.wantReason, info.Reason)
+			assert.Equal(t, errorDomain, info.Domain)
+			assert.Equal(t, tt.wantMeta, info.Metadata)
+		})
+	}
+}
+
+func TestStreamEvents(t *testing.T) {
+	client, _ := setupServer(t)
+	createAccounts(t, client)
+
+	ctx, cancel := context.WithTimeout(withKey("admin-key"), 5*time.Second)
+	defer cancel()
+	after := uint64(0)
+	stream, err := client.StreamEvents(ctx, &ledgerpb.StreamEventsRequest{After: &after})
+	require.NoError(t, err)
+
+	// Events already in the log are replayed
+	for _, want := range []string{"ACC001", "ACC002"} {
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, models.EventAccountCreated, event.Type)
+		assert.Equal(t, []string{want}, event.Accounts)
+		assert.Equal(t, want, event.Data.GetStructValue().Fields["id"].GetStringValue())
+	}
+
+	// New events follow as they are committed. The cursor is explicit since
+	// the call may reach the server after the transaction is recorded.
+	seen := uint64(2)
+	filtered, err := client.StreamEvents(ctx, &ledgerpb.StreamEventsRequest{
+		After: &seen,
+		Types: []string{models.EventTransactionRecorded},
+	})
+	require.NoError(t, err)
+	_, err = client.RecordTransaction(withKey("admin-key"), transfer("TX001", "25"))
+	require.NoError(t, err)
+
+	for _, s := range []ledgerpb.LedgerService_StreamEventsClient{stream, filtered} {
+		event, err := s.Recv()
+		require.NoError(t, err)
+		assert.Equal(t, models.EventTransactionRecorded, event.Type)
+		assert.ElementsMatch(t, []string{"ACC001", "ACC002"}, event.Accounts)
+	}
+
+	t.Run("unknown event type", func(t *testing.T) {
+		stream, err := client.StreamEvents(ctx, &ledgerpb.StreamEventsRequest{Types: []string{"account.deleted"}})
+		require.NoError(t, err)
+		_, err = stream.Recv()
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	})
+}
@@ -0,0 +1,206 @@
+package grpcapi
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"ledgerproject/audit"
+	"ledgerproject/auth"
+	"ledgerproject/grpcapi/ledgerpb"
+	"ledgerproject/logger"
+	"ledgerproject/models"
+)
+
+// Audit actions, shared with the HTTP API so that the trail reads the same
+// whichever API made a change.
+const (
+	actionAccountCreate     = "account.create"
+	actionTransactionRecord = "transaction.record"
+)
+
+// eventBatch is the number of events read from the log at a time.
+const eventBatch = 500
+
+func (s *Server) CreateAccount(ctx context.Context, req *ledgerpb.CreateAccountRequest) (*ledgerpb.Account, error) {
+	log := logger.FromContext(ctx)
+	account, err := accountFromProto(req.Account)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	c := callFrom(ctx)
+	if err := c.ledger.CreateAccount(ctx, account); err != nil {
+		log.Error("Failed to create account", zap.Error(err), zap.String("account_id", account.ID))
+		return nil, ledgerStatus(err, codes.InvalidArgument)
+	}
+
+	account.Frozen = false
+	recordAudit(ctx, actionAccountCreate, account.ID, account)
+	log.Info("Account created successfully", zap.String("account_id", account.ID))
+	return accountToProto(account), nil
+}
+
+func (s *Server) RecordTransaction(ctx context.Context, req *ledgerpb.RecordTransactionRequest) (*ledgerpb.Transaction, error) {
+	log := logger.FromContext(ctx)
+	tx, err := transactionFromProto(req.Transaction)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// Only the owners of the debited accounts may move money out of them
+	for _, leg := range tx.Legs() {
+		if leg.Amount.Amount.IsNegative() {
+			if err := allowAccount(ctx, leg.Account); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	c := callFrom(ctx)
+	if err := c.ledger.RecordTransaction(ctx, tx); err != nil {
+		log.Error("Failed to record transaction", zap.Error(err), zap.String("transaction_id", tx.ID))
+		return nil, ledgerStatus(err, codes.InvalidArgument)
+	}
+
+	recordAudit(ctx, actionTransactionRecord, tx.ID, tx)
+	log.Info("Transaction recorded successfully", zap.String("transaction_id", tx.ID))
+	return transactionToProto(tx), nil
+}
+
+func (s *Server) GetBalance(ctx context.Context, req *ledgerpb.GetBalanceRequest) (*ledgerpb.Money, error) {
+	if err := allowAccount(ctx, req.AccountId); err != nil {
+		return nil, err
+	}
+	balance, err := callFrom(ctx).ledger.GetAccountBalance(ctx, req.AccountId)
+	if err != nil {
+		return nil, ledgerStatus(err, codes.NotFound)
+	}
+	return moneyToProto(balance), nil
+}
+
+func (s *Server) GetTransactionHistory(ctx context.Context,
+	req *ledgerpb.GetTransactionHistoryRequest) (*ledgerpb.GetTransactionHistoryResponse, error) {
+	if err := allowAccount(ctx, req.AccountId); err != nil {
+		return nil, err
+	}
+	resp := &ledgerpb.GetTransactionHistoryResponse{}
+	for _, tx := range callFrom(ctx).ledger.GetTransactionHistory(ctx, req.AccountId) {
+		resp.Transactions = append(resp.Transactions, transactionToProto(tx))
+	}
+	return resp, nil
+}
+
+func (s *Server) StreamTransactionHistory(req *ledgerpb.GetTransactionHistoryRequest,
+	stream grpc.ServerStreamingServer[ledgerpb.Transaction]) error {
+	ctx := stream.Context()
+	if err := allowAccount(ctx, req.AccountId); err != nil {
+		return err
+	}
+	for _, tx := range callFrom(ctx).ledger.GetTransactionHistory(ctx, req.AccountId) {
+		if err := stream.Send(transactionToProto(tx)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StreamEvents sends every matching event after the cursor and then waits
+// for new ones until the client goes away or the server stops.
+func (s *Server) StreamEvents(req *ledgerpb.StreamEventsRequest, stream grpc.ServerStreamingServer[ledgerpb.Event]) error {
+	ctx := stream.Context()
+	book := callFrom(ctx).ledger
+
+	types := make(map[string]bool)
+	for _, t := range req.Types {
+		if !models.KnownEventType(t) {
+			return status.Errorf(codes.InvalidArgument, "unknown event type %q", t)
+		}
+		types[t] = true
+	}
+	accounts := make(map[string]bool)
+	for _, id := range req.Accounts {
+		accounts[id] = true
+	}
+	matches := func(event models.Event) bool {
+		if len(types) > 0 && !types[event.Type] {
+			return false
+		}
+		if len(accounts) == 0 {
+			return true
+		}
+		for _, id := range event.Accounts {
+			if accounts[id] {
+				return true
+			}
+		}
+		return false
+	}
+
+	cursor := book.LastSequence()
+	if req.After != nil {
+		cursor = req.GetAfter()
+	}
+	for {
+		changed := book.EventsChanged()
+		events := book.Events(cursor, eventBatch)
+		for _, event := range events {
+			cursor = event.Sequence
+			if !matches(event) {
+				continue
+			}
+			msg, err := eventToProto(event)
+			if err != nil {
+				return status.Error(codes.Internal, err.Error())
+			}
+			if err := stream.Send(msg); err != nil {
+				return err
+			}
+		}
+		if len(events) == eventBatch {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changed:
+		}
+	}
+}
+
+// allowAccount refuses principals that do not own the account.
+func allowAccount(ctx context.Context, accountID string) error {
+	principal, ok := auth.PrincipalFrom(ctx)
+	if !ok || principal.CanAccessAccount(accountID) {
+		return nil
+	}
+	logger.FromContext(ctx).Warn("Account access denied", zap.String("account_id", accountID))
+	return status.Error(codes.PermissionDenied, fmt.Sprintf("access to account %s denied", accountID))
+}
+
+// recordAudit appends a change made through the gRPC API to the audit trail
+// of the call's tenant.
+func recordAudit(ctx context.Context, action, target string, after interface{}) {
+	c := callFrom(ctx)
+	if c.audit == nil {
+		return
+	}
+	principal, _ := auth.PrincipalFrom(ctx)
+	entry := audit.Entry{
+		Actor:     principal.ID,
+		Role:      principal.Role,
+		Action:    action,
+		Target:    target,
+		RequestID: c.requestID,
+		ClientIP:  peerAddr(ctx),
+	}
+	if _, err := c.audit.Record(entry, nil, after); err != nil {
+		logger.FromContext(ctx).Error("Failed to record audit entry",
+			zap.Error(err),
+			zap.String("action", action),
+			zap.String("target", target))
+	}
+}
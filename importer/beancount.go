@@ -0,0 +1,204 @@
+package importer
+
+import (
+	"ledgerproject/models"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// beancountMetadata matches "key: value" metadata lines below a directive.
+var beancountMetadata = regexp.MustCompile(`^[a-z][A-Za-z0-9_-]*:`)
+
+// datedAssertion is a beancount balance assertion. Beancount checks balances
+// at the start of the given day, so its position is resolved once all
+// entries are known.
+type datedAssertion struct {
+	date      time.Time
+	assertion Assertion
+}
+
+func (p *parser) parseBeancount(lines []string) {
+	var pending *pendingTx
+	var balances []datedAssertion
+
+	for i, raw := range lines {
+		lineNo := i + 1
+		line := stripComment(raw, ";")
+		trimmed := strings.TrimSpace(line)
+
+		if isIndented(raw) {
+			if trimmed == "" || beancountMetadata.MatchString(trimmed) {
+				continue
+			}
+			if pending == nil {
+				p.errorf(lineNo, "posting outside of a transaction")
+				continue
+			}
+			p.parseBeancountPosting(pending, lineNo, trimmed)
+			continue
+		}
+
+		p.finish(pending)
+		pending = nil
+
+		if trimmed == "" || strings.HasPrefix(trimmed, "*") || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		fields := strings.Fields(trimmed)
+		switch fields[0] {
+		case "option", "plugin", "pushtag", "poptag":
+			continue
+		case "include":
+			p.errorf(lineNo, "include directives are not supported, concatenate the files instead")
+			continue
+		}
+
+		date, err := parseDate(fields[0])
+		if err != nil {
+			p.errorf(lineNo, "%v", err)
+			continue
+		}
+		if len(fields) < 2 {
+			p.errorf(lineNo, "directive is missing after date")
+			continue
+		}
+
+		switch fields[1] {
+		case "open":
+			if len(fields) < 3 {
+				p.errorf(lineNo, "open directive needs an account")
+				continue
+			}
+			currency := ""
+			if len(fields) > 3 {
+				currency = fields[3]
+				if strings.Contains(currency, ",") {
+					p.errorf(lineNo, "account %s may only hold one currency", fields[2])
+					continue
+				}
+			}
+			p.declare(lineNo, fields[2], currency)
+		case "balance":
+			if len(fields) < 5 {
+				p.errorf(lineNo, "balance directive needs an account and an amount")
+				continue
+			}
+			amount, err := parseAmount(fields[3] + " " + fields[4])
+			if err != nil {
+				p.errorf(lineNo, "invalid balance assertion: %v", err)
+				continue
+			}
+			balances = append(balances, datedAssertion{
+				date:      date,
+				assertion: Assertion{Line: lineNo, Account: fields[2], Balance: amount},
+			})
+		case "*", "!", "txn":
+			pending = p.parseBeancountHeader(lineNo, date, trimmed)
+		case "pad":
+			p.errorf(lineNo, "pad directives are not supported")
+		case "close", "commodity", "price", "note", "document", "event", "custom", "query":
+			// Directives that do not affect balances are ignored
+		default:
+			p.errorf(lineNo, "unknown directive %q", fields[1])
+		}
+	}
+
+	p.finish(pending)
+
+	// Beancount orders entries by date rather than by position in the file
+	sort.SliceStable(p.entries, func(a, b int) bool {
+		return p.entries[a].Date.Before(p.entries[b].Date)
+	})
+	for _, b := range balances {
+		b.assertion.After = sort.Search(len(p.entries), func(i int) bool {
+			return !p.entries[i].Date.Before(b.date)
+		})
+		p.assertions = append(p.assertions, b.assertion)
+	}
+	sort.SliceStable(p.assertions, func(a, b int) bool {
+		return p.assertions[a].After < p.assertions[b].After
+	})
+}
+
+// parseBeancountHeader parses `DATE FLAG ["PAYEE"] "NARRATION" [#tag] [^link]`.
+// The first link, if any, becomes the transaction ID.
+func (p *parser) parseBeancountHeader(lineNo int, date time.Time, line string) *pendingTx {
+	var texts []string
+	id := p.defaultID(FormatBeancount, lineNo)
+	linked := false
+
+	rest := line
+	for rest != "" {
+		rest = strings.TrimSpace(rest)
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				p.errorf(lineNo, "unterminated string")
+				return &pendingTx{line: lineNo, invalid: true}
+			}
+			texts = append(texts, rest[1:end+1])
+			rest = rest[end+2:]
+			continue
+		}
+
+		word, remainder, _ := strings.Cut(rest, " ")
+		if strings.HasPrefix(word, "^") && !linked {
+			id = word[1:]
+			linked = true
+		}
+		rest = remainder
+	}
+
+	description := strings.Join(texts, " - ")
+	return newPendingTx(lineNo, date, id, description)
+}
+
+// parseBeancountPosting parses "[FLAG] ACCOUNT [NUMBER CURRENCY]".
+func (p *parser) parseBeancountPosting(pending *pendingTx, lineNo int, line string) {
+	if pending.invalid {
+		return
+	}
+
+	fields := strings.Fields(line)
+	if fields[0] == "*" || fields[0] == "!" {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return
+	}
+	if strings.ContainsAny(line, "{@") {
+		p.errorf(lineNo, "postings with cost or price are not supported")
+		pending.invalid = true
+		return
+	}
+
+	account := fields[0]
+	pending.postings = append(pending.postings, models.Posting{Account: account})
+	idx := len(pending.postings) - 1
+
+	switch len(fields) {
+	case 1:
+		if pending.missing >= 0 {
+			p.errorf(lineNo, "only one posting may omit its amount")
+			pending.invalid = true
+			return
+		}
+		pending.missing = idx
+		p.declare(lineNo, account, "")
+	case 3:
+		amount, err := parseAmount(fields[1] + " " + fields[2])
+		if err != nil {
+			p.errorf(lineNo, "%v", err)
+			pending.invalid = true
+			return
+		}
+		pending.postings[idx].Amount = amount
+		p.declare(lineNo, account, amount.Currency)
+	default:
+		p.errorf(lineNo, "invalid posting %q", line)
+		pending.invalid = true
+	}
+}
@@ -0,0 +1,94 @@
+package importer
+
+import (
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+const beancountJournal = `option "title" "Books"
+
+2024-01-01 open Assets:Bank USD
+2024-01-01 open Equity:Opening USD
+2024-01-01 open Expenses:Rent
+
+2024-02-01 * "Landlord" "February rent" ^rent-2024-02
+  Expenses:Rent   800.00 USD
+  Assets:Bank
+
+2024-01-02 * "Opening balance"
+  id: "ignored metadata"
+  Assets:Bank       2000 USD
+  Equity:Opening   -2000 USD
+
+2024-02-01 balance Assets:Bank 2000 USD
+2024-03-01 balance Assets:Bank 1200 USD
+`
+
+func TestParseBeancount(t *testing.T) {
+	journal, errs := Parse(strings.NewReader(beancountJournal), FormatBeancount)
+	require.Empty(t, errs)
+
+	require.Len(t, journal.Accounts, 3)
+	assert.Equal(t, "Assets:Bank", journal.Accounts[0].ID)
+	assert.Equal(t, "expense", journal.Accounts[2].Type)
+	assert.Equal(t, "USD", journal.Accounts[2].Currency)
+
+	// Entries are ordered by date, not by file position
+	require.Len(t, journal.Entries, 2)
+	assert.Regexp(t, `^beancount:[0-9a-f]{8}:11$`, journal.Entries[0].Transaction.ID)
+	assert.Equal(t, "Opening balance", journal.Entries[0].Transaction.Description)
+	assert.Equal(t, "rent-2024-02", journal.Entries[1].Transaction.ID)
+	assert.Equal(t, "Landlord - February rent", journal.Entries[1].Transaction.Description)
+	assert.True(t, journal.Entries[1].Transaction.Postings[1].Amount.Amount.Equal(decimal.NewFromInt(-800)))
+
+	// Balance directives hold at the start of their day
+	require.Len(t, journal.Assertions, 2)
+	assert.Equal(t, 1, journal.Assertions[0].After)
+	assert.Equal(t, 2, journal.Assertions[1].After)
+}
+
+func TestParseBeancountErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		journal string
+		line    int
+		errMsg  string
+	}{
+		{
+			name:    "multi-currency account",
+			journal: "2024-01-01 open Assets:Bank USD,EUR\n",
+			line:    1,
+			errMsg:  "may only hold one currency",
+		},
+		{
+			name:    "pad directive",
+			journal: "2024-01-01 pad Assets:Bank Equity:Opening\n",
+			line:    1,
+			errMsg:  "pad directives are not supported",
+		},
+		{
+			name:    "priced posting",
+			journal: "2024-01-01 * \"Buy\"\n  Assets:Stock  1 AAPL @ 100 USD\n  Assets:Bank\n",
+			line:    2,
+			errMsg:  "cost or price",
+		},
+		{
+			name:    "unknown directive",
+			journal: "2024-01-01 frobnicate\n",
+			line:    1,
+			errMsg:  "unknown directive",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := Parse(strings.NewReader(tt.journal), FormatBeancount)
+			require.NotEmpty(t, errs)
+			assert.Equal(t, tt.line, errs[0].Line)
+			assert.Contains(t, errs[0].Error(), tt.errMsg)
+		})
+	}
+}
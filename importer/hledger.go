@@ -0,0 +1,214 @@
+package importer
+
+import (
+	"ledgerproject/models"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// postingSeparator splits an hledger posting into account and amount. Account
+// names may contain single spaces, so the amount follows two or more spaces
+// or a tab.
+var postingSeparator = regexp.MustCompile(`\t|  +`)
+
+// pendingTx collects the postings of a transaction while its indented lines
+// are being read.
+type pendingTx struct {
+	line       int
+	date       time.Time
+	tx         models.Transaction
+	postings   []models.Posting
+	missing    int
+	assertions []Assertion
+	invalid    bool
+}
+
+func newPendingTx(line int, date time.Time, id, description string) *pendingTx {
+	return &pendingTx{
+		line:    line,
+		date:    date,
+		tx:      models.Transaction{ID: id, Description: description},
+		missing: -1,
+	}
+}
+
+// finish balances the pending transaction and appends it to the journal.
+func (p *parser) finish(pending *pendingTx) {
+	if pending == nil || pending.invalid {
+		return
+	}
+	if len(pending.postings) < 2 {
+		p.errorf(pending.line, "transaction needs at least two postings")
+		return
+	}
+
+	postings := p.balance(pending.line, pending.postings, pending.missing)
+	if postings == nil {
+		return
+	}
+
+	pending.tx.Postings = postings
+	p.addEntry(pending.line, pending.date, pending.tx)
+	for _, a := range pending.assertions {
+		a.After = len(p.entries)
+		p.assertions = append(p.assertions, a)
+	}
+}
+
+func (p *parser) parseHledger(lines []string) {
+	var pending *pendingTx
+	inComment := false
+
+	for i, raw := range lines {
+		lineNo := i + 1
+
+		if inComment {
+			if strings.TrimSpace(raw) == "end comment" {
+				inComment = false
+			}
+			continue
+		}
+
+		if isIndented(raw) {
+			line := stripComment(raw, ";")
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if pending == nil {
+				p.errorf(lineNo, "posting outside of a transaction")
+				continue
+			}
+			p.parseHledgerPosting(pending, lineNo, strings.TrimSpace(line))
+			continue
+		}
+
+		p.finish(pending)
+		pending = nil
+
+		line := stripComment(raw, ";")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, "*") {
+			continue
+		}
+
+		fields := strings.Fields(trimmed)
+		switch fields[0] {
+		case "comment":
+			inComment = true
+			continue
+		case "account":
+			if len(fields) < 2 {
+				p.errorf(lineNo, "account directive needs a name")
+				continue
+			}
+			name := strings.TrimSpace(postingSeparator.Split(strings.TrimSpace(trimmed[len("account"):]), 2)[0])
+			p.declare(lineNo, name, "")
+			continue
+		case "commodity", "P", "D", "Y", "year", "decimal-mark", "payee", "tag", "alias", "end":
+			// Directives that do not affect balances are ignored
+			continue
+		case "include":
+			p.errorf(lineNo, "include directives are not supported, concatenate the files instead")
+			continue
+		}
+
+		pending = p.parseHledgerHeader(lineNo, trimmed)
+	}
+
+	p.finish(pending)
+}
+
+// parseHledgerHeader parses "DATE[=DATE2] [*|!] [(CODE)] DESCRIPTION".
+func (p *parser) parseHledgerHeader(lineNo int, line string) *pendingTx {
+	dateField, rest, _ := strings.Cut(line, " ")
+	dateField, _, _ = strings.Cut(dateField, "=")
+	date, err := parseDate(dateField)
+	if err != nil {
+		p.errorf(lineNo, "%v", err)
+		// Swallow the postings that belong to the broken header
+		return &pendingTx{line: lineNo, invalid: true}
+	}
+
+	rest = strings.TrimSpace(rest)
+	if strings.HasPrefix(rest, "*") || strings.HasPrefix(rest, "!") {
+		rest = strings.TrimSpace(rest[1:])
+	}
+
+	id := p.defaultID(FormatHledger, lineNo)
+	if strings.HasPrefix(rest, "(") {
+		if end := strings.Index(rest, ")"); end > 0 {
+			id = rest[1:end]
+			rest = strings.TrimSpace(rest[end+1:])
+		}
+	}
+
+	return newPendingTx(lineNo, date, id, rest)
+}
+
+// parseHledgerPosting parses "[*|!] ACCOUNT  [AMOUNT] [= ASSERTION]".
+func (p *parser) parseHledgerPosting(pending *pendingTx, lineNo int, line string) {
+	if pending.invalid {
+		return
+	}
+	if strings.HasPrefix(line, "*") || strings.HasPrefix(line, "!") {
+		line = strings.TrimSpace(line[1:])
+	}
+	if strings.HasPrefix(line, "(") || strings.HasPrefix(line, "[") {
+		p.errorf(lineNo, "virtual postings are not supported")
+		pending.invalid = true
+		return
+	}
+
+	parts := postingSeparator.Split(line, 2)
+	account := strings.TrimSpace(parts[0])
+	amountField := ""
+	if len(parts) == 2 {
+		amountField = strings.TrimSpace(parts[1])
+	}
+
+	var assertion string
+	if i := strings.Index(amountField, "="); i >= 0 {
+		assertion = strings.TrimLeft(amountField[i:], "=*")
+		amountField = strings.TrimSpace(amountField[:i])
+	}
+	if strings.Contains(amountField, "@") {
+		p.errorf(lineNo, "priced postings are not supported")
+		pending.invalid = true
+		return
+	}
+
+	posting := models.Posting{Account: account}
+	if amountField == "" {
+		if pending.missing >= 0 {
+			p.errorf(lineNo, "only one posting may omit its amount")
+			pending.invalid = true
+			return
+		}
+		pending.missing = len(pending.postings)
+		p.declare(lineNo, account, "")
+	} else {
+		amount, err := parseAmount(amountField)
+		if err != nil {
+			p.errorf(lineNo, "%v", err)
+			pending.invalid = true
+			return
+		}
+		posting.Amount = amount
+		p.declare(lineNo, account, amount.Currency)
+	}
+	pending.postings = append(pending.postings, posting)
+
+	if assertion != "" {
+		balance, err := parseAmount(assertion)
+		if err != nil {
+			p.errorf(lineNo, "invalid balance assertion: %v", err)
+			return
+		}
+		pending.assertions = append(pending.assertions, Assertion{
+			Line:    lineNo,
+			Account: account,
+			Balance: balance,
+		})
+	}
+}
@@ -0,0 +1,143 @@
+package importer
+
+import (
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+const hledgerJournal = `; opening books
+account assets:bank:checking
+
+2024-01-01 * (OPEN1) Opening balance
+    assets:bank:checking      1,000.00 USD
+    equity:opening
+
+2024-01-05 Grocery store  ; weekly shop
+    expenses:food             USD 45.50
+    assets:bank:checking      = 954.50 USD
+
+comment
+2024-01-06 ignored
+    expenses:food  1 USD
+end comment
+`
+
+func TestParseHledger(t *testing.T) {
+	journal, errs := Parse(strings.NewReader(hledgerJournal), FormatHledger)
+	require.Empty(t, errs)
+
+	require.Len(t, journal.Accounts, 3)
+	assert.Equal(t, "assets:bank:checking", journal.Accounts[0].ID)
+	assert.Equal(t, "asset", journal.Accounts[0].Type)
+	assert.Equal(t, "USD", journal.Accounts[0].Currency)
+	assert.Equal(t, "equity", journal.Accounts[1].Type)
+	assert.Equal(t, "expense", journal.Accounts[2].Type)
+
+	require.Len(t, journal.Entries, 2)
+	opening := journal.Entries[0].Transaction
+	assert.Equal(t, "OPEN1", opening.ID)
+	assert.Equal(t, "Opening balance", opening.Description)
+	require.Len(t, opening.Postings, 2)
+	assert.True(t, opening.Postings[0].Amount.Amount.Equal(decimal.NewFromInt(1000)))
+	assert.True(t, opening.Postings[1].Amount.Amount.Equal(decimal.NewFromInt(-1000)))
+	assert.Equal(t, "USD", opening.Postings[1].Amount.Currency)
+
+	grocery := journal.Entries[1]
+	assert.Regexp(t, `^hledger:[0-9a-f]{8}:8$`, grocery.Transaction.ID)
+	assert.Equal(t, "Grocery store", grocery.Transaction.Description)
+	assert.Equal(t, 2024, grocery.Date.Year())
+	assert.Equal(t, grocery.Date, grocery.Transaction.DateTime)
+	assert.True(t, grocery.Transaction.Postings[1].Amount.Amount.Equal(decimal.NewFromFloat(-45.5)))
+
+	require.Len(t, journal.Assertions, 1)
+	assert.Equal(t, 10, journal.Assertions[0].Line)
+	assert.Equal(t, 2, journal.Assertions[0].After)
+	assert.True(t, journal.Assertions[0].Balance.Amount.Equal(decimal.NewFromFloat(954.5)))
+}
+
+func TestParseHledgerErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		journal string
+		line    int
+		errMsg  string
+	}{
+		{
+			name:    "bad date",
+			journal: "2024-13-01 Broken\n    assets:bank  1 USD\n    equity:opening\n",
+			line:    1,
+			errMsg:  "invalid date",
+		},
+		{
+			name:    "unbalanced",
+			journal: "2024-01-01 Unbalanced\n    assets:bank  1 USD\n    equity:opening  -2 USD\n",
+			line:    1,
+			errMsg:  "unbalanced by -1 USD",
+		},
+		{
+			name:    "two missing amounts",
+			journal: "2024-01-01 Missing\n    assets:bank\n    equity:opening\n",
+			line:    3,
+			errMsg:  "only one posting may omit its amount",
+		},
+		{
+			name:    "mixed commodities in one account",
+			journal: "2024-01-01 A\n    assets:bank  1 USD\n    equity:opening\n2024-01-02 B\n    assets:bank  1 EUR\n    equity:other\n",
+			line:    5,
+			errMsg:  "already uses USD",
+		},
+		{
+			name:    "virtual posting",
+			journal: "2024-01-01 Virtual\n    (assets:budget)  1 USD\n",
+			line:    2,
+			errMsg:  "virtual postings are not supported",
+		},
+		{
+			name:    "orphan posting",
+			journal: "    assets:bank  1 USD\n",
+			line:    1,
+			errMsg:  "posting outside of a transaction",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := Parse(strings.NewReader(tt.journal), FormatHledger)
+			require.NotEmpty(t, errs)
+			assert.Equal(t, tt.line, errs[0].Line)
+			assert.Contains(t, errs[0].Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		input    string
+		amount   string
+		currency string
+		wantErr  bool
+	}{
+		{input: "100.00 USD", amount: "100", currency: "USD"},
+		{input: "EUR -12.5", amount: "-12.5", currency: "EUR"},
+		{input: "$1,234.56", amount: "1234.56", currency: "USD"},
+		{input: "-£3", amount: "-3", currency: "GBP"},
+		{input: "100", wantErr: true},
+		{input: "abc USD", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			money, err := parseAmount(tt.input)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.amount, money.Amount.String())
+			assert.Equal(t, tt.currency, money.Currency)
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"io"
+	"ledgerproject/ledger"
+	"ledgerproject/logger"
+	"ledgerproject/models"
+	"ledgerproject/services"
+)
+
+// Importer loads parsed journals into the ledger. Every import is first
+// replayed against a detached scratch ledger, and only a clean run is
+// committed to the live books.
+type Importer struct {
+	ledger            ledger.LedgerService
+	currencyValidator *services.CurrencyValidator
+}
+
+// Report summarises an import run.
+type Report struct {
+	DryRun            bool               `json:"dry_run"`
+	Committed         bool               `json:"committed"`
+	Accounts          int                `json:"accounts"`
+	Transactions      int                `json:"transactions"`
+	ParseErrors       []ParseError       `json:"parse_errors,omitempty"`
+	Errors            []EntryError       `json:"errors,omitempty"`
+	AssertionFailures []AssertionFailure `json:"assertion_failures,omitempty"`
+}
+
+// EntryError is a journal entry rejected by currency or ledger validation.
+type EntryError struct {
+	Line    int    `json:"line"`
+	ID      string `json:"id,omitempty"`
+	Message string `json:"message"`
+}
+
+// AssertionFailure is a balance assertion that did not hold.
+type AssertionFailure struct {
+	Line     int          `json:"line"`
+	Account  string       `json:"account"`
+	Expected models.Money `json:"expected"`
+	Actual   models.Money `json:"actual"`
+}
+
+// OK reports whether the run found no problems.
+func (r *Report) OK() bool {
+	return len(r.ParseErrors) == 0 && len(r.Errors) == 0 && len(r.AssertionFailures) == 0
+}
+
+func NewImporter(l ledger.LedgerService, cv *services.CurrencyValidator) *Importer {
+	return &Importer{
+		ledger:            l,
+		currencyValidator: cv,
+	}
+}
+
+// Import parses the journal and loads it into the ledger. With dryRun set, or
+// when any problem is found, nothing is written to the ledger.
+func (imp *Importer) Import(ctx context.Context, r io.Reader, format Format, dryRun bool) *Report {
+	log := logger.FromContext(ctx)
+	report := &Report{DryRun: dryRun}
+
+	journal, parseErrors := Parse(r, format)
+	report.ParseErrors = parseErrors
+	if journal == nil {
+		return report
+	}
+	report.Accounts = len(journal.Accounts)
+	report.Transactions = len(journal.Entries)
+
+	for _, acc := range journal.Accounts {
+		if !imp.currencyValidator.IsValid(acc.Currency) {
+			report.Errors = append(report.Errors, EntryError{
+				ID:      acc.ID,
+				Message: fmt.Sprintf("invalid currency code: %s", acc.Currency),
+			})
+		}
+	}
+	if !report.OK() {
+		log.Error("Journal import rejected before replay",
+			zap.Int("parse_errors", len(report.ParseErrors)),
+			zap.Int("errors", len(report.Errors)))
+		return report
+	}
+
+	imp.replay(ctx, journal, report)
+	if dryRun || !report.OK() {
+		log.Info("Journal import dry run finished",
+			zap.Bool("dry_run", dryRun),
+			zap.Int("errors", len(report.Errors)),
+			zap.Int("assertion_failures", len(report.AssertionFailures)))
+		return report
+	}
+
+	imp.commit(ctx, journal, report)
+	report.Committed = report.OK()
+	log.Info("Journal import committed",
+		zap.Int("accounts", report.Accounts),
+		zap.Int("transactions", report.Transactions),
+		zap.Int("errors", len(report.Errors)))
+	return report
+}
+
+// replay applies the journal to a scratch ledger seeded with the current
+// balances of any accounts that already exist in the live ledger.
+func (imp *Importer) replay(ctx context.Context, journal *Journal, report *Report) {
+	scratch := ledger.NewDetachedLedger(imp.currencyValidator)
+
+	for _, acc := range journal.Accounts {
+		if balance, err := imp.ledger.GetAccountBalance(ctx, acc.ID); err == nil {
+			acc.Balance = balance
+		}
+		if err := scratch.CreateAccount(ctx, acc); err != nil {
+			report.Errors = append(report.Errors, EntryError{ID: acc.ID, Message: err.Error()})
+		}
+	}
+
+	next := 0
+	check := func(applied int) {
+		for ; next < len(journal.Assertions) && journal.Assertions[next].After <= applied; next++ {
+			a := journal.Assertions[next]
+			actual, err := scratch.GetAccountBalance(ctx, a.Account)
+			if err != nil {
+				report.Errors = append(report.Errors, EntryError{Line: a.Line, Message: err.Error()})
+				continue
+			}
+			if !actual.Amount.Equal(a.Balance.Amount) || actual.Currency != a.Balance.Currency {
+				report.AssertionFailures = append(report.AssertionFailures, AssertionFailure{
+					Line:     a.Line,
+					Account:  a.Account,
+					Expected: a.Balance,
+					Actual:   actual,
+				})
+			}
+		}
+	}
+
+	check(0)
+	for i, entry := range journal.Entries {
+		if err := scratch.ImportTransaction(ctx, entry.Transaction); err != nil {
+			report.Errors = append(report.Errors, EntryError{
+				Line:    entry.Line,
+				ID:      entry.Transaction.ID,
+				Message: err.Error(),
+			})
+		}
+		check(i + 1)
+	}
+}
+
+// commit writes a verified journal to the live ledger in one batch, keeping
+// the dates of the entries. Accounts that already exist are reused. When the
+// ledger rejects an entry, such as one already imported, nothing is written
+// and the entry is reported.
+func (imp *Importer) commit(ctx context.Context, journal *Journal, report *Report) {
+	txs := make([]models.Transaction, len(journal.Entries))
+	for i, entry := range journal.Entries {
+		txs[i] = entry.Transaction
+	}
+	err := imp.ledger.ImportBatch(ctx, journal.Accounts, txs)
+	if err == nil {
+		return
+	}
+
+	entryErr := EntryError{Message: err.Error()}
+	var ledgerErr *ledger.Error
+	if errors.As(err, &ledgerErr) {
+		entryErr.ID = ledgerErr.TransactionID
+		for _, entry := range journal.Entries {
+			if entry.Transaction.ID == ledgerErr.TransactionID {
+				entryErr.Line = entry.Line
+				break
+			}
+		}
+		if entryErr.ID == "" {
+			entryErr.ID = ledgerErr.AccountID
+		}
+	}
+	report.Errors = append(report.Errors, entryErr)
+}
@@ -0,0 +1,247 @@
+package importer
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"ledgerproject/config"
+	"ledgerproject/ledger"
+	"ledgerproject/logger"
+	"ledgerproject/models"
+	"ledgerproject/services"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupTestLogger initializes a test logger
+func setupTestLogger(t *testing.T) *zap.Logger {
+	testLogger := zaptest.NewLogger(t)
+	// Initialize the package-level logger
+	if err := logger.Init(true); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	return testLogger
+}
+
+// setupEmpty returns an importer over an empty ledger.
+func setupEmpty(t *testing.T) (*Importer, ledger.LedgerService) {
+	setupTestLogger(t)
+
+	cfg := &config.Config{
+		CurrencyFile: "../data/iso4217_currency_test.json",
+	}
+	validator, err := services.NewCurrencyValidator(cfg)
+	require.NoError(t, err)
+
+	l := ledger.NewDetachedLedger(validator)
+	return NewImporter(l, validator), l
+}
+
+// setupTest returns an importer over a ledger that already holds a funded
+// bank account.
+func setupTest(t *testing.T) (*Importer, ledger.LedgerService) {
+	imp, l := setupEmpty(t)
+	require.NoError(t, l.CreateAccount(context.Background(), models.Account{
+		ID:       "assets:bank",
+		Name:     "Bank",
+		Type:     "asset",
+		Currency: "USD",
+		Balance:  models.Money{Amount: decimal.NewFromInt(1000), Currency: "USD"},
+	}))
+	return imp, l
+}
+
+const spendingJournal = `2024-03-01 Rent
+    expenses:rent     600 USD
+    assets:bank      -600 USD
+
+2024-03-02 Coffee
+    expenses:food     4.50 USD
+    assets:bank       = 395.50 USD
+`
+
+func TestImportDryRun(t *testing.T) {
+	imp, l := setupTest(t)
+
+	report := imp.Import(context.Background(), strings.NewReader(spendingJournal), FormatHledger, true)
+
+	assert.True(t, report.OK())
+	assert.False(t, report.Committed)
+	assert.Equal(t, 3, report.Accounts)
+	assert.Equal(t, 2, report.Transactions)
+
+	// Nothing reaches the live ledger during a dry run
+	_, err := l.GetAccountBalance(context.Background(), "expenses:rent")
+	assert.Error(t, err)
+	balance, err := l.GetAccountBalance(context.Background(), "assets:bank")
+	require.NoError(t, err)
+	assert.True(t, balance.Amount.Equal(decimal.NewFromInt(1000)))
+}
+
+func TestImportCommit(t *testing.T) {
+	imp, l := setupTest(t)
+
+	report := imp.Import(context.Background(), strings.NewReader(spendingJournal), FormatHledger, false)
+
+	require.True(t, report.OK(), "unexpected report: %+v", report)
+	assert.True(t, report.Committed)
+
+	balance, err := l.GetAccountBalance(context.Background(), "assets:bank")
+	require.NoError(t, err)
+	assert.True(t, balance.Amount.Equal(decimal.NewFromFloat(395.5)))
+
+	rent, err := l.GetAccountBalance(context.Background(), "expenses:rent")
+	require.NoError(t, err)
+	assert.True(t, rent.Amount.Equal(decimal.NewFromInt(600)))
+
+	// Transactions keep the dates of their entries
+	history := l.GetTransactionHistory(context.Background(), "assets:bank")
+	require.Len(t, history, 2)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), history[0].DateTime)
+	assert.Equal(t, time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC), history[1].DateTime)
+}
+
+const openingJournal = `2024-01-01 Opening balances
+    assets:bank            1000 USD
+    equity:opening
+
+2024-01-31 Salary
+    assets:bank            3000 USD
+    income:salary
+
+2024-02-01 Rent on the card
+    expenses:rent          1200 USD
+    liabilities:card
+`
+
+func TestImportIntoEmptyLedger(t *testing.T) {
+	imp, l := setupEmpty(t)
+
+	report := imp.Import(context.Background(), strings.NewReader(openingJournal), FormatHledger, false)
+
+	require.True(t, report.OK(), "unexpected report: %+v", report)
+	assert.True(t, report.Committed)
+
+	expected := map[string]int64{
+		"assets:bank":      4000,
+		"equity:opening":   -1000,
+		"income:salary":    -3000,
+		"expenses:rent":    1200,
+		"liabilities:card": -1200,
+	}
+	for id, amount := range expected {
+		balance, err := l.GetAccountBalance(context.Background(), id)
+		require.NoError(t, err)
+		assert.True(t, balance.Amount.Equal(decimal.NewFromInt(amount)), "balance of %s is %s", id, balance.Amount)
+	}
+	assert.NoError(t, l.VerifyLedgerBalance(context.Background()))
+
+	history := l.GetTransactionHistory(context.Background(), "assets:bank")
+	require.Len(t, history, 2)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), history[0].DateTime)
+	assert.Equal(t, time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC), history[1].DateTime)
+}
+
+func TestImportTwice(t *testing.T) {
+	imp, l := setupEmpty(t)
+	ctx := context.Background()
+
+	first := imp.Import(ctx, strings.NewReader(openingJournal), FormatHledger, false)
+	require.True(t, first.Committed, "unexpected report: %+v", first)
+
+	// The same journal again is rejected as a whole and posts nothing
+	second := imp.Import(ctx, strings.NewReader(openingJournal), FormatHledger, false)
+	assert.False(t, second.Committed)
+	require.Len(t, second.Errors, 1)
+	assert.Equal(t, 1, second.Errors[0].Line)
+	assert.Contains(t, second.Errors[0].Message, "already exists")
+
+	balance, err := l.GetAccountBalance(ctx, "assets:bank")
+	require.NoError(t, err)
+	assert.True(t, balance.Amount.Equal(decimal.NewFromInt(4000)))
+	assert.Len(t, l.GetTransactionHistory(ctx, "assets:bank"), 2)
+
+	// A different journal with entries on the same lines is not a duplicate
+	other := strings.Replace(openingJournal, "3000 USD", "2500 USD", 1)
+	third := imp.Import(ctx, strings.NewReader(other), FormatHledger, false)
+	assert.True(t, third.Committed, "unexpected report: %+v", third)
+}
+
+func TestImportRejected(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  Format
+		journal string
+		check   func(t *testing.T, report *Report)
+	}{
+		{
+			name:    "failed balance assertion",
+			format:  FormatHledger,
+			journal: "2024-03-01 Rent\n    expenses:rent  600 USD\n    assets:bank  = 500 USD\n",
+			check: func(t *testing.T, report *Report) {
+				require.Len(t, report.AssertionFailures, 1)
+				failure := report.AssertionFailures[0]
+				assert.Equal(t, 3, failure.Line)
+				assert.True(t, failure.Expected.Amount.Equal(decimal.NewFromInt(500)))
+				assert.True(t, failure.Actual.Amount.Equal(decimal.NewFromInt(400)))
+			},
+		},
+		{
+			name:    "insufficient funds",
+			format:  FormatHledger,
+			journal: "2024-03-01 Car\n    expenses:car  5000 USD\n    assets:bank\n",
+			check: func(t *testing.T, report *Report) {
+				require.Len(t, report.Errors, 1)
+				assert.Equal(t, 1, report.Errors[0].Line)
+				assert.Contains(t, report.Errors[0].Message, "insufficient funds")
+			},
+		},
+		{
+			name:    "unknown currency",
+			format:  FormatBeancount,
+			journal: "2024-01-01 open Assets:Gold XAU\n",
+			check: func(t *testing.T, report *Report) {
+				require.Len(t, report.Errors, 1)
+				assert.Contains(t, report.Errors[0].Message, "invalid currency code: XAU")
+			},
+		},
+		{
+			name:    "parse error",
+			format:  FormatHledger,
+			journal: "2024-03-01 Rent\n    expenses:rent  600 USD\n    assets:bank  -500 USD\n",
+			check: func(t *testing.T, report *Report) {
+				require.Len(t, report.ParseErrors, 1)
+				assert.Equal(t, 1, report.ParseErrors[0].Line)
+			},
+		},
+		{
+			name:    "unsupported format",
+			format:  Format("ledger-cli"),
+			journal: "",
+			check: func(t *testing.T, report *Report) {
+				require.Len(t, report.ParseErrors, 1)
+				assert.Contains(t, report.ParseErrors[0].Message, "unsupported journal format")
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			imp, l := setupTest(t)
+
+			report := imp.Import(context.Background(), strings.NewReader(tt.journal), tt.format, false)
+
+			assert.False(t, report.OK())
+			assert.False(t, report.Committed)
+			tt.check(t, report)
+
+			balance, err := l.GetAccountBalance(context.Background(), "assets:bank")
+			require.NoError(t, err)
+			assert.True(t, balance.Amount.Equal(decimal.NewFromInt(1000)))
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package importer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"io"
+	"ledgerproject/models"
+	"strings"
+	"time"
+)
+
+// Format identifies a plain-text journal dialect.
+type Format string
+
+const (
+	FormatHledger   Format = "hledger"
+	FormatBeancount Format = "beancount"
+)
+
+// Journal is the result of parsing a plain-text journal. Transactions keep
+// the order in which they must be applied to the ledger.
+type Journal struct {
+	Accounts   []models.Account
+	Entries    []Entry
+	Assertions []Assertion
+}
+
+// Entry is a parsed transaction together with its source line. The
+// transaction is dated with Date.
+type Entry struct {
+	Line        int
+	Date        time.Time
+	Transaction models.Transaction
+}
+
+// Assertion is a balance assertion taken from the journal. It is checked once
+// the first After entries have been applied.
+type Assertion struct {
+	Line    int
+	Account string
+	Balance models.Money
+	After   int
+}
+
+// ParseError describes a journal line that could not be parsed.
+type ParseError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e ParseError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Parse reads a journal in the given format. Parsing continues past bad
+// lines so that every problem can be reported at once.
+func Parse(r io.Reader, format Format) (*Journal, []ParseError) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, []ParseError{{Line: 0, Message: fmt.Sprintf("error reading journal: %v", err)}}
+	}
+
+	digest := sha256.Sum256(data)
+	p := &parser{accounts: make(map[string]*models.Account), source: hex.EncodeToString(digest[:4])}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	switch format {
+	case FormatHledger:
+		p.parseHledger(lines)
+	case FormatBeancount:
+		p.parseBeancount(lines)
+	default:
+		return nil, []ParseError{{Line: 0, Message: fmt.Sprintf("unsupported journal format: %s", format)}}
+	}
+
+	return p.journal(), p.errors
+}
+
+// parser holds the state shared by both dialects.
+type parser struct {
+	source     string
+	accounts   map[string]*models.Account
+	order      []string
+	entries    []Entry
+	assertions []Assertion
+	errors     []ParseError
+}
+
+// defaultID returns the ID of an entry without a code or link:
+// FORMAT:DIGEST:LINE, where DIGEST identifies the journal's content. The
+// same journal imported again gets the same IDs, which the ledger rejects,
+// while entries of different journals never collide.
+func (p *parser) defaultID(format Format, line int) string {
+	return fmt.Sprintf("%s:%s:%d", format, p.source, line)
+}
+
+func (p *parser) errorf(line int, format string, args ...interface{}) {
+	p.errors = append(p.errors, ParseError{Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// declare registers an account, optionally fixing its currency. Ledger
+// accounts hold a single currency, so a second commodity is an error.
+func (p *parser) declare(line int, name, currency string) {
+	acc, exists := p.accounts[name]
+	if !exists {
+		acc = &models.Account{
+			ID:   name,
+			Name: name,
+			Type: accountType(name),
+		}
+		p.accounts[name] = acc
+		p.order = append(p.order, name)
+	}
+
+	if currency == "" {
+		return
+	}
+	if acc.Currency == "" {
+		acc.Currency = currency
+		acc.Balance = models.Money{Amount: decimal.Zero, Currency: currency}
+		return
+	}
+	if acc.Currency != currency {
+		p.errorf(line, "account %s already uses %s, cannot post %s", name, acc.Currency, currency)
+	}
+}
+
+// balance fills in a single missing posting amount and checks that the
+// postings of a transaction sum to zero in every currency.
+func (p *parser) balance(line int, postings []models.Posting, missing int) []models.Posting {
+	totals := make(map[string]decimal.Decimal)
+	var currencies []string
+	for i, posting := range postings {
+		if i == missing {
+			continue
+		}
+		if _, seen := totals[posting.Amount.Currency]; !seen {
+			currencies = append(currencies, posting.Amount.Currency)
+		}
+		totals[posting.Amount.Currency] = totals[posting.Amount.Currency].Add(posting.Amount.Amount)
+	}
+
+	if missing >= 0 {
+		if len(currencies) != 1 {
+			p.errorf(line, "cannot infer posting amount for %s", postings[missing].Account)
+			return nil
+		}
+		currency := currencies[0]
+		postings[missing].Amount = models.Money{Amount: totals[currency].Neg(), Currency: currency}
+		p.declare(line, postings[missing].Account, currency)
+		return postings
+	}
+
+	for _, currency := range currencies {
+		if !totals[currency].IsZero() {
+			p.errorf(line, "transaction is unbalanced by %s %s", totals[currency].String(), currency)
+			return nil
+		}
+	}
+	return postings
+}
+
+func (p *parser) addEntry(line int, date time.Time, tx models.Transaction) {
+	tx.DateTime = date
+	p.entries = append(p.entries, Entry{Line: line, Date: date, Transaction: tx})
+}
+
+func (p *parser) journal() *Journal {
+	j := &Journal{Entries: p.entries, Assertions: p.assertions}
+	for _, name := range p.order {
+		acc := p.accounts[name]
+		if acc.Currency == "" {
+			// Declared but never used with a commodity; nothing to create.
+			continue
+		}
+		j.Accounts = append(j.Accounts, *acc)
+	}
+	return j
+}
+
+// accountType maps the top-level account segment onto the ledger's account
+// types.
+func accountType(name string) string {
+	root := strings.ToLower(strings.SplitN(name, ":", 2)[0])
+	switch root {
+	case "assets", "asset":
+		return "asset"
+	case "liabilities", "liability":
+		return "liability"
+	case "equity":
+		return "equity"
+	case "income", "revenue", "revenues":
+		return "revenue"
+	case "expenses", "expense":
+		return "expense"
+	default:
+		return root
+	}
+}
+
+// currencySymbols maps common commodity symbols onto ISO 4217 codes.
+var currencySymbols = map[string]string{
+	"$": "USD",
+	"€": "EUR",
+	"£": "GBP",
+	"¥": "JPY",
+}
+
+// parseAmount accepts "100.00 USD", "USD 100.00", "-$5" and "$-5" forms.
+func parseAmount(s string) (models.Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return models.Money{}, fmt.Errorf("empty amount")
+	}
+
+	var number, commodity string
+	fields := strings.Fields(s)
+	switch len(fields) {
+	case 1:
+		// Symbol glued to the number, e.g. $100 or -$100
+		sign := ""
+		rest := fields[0]
+		if strings.HasPrefix(rest, "-") {
+			sign, rest = "-", rest[1:]
+		}
+		for symbol := range currencySymbols {
+			if strings.HasPrefix(rest, symbol) {
+				commodity, number = symbol, sign+rest[len(symbol):]
+				break
+			}
+		}
+		if commodity == "" {
+			return models.Money{}, fmt.Errorf("amount %q has no commodity", s)
+		}
+	case 2:
+		if isNumber(fields[0]) {
+			number, commodity = fields[0], fields[1]
+		} else {
+			commodity, number = fields[0], fields[1]
+		}
+	default:
+		return models.Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+
+	if code, ok := currencySymbols[commodity]; ok {
+		commodity = code
+	}
+
+	amount, err := decimal.NewFromString(strings.ReplaceAll(number, ",", ""))
+	if err != nil {
+		return models.Money{}, fmt.Errorf("invalid amount %q", s)
+	}
+	return models.Money{Amount: amount, Currency: commodity}, nil
+}
+
+func isNumber(s string) bool {
+	_, err := decimal.NewFromString(strings.ReplaceAll(s, ",", ""))
+	return err == nil
+}
+
+// stripComment removes a trailing comment introduced by any of the markers.
+func stripComment(line string, markers ...string) string {
+	for _, marker := range markers {
+		if i := strings.Index(line, marker); i >= 0 {
+			line = line[:i]
+		}
+	}
+	return strings.TrimRight(line, " \t")
+}
+
+func isIndented(line string) bool {
+	return strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
+}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range []string{"2006-01-02", "2006/01/02", "2006.01.02"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
@@ -0,0 +1,47 @@
+package interest
+
+import (
+	"fmt"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// Supported day-count conventions.
+const (
+	Actual360 = "ACT/360"
+	Actual365 = "ACT/365"
+	Thirty360 = "30/360"
+)
+
+// yearFraction returns the fraction of a year between start and end under the
+// given convention. ACT/365 is the fixed variant: leap years still use 365.
+func yearFraction(convention string, start, end time.Time) (decimal.Decimal, error) {
+	switch convention {
+	case Actual360:
+		return decimal.NewFromInt(actualDays(start, end)).Div(decimal.NewFromInt(360)), nil
+	case Actual365:
+		return decimal.NewFromInt(actualDays(start, end)).Div(decimal.NewFromInt(365)), nil
+	case Thirty360:
+		return decimal.NewFromInt(thirty360Days(start, end)).Div(decimal.NewFromInt(360)), nil
+	default:
+		return decimal.Zero, fmt.Errorf("unsupported day-count convention %q", convention)
+	}
+}
+
+func actualDays(start, end time.Time) int64 {
+	return int64(end.Sub(start).Hours() / 24)
+}
+
+// thirty360Days counts days using the US (bond basis) 30/360 rule, so every
+// month contributes 30 days regardless of its length.
+func thirty360Days(start, end time.Time) int64 {
+	y1, m1, d1 := start.Date()
+	y2, m2, d2 := end.Date()
+	if d1 == 31 {
+		d1 = 30
+	}
+	if d2 == 31 && d1 == 30 {
+		d2 = 30
+	}
+	return int64(360*(y2-y1) + 30*(int(m2)-int(m1)) + (d2 - d1))
+}
@@ -0,0 +1,52 @@
+package interest
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func date(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestYearFraction(t *testing.T) {
+	tests := []struct {
+		name       string
+		convention string
+		start, end time.Time
+		want       string
+	}{
+		{"ACT/360 one day", Actual360, date(2024, 3, 1), date(2024, 3, 2), "0.0027777777777778"},
+		{"ACT/360 full year", Actual360, date(2023, 1, 1), date(2024, 1, 1), "1.0138888888888889"},
+		{"ACT/365 leap year", Actual365, date(2024, 1, 1), date(2025, 1, 1), "1.0027397260273973"},
+		{"30/360 month end", Thirty360, date(2024, 1, 30), date(2024, 1, 31), "0"},
+		{"30/360 into next month", Thirty360, date(2024, 1, 31), date(2024, 2, 1), "0.0027777777777778"},
+		{"30/360 end of February", Thirty360, date(2023, 2, 28), date(2023, 3, 1), "0.0083333333333333"},
+		{"30/360 full year", Thirty360, date(2023, 1, 1), date(2024, 1, 1), "1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := yearFraction(tt.convention, tt.start, tt.end)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got.String())
+		})
+	}
+
+	t.Run("unknown convention", func(t *testing.T) {
+		_, err := yearFraction("ACT/ACT", date(2024, 1, 1), date(2024, 1, 2))
+		assert.Error(t, err)
+	})
+}
+
+func TestThirty360MonthSumsToThirty(t *testing.T) {
+	for _, month := range []time.Month{time.January, time.February, time.April} {
+		total := int64(0)
+		for d := date(2023, month, 1); d.Month() == month; d = d.AddDate(0, 0, 1) {
+			total += thirty360Days(d, d.AddDate(0, 0, 1))
+		}
+		assert.Equal(t, int64(30), total, month.String())
+	}
+}
@@ -0,0 +1,270 @@
+package interest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"ledgerproject/config"
+	"ledgerproject/ledger"
+	"ledgerproject/logger"
+	"ledgerproject/models"
+	"ledgerproject/services"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotConfigured is returned for accounts without an interest setup.
+var ErrNotConfigured = errors.New("interest is not configured")
+
+// Terms describe how interest accrues on an account. Rate is the annual rate
+// as a fraction (0.045 for 4.5%). Positive interest is credited to the
+// account and debited from the counter account, which is the interest expense
+// account for deposits or the interest income account for loans; a negative
+// rate reverses the direction.
+type Terms struct {
+	Rate           decimal.Decimal `json:"rate"`
+	DayCount       string          `json:"day_count"`
+	CounterAccount string          `json:"counter_account"`
+}
+
+// Accrual is the interest state of one account. AccruedUntil is the first
+// day that has not been accrued yet; Residual is the unposted remainder below
+// the currency's precision, carried into the next day.
+type Accrual struct {
+	AccountID string `json:"account_id"`
+	Terms
+	AccruedUntil time.Time       `json:"accrued_until"`
+	Residual     decimal.Decimal `json:"residual"`
+}
+
+// Engine accrues daily interest on configured accounts and posts it to the
+// ledger.
+type Engine struct {
+	ledger            ledger.LedgerService
+	currencyValidator *services.CurrencyValidator
+	interval          time.Duration
+	accruals          map[string]*Accrual
+	now               func() time.Time
+	mu                sync.Mutex
+}
+
+func NewEngine(l ledger.LedgerService, cv *services.CurrencyValidator, cfg *config.Config) *Engine {
+	return &Engine{
+		ledger:            l,
+		currencyValidator: cv,
+		interval:          cfg.InterestAccrualInterval,
+		accruals:          make(map[string]*Accrual),
+		now:               time.Now,
+	}
+}
+
+// Configure sets the interest terms of an account. Accrual of a newly
+// configured account starts today; reconfiguring keeps the accrual position
+// and residual so no day is accrued twice.
+func (e *Engine) Configure(ctx context.Context, accountID string, terms Terms) (*Accrual, error) {
+	log := logger.FromContext(ctx)
+
+	if _, err := yearFraction(terms.DayCount, time.Time{}, time.Time{}); err != nil {
+		return nil, err
+	}
+	if terms.CounterAccount == "" {
+		return nil, fmt.Errorf("counter account is required")
+	}
+	if terms.CounterAccount == accountID {
+		return nil, fmt.Errorf("counter account must differ from account %s", accountID)
+	}
+	balance, err := e.ledger.GetAccountBalance(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	counter, err := e.ledger.GetAccountBalance(ctx, terms.CounterAccount)
+	if err != nil {
+		return nil, err
+	}
+	if counter.Currency != balance.Currency {
+		return nil, fmt.Errorf("counter account %s currency %s does not match account currency %s",
+			terms.CounterAccount, counter.Currency, balance.Currency)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	accrual, exists := e.accruals[accountID]
+	if !exists {
+		accrual = &Accrual{
+			AccountID:    accountID,
+			AccruedUntil: day(e.now()),
+			Residual:     decimal.Zero,
+		}
+		e.accruals[accountID] = accrual
+	}
+	accrual.Terms = terms
+
+	log.Info("Interest configured",
+		zap.String("account_id", accountID),
+		zap.String("rate", terms.Rate.String()),
+		zap.String("day_count", terms.DayCount),
+		zap.String("counter_account", terms.CounterAccount))
+	copied := *accrual
+	return &copied, nil
+}
+
+// Get returns the interest state of an account.
+func (e *Engine) Get(accountID string) (*Accrual, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	accrual, exists := e.accruals[accountID]
+	if !exists {
+		return nil, fmt.Errorf("%w for account %s", ErrNotConfigured, accountID)
+	}
+	copied := *accrual
+	return &copied, nil
+}
+
+// Run accrues interest on every tick until the context is cancelled.
+func (e *Engine) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	for {
+		e.AccrueDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// AccrueDue posts interest for every completed day that has not been accrued
+// yet and returns the number of transactions recorded. Each day accrues on
+// the account's balance at the end of that day, so a run catching up several
+// days does not apply later postings to earlier days. An account whose
+// posting fails stays at the failed day and is retried on the next run.
+func (e *Engine) AccrueDue(ctx context.Context) int {
+	log := logger.FromContext(ctx)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	today := day(e.now())
+	ids := make([]string, 0, len(e.accruals))
+	for id := range e.accruals {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	posted := 0
+	for _, id := range ids {
+		accrual := e.accruals[id]
+		if !accrual.AccruedUntil.Before(today) {
+			continue
+		}
+		balances, err := e.balanceHistory(ctx, accrual.AccountID)
+		if err != nil {
+			log.Error("Interest accrual failed", zap.Error(err), zap.String("account_id", id))
+			continue
+		}
+		for accrual.AccruedUntil.Before(today) {
+			recorded, err := e.accrueDay(ctx, accrual, balances)
+			if err != nil {
+				log.Error("Interest accrual failed",
+					zap.Error(err),
+					zap.String("account_id", id),
+					zap.Time("date", accrual.AccruedUntil))
+				break
+			}
+			if recorded {
+				posted++
+			}
+		}
+	}
+	return posted
+}
+
+// balanceHistory derives the past balances of an account from its current
+// balance and transaction history.
+type balanceHistory struct {
+	accountID string
+	current   models.Money
+	history   []models.Transaction
+}
+
+func (e *Engine) balanceHistory(ctx context.Context, accountID string) (*balanceHistory, error) {
+	current, err := e.ledger.GetAccountBalance(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	return &balanceHistory{
+		accountID: accountID,
+		current:   current,
+		history:   e.ledger.GetTransactionHistory(ctx, accountID),
+	}, nil
+}
+
+// at returns the balance at t: the current balance without the transactions
+// dated t or later.
+func (b *balanceHistory) at(t time.Time) models.Money {
+	balance := b.current
+	for _, tx := range b.history {
+		if !tx.DateTime.Before(t) {
+			balance.Amount = balance.Amount.Sub(tx.Delta(b.accountID))
+		}
+	}
+	return balance
+}
+
+// accrueDay accrues interest for the day at accrual.AccruedUntil on the
+// balance at the end of that day, and advances the accrual by one day when
+// it succeeds. The interest posted counts towards the following days.
+func (e *Engine) accrueDay(ctx context.Context, accrual *Accrual, balances *balanceHistory) (bool, error) {
+	log := logger.FromContext(ctx)
+	start := accrual.AccruedUntil
+	end := start.AddDate(0, 0, 1)
+
+	balance := balances.at(end)
+	fraction, err := yearFraction(accrual.DayCount, start, end)
+	if err != nil {
+		return false, err
+	}
+
+	exact := accrual.Residual.Add(balance.Amount.Mul(accrual.Rate).Mul(fraction))
+	amount := exact.Truncate(e.currencyValidator.MinorUnits(balance.Currency))
+
+	if !amount.IsZero() {
+		tx := models.Transaction{
+			ID:            fmt.Sprintf("interest-%s-%s", accrual.AccountID, start.Format("20060102")),
+			Description:   fmt.Sprintf("Interest for %s", start.Format("2006-01-02")),
+			DebitAccount:  accrual.CounterAccount,
+			CreditAccount: accrual.AccountID,
+			Amount:        models.Money{Amount: amount, Currency: balance.Currency},
+		}
+		if amount.IsNegative() {
+			tx.DebitAccount, tx.CreditAccount = accrual.AccountID, accrual.CounterAccount
+			tx.Amount.Amount = amount.Neg()
+		}
+		if err := e.ledger.RecordSystemTransaction(ctx, tx); err != nil {
+			return false, err
+		}
+		log.Info("Interest posted",
+			zap.String("account_id", accrual.AccountID),
+			zap.String("tx_id", tx.ID),
+			zap.String("amount", amount.String()))
+		// Dated now, the posting is not in the history: add it to the
+		// balance every later day starts from
+		balances.current.Amount = balances.current.Amount.Add(amount)
+	}
+
+	accrual.Residual = exact.Sub(amount)
+	accrual.AccruedUntil = end
+	return !amount.IsZero(), nil
+}
+
+// day truncates t to midnight UTC.
+func day(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
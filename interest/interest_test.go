@@ -0,0 +1,265 @@
+package interest
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"ledgerproject/config"
+	"ledgerproject/fees"
+	"ledgerproject/ledger"
+	"ledgerproject/logger"
+	"ledgerproject/models"
+	"ledgerproject/services"
+	"testing"
+	"time"
+)
+
+// setupTestLogger initializes a test logger
+func setupTestLogger(t *testing.T) *zap.Logger {
+	testLogger := zaptest.NewLogger(t)
+	// Initialize the package-level logger
+	if err := logger.Init(true); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	return testLogger
+}
+
+// setupTest returns an engine whose clock reads *now, over a ledger with a
+// SAVINGS account holding 1000 in the given currency and an EXPENSE account
+// funding the interest.
+func setupTest(t *testing.T, currency string, now *time.Time) (*Engine, ledger.LedgerService) {
+	return setupWithFees(t, currency, now, nil)
+}
+
+// setupWithFees is setupTest over a ledger that charges the given fees.
+func setupWithFees(t *testing.T, currency string, now *time.Time, fees ledger.FeeSchedule) (*Engine, ledger.LedgerService) {
+	setupTestLogger(t)
+
+	validator, err := services.NewCurrencyValidator(&config.Config{
+		CurrencyFile: "../data/iso4217_currency_test.json",
+	})
+	require.NoError(t, err)
+
+	l := ledger.NewLedger(validator, fees, nil, 0)
+	money := func(amount int64) models.Money {
+		return models.Money{Amount: decimal.NewFromInt(amount), Currency: currency}
+	}
+	for _, acc := range []models.Account{
+		{ID: "SAVINGS", Name: "Savings", Currency: currency, Balance: money(1000)},
+		{ID: "EXPENSE", Name: "Interest expense", Currency: currency, Balance: money(100)},
+		{ID: "OTHER", Name: "Other", Currency: "EUR"},
+		{ID: "FEES", Name: "Fee revenue", Type: "Revenue", Currency: currency},
+	} {
+		require.NoError(t, l.CreateAccount(context.Background(), acc))
+	}
+
+	e := NewEngine(l, validator, &config.Config{InterestAccrualInterval: time.Hour})
+	e.now = func() time.Time { return *now }
+	return e, l
+}
+
+func balance(t *testing.T, l ledger.LedgerService, id string) string {
+	b, err := l.GetAccountBalance(context.Background(), id)
+	require.NoError(t, err)
+	return b.Amount.String()
+}
+
+func TestConfigure(t *testing.T) {
+	now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+	e, _ := setupTest(t, "USD", &now)
+
+	tests := []struct {
+		name    string
+		account string
+		terms   Terms
+		wantErr bool
+	}{
+		{"valid", "SAVINGS", Terms{Rate: decimal.RequireFromString("0.05"), DayCount: Actual365, CounterAccount: "EXPENSE"}, false},
+		{"unknown day count", "SAVINGS", Terms{DayCount: "ACT/ACT", CounterAccount: "EXPENSE"}, true},
+		{"missing counter account", "SAVINGS", Terms{DayCount: Actual360}, true},
+		{"counter account is the account", "SAVINGS", Terms{DayCount: Actual360, CounterAccount: "SAVINGS"}, true},
+		{"unknown account", "MISSING", Terms{DayCount: Actual360, CounterAccount: "EXPENSE"}, true},
+		{"currency mismatch", "SAVINGS", Terms{DayCount: Actual360, CounterAccount: "OTHER"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accrual, err := e.Configure(context.Background(), tt.account, tt.terms)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, date(2024, 3, 1), accrual.AccruedUntil)
+		})
+	}
+
+	_, err := e.Get("EXPENSE")
+	assert.ErrorIs(t, err, ErrNotConfigured)
+}
+
+func TestAccrueDue(t *testing.T) {
+	t.Run("carries the rounding residual", func(t *testing.T) {
+		now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+		e, l := setupTest(t, "USD", &now)
+		_, err := e.Configure(context.Background(), "SAVINGS", Terms{
+			Rate: decimal.RequireFromString("0.05"), DayCount: Actual365, CounterAccount: "EXPENSE",
+		})
+		require.NoError(t, err)
+
+		// Nothing is due until the first day has ended
+		assert.Equal(t, 0, e.AccrueDue(context.Background()))
+
+		// 1000 * 5% / 365 = 0.13698... per day: 0.13 is posted and the
+		// remainder is carried until it adds up to an extra cent
+		now = time.Date(2024, 3, 3, 1, 0, 0, 0, time.UTC)
+		assert.Equal(t, 2, e.AccrueDue(context.Background()))
+		assert.Equal(t, "1000.27", balance(t, l, "SAVINGS"))
+		assert.Equal(t, "99.73", balance(t, l, "EXPENSE"))
+
+		accrual, err := e.Get("SAVINGS")
+		require.NoError(t, err)
+		assert.Equal(t, date(2024, 3, 3), accrual.AccruedUntil)
+		assert.True(t, accrual.Residual.IsPositive())
+		assert.True(t, accrual.Residual.LessThan(decimal.RequireFromString("0.01")))
+
+		history := l.GetTransactionHistory(context.Background(), "SAVINGS")
+		require.Len(t, history, 2)
+		assert.Equal(t, "interest-SAVINGS-20240301", history[0].ID)
+		assert.Equal(t, "EXPENSE", history[0].DebitAccount)
+		assert.Equal(t, "0.13", history[0].Amount.Amount.String())
+		assert.Equal(t, "0.14", history[1].Amount.Amount.String())
+
+		// Running again on the same day posts nothing
+		assert.Equal(t, 0, e.AccrueDue(context.Background()))
+	})
+
+	t.Run("is not charged fees", func(t *testing.T) {
+		validator, err := services.NewCurrencyValidator(&config.Config{
+			CurrencyFile: "../data/iso4217_currency_test.json",
+		})
+		require.NoError(t, err)
+		schedule, err := fees.NewEngine(validator, &config.Config{})
+		require.NoError(t, err)
+		require.NoError(t, schedule.SetRules([]fees.Rule{
+			{ID: "transfer", Kind: fees.KindFlat, Flat: decimal.NewFromInt(1), RevenueAccount: "FEES"},
+		}))
+
+		now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+		e, l := setupWithFees(t, "USD", &now, schedule)
+		_, err = e.Configure(context.Background(), "SAVINGS", Terms{
+			Rate: decimal.RequireFromString("0.365"), DayCount: Actual365, CounterAccount: "EXPENSE",
+		})
+		require.NoError(t, err)
+
+		now = time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)
+		assert.Equal(t, 1, e.AccrueDue(context.Background()))
+		assert.Equal(t, "1001", balance(t, l, "SAVINGS"))
+		assert.Equal(t, "99", balance(t, l, "EXPENSE"))
+		assert.Equal(t, "0", balance(t, l, "FEES"))
+
+		// The same transfer made by a client is charged
+		require.NoError(t, l.RecordTransaction(context.Background(), models.Transaction{
+			ID: "TX001", DebitAccount: "EXPENSE", CreditAccount: "SAVINGS",
+			Amount: models.Money{Amount: decimal.NewFromInt(1), Currency: "USD"},
+		}))
+		assert.Equal(t, "1", balance(t, l, "FEES"))
+	})
+
+	t.Run("respects currency precision", func(t *testing.T) {
+		now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+		e, l := setupTest(t, "JPY", &now)
+		_, err := e.Configure(context.Background(), "SAVINGS", Terms{
+			Rate: decimal.RequireFromString("0.36"), DayCount: Actual360, CounterAccount: "EXPENSE",
+		})
+		require.NoError(t, err)
+
+		// 1 JPY on the first day; the compounded fractions of a yen on the
+		// following days (0.001, then 0.002) are carried, not posted
+		now = now.AddDate(0, 0, 3)
+		assert.Equal(t, 3, e.AccrueDue(context.Background()))
+		assert.Equal(t, "1003", balance(t, l, "SAVINGS"))
+
+		accrual, err := e.Get("SAVINGS")
+		require.NoError(t, err)
+		assert.Equal(t, "0.003", accrual.Residual.StringFixed(3))
+	})
+
+	t.Run("negative rate debits the account", func(t *testing.T) {
+		now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+		e, l := setupTest(t, "USD", &now)
+		_, err := e.Configure(context.Background(), "SAVINGS", Terms{
+			Rate: decimal.RequireFromString("-0.36"), DayCount: Actual360, CounterAccount: "EXPENSE",
+		})
+		require.NoError(t, err)
+
+		now = now.AddDate(0, 0, 1)
+		assert.Equal(t, 1, e.AccrueDue(context.Background()))
+		assert.Equal(t, "999", balance(t, l, "SAVINGS"))
+		assert.Equal(t, "101", balance(t, l, "EXPENSE"))
+	})
+
+	t.Run("catch-up accrues on each day's closing balance", func(t *testing.T) {
+		now := time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)
+		e, l := setupTest(t, "USD", &now)
+		require.NoError(t, l.CreateAccount(context.Background(), models.Account{
+			ID: "PAYROLL", Name: "Payroll", Currency: "USD",
+			Balance: models.Money{Amount: decimal.NewFromInt(1000), Currency: "USD"},
+		}))
+		// 0.1% a day
+		_, err := e.Configure(context.Background(), "SAVINGS", Terms{
+			Rate: decimal.RequireFromString("0.365"), DayCount: Actual365, CounterAccount: "EXPENSE",
+		})
+		require.NoError(t, err)
+
+		// A deposit booked in the middle of the gap
+		require.NoError(t, l.ImportTransaction(context.Background(), models.Transaction{
+			ID: "DEPOSIT", DebitAccount: "PAYROLL", CreditAccount: "SAVINGS",
+			Amount:   models.Money{Amount: decimal.NewFromInt(1000), Currency: "USD"},
+			DateTime: time.Date(2024, 3, 2, 12, 0, 0, 0, time.UTC),
+		}))
+
+		// March 1 closes at 1000 and earns 1; March 2 closes at 2001 with
+		// the deposit and earns 2.001; March 3 closes at 2003 and earns
+		// 2.003 plus the carried 0.001
+		now = time.Date(2024, 3, 4, 1, 0, 0, 0, time.UTC)
+		assert.Equal(t, 3, e.AccrueDue(context.Background()))
+		assert.Equal(t, "2005", balance(t, l, "SAVINGS"))
+
+		var amounts []string
+		for _, tx := range l.GetTransactionHistory(context.Background(), "SAVINGS") {
+			if tx.ID != "DEPOSIT" {
+				amounts = append(amounts, tx.Amount.Amount.String())
+			}
+		}
+		assert.Equal(t, []string{"1", "2", "2"}, amounts)
+	})
+
+	t.Run("failed posting is retried", func(t *testing.T) {
+		now := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+		e, l := setupTest(t, "USD", &now)
+		// 1000 * 36.5 / 365 = 100 a day drains the expense account after one day
+		_, err := e.Configure(context.Background(), "SAVINGS", Terms{
+			Rate: decimal.RequireFromString("36.5"), DayCount: Actual365, CounterAccount: "EXPENSE",
+		})
+		require.NoError(t, err)
+
+		now = now.AddDate(0, 0, 2)
+		assert.Equal(t, 1, e.AccrueDue(context.Background()))
+		accrual, err := e.Get("SAVINGS")
+		require.NoError(t, err)
+		assert.Equal(t, date(2024, 3, 2), accrual.AccruedUntil)
+
+		require.NoError(t, l.RecordTransaction(context.Background(), models.Transaction{
+			ID: "TOPUP", DebitAccount: "SAVINGS", CreditAccount: "EXPENSE",
+			Amount: models.Money{Amount: decimal.NewFromInt(500), Currency: "USD"},
+		}))
+		assert.Equal(t, 1, e.AccrueDue(context.Background()))
+		accrual, err = e.Get("SAVINGS")
+		require.NoError(t, err)
+		assert.Equal(t, date(2024, 3, 3), accrual.AccruedUntil)
+	})
+}
@@ -0,0 +1,59 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of ledger failure. Every error the ledger returns for a rejected
+// operation is an *Error that matches one of them with errors.Is.
+var (
+	ErrAccountNotFound   = errors.New("account not found")
+	ErrDuplicate         = errors.New("already exists")
+	ErrAccountFrozen     = errors.New("account is frozen")
+	ErrAccountNotFrozen  = errors.New("account is not frozen")
+	ErrInvalidCurrency   = errors.New("invalid currency")
+	ErrCurrencyMismatch  = errors.New("currency mismatch")
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrUnbalanced        = errors.New("books would be unbalanced")
+	ErrInvalid           = errors.New("invalid request")
+)
+
+// Error is a rejected ledger operation. Kind is the sentinel it matches; the
+// other fields name the account, transaction and currency involved, when
+// known.
+type Error struct {
+	Kind          error
+	Message       string
+	AccountID     string
+	TransactionID string
+	Currency      string
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// newError returns an error of the given kind with a formatted message.
+func newError(kind error, format string, args ...interface{}) *Error {
+	return &Error{Kind: kind, Message: fmt.Sprintf(format, args...)}
+}
+
+func (e *Error) account(id string) *Error {
+	e.AccountID = id
+	return e
+}
+
+func (e *Error) transaction(id string) *Error {
+	e.TransactionID = id
+	return e
+}
+
+func (e *Error) currency(code string) *Error {
+	e.Currency = code
+	return e
+}
@@ -0,0 +1,76 @@
+package ledger
+
+import (
+	"fmt"
+	"ledgerproject/models"
+	"time"
+)
+
+// emit appends an event to the ledger's outbox. The caller must hold l.mu for
+// writing, so an event is stored together with the change it describes and
+// never for a rejected one.
+func (l *ledger) emit(eventType string, accounts []string, data interface{}) {
+	sequence := l.nextSequence()
+	l.events = append(l.events, models.Event{
+		Sequence: sequence,
+		ID:       fmt.Sprintf("evt-%d", sequence),
+		Type:     eventType,
+		Time:     time.Now().UTC(),
+		Accounts: uniqueAccounts(accounts),
+		Data:     data,
+	})
+
+	close(l.eventsChanged)
+	l.eventsChanged = make(chan struct{})
+}
+
+// nextSequence returns the sequence number the next committed change will
+// get. The caller must hold l.mu.
+func (l *ledger) nextSequence() uint64 {
+	return uint64(len(l.events)) + 1
+}
+
+// LastSequence returns the sequence number of the newest event, or zero
+// when nothing has been committed yet.
+func (l *ledger) LastSequence() uint64 {
+	l.rlock()
+	defer l.mu.RUnlock()
+	return uint64(len(l.events))
+}
+
+// Events returns up to limit events with a sequence number greater than
+// after, oldest first. A limit of zero or less returns all of them.
+func (l *ledger) Events(after uint64, limit int) []models.Event {
+	l.rlock()
+	defer l.mu.RUnlock()
+
+	if after >= uint64(len(l.events)) {
+		return []models.Event{}
+	}
+	events := l.events[after:]
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return append([]models.Event{}, events...)
+}
+
+// EventsChanged returns a channel that is closed when the next event is
+// committed. Callers take the channel before reading Events so that no event
+// slips in between.
+func (l *ledger) EventsChanged() <-chan struct{} {
+	l.rlock()
+	defer l.mu.RUnlock()
+	return l.eventsChanged
+}
+
+func uniqueAccounts(accounts []string) []string {
+	var unique []string
+	seen := make(map[string]bool)
+	for _, id := range accounts {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
@@ -3,13 +3,61 @@ package ledger
 import (
 	"context"
 	"ledgerproject/models"
+	"time"
 )
 
+// LedgerService is the book of accounts and transactions. Operations take
+// the context of the request or job they serve and log with its logger.
 type LedgerService interface {
-	CreateAccount(account models.Account) error
-	RecordTransaction(tx models.Transaction) error
-	GetAccountBalance(accountID string) (models.Money, error)
-	GetTransactionHistory(accountID string) []models.Transaction
-	VerifyLedgerBalance() error
+	CreateAccount(ctx context.Context, account models.Account) error
+	RecordTransaction(ctx context.Context, tx models.Transaction) error
+	RecordSystemTransaction(ctx context.Context, tx models.Transaction) error
+	ImportTransaction(ctx context.Context, tx models.Transaction) error
+	ImportBatch(ctx context.Context, accounts []models.Account, txs []models.Transaction) error
+	GetAccountBalance(ctx context.Context, accountID string) (models.Money, error)
+	GetTransactionHistory(ctx context.Context, accountID string) []models.Transaction
+	FindAccounts(ctx context.Context, filter models.MetadataFilter) []models.Account
+	FindTransactions(ctx context.Context, filter models.MetadataFilter) []models.Transaction
+	FreezeAccount(ctx context.Context, accountID string) error
+	UnfreezeAccount(ctx context.Context, accountID string) error
+	Events(after uint64, limit int) []models.Event
+	EventsChanged() <-chan struct{}
+	LastSequence() uint64
+	VerifyLedgerBalance(ctx context.Context) error
 	PerformPeriodicBalanceCheck(context.Context)
+	PreviewFees(ctx context.Context, tx models.Transaction) ([]models.Posting, error)
+	Stats() Stats
+}
+
+// Stats describes the size of the book and its last balance check.
+// LastBalanceCheck is nil until the first check has run.
+type Stats struct {
+	Accounts         int
+	Transactions     int
+	Events           uint64
+	LastBalanceCheck *BalanceCheck
+}
+
+// BalanceCheck is the outcome of a VerifyLedgerBalance run. Err is nil when
+// the books were balanced.
+type BalanceCheck struct {
+	At       time.Time
+	Duration time.Duration
+	Err      error
+}
+
+// FeeSchedule supplies the fee legs charged on a simple debit/credit
+// transfer. The legs are recorded atomically with the transfer.
+type FeeSchedule interface {
+	Fees(tx models.Transaction, debit models.Account) ([]models.Posting, error)
+}
+
+// Metrics receives measurements of the ledger's work: the outcome of every
+// transaction attempt, how long callers waited for the book's lock, the size
+// of the book and the result of each balance check.
+type Metrics interface {
+	TransactionAttempted(outcome string)
+	LockWaited(mode string, wait time.Duration)
+	BookSize(accounts, transactions int)
+	BalanceVerified(duration time.Duration, err error)
 }
@@ -4,26 +4,43 @@ import (
 	"context"
 	"fmt"
 	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 	"ledgerproject/logger"
 	"ledgerproject/models"
 	"ledgerproject/services"
+	"ledgerproject/tracing"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type ledger struct {
 	accounts          map[string]*models.Account
 	transactions      []models.Transaction
+	txIDs             map[string]bool
+	events            []models.Event
+	eventsChanged     chan struct{}
 	currencyValidator *services.CurrencyValidator
+	fees              FeeSchedule
+	metrics           Metrics
+	lastCheck         atomic.Pointer[BalanceCheck]
+	checkInterval     time.Duration
 	mu                sync.RWMutex
 }
 
-func NewLedger(cv *services.CurrencyValidator) LedgerService {
-	l := &ledger{
-		accounts:          make(map[string]*models.Account),
-		transactions:      []models.Transaction{},
-		currencyValidator: cv,
+// NewLedger returns the ledger and starts its balance check, run every
+// checkInterval. Fees may be nil when no fees are charged, and metrics when
+// nothing is measured.
+func NewLedger(cv *services.CurrencyValidator, fees FeeSchedule, metrics Metrics, checkInterval time.Duration) LedgerService {
+	l := newLedger(cv)
+	l.fees = fees
+	l.checkInterval = checkInterval
+	if metrics != nil {
+		l.metrics = metrics
 	}
 
 	// Start periodic balance checking
@@ -33,16 +50,47 @@ func NewLedger(cv *services.CurrencyValidator) LedgerService {
 	return l
 }
 
-func (l *ledger) CreateAccount(account models.Account) error {
-	log := logger.Get()
-	l.mu.Lock()
+// NewDetachedLedger returns an in-memory ledger without the periodic balance
+// check or fees. It is meant for scratch work such as import dry runs.
+func NewDetachedLedger(cv *services.CurrencyValidator) LedgerService {
+	return newLedger(cv)
+}
+
+func newLedger(cv *services.CurrencyValidator) *ledger {
+	return &ledger{
+		accounts:          make(map[string]*models.Account),
+		transactions:      []models.Transaction{},
+		txIDs:             make(map[string]bool),
+		currencyValidator: cv,
+		eventsChanged:     make(chan struct{}),
+		metrics:           nopMetrics{},
+	}
+}
+
+func (l *ledger) CreateAccount(ctx context.Context, account models.Account) (err error) {
+	ctx, span := tracing.Start(ctx, "ledger.CreateAccount",
+		trace.WithAttributes(attribute.String("ledger.account_id", account.ID)))
+	defer func() { tracing.End(span, err) }()
+	l.lock()
 	defer l.mu.Unlock()
+	return l.createAccount(ctx, account)
+}
+
+// createAccount adds the account to the book. The caller must hold l.mu.
+func (l *ledger) createAccount(ctx context.Context, account models.Account) error {
+	log := logger.FromContext(ctx)
 
 	// Validate if account already exists
 	if _, exists := l.accounts[account.ID]; exists {
 		log.Error("Account already exists", zap.String("account_id", account.ID))
-		return fmt.Errorf("account %s already exists", account.ID)
+		return newError(ErrDuplicate, "account %s already exists", account.ID).account(account.ID)
+	}
+
+	if err := models.ValidateMetadata(account.Metadata, account.Tags); err != nil {
+		log.Error("Invalid account metadata", zap.Error(err), zap.String("account_id", account.ID))
+		return newError(ErrInvalid, "%v", err).account(account.ID)
 	}
+	account.Metadata, account.Tags = models.CloneMetadata(account.Metadata, account.Tags)
 
 	// Validate currency
 	if !l.currencyValidator.IsValid(account.Currency) {
@@ -50,7 +98,8 @@ func (l *ledger) CreateAccount(account models.Account) error {
 			zap.String("account_id", account.ID),
 			zap.String("account_currency", account.Currency),
 		)
-		return fmt.Errorf("invalid currency code: %s", account.Currency)
+		return newError(ErrInvalidCurrency, "invalid currency code: %s", account.Currency).
+			account(account.ID).currency(account.Currency)
 	}
 
 	// Initialize balance if zero
@@ -65,13 +114,16 @@ func (l *ledger) CreateAccount(account models.Account) error {
 				zap.String("balance_currency", account.Balance.Currency),
 				zap.String("account_currency", account.Currency),
 			)
-			return fmt.Errorf("balance currency (%s) does not match account currency (%s)",
-				account.Balance.Currency, account.Currency)
+			return newError(ErrCurrencyMismatch, "balance currency (%s) does not match account currency (%s)",
+				account.Balance.Currency, account.Currency).account(account.ID).currency(account.Balance.Currency)
 		}
 	}
 
 	account.CreateDateTime = time.Now().UTC()
+	account.Frozen = false
 	l.accounts[account.ID] = &account
+	l.emit(models.EventAccountCreated, []string{account.ID}, account)
+	l.metrics.BookSize(len(l.accounts), len(l.transactions))
 
 	log.Info("Account created successfully",
 		zap.String("account_id", account.ID),
@@ -80,40 +132,188 @@ func (l *ledger) CreateAccount(account models.Account) error {
 	return nil
 }
 
-func (l *ledger) RecordTransaction(tx models.Transaction) error {
-	log := logger.Get()
-	l.mu.Lock()
+// recordOptions vary how a transaction is recorded. keepDate keeps a
+// supplied DateTime instead of dating the transaction when it is recorded;
+// feeExempt skips the fee schedule; imported lets the accounts of other
+// books' journals go negative where their type allows it.
+type recordOptions struct {
+	keepDate  bool
+	feeExempt bool
+	imported  bool
+}
+
+// dateOf returns the date to record the transaction with.
+func (o recordOptions) dateOf(tx models.Transaction) time.Time {
+	if o.keepDate && !tx.DateTime.IsZero() {
+		return tx.DateTime
+	}
+	return time.Now().UTC()
+}
+
+// RecordTransaction validates and applies the transaction atomically, dated
+// now. Its span has a child span for each stage: waiting for the lock,
+// validation, applying the balances and persisting the entry and its event.
+func (l *ledger) RecordTransaction(ctx context.Context, tx models.Transaction) error {
+	return l.record(ctx, "ledger.RecordTransaction", tx, recordOptions{})
+}
+
+// RecordSystemTransaction records a transaction the ledger's own jobs
+// generate, such as interest accruals and standing order occurrences. It is
+// validated like any other but is not charged fees.
+func (l *ledger) RecordSystemTransaction(ctx context.Context, tx models.Transaction) error {
+	return l.record(ctx, "ledger.RecordSystemTransaction", tx, recordOptions{feeExempt: true})
+}
+
+// ImportTransaction records a transaction taken from another book, such as a
+// journal, keeping its date. Transactions without a date are dated now.
+func (l *ledger) ImportTransaction(ctx context.Context, tx models.Transaction) error {
+	return l.record(ctx, "ledger.ImportTransaction", tx, recordOptions{keepDate: true, imported: true})
+}
+
+// ImportBatch creates the accounts that do not exist yet and records the
+// transactions, keeping their dates, as one operation under one lock. The
+// batch is rehearsed on a copy of the book first, so either every
+// transaction is recorded or, when one is rejected, the book is left
+// unchanged and its error is returned.
+func (l *ledger) ImportBatch(ctx context.Context, accounts []models.Account, txs []models.Transaction) (err error) {
+	ctx, span := tracing.Start(ctx, "ledger.ImportBatch",
+		trace.WithAttributes(attribute.Int("ledger.transactions", len(txs))))
+	defer func() { tracing.End(span, err) }()
+	log := logger.FromContext(ctx)
+
+	l.lock()
+	defer l.mu.Unlock()
+
+	if err := l.copy().importBatch(ctx, accounts, txs); err != nil {
+		log.Error("Import batch rejected", zap.Error(err))
+		return err
+	}
+	return l.importBatch(ctx, accounts, txs)
+}
+
+// importBatch applies a batch. The caller must hold l.mu.
+func (l *ledger) importBatch(ctx context.Context, accounts []models.Account, txs []models.Transaction) error {
+	for _, acc := range accounts {
+		if _, exists := l.accounts[acc.ID]; exists {
+			continue
+		}
+		if err := l.createAccount(ctx, acc); err != nil {
+			return err
+		}
+	}
+	for _, tx := range txs {
+		if err := l.recordLocked(ctx, tracing.NewStages(ctx), tx, recordOptions{keepDate: true, imported: true}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copy returns a detached ledger holding copies of the accounts and the
+// transaction IDs, to rehearse changes on. The caller must hold l.mu.
+func (l *ledger) copy() *ledger {
+	c := newLedger(l.currencyValidator)
+	c.fees = l.fees
+	for id, acc := range l.accounts {
+		account := *acc
+		c.accounts[id] = &account
+	}
+	for id := range l.txIDs {
+		c.txIDs[id] = true
+	}
+	return c
+}
+
+func (l *ledger) record(ctx context.Context, name string, tx models.Transaction, opts recordOptions) (err error) {
+	ctx, span := tracing.Start(ctx, name,
+		trace.WithAttributes(attribute.String("ledger.tx_id", tx.ID)))
+	stages := tracing.NewStages(ctx)
+	defer func() {
+		stages.End(err)
+		tracing.End(span, err)
+	}()
+
+	stages.Start("ledger.lock_wait")
+	l.lock()
 	defer l.mu.Unlock()
+	return l.recordLocked(ctx, stages, tx, opts)
+}
+
+// recordLocked validates and applies a transaction. The caller must hold
+// l.mu.
+func (l *ledger) recordLocked(ctx context.Context, stages *tracing.Stages, tx models.Transaction, opts recordOptions) error {
+	log := logger.FromContext(ctx)
+
+	stages.Start("ledger.validate")
+	if tx.ID != "" && l.txIDs[tx.ID] {
+		log.Error("Transaction already exists", zap.String("tx_id", tx.ID))
+		return l.reject(outcomeDuplicate, newError(ErrDuplicate, "transaction %s already exists", tx.ID).transaction(tx.ID))
+	}
+	if err := models.ValidateMetadata(tx.Metadata, tx.Tags); err != nil {
+		log.Error("Invalid transaction metadata", zap.Error(err), zap.String("tx_id", tx.ID))
+		return l.reject(outcomeInvalid, newError(ErrInvalid, "%v", err).transaction(tx.ID))
+	}
+	tx.Metadata, tx.Tags = models.CloneMetadata(tx.Metadata, tx.Tags)
+
+	if len(tx.Postings) > 0 {
+		return l.recordPostings(ctx, stages, tx, opts)
+	}
 
 	debitAcc, exists := l.accounts[tx.DebitAccount]
 	if !exists {
 		log.Error("Debit account not found", zap.String("account_id", tx.DebitAccount))
-		return fmt.Errorf("debit account %s does not exist", tx.DebitAccount)
+		return l.reject(outcomeUnknownAccount, newError(ErrAccountNotFound, "debit account %s does not exist", tx.DebitAccount).
+			account(tx.DebitAccount).transaction(tx.ID))
 	}
 	initialDebitBalance := debitAcc.Balance.Amount
 
 	creditAcc, exists := l.accounts[tx.CreditAccount]
 	if !exists {
 		log.Error("Credit account not found", zap.String("account_id", tx.CreditAccount))
-		return fmt.Errorf("credit account %s does not exist", tx.CreditAccount)
+		return l.reject(outcomeUnknownAccount, newError(ErrAccountNotFound, "credit account %s does not exist", tx.CreditAccount).
+			account(tx.CreditAccount).transaction(tx.ID))
 	}
 	initialCreditBalance := creditAcc.Balance.Amount
 
+	for _, acc := range []*models.Account{debitAcc, creditAcc} {
+		if acc.Frozen {
+			log.Error("Account is frozen", zap.String("account_id", acc.ID))
+			return l.reject(outcomeFrozenAccount, newError(ErrAccountFrozen, "account %s is frozen", acc.ID).
+				account(acc.ID).transaction(tx.ID))
+		}
+	}
+
 	if debitAcc.Currency != tx.Amount.Currency || creditAcc.Currency != tx.Amount.Currency {
 		log.Error("Currency mismatch",
 			zap.String("debit_currency", debitAcc.Currency),
 			zap.String("credit_currency", creditAcc.Currency),
 			zap.String("tx_currency", tx.Amount.Currency))
-		return fmt.Errorf("currency mismatch between accounts and transaction")
+		return l.reject(outcomeCurrencyMismatch, newError(ErrCurrencyMismatch, "currency mismatch between accounts and transaction").
+			transaction(tx.ID).currency(tx.Amount.Currency))
+	}
+
+	// Fees are charged to the debit account in the same entry
+	if l.fees != nil && !opts.feeExempt {
+		legs, err := l.fees.Fees(tx, *debitAcc)
+		if err != nil {
+			log.Error("Failed to evaluate fees", zap.Error(err), zap.String("tx_id", tx.ID))
+			return l.reject(outcomeInvalid, newError(ErrInvalid, "%v", err).transaction(tx.ID))
+		}
+		if len(legs) > 0 {
+			tx.Postings = append(tx.Legs(), legs...)
+			return l.recordPostings(ctx, stages, tx, opts)
+		}
 	}
 
 	// Check if debit account has sufficient funds
-	if debitAcc.Balance.Amount.LessThan(tx.Amount.Amount) {
+	if !opts.overdrawable(debitAcc) && debitAcc.Balance.Amount.LessThan(tx.Amount.Amount) {
 		log.Error("Insufficient funds in debit account", zap.String("account_id", tx.DebitAccount))
-		return fmt.Errorf("insufficient funds in debit account %s", tx.DebitAccount)
+		return l.reject(outcomeInsufficientFunds, newError(ErrInsufficientFunds, "insufficient funds in debit account %s", tx.DebitAccount).
+			account(tx.DebitAccount).transaction(tx.ID).currency(tx.Amount.Currency))
 	}
 
 	// Perform the transaction
+	stages.Start("ledger.apply")
 	debitAcc.Balance.Amount = debitAcc.Balance.Amount.Sub(tx.Amount.Amount)
 	creditAcc.Balance.Amount = creditAcc.Balance.Amount.Add(tx.Amount.Amount)
 
@@ -129,44 +329,224 @@ func (l *ledger) RecordTransaction(tx models.Transaction) error {
 			zap.String("difference", totalChange.String()),
 			zap.String("currency", tx.Amount.Currency),
 		)
-		return fmt.Errorf("transaction failed: books would be unbalanced by %s %s",
-			totalChange.String(), tx.Amount.Currency)
+		return l.reject(outcomeUnbalanced, newError(ErrUnbalanced, "transaction failed: books would be unbalanced by %s %s",
+			totalChange.String(), tx.Amount.Currency).transaction(tx.ID).currency(tx.Amount.Currency))
 	}
 
 	// Record the transaction
-	tx.DateTime = time.Now().UTC()
+	stages.Start("ledger.persist")
+	tx.DateTime = opts.dateOf(tx)
+	tx.Sequence = l.nextSequence()
+	l.transactions = append(l.transactions, tx)
+	l.txIDs[tx.ID] = true
+	l.emit(models.EventTransactionRecorded, []string{tx.DebitAccount, tx.CreditAccount}, tx)
+	l.recorded()
+
+	log.Info("Transaction recorded successfully",
+		zap.String("tx_id", tx.ID),
+		zap.Time("datetime", tx.DateTime))
+	return nil
+}
+
+// recordPostings applies a multi-posting transaction. The caller must hold
+// l.mu. Every leg is validated before any balance is touched, so a rejected
+// transaction leaves the books unchanged.
+func (l *ledger) recordPostings(ctx context.Context, stages *tracing.Stages, tx models.Transaction, opts recordOptions) error {
+	log := logger.FromContext(ctx)
+
+	if len(tx.Postings) < 2 {
+		log.Error("Transaction has too few postings", zap.String("tx_id", tx.ID))
+		return l.reject(outcomeInvalid, newError(ErrInvalid, "transaction %s needs at least two postings", tx.ID).transaction(tx.ID))
+	}
+
+	totals := make(map[string]decimal.Decimal)
+	deltas := make(map[string]decimal.Decimal)
+	var order []string
+	for _, p := range tx.Postings {
+		acc, exists := l.accounts[p.Account]
+		if !exists {
+			log.Error("Posting account not found", zap.String("account_id", p.Account))
+			return l.reject(outcomeUnknownAccount, newError(ErrAccountNotFound, "account %s does not exist", p.Account).
+				account(p.Account).transaction(tx.ID))
+		}
+		if acc.Currency != p.Amount.Currency {
+			log.Error("Currency mismatch",
+				zap.String("account_id", p.Account),
+				zap.String("account_currency", acc.Currency),
+				zap.String("posting_currency", p.Amount.Currency))
+			return l.reject(outcomeCurrencyMismatch, newError(ErrCurrencyMismatch, "currency mismatch between account %s and posting", p.Account).
+				account(p.Account).transaction(tx.ID).currency(p.Amount.Currency))
+		}
+		if acc.Frozen {
+			log.Error("Account is frozen", zap.String("account_id", p.Account))
+			return l.reject(outcomeFrozenAccount, newError(ErrAccountFrozen, "account %s is frozen", p.Account).
+				account(p.Account).transaction(tx.ID))
+		}
+		if _, seen := deltas[p.Account]; !seen {
+			order = append(order, p.Account)
+		}
+		deltas[p.Account] = deltas[p.Account].Add(p.Amount.Amount)
+		totals[p.Amount.Currency] = totals[p.Amount.Currency].Add(p.Amount.Amount)
+	}
+
+	for currency, total := range totals {
+		if !total.IsZero() {
+			log.Error("Transaction failed: books could not be balanced",
+				zap.String("difference", total.String()),
+				zap.String("currency", currency),
+			)
+			return l.reject(outcomeUnbalanced, newError(ErrUnbalanced, "transaction failed: books would be unbalanced by %s %s",
+				total.String(), currency).transaction(tx.ID).currency(currency))
+		}
+	}
+
+	// Check that every net-debited account that may not be overdrawn has
+	// sufficient funds
+	for _, id := range order {
+		delta := deltas[id]
+		if delta.IsNegative() && !opts.overdrawable(l.accounts[id]) && l.accounts[id].Balance.Amount.Add(delta).IsNegative() {
+			log.Error("Insufficient funds in debited account", zap.String("account_id", id))
+			return l.reject(outcomeInsufficientFunds, newError(ErrInsufficientFunds, "insufficient funds in debit account %s", id).
+				account(id).transaction(tx.ID).currency(l.accounts[id].Currency))
+		}
+	}
+
+	stages.Start("ledger.apply")
+	for _, id := range order {
+		acc := l.accounts[id]
+		acc.Balance.Amount = acc.Balance.Amount.Add(deltas[id])
+	}
+
+	stages.Start("ledger.persist")
+	tx.DateTime = opts.dateOf(tx)
+	tx.Sequence = l.nextSequence()
 	l.transactions = append(l.transactions, tx)
+	l.txIDs[tx.ID] = true
+	l.emit(models.EventTransactionRecorded, order, tx)
+	l.recorded()
 
 	log.Info("Transaction recorded successfully",
 		zap.String("tx_id", tx.ID),
+		zap.Int("postings", len(tx.Postings)),
 		zap.Time("datetime", tx.DateTime))
 	return nil
 }
 
-func (l *ledger) GetAccountBalance(accountID string) (models.Money, error) {
-	log := logger.Get()
-	l.mu.RLock()
+// overdrawable reports whether an account may go below zero. A debit lowers
+// a balance, so in a journal kept elsewhere equity, revenue and liability
+// accounts carry negative balances in normal use, such as opening balances,
+// income and loans. Only imports may take them there: account types are set
+// by whoever creates the account, so through the API such an account would
+// be an unlimited source of funds.
+func (o recordOptions) overdrawable(acc *models.Account) bool {
+	if !o.imported {
+		return false
+	}
+	switch strings.ToLower(acc.Type) {
+	case "equity", "revenue", "income", "liability":
+		return true
+	}
+	return false
+}
+
+// FreezeAccount blocks all postings to and from the account.
+func (l *ledger) FreezeAccount(ctx context.Context, accountID string) error {
+	return l.setFrozen(ctx, accountID, true)
+}
+
+// UnfreezeAccount lifts a freeze.
+func (l *ledger) UnfreezeAccount(ctx context.Context, accountID string) error {
+	return l.setFrozen(ctx, accountID, false)
+}
+
+func (l *ledger) setFrozen(ctx context.Context, accountID string, frozen bool) (err error) {
+	name := "ledger.UnfreezeAccount"
+	if frozen {
+		name = "ledger.FreezeAccount"
+	}
+	ctx, span := tracing.Start(ctx, name, trace.WithAttributes(attribute.String("ledger.account_id", accountID)))
+	defer func() { tracing.End(span, err) }()
+	log := logger.FromContext(ctx)
+	l.lock()
+	defer l.mu.Unlock()
+
+	account, exists := l.accounts[accountID]
+	if !exists {
+		log.Error("Account not found", zap.String("account_id", accountID))
+		return newError(ErrAccountNotFound, "account %s does not exist", accountID).account(accountID)
+	}
+	if account.Frozen == frozen {
+		if frozen {
+			return newError(ErrAccountFrozen, "account %s is already frozen", accountID).account(accountID)
+		}
+		return newError(ErrAccountNotFrozen, "account %s is not frozen", accountID).account(accountID)
+	}
+
+	account.Frozen = frozen
+	eventType := models.EventAccountUnfrozen
+	if frozen {
+		eventType = models.EventAccountFrozen
+	}
+	l.emit(eventType, []string{accountID}, *account)
+
+	log.Info("Account freeze updated",
+		zap.String("account_id", accountID),
+		zap.Bool("frozen", frozen))
+	return nil
+}
+
+// PreviewFees returns the fee legs RecordTransaction would add to the
+// transaction, without recording anything. Multi-posting transactions carry
+// no fees.
+func (l *ledger) PreviewFees(ctx context.Context, tx models.Transaction) ([]models.Posting, error) {
+	log := logger.FromContext(ctx)
+	l.rlock()
+	defer l.mu.RUnlock()
+
+	if l.fees == nil || len(tx.Postings) > 0 {
+		return nil, nil
+	}
+	debitAcc, exists := l.accounts[tx.DebitAccount]
+	if !exists {
+		log.Error("Debit account not found", zap.String("account_id", tx.DebitAccount))
+		return nil, newError(ErrAccountNotFound, "debit account %s does not exist", tx.DebitAccount).
+			account(tx.DebitAccount).transaction(tx.ID)
+	}
+	if debitAcc.Currency != tx.Amount.Currency {
+		return nil, newError(ErrCurrencyMismatch, "currency mismatch between accounts and transaction").
+			transaction(tx.ID).currency(tx.Amount.Currency)
+	}
+	legs, err := l.fees.Fees(tx, *debitAcc)
+	if err != nil {
+		return nil, newError(ErrInvalid, "%v", err).transaction(tx.ID)
+	}
+	return legs, nil
+}
+
+func (l *ledger) GetAccountBalance(ctx context.Context, accountID string) (models.Money, error) {
+	log := logger.FromContext(ctx)
+	l.rlock()
 	defer l.mu.RUnlock()
 
 	account, exists := l.accounts[accountID]
 	if !exists {
 		log.Error("Account not found", zap.String("account_id", accountID))
-		return models.Money{}, fmt.Errorf("account %s does not exist", accountID)
+		return models.Money{}, newError(ErrAccountNotFound, "account %s does not exist", accountID).account(accountID)
 	}
 
 	log.Info("Account balance reported successfully", zap.String("account_id", account.ID))
 	return account.Balance, nil
 }
 
-func (l *ledger) GetTransactionHistory(accountID string) []models.Transaction {
-	log := logger.Get()
-	l.mu.RLock()
+func (l *ledger) GetTransactionHistory(ctx context.Context, accountID string) []models.Transaction {
+	log := logger.FromContext(ctx)
+	l.rlock()
 	defer l.mu.RUnlock()
 
 	var history []models.Transaction
 	for _, tx := range l.transactions {
-		if tx.DebitAccount == accountID || tx.CreditAccount == accountID {
-			history = append(history, tx)
+		if tx.Involves(accountID) {
+			history = append(history, cloneTransaction(tx))
 		}
 	}
 
@@ -174,9 +554,68 @@ func (l *ledger) GetTransactionHistory(accountID string) []models.Transaction {
 	return history
 }
 
+// FindAccounts returns the accounts matching the filter, ordered by ID.
+func (l *ledger) FindAccounts(ctx context.Context, filter models.MetadataFilter) []models.Account {
+	log := logger.FromContext(ctx)
+	l.rlock()
+	defer l.mu.RUnlock()
+
+	accounts := []models.Account{}
+	for _, account := range l.accounts {
+		if filter.Matches(account.Metadata, account.Tags) {
+			accounts = append(accounts, cloneAccount(*account))
+		}
+	}
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].ID < accounts[j].ID })
+
+	log.Info("Accounts queried successfully", zap.Int("count", len(accounts)))
+	return accounts
+}
+
+// FindTransactions returns the transactions matching the filter in the order
+// they were recorded.
+func (l *ledger) FindTransactions(ctx context.Context, filter models.MetadataFilter) []models.Transaction {
+	log := logger.FromContext(ctx)
+	l.rlock()
+	defer l.mu.RUnlock()
+
+	transactions := []models.Transaction{}
+	for _, tx := range l.transactions {
+		if filter.Matches(tx.Metadata, tx.Tags) {
+			transactions = append(transactions, cloneTransaction(tx))
+		}
+	}
+
+	log.Info("Transactions queried successfully", zap.Int("count", len(transactions)))
+	return transactions
+}
+
+// cloneAccount copies the account's metadata and tags so that callers
+// cannot change the stored account through the returned copy.
+func cloneAccount(account models.Account) models.Account {
+	account.Metadata, account.Tags = models.CloneMetadata(account.Metadata, account.Tags)
+	return account
+}
+
+// cloneTransaction copies the transaction's postings, metadata and tags so
+// that callers cannot change the stored transaction through the returned
+// copy.
+func cloneTransaction(tx models.Transaction) models.Transaction {
+	if tx.Postings != nil {
+		tx.Postings = append([]models.Posting{}, tx.Postings...)
+	}
+	tx.Metadata, tx.Tags = models.CloneMetadata(tx.Metadata, tx.Tags)
+	return tx
+}
+
+// PerformPeriodicBalanceCheck checks the balance every check interval until
+// ctx is done. Ledgers without an interval are never checked.
 func (l *ledger) PerformPeriodicBalanceCheck(ctx context.Context) {
-	log := logger.Get()
-	ticker := time.NewTicker(1 * time.Hour)
+	if l.checkInterval <= 0 {
+		return
+	}
+	log := logger.FromContext(ctx)
+	ticker := time.NewTicker(l.checkInterval)
 	defer ticker.Stop()
 
 	for {
@@ -184,18 +623,38 @@ func (l *ledger) PerformPeriodicBalanceCheck(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			if err := l.VerifyLedgerBalance(); err != nil {
+			if err := l.checkBalance(ctx); err != nil {
 				// Log the error or trigger an alert
 				log.Error("CRITICAL: Ledger balance check failed", zap.Error(err))
-				// Could also trigger notifications to administrators
 			}
 		}
 	}
 }
 
-func (l *ledger) VerifyLedgerBalance() error {
-	log := logger.Get()
-	l.mu.RLock()
+// checkBalance verifies the ledger and publishes a balance.check_failed
+// event when it is unbalanced.
+func (l *ledger) checkBalance(ctx context.Context) error {
+	err := l.VerifyLedgerBalance(ctx)
+	if err != nil {
+		l.lock()
+		l.emit(models.EventBalanceCheckFailed, nil, models.BalanceCheckFailure{Error: err.Error()})
+		l.mu.Unlock()
+	}
+	return err
+}
+
+func (l *ledger) VerifyLedgerBalance(ctx context.Context) (err error) {
+	ctx, span := tracing.Start(ctx, "ledger.VerifyLedgerBalance")
+	start := time.Now()
+	defer func() {
+		elapsed := time.Since(start)
+		l.lastCheck.Store(&BalanceCheck{At: time.Now().UTC(), Duration: elapsed, Err: err})
+		l.metrics.BalanceVerified(elapsed, err)
+		tracing.End(span, err)
+	}()
+	log := logger.FromContext(ctx)
+
+	l.rlock()
 	defer l.mu.RUnlock()
 
 	// Group accounts by currency
@@ -223,3 +682,16 @@ func (l *ledger) VerifyLedgerBalance() error {
 
 	return nil
 }
+
+// Stats returns the size of the book and the outcome of its last balance
+// check.
+func (l *ledger) Stats() Stats {
+	l.rlock()
+	defer l.mu.RUnlock()
+	return Stats{
+		Accounts:         len(l.accounts),
+		Transactions:     len(l.transactions),
+		Events:           uint64(len(l.events)),
+		LastBalanceCheck: l.lastCheck.Load(),
+	}
+}
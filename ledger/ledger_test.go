@@ -2,6 +2,7 @@ package ledger
 
 import (
 	"context"
+	"fmt"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -52,7 +53,7 @@ func setupTest(t *testing.T) *testSetup {
 	require.NoError(t, err)
 
 	// Create test ledger with validator
-	testLedger := NewLedger(validator)
+	testLedger := NewLedger(validator, nil, nil, time.Hour)
 
 	return &testSetup{
 		ledger:      testLedger,
@@ -137,7 +138,7 @@ func TestCreateAccount(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := setup.ledger.CreateAccount(tt.account)
+			err := setup.ledger.CreateAccount(context.Background(), tt.account)
 			if tt.wantErr {
 				assert.Error(t, err)
 				assert.Contains(t, err.Error(), tt.errMsg)
@@ -145,7 +146,7 @@ func TestCreateAccount(t *testing.T) {
 				assert.NoError(t, err)
 
 				// Verify account was created correctly
-				balance, err := setup.ledger.GetAccountBalance(tt.account.ID)
+				balance, err := setup.ledger.GetAccountBalance(context.Background(), tt.account.ID)
 				assert.NoError(t, err)
 				assert.Equal(t, tt.account.Currency, balance.Currency)
 			}
@@ -180,7 +181,7 @@ func TestRecordTransaction(t *testing.T) {
 
 	// Create accounts
 	for _, acc := range accounts {
-		err := setup.ledger.CreateAccount(acc)
+		err := setup.ledger.CreateAccount(context.Background(), acc)
 		require.NoError(t, err)
 	}
 
@@ -223,7 +224,7 @@ func TestRecordTransaction(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := setup.ledger.RecordTransaction(tt.tx)
+			err := setup.ledger.RecordTransaction(context.Background(), tt.tx)
 			if tt.wantErr {
 				assert.Error(t, err)
 				assert.Contains(t, err.Error(), tt.errMsg)
@@ -231,7 +232,7 @@ func TestRecordTransaction(t *testing.T) {
 				assert.NoError(t, err)
 
 				// Verify transaction was recorded correctly
-				history := setup.ledger.GetTransactionHistory(tt.tx.DebitAccount)
+				history := setup.ledger.GetTransactionHistory(context.Background(), tt.tx.DebitAccount)
 				assert.Condition(t, func() bool {
 					for _, hist := range history {
 						if hist.ID == tt.tx.ID &&
@@ -276,12 +277,12 @@ func TestVerifyLedgerBalance(t *testing.T) {
 
 	// Create accounts
 	for _, acc := range accounts {
-		err := setup.ledger.CreateAccount(acc)
+		err := setup.ledger.CreateAccount(context.Background(), acc)
 		require.NoError(t, err)
 	}
 
 	// Verify ledger balance
-	err := setup.ledger.VerifyLedgerBalance()
+	err := setup.ledger.VerifyLedgerBalance(context.Background())
 	assert.NoError(t, err)
 }
 
@@ -318,10 +319,597 @@ func TestPeriodicBalanceCheck(t *testing.T) {
 
 	// Create accounts
 	for _, acc := range accounts {
-		err := setup.ledger.CreateAccount(acc)
+		err := setup.ledger.CreateAccount(context.Background(), acc)
 		require.NoError(t, err)
 	}
 
 	// Let the periodic check run at least once
 	time.Sleep(100 * time.Millisecond)
 }
+
+func TestRecordTransactionPostings(t *testing.T) {
+	setup := setupTest(t)
+
+	accounts := []models.Account{
+		{
+			ID:       "CASH",
+			Name:     "Cash",
+			Currency: setup.validCurr,
+			Balance: models.Money{
+				Amount:   decimal.NewFromInt(1000),
+				Currency: setup.validCurr,
+			},
+		},
+		{ID: "RENT", Name: "Rent", Currency: setup.validCurr},
+		{ID: "FEES", Name: "Fees", Currency: setup.validCurr},
+		{ID: "EURO", Name: "Euro Cash", Currency: "EUR"},
+	}
+	for _, acc := range accounts {
+		require.NoError(t, setup.ledger.CreateAccount(context.Background(), acc))
+	}
+
+	usd := func(amount int64) models.Money {
+		return models.Money{Amount: decimal.NewFromInt(amount), Currency: setup.validCurr}
+	}
+
+	tests := []struct {
+		name     string
+		postings []models.Posting
+		errMsg   string
+	}{
+		{
+			name: "Too few postings",
+			postings: []models.Posting{
+				{Account: "CASH", Amount: usd(0)},
+			},
+			errMsg: "needs at least two postings",
+		},
+		{
+			name: "Unknown account",
+			postings: []models.Posting{
+				{Account: "CASH", Amount: usd(-10)},
+				{Account: "MISSING", Amount: usd(10)},
+			},
+			errMsg: "account MISSING does not exist",
+		},
+		{
+			name: "Currency mismatch",
+			postings: []models.Posting{
+				{Account: "CASH", Amount: usd(-10)},
+				{Account: "EURO", Amount: usd(10)},
+			},
+			errMsg: "currency mismatch between account EURO and posting",
+		},
+		{
+			name: "Unbalanced postings",
+			postings: []models.Posting{
+				{Account: "CASH", Amount: usd(-10)},
+				{Account: "RENT", Amount: usd(9)},
+			},
+			errMsg: "books would be unbalanced by -1 USD",
+		},
+		{
+			name: "Insufficient funds",
+			postings: []models.Posting{
+				{Account: "CASH", Amount: usd(-1500)},
+				{Account: "RENT", Amount: usd(1500)},
+			},
+			errMsg: "insufficient funds in debit account CASH",
+		},
+		{
+			name: "Valid split payment",
+			postings: []models.Posting{
+				{Account: "CASH", Amount: usd(-810)},
+				{Account: "RENT", Amount: usd(800)},
+				{Account: "FEES", Amount: usd(10)},
+			},
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := setup.ledger.RecordTransaction(context.Background(), models.Transaction{
+				ID:       fmt.Sprintf("MTX%03d", i),
+				Postings: tt.postings,
+			})
+			if tt.errMsg != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+
+	// Only the valid split payment moved money
+	expected := map[string]int64{"CASH": 190, "RENT": 800, "FEES": 10}
+	for id, amount := range expected {
+		balance, err := setup.ledger.GetAccountBalance(context.Background(), id)
+		require.NoError(t, err)
+		assert.True(t, balance.Amount.Equal(decimal.NewFromInt(amount)), "balance of %s", id)
+	}
+
+	history := setup.ledger.GetTransactionHistory(context.Background(), "FEES")
+	require.Len(t, history, 1)
+	assert.Len(t, history[0].Postings, 3)
+}
+
+func TestOverdrawableAccounts(t *testing.T) {
+	usd := func(amount int64) models.Money {
+		return models.Money{Amount: decimal.NewFromInt(amount), Currency: "USD"}
+	}
+
+	tests := []struct {
+		accountType string
+		wantErr     bool
+	}{
+		{accountType: "equity"},
+		{accountType: "Revenue"},
+		{accountType: "income"},
+		{accountType: "liability"},
+		{accountType: "asset", wantErr: true},
+		{accountType: "expense", wantErr: true},
+		{accountType: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accountType, func(t *testing.T) {
+			setup := setupTest(t)
+			ctx := context.Background()
+			require.NoError(t, setup.ledger.CreateAccount(ctx, models.Account{ID: "SOURCE", Type: tt.accountType, Currency: "USD"}))
+			require.NoError(t, setup.ledger.CreateAccount(ctx, models.Account{ID: "CASH", Type: "asset", Currency: "USD"}))
+
+			// Transactions recorded through the API never overdraw, whatever
+			// the account type
+			err := setup.ledger.RecordTransaction(ctx, models.Transaction{ID: "API1", DebitAccount: "SOURCE", CreditAccount: "CASH", Amount: usd(100)})
+			assert.ErrorIs(t, err, ErrInsufficientFunds)
+			err = setup.ledger.RecordTransaction(ctx, models.Transaction{ID: "API2", Postings: []models.Posting{
+				{Account: "SOURCE", Amount: usd(-50)},
+				{Account: "CASH", Amount: usd(50)},
+			}})
+			assert.ErrorIs(t, err, ErrInsufficientFunds)
+
+			// Imports apply the same rule on the transfer and postings paths
+			errTransfer := setup.ledger.ImportTransaction(ctx, models.Transaction{ID: "TX1", DebitAccount: "SOURCE", CreditAccount: "CASH", Amount: usd(100)})
+			errPostings := setup.ledger.ImportBatch(ctx, nil, []models.Transaction{{ID: "TX2", Postings: []models.Posting{
+				{Account: "SOURCE", Amount: usd(-50)},
+				{Account: "CASH", Amount: usd(50)},
+			}}})
+			if tt.wantErr {
+				assert.ErrorIs(t, errTransfer, ErrInsufficientFunds)
+				assert.ErrorIs(t, errPostings, ErrInsufficientFunds)
+				return
+			}
+			require.NoError(t, errTransfer)
+			require.NoError(t, errPostings)
+			balance, err := setup.ledger.GetAccountBalance(ctx, "SOURCE")
+			require.NoError(t, err)
+			assert.True(t, balance.Amount.Equal(decimal.NewFromInt(-150)))
+		})
+	}
+}
+
+// flatFee charges a fixed fee on every transfer debited from an asset account.
+type flatFee struct {
+	fee decimal.Decimal
+}
+
+func (f flatFee) Fees(tx models.Transaction, debit models.Account) ([]models.Posting, error) {
+	if debit.Type != "asset" {
+		return nil, nil
+	}
+	return []models.Posting{
+		{Account: debit.ID, Amount: models.Money{Amount: f.fee.Neg(), Currency: tx.Amount.Currency}},
+		{Account: "FEES", Amount: models.Money{Amount: f.fee, Currency: tx.Amount.Currency}},
+	}, nil
+}
+
+func TestRecordTransactionFees(t *testing.T) {
+	setup := setupTest(t)
+	l := newLedger(setup.validator)
+	l.fees = flatFee{fee: decimal.NewFromInt(5)}
+
+	usd := func(amount int64) models.Money {
+		return models.Money{Amount: decimal.NewFromInt(amount), Currency: setup.validCurr}
+	}
+	for _, acc := range []models.Account{
+		{ID: "CASH", Name: "Cash", Type: "asset", Currency: setup.validCurr, Balance: usd(100)},
+		{ID: "LOAN", Name: "Loan", Type: "liability", Currency: setup.validCurr, Balance: usd(100)},
+		{ID: "SHOP", Name: "Shop", Type: "revenue", Currency: setup.validCurr},
+		{ID: "FEES", Name: "Fees", Type: "revenue", Currency: setup.validCurr},
+	} {
+		require.NoError(t, l.CreateAccount(context.Background(), acc))
+	}
+
+	t.Run("preview", func(t *testing.T) {
+		legs, err := l.PreviewFees(context.Background(), models.Transaction{DebitAccount: "CASH", CreditAccount: "SHOP", Amount: usd(50)})
+		require.NoError(t, err)
+		assert.Len(t, legs, 2)
+
+		_, err = l.PreviewFees(context.Background(), models.Transaction{DebitAccount: "MISSING", CreditAccount: "SHOP", Amount: usd(50)})
+		assert.Error(t, err)
+	})
+
+	t.Run("fee is added to the entry", func(t *testing.T) {
+		require.NoError(t, l.RecordTransaction(context.Background(), models.Transaction{
+			ID: "TX1", DebitAccount: "CASH", CreditAccount: "SHOP", Amount: usd(50),
+		}))
+
+		expected := map[string]int64{"CASH": 45, "SHOP": 50, "FEES": 5}
+		for id, amount := range expected {
+			balance, err := l.GetAccountBalance(context.Background(), id)
+			require.NoError(t, err)
+			assert.True(t, balance.Amount.Equal(decimal.NewFromInt(amount)), "balance of %s", id)
+		}
+
+		history := l.GetTransactionHistory(context.Background(), "FEES")
+		require.Len(t, history, 1)
+		assert.Equal(t, "TX1", history[0].ID)
+		assert.Len(t, history[0].Postings, 4)
+	})
+
+	t.Run("fee counts towards insufficient funds", func(t *testing.T) {
+		err := l.RecordTransaction(context.Background(), models.Transaction{
+			ID: "TX2", DebitAccount: "CASH", CreditAccount: "SHOP", Amount: usd(42),
+		})
+		assert.ErrorContains(t, err, "insufficient funds in debit account CASH")
+
+		balance, err := l.GetAccountBalance(context.Background(), "CASH")
+		require.NoError(t, err)
+		assert.True(t, balance.Amount.Equal(decimal.NewFromInt(45)))
+	})
+
+	t.Run("transfers without a matching rule are unchanged", func(t *testing.T) {
+		require.NoError(t, l.RecordTransaction(context.Background(), models.Transaction{
+			ID: "TX3", DebitAccount: "LOAN", CreditAccount: "SHOP", Amount: usd(100),
+		}))
+		history := l.GetTransactionHistory(context.Background(), "LOAN")
+		require.Len(t, history, 1)
+		assert.Empty(t, history[0].Postings)
+	})
+}
+
+func TestMetadata(t *testing.T) {
+	setup := setupTest(t)
+
+	usd := func(amount int64) models.Money {
+		return models.Money{Amount: decimal.NewFromInt(amount), Currency: setup.validCurr}
+	}
+	accounts := []models.Account{
+		{ID: "CASH", Name: "Cash", Currency: setup.validCurr, Balance: usd(100),
+			Metadata: map[string]string{"cost_centre": "CC-42"}, Tags: []string{"operating"}},
+		{ID: "SHOP", Name: "Shop", Currency: setup.validCurr,
+			Metadata: map[string]string{"cost_centre": "CC-7"}},
+	}
+	for _, acc := range accounts {
+		require.NoError(t, setup.ledger.CreateAccount(context.Background(), acc))
+	}
+
+	err := setup.ledger.CreateAccount(context.Background(), models.Account{
+		ID: "BAD", Currency: setup.validCurr, Tags: []string{""},
+	})
+	assert.Error(t, err)
+
+	metadata := map[string]string{"order_id": "ORD-1"}
+	require.NoError(t, setup.ledger.RecordTransaction(context.Background(), models.Transaction{
+		ID: "TX1", DebitAccount: "CASH", CreditAccount: "SHOP", Amount: usd(10),
+		Metadata: metadata, Tags: []string{"online"},
+	}))
+	require.NoError(t, setup.ledger.RecordTransaction(context.Background(), models.Transaction{
+		ID: "TX2", DebitAccount: "CASH", CreditAccount: "SHOP", Amount: usd(10),
+	}))
+	// The ledger keeps its own copy
+	metadata["order_id"] = "changed"
+
+	err = setup.ledger.RecordTransaction(context.Background(), models.Transaction{
+		ID: "TX3", DebitAccount: "CASH", CreditAccount: "SHOP", Amount: usd(10),
+		Metadata: map[string]string{"": "value"},
+	})
+	assert.Error(t, err)
+
+	history := setup.ledger.GetTransactionHistory(context.Background(), "CASH")
+	require.Len(t, history, 2)
+	assert.Equal(t, "ORD-1", history[0].Metadata["order_id"])
+	assert.Equal(t, []string{"online"}, history[0].Tags)
+
+	found := setup.ledger.FindAccounts(context.Background(), models.MetadataFilter{Key: "cost_centre"})
+	require.Len(t, found, 2)
+	assert.Equal(t, "CASH", found[0].ID)
+	assert.Equal(t, "SHOP", found[1].ID)
+
+	found = setup.ledger.FindAccounts(context.Background(), models.MetadataFilter{Tag: "operating"})
+	require.Len(t, found, 1)
+	assert.Equal(t, "CASH", found[0].ID)
+
+	txs := setup.ledger.FindTransactions(context.Background(), models.MetadataFilter{Key: "order_id", Value: "ORD-1"})
+	require.Len(t, txs, 1)
+	assert.Equal(t, "TX1", txs[0].ID)
+	assert.Empty(t, setup.ledger.FindTransactions(context.Background(), models.MetadataFilter{Tag: "refund"}))
+
+	// Results are copies as well
+	txs[0].Metadata["order_id"] = "changed"
+	txs[0].Tags[0] = "changed"
+	history[0].Metadata["extra"] = "value"
+	found[0].Metadata["cost_centre"] = "changed"
+	found[0].Tags[0] = "changed"
+	history = setup.ledger.GetTransactionHistory(context.Background(), "CASH")
+	assert.Equal(t, map[string]string{"order_id": "ORD-1"}, history[0].Metadata)
+	assert.Equal(t, []string{"online"}, history[0].Tags)
+	found = setup.ledger.FindAccounts(context.Background(), models.MetadataFilter{Tag: "operating"})
+	require.Len(t, found, 1)
+	assert.Equal(t, "CC-42", found[0].Metadata["cost_centre"])
+}
+
+func TestEvents(t *testing.T) {
+	setup := setupTest(t)
+	l := setup.ledger
+
+	for _, acc := range []models.Account{
+		{ID: "ACC001", Name: "Account 1", Currency: "USD", Balance: models.Money{Amount: decimal.NewFromInt(100), Currency: "USD"}},
+		{ID: "ACC002", Name: "Account 2", Currency: "USD"},
+	} {
+		require.NoError(t, l.CreateAccount(context.Background(), acc))
+	}
+	require.NoError(t, l.RecordTransaction(context.Background(), models.Transaction{
+		ID:            "TX001",
+		DebitAccount:  "ACC001",
+		CreditAccount: "ACC002",
+		Amount:        models.Money{Amount: decimal.NewFromInt(10), Currency: "USD"},
+	}))
+	require.NoError(t, l.FreezeAccount(context.Background(), "ACC002"))
+
+	// Rejected changes leave no event behind
+	assert.Error(t, l.FreezeAccount(context.Background(), "ACC002"))
+	assert.Error(t, l.FreezeAccount(context.Background(), "MISSING"))
+	err := l.RecordTransaction(context.Background(), models.Transaction{
+		ID:            "TX002",
+		DebitAccount:  "ACC001",
+		CreditAccount: "ACC002",
+		Amount:        models.Money{Amount: decimal.NewFromInt(10), Currency: "USD"},
+	})
+	assert.ErrorContains(t, err, "frozen")
+
+	require.NoError(t, l.UnfreezeAccount(context.Background(), "ACC002"))
+
+	events := l.Events(0, 0)
+	require.Len(t, events, 5)
+	types := make([]string, len(events))
+	for i, event := range events {
+		assert.Equal(t, uint64(i+1), event.Sequence)
+		types[i] = event.Type
+	}
+	assert.Equal(t, []string{
+		models.EventAccountCreated,
+		models.EventAccountCreated,
+		models.EventTransactionRecorded,
+		models.EventAccountFrozen,
+		models.EventAccountUnfrozen,
+	}, types)
+	assert.Equal(t, []string{"ACC001", "ACC002"}, events[2].Accounts)
+
+	// Cursor and limit
+	page := l.Events(2, 2)
+	require.Len(t, page, 2)
+	assert.Equal(t, uint64(3), page[0].Sequence)
+	assert.Empty(t, l.Events(5, 10))
+
+	// A failed balance check is recorded as well
+	require.Error(t, l.(*ledger).checkBalance(context.Background()))
+	last := l.Events(5, 0)
+	require.Len(t, last, 1)
+	assert.Equal(t, models.EventBalanceCheckFailed, last[0].Type)
+}
+
+func TestTransactionSequence(t *testing.T) {
+	setup := setupTest(t)
+	l := setup.ledger
+
+	for _, acc := range []models.Account{
+		{ID: "ACC001", Name: "Account 1", Currency: "USD", Balance: models.Money{Amount: decimal.NewFromInt(100), Currency: "USD"}},
+		{ID: "ACC002", Name: "Account 2", Currency: "USD"},
+	} {
+		require.NoError(t, l.CreateAccount(context.Background(), acc))
+	}
+	assert.Equal(t, uint64(2), l.LastSequence())
+
+	// A sequence sent by the client is replaced
+	require.NoError(t, l.RecordTransaction(context.Background(), models.Transaction{
+		ID:            "TX001",
+		Sequence:      99,
+		DebitAccount:  "ACC001",
+		CreditAccount: "ACC002",
+		Amount:        models.Money{Amount: decimal.NewFromInt(10), Currency: "USD"},
+	}))
+	require.NoError(t, l.FreezeAccount(context.Background(), "ACC001"))
+	require.NoError(t, l.UnfreezeAccount(context.Background(), "ACC001"))
+	require.NoError(t, l.RecordTransaction(context.Background(), models.Transaction{
+		ID: "TX002",
+		Postings: []models.Posting{
+			{Account: "ACC001", Amount: models.Money{Amount: decimal.NewFromInt(-5), Currency: "USD"}},
+			{Account: "ACC002", Amount: models.Money{Amount: decimal.NewFromInt(5), Currency: "USD"}},
+		},
+	}))
+
+	history := l.GetTransactionHistory(context.Background(), "ACC001")
+	require.Len(t, history, 2)
+	assert.Equal(t, uint64(3), history[0].Sequence)
+	assert.Equal(t, uint64(6), history[1].Sequence)
+	assert.Equal(t, uint64(6), l.LastSequence())
+
+	// The journal entry in the change log carries the same sequence
+	events := l.Events(5, 0)
+	require.Len(t, events, 1)
+	tx, ok := events[0].Data.(models.Transaction)
+	require.True(t, ok)
+	assert.Equal(t, events[0].Sequence, tx.Sequence)
+}
+
+func TestImportTransaction(t *testing.T) {
+	setup := setupTest(t)
+	l := setup.ledger
+	ctx := context.Background()
+	for _, acc := range []models.Account{
+		{ID: "ACC001", Currency: "USD", Balance: models.Money{Amount: decimal.NewFromInt(100), Currency: "USD"}},
+		{ID: "ACC002", Currency: "USD"},
+	} {
+		require.NoError(t, l.CreateAccount(ctx, acc))
+	}
+	booked := time.Date(2023, 12, 31, 0, 0, 0, 0, time.UTC)
+	transfer := func(id string, date time.Time) models.Transaction {
+		return models.Transaction{ID: id, DateTime: date, DebitAccount: "ACC001", CreditAccount: "ACC002",
+			Amount: models.Money{Amount: decimal.NewFromInt(10), Currency: "USD"}}
+	}
+
+	before := time.Now().UTC()
+	require.NoError(t, l.ImportTransaction(ctx, transfer("TX001", booked)))
+	require.NoError(t, l.ImportTransaction(ctx, transfer("TX002", time.Time{})))
+	require.NoError(t, l.RecordTransaction(ctx, transfer("TX003", booked)))
+
+	history := l.GetTransactionHistory(ctx, "ACC001")
+	require.Len(t, history, 3)
+	assert.Equal(t, booked, history[0].DateTime, "imports keep their date")
+	assert.False(t, history[1].DateTime.Before(before), "undated imports are dated now")
+	assert.False(t, history[2].DateTime.Before(before), "recorded transactions are dated now")
+}
+
+func TestImportBatch(t *testing.T) {
+	setup := setupTest(t)
+	l := setup.ledger
+	ctx := context.Background()
+	usd := func(amount int64) models.Money {
+		return models.Money{Amount: decimal.NewFromInt(amount), Currency: "USD"}
+	}
+	require.NoError(t, l.CreateAccount(ctx, models.Account{ID: "bank", Type: "Asset", Currency: "USD"}))
+	accounts := []models.Account{
+		{ID: "bank", Type: "Asset", Currency: "USD"},
+		{ID: "opening", Type: "Equity", Currency: "USD"},
+		{ID: "rent", Type: "Expense", Currency: "USD"},
+	}
+	opening := models.Transaction{ID: "TX001", DebitAccount: "opening", CreditAccount: "bank", Amount: usd(100)}
+	rent := models.Transaction{ID: "TX002", DebitAccount: "bank", CreditAccount: "rent", Amount: usd(500)}
+
+	// A rejected transaction leaves the book as it was
+	err := l.ImportBatch(ctx, accounts, []models.Transaction{opening, rent})
+	assert.ErrorIs(t, err, ErrInsufficientFunds)
+	_, err = l.GetAccountBalance(ctx, "opening")
+	assert.ErrorIs(t, err, ErrAccountNotFound)
+	assert.Empty(t, l.GetTransactionHistory(ctx, "bank"))
+	assert.Equal(t, uint64(1), l.LastSequence(), "only the bank account was created")
+
+	rent.Amount = usd(60)
+	require.NoError(t, l.ImportBatch(ctx, accounts, []models.Transaction{opening, rent}))
+	balance, err := l.GetAccountBalance(ctx, "bank")
+	require.NoError(t, err)
+	assert.True(t, balance.Amount.Equal(decimal.NewFromInt(40)))
+
+	// Transaction IDs are unique, within a batch and across the book
+	var ledgerErr *Error
+	err = l.ImportBatch(ctx, nil, []models.Transaction{opening})
+	require.ErrorAs(t, err, &ledgerErr)
+	assert.ErrorIs(t, err, ErrDuplicate)
+	assert.Equal(t, "TX001", ledgerErr.TransactionID)
+	assert.ErrorIs(t, l.RecordTransaction(ctx, rent), ErrDuplicate)
+	third := models.Transaction{ID: "TX003", DebitAccount: "opening", CreditAccount: "bank", Amount: usd(1)}
+	assert.ErrorIs(t, l.ImportBatch(ctx, nil, []models.Transaction{third, third}), ErrDuplicate)
+	assert.Len(t, l.GetTransactionHistory(ctx, "bank"), 2)
+}
+
+func TestStats(t *testing.T) {
+	setup := setupTest(t)
+	assert.Nil(t, setup.ledger.Stats().LastBalanceCheck)
+
+	require.NoError(t, setup.ledger.CreateAccount(context.Background(), models.Account{ID: "ACC001", Name: "Cash", Currency: "USD"}))
+	require.NoError(t, setup.ledger.CreateAccount(context.Background(), models.Account{ID: "ACC002", Name: "Loan", Currency: "USD",
+		Balance: models.Money{Amount: decimal.NewFromInt(10), Currency: "USD"}}))
+	require.Error(t, setup.ledger.VerifyLedgerBalance(context.Background()))
+
+	stats := setup.ledger.Stats()
+	assert.Equal(t, 2, stats.Accounts)
+	assert.Equal(t, 0, stats.Transactions)
+	assert.Equal(t, uint64(2), stats.Events)
+	require.NotNil(t, stats.LastBalanceCheck)
+	assert.ErrorContains(t, stats.LastBalanceCheck.Err, "ledger is unbalanced")
+	assert.False(t, stats.LastBalanceCheck.At.IsZero())
+}
+
+func TestErrorKinds(t *testing.T) {
+	setup := setupTest(t)
+	ctx := context.Background()
+	usd := func(amount int64) models.Money {
+		return models.Money{Amount: decimal.NewFromInt(amount), Currency: "USD"}
+	}
+	require.NoError(t, setup.ledger.CreateAccount(ctx, models.Account{ID: "ACC001", Name: "Cash", Currency: "USD", Balance: usd(100)}))
+	require.NoError(t, setup.ledger.CreateAccount(ctx, models.Account{ID: "ACC002", Name: "Revenue", Currency: "USD"}))
+	require.NoError(t, setup.ledger.CreateAccount(ctx, models.Account{ID: "ACC003", Name: "Euro", Currency: "EUR"}))
+
+	tests := []struct {
+		name        string
+		call        func() error
+		wantKind    error
+		wantAccount string
+	}{
+		{
+			name:        "duplicate account",
+			call:        func() error { return setup.ledger.CreateAccount(ctx, models.Account{ID: "ACC001", Currency: "USD"}) },
+			wantKind:    ErrDuplicate,
+			wantAccount: "ACC001",
+		},
+		{
+			name:        "invalid currency",
+			call:        func() error { return setup.ledger.CreateAccount(ctx, models.Account{ID: "ACC009", Currency: "XXX"}) },
+			wantKind:    ErrInvalidCurrency,
+			wantAccount: "ACC009",
+		},
+		{
+			name: "unknown account",
+			call: func() error {
+				return setup.ledger.RecordTransaction(ctx, models.Transaction{ID: "TX1", DebitAccount: "ACC999", CreditAccount: "ACC002", Amount: usd(1)})
+			},
+			wantKind:    ErrAccountNotFound,
+			wantAccount: "ACC999",
+		},
+		{
+			name: "currency mismatch",
+			call: func() error {
+				return setup.ledger.RecordTransaction(ctx, models.Transaction{ID: "TX2", DebitAccount: "ACC001", CreditAccount: "ACC003", Amount: usd(1)})
+			},
+			wantKind: ErrCurrencyMismatch,
+		},
+		{
+			name: "insufficient funds",
+			call: func() error {
+				return setup.ledger.RecordTransaction(ctx, models.Transaction{ID: "TX3", Postings: []models.Posting{
+					{Account: "ACC002", Amount: usd(-5)},
+					{Account: "ACC001", Amount: usd(5)},
+				}})
+			},
+			wantKind:    ErrInsufficientFunds,
+			wantAccount: "ACC002",
+		},
+		{
+			name: "unbalanced postings",
+			call: func() error {
+				return setup.ledger.RecordTransaction(ctx, models.Transaction{ID: "TX4", Postings: []models.Posting{
+					{Account: "ACC001", Amount: usd(-5)},
+					{Account: "ACC002", Amount: usd(4)},
+				}})
+			},
+			wantKind: ErrUnbalanced,
+		},
+		{
+			name:        "not frozen",
+			call:        func() error { return setup.ledger.UnfreezeAccount(ctx, "ACC001") },
+			wantKind:    ErrAccountNotFrozen,
+			wantAccount: "ACC001",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.call()
+			require.ErrorIs(t, err, tt.wantKind)
+			var ledgerErr *Error
+			require.ErrorAs(t, err, &ledgerErr)
+			assert.Equal(t, tt.wantAccount, ledgerErr.AccountID)
+		})
+	}
+}
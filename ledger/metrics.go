@@ -0,0 +1,57 @@
+package ledger
+
+import (
+	"time"
+)
+
+// Outcomes of a transaction attempt, as reported to Metrics.
+const (
+	outcomeRecorded          = "recorded"
+	outcomeInvalid           = "invalid"
+	outcomeDuplicate         = "duplicate"
+	outcomeUnknownAccount    = "unknown_account"
+	outcomeFrozenAccount     = "frozen_account"
+	outcomeCurrencyMismatch  = "currency_mismatch"
+	outcomeInsufficientFunds = "insufficient_funds"
+	outcomeUnbalanced        = "unbalanced"
+)
+
+// Lock modes, as reported to Metrics.
+const (
+	lockWrite = "write"
+	lockRead  = "read"
+)
+
+type nopMetrics struct{}
+
+func (nopMetrics) TransactionAttempted(string)          {}
+func (nopMetrics) LockWaited(string, time.Duration)     {}
+func (nopMetrics) BookSize(int, int)                    {}
+func (nopMetrics) BalanceVerified(time.Duration, error) {}
+
+// lock takes the write lock and reports how long it took to get it.
+func (l *ledger) lock() {
+	start := time.Now()
+	l.mu.Lock()
+	l.metrics.LockWaited(lockWrite, time.Since(start))
+}
+
+// rlock takes the read lock and reports how long it took to get it.
+func (l *ledger) rlock() {
+	start := time.Now()
+	l.mu.RLock()
+	l.metrics.LockWaited(lockRead, time.Since(start))
+}
+
+// reject reports a failed transaction attempt and returns its error.
+func (l *ledger) reject(outcome string, err error) error {
+	l.metrics.TransactionAttempted(outcome)
+	return err
+}
+
+// recorded reports a committed transaction. The caller must hold the write
+// lock.
+func (l *ledger) recorded() {
+	l.metrics.TransactionAttempted(outcomeRecorded)
+	l.metrics.BookSize(len(l.accounts), len(l.transactions))
+}
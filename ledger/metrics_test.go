@@ -0,0 +1,106 @@
+package ledger
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ledgerproject/models"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingMetrics keeps what the ledger reports.
+type recordingMetrics struct {
+	outcomes     []string
+	lockWaits    map[string]int
+	accounts     int
+	transactions int
+	verified     []error
+	mu           sync.Mutex
+}
+
+func (m *recordingMetrics) TransactionAttempted(outcome string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.outcomes = append(m.outcomes, outcome)
+}
+
+func (m *recordingMetrics) LockWaited(mode string, _ time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lockWaits[mode]++
+}
+
+func (m *recordingMetrics) BookSize(accounts, transactions int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.accounts, m.transactions = accounts, transactions
+}
+
+func (m *recordingMetrics) BalanceVerified(_ time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.verified = append(m.verified, err)
+}
+
+func TestMetrics(t *testing.T) {
+	setup := setupTest(t)
+	metrics := &recordingMetrics{lockWaits: make(map[string]int)}
+	l := newLedger(setup.validator)
+	l.metrics = metrics
+
+	usd := func(amount int64) models.Money {
+		return models.Money{Amount: decimal.NewFromInt(amount), Currency: "USD"}
+	}
+	require.NoError(t, l.CreateAccount(context.Background(), models.Account{ID: "ACC001", Name: "Cash", Currency: "USD", Balance: usd(100)}))
+	require.NoError(t, l.CreateAccount(context.Background(), models.Account{ID: "ACC002", Name: "Revenue", Currency: "USD"}))
+	require.NoError(t, l.CreateAccount(context.Background(), models.Account{ID: "ACC003", Name: "Euro", Currency: "EUR"}))
+
+	tests := []struct {
+		name        string
+		tx          models.Transaction
+		wantOutcome string
+	}{
+		{
+			name:        "recorded",
+			tx:          models.Transaction{ID: "TX001", DebitAccount: "ACC001", CreditAccount: "ACC002", Amount: usd(40)},
+			wantOutcome: outcomeRecorded,
+		},
+		{
+			name:        "unknown account",
+			tx:          models.Transaction{ID: "TX002", DebitAccount: "ACC001", CreditAccount: "ACC999", Amount: usd(10)},
+			wantOutcome: outcomeUnknownAccount,
+		},
+		{
+			name:        "currency mismatch",
+			tx:          models.Transaction{ID: "TX003", DebitAccount: "ACC001", CreditAccount: "ACC003", Amount: usd(10)},
+			wantOutcome: outcomeCurrencyMismatch,
+		},
+		{
+			name:        "insufficient funds",
+			tx:          models.Transaction{ID: "TX004", DebitAccount: "ACC001", CreditAccount: "ACC002", Amount: usd(500)},
+			wantOutcome: outcomeInsufficientFunds,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_ = l.RecordTransaction(context.Background(), tt.tx)
+			metrics.mu.Lock()
+			defer metrics.mu.Unlock()
+			assert.Equal(t, tt.wantOutcome, metrics.outcomes[len(metrics.outcomes)-1])
+		})
+	}
+
+	assert.Equal(t, 3, metrics.accounts)
+	assert.Equal(t, 1, metrics.transactions)
+	assert.Positive(t, metrics.lockWaits[lockWrite])
+
+	// The opening balance leaves the books unbalanced
+	assert.Error(t, l.VerifyLedgerBalance(context.Background()))
+	assert.Positive(t, metrics.lockWaits[lockRead])
+	require.Len(t, metrics.verified, 1)
+	assert.Error(t, metrics.verified[0])
+}
@@ -0,0 +1,77 @@
+package ledger
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	"ledgerproject/models"
+	"testing"
+)
+
+func TestRecordTransactionSpans(t *testing.T) {
+	setup := setupTest(t)
+	usd := func(amount int64) models.Money {
+		return models.Money{Amount: decimal.NewFromInt(amount), Currency: "USD"}
+	}
+	require.NoError(t, setup.ledger.CreateAccount(context.Background(), models.Account{ID: "ACC001", Name: "Cash", Currency: "USD", Balance: usd(100)}))
+	require.NoError(t, setup.ledger.CreateAccount(context.Background(), models.Account{ID: "ACC002", Name: "Revenue", Currency: "USD"}))
+
+	tests := []struct {
+		name       string
+		tx         models.Transaction
+		wantStages []string
+		wantStatus codes.Code
+	}{
+		{
+			name:       "recorded",
+			tx:         models.Transaction{ID: "TX001", DebitAccount: "ACC001", CreditAccount: "ACC002", Amount: usd(40)},
+			wantStages: []string{"ledger.lock_wait", "ledger.validate", "ledger.apply", "ledger.persist"},
+			wantStatus: codes.Unset,
+		},
+		{
+			name: "recorded with postings",
+			tx: models.Transaction{ID: "TX002", Postings: []models.Posting{
+				{Account: "ACC001", Amount: usd(-10)},
+				{Account: "ACC002", Amount: usd(10)},
+			}},
+			wantStages: []string{"ledger.lock_wait", "ledger.validate", "ledger.apply", "ledger.persist"},
+			wantStatus: codes.Unset,
+		},
+		{
+			name:       "rejected",
+			tx:         models.Transaction{ID: "TX003", DebitAccount: "ACC001", CreditAccount: "ACC002", Amount: usd(500)},
+			wantStages: []string{"ledger.lock_wait", "ledger.validate"},
+			wantStatus: codes.Error,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+			t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+			_ = setup.ledger.RecordTransaction(context.Background(), tt.tx)
+
+			ended := recorder.Ended()
+			require.Len(t, ended, len(tt.wantStages)+1)
+			operation := ended[len(ended)-1]
+			assert.Equal(t, "ledger.RecordTransaction", operation.Name())
+			assert.Equal(t, tt.wantStatus, operation.Status().Code)
+
+			var stages []string
+			for _, span := range ended[:len(ended)-1] {
+				stages = append(stages, span.Name())
+				assert.Equal(t, operation.SpanContext().SpanID(), span.Parent().SpanID())
+			}
+			assert.Equal(t, tt.wantStages, stages)
+			assert.Equal(t, tt.wantStatus, ended[len(ended)-2].Status().Code, "the failing stage is marked")
+		})
+	}
+}
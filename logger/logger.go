@@ -1,36 +1,53 @@
 package logger
 
 import (
+	"context"
+	"fmt"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"ledgerproject/config"
-	"os"
-	"strings"
 )
 
 var log *zap.Logger
 
-// NewLogger creates a new zap logger instance based on the provided config
+// NewLogger creates the global logger with the level and format of the
+// configuration.
 func NewLogger(cfg *config.Config) (*zap.Logger, error) {
-	isDevelopment := strings.ToLower(os.Getenv("APP_ENV")) != "production"
-	if err := Init(isDevelopment); err != nil {
+	if err := Configure(cfg.LogLevel, cfg.LogFormat); err != nil {
 		return nil, err
 	}
 	return Get(), nil
 }
 
+// Init creates the global logger with debug level console output, or for
+// production with info level JSON output.
 func Init(isDevelopment bool) error {
-	var cfg zap.Config
 	if isDevelopment {
-		// Colored console output with debug level
+		return Configure("debug", "console")
+	}
+	return Configure("info", "json")
+}
+
+// Configure creates the global logger. The format is "console" for coloured
+// text or "json".
+func Configure(level, format string) error {
+	lvl, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+
+	var cfg zap.Config
+	switch format {
+	case "console":
 		cfg = zap.NewDevelopmentConfig()
 		cfg.EncoderConfig.EncodeLevel = zapcore.CapitalColorLevelEncoder
-	} else {
-		// JSON format with info level
+	case "json":
 		cfg = zap.NewProductionConfig()
+	default:
+		return fmt.Errorf("invalid log format %q", format)
 	}
+	cfg.Level = zap.NewAtomicLevelAt(lvl)
 
-	var err error
 	log, err = cfg.Build()
 	if err != nil {
 		return err
@@ -48,3 +65,20 @@ func Get() *zap.Logger {
 func Sync() error {
 	return log.Sync()
 }
+
+type contextKey struct{}
+
+// WithContext returns a copy of ctx carrying log, so that code serving the
+// same request logs with the same fields.
+func WithContext(ctx context.Context, log *zap.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, log)
+}
+
+// FromContext returns the logger stored in ctx, or the global logger when
+// there is none.
+func FromContext(ctx context.Context) *zap.Logger {
+	if log, ok := ctx.Value(contextKey{}).(*zap.Logger); ok {
+		return log
+	}
+	return Get()
+}
@@ -2,45 +2,102 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"go.uber.org/fx"
 	"go.uber.org/fx/fxevent"
 	"go.uber.org/zap"
 	"ledgerproject/api"
+	"ledgerproject/audit"
+	"ledgerproject/auth"
+	"ledgerproject/cli"
 	"ledgerproject/config"
+	"ledgerproject/fees"
+	"ledgerproject/grpcapi"
+	"ledgerproject/importer"
+	"ledgerproject/interest"
 	"ledgerproject/ledger"
 	"ledgerproject/logger"
+	"ledgerproject/metrics"
+	"ledgerproject/reconciliation"
+	"ledgerproject/scheduler"
 	"ledgerproject/services"
+	"ledgerproject/tenants"
+	"ledgerproject/tlsconfig"
+	"ledgerproject/tracing"
+	"ledgerproject/webhooks"
+	"net"
+	"net/http"
 	"os"
 	"strings"
 	"time"
 )
 
-// getEnvironmentOption returns the appropriate fx.Option based on the environment
-func getEnvironmentOption() fx.Option {
-	env := strings.ToLower(os.Getenv("APP_ENV"))
+// Version and BuildTime are set at link time by the Makefile.
+var (
+	Version   = "dev"
+	BuildTime = "unknown"
+)
 
-	switch env {
-	case "test":
-		return config.Test()
-	case "prod", "production":
-		return config.Production()
+// main runs the server when called without arguments, with flags or with
+// "serve", and otherwise the command-line subcommand named by the first
+// argument.
+func main() {
+	args := os.Args[1:]
+	switch {
+	case len(args) == 0 || strings.HasPrefix(args[0], "-"):
+		serve(args)
+	case args[0] == "serve":
+		serve(args[1:])
 	default:
-		// Default to development if environment is not specified or unknown
-		return config.Development()
+		os.Exit(cli.Run(args, os.Stdout, os.Stderr))
 	}
 }
 
-func main() {
+// serve runs the server with the configuration of APP_ENV, overridden by the
+// configuration file, the environment and the flags in args.
+func serve(args []string) {
+	fs := flag.NewFlagSet("ledger serve", flag.ExitOnError)
+	flags := config.RegisterFlags(fs)
+	_ = fs.Parse(args)
+	if fs.NArg() > 0 {
+		fmt.Fprintf(os.Stderr, "unexpected argument %q\n", fs.Arg(0))
+		os.Exit(2)
+	}
+	cfg, err := flags.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+
 	app := fx.New(
-		// Use environment-specific config based on APP_ENV
-		getEnvironmentOption(),
+		fx.Supply(cfg),
+
+		fx.Supply(api.BuildInfo{Version: Version, BuildTime: BuildTime}),
 
 		// Provide core dependencies
 		fx.Provide(
 			logger.NewLogger,
+			tracing.NewProvider,
 			services.NewCurrencyValidator,
-			ledger.NewLedger,
+			fees.NewEngine,
+			feeSchedule,
+			metrics.New,
+			ledgerMetrics,
+			newLedger,
+			importer.NewImporter,
+			reconciliation.NewStore,
+			reconciliation.NewService,
+			interest.NewEngine,
+			scheduler.NewScheduler,
+			webhooks.NewDispatcher,
+			auth.NewAuthenticator,
+			tlsconfig.New,
+			audit.NewLog,
+			tenants.NewRegistry,
 			api.NewServer,
+			grpcapi.NewServer,
 		),
 
 		// Register lifecycle hooks
@@ -65,35 +122,111 @@ func main() {
 	<-app.Done()
 }
 
-func registerHooks(lc fx.Lifecycle, server *api.Server, log *zap.Logger) {
+// feeSchedule lets the ledger charge fees from the fee engine.
+func feeSchedule(e *fees.Engine) ledger.FeeSchedule {
+	return e
+}
+
+// newLedger creates the default tenant's ledger.
+func newLedger(cv *services.CurrencyValidator, fees ledger.FeeSchedule, m ledger.Metrics, cfg *config.Config) ledger.LedgerService {
+	return ledger.NewLedger(cv, fees, m, cfg.BalanceCheckInterval)
+}
+
+// ledgerMetrics measures the default tenant's ledger.
+func ledgerMetrics(m *metrics.Metrics) ledger.Metrics {
+	return m.Ledger(tenants.DefaultID)
+}
+
+func registerHooks(lc fx.Lifecycle, server *api.Server, grpcServer *grpcapi.Server, accruals *interest.Engine,
+	orders *scheduler.Scheduler, dispatcher *webhooks.Dispatcher, trail *audit.Log, registry *tenants.Registry,
+	tracer *tracing.Provider, log *zap.Logger) {
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+
 	lc.Append(fx.Hook{
 		OnStart: func(ctx context.Context) error {
+			// Bind the ports before serving, so that a taken port fails the
+			// start instead of leaving a server that is ready but unreachable
+			lis, err := server.Listen()
+			if err != nil {
+				log.Error("Failed to listen", zap.Error(err))
+				return err
+			}
+			var grpcLis net.Listener
+			if grpcServer.Enabled() {
+				if grpcLis, err = grpcServer.Listen(); err != nil {
+					lis.Close()
+					log.Error("Failed to listen for gRPC", zap.Error(err))
+					return err
+				}
+			}
+
 			log.Info("Starting server")
-			// Start server in a goroutine
 			go func() {
-				if err := server.Start(); err != nil {
-					log.Error("Failed to start server", zap.Error(err))
+				if err := server.Serve(lis); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					log.Error("Server stopped", zap.Error(err))
 				}
 			}()
+
+			if grpcLis != nil {
+				log.Info("Starting gRPC server")
+				go func() {
+					if err := grpcServer.Serve(grpcLis); err != nil {
+						log.Error("gRPC server stopped", zap.Error(err))
+					}
+				}()
+			}
+
+			log.Info("Starting interest accrual")
+			go accruals.Run(jobsCtx)
+
+			log.Info("Starting standing order scheduler")
+			go orders.Run(jobsCtx)
+
+			log.Info("Starting webhook dispatcher")
+			go dispatcher.Run(jobsCtx)
+
+			log.Info("Starting tenant jobs")
+			go registry.Run(jobsCtx)
+
+			// The constructors have loaded the currencies and replayed the
+			// audit trails, and the listeners are bound
+			server.SetReady(true)
 			return nil
 		},
 		OnStop: func(ctx context.Context) error {
 			log.Info("Stopping server")
+			server.SetReady(false)
+			stopJobs()
 
 			// Graceful shutdown with timeout
 			shutdownCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 			defer cancel()
 
-			if err := server.Shutdown(shutdownCtx); err != nil {
-				log.Error("Server shutdown error", zap.Error(err))
-				return err
+			// A failed shutdown is reported once the rest is torn down
+			shutdownErr := server.Shutdown(shutdownCtx)
+			if shutdownErr != nil {
+				log.Error("Server shutdown error", zap.Error(shutdownErr))
+			}
+			if err := grpcServer.Shutdown(shutdownCtx); err != nil {
+				log.Error("gRPC server shutdown error", zap.Error(err))
+			}
+
+			if err := trail.Close(); err != nil {
+				log.Error("Failed to close audit log", zap.Error(err))
+			}
+			if err := registry.Close(); err != nil {
+				log.Error("Failed to close tenant audit logs", zap.Error(err))
+			}
+
+			if err := tracer.Shutdown(shutdownCtx); err != nil {
+				log.Error("Failed to flush traces", zap.Error(err))
 			}
 
 			if err := logger.Sync(); err != nil {
 				log.Error("Failed to sync logger", zap.Error(err))
 			}
 
-			return nil
+			return shutdownErr
 		},
 	})
 }
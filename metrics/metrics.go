@@ -0,0 +1,148 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const namespace = "ledger"
+
+// lockBuckets resolve the sub-millisecond waits of an idle ledger as well as
+// the long ones behind a large import.
+var lockBuckets = prometheus.ExponentialBuckets(0.00001, 4, 10)
+
+// Metrics collects the measurements of the HTTP API and of every tenant's
+// ledger, and serves them in the Prometheus text format.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	transactions       *prometheus.CounterVec
+	lockWait           *prometheus.HistogramVec
+	accountCount       *prometheus.GaugeVec
+	transactionCount   *prometheus.GaugeVec
+	balanceCheckOK     *prometheus.GaugeVec
+	balanceCheckTime   *prometheus.GaugeVec
+	balanceCheckLastAt *prometheus.GaugeVec
+}
+
+// New returns the metrics on a registry of their own, along with the Go
+// runtime and process metrics.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests served, by route, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "Time taken to serve HTTP requests, by route, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		transactions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "transaction_attempts_total",
+			Help:      "Transactions submitted to the ledger, by outcome.",
+		}, []string{"tenant", "outcome"}),
+		lockWait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "lock_wait_seconds",
+			Help:      "Time spent waiting for the ledger lock, by lock mode.",
+			Buckets:   lockBuckets,
+		}, []string{"tenant", "mode"}),
+		accountCount: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "accounts",
+			Help:      "Accounts in the ledger.",
+		}, []string{"tenant"}),
+		transactionCount: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "transactions",
+			Help:      "Transactions recorded in the ledger.",
+		}, []string{"tenant"}),
+		balanceCheckOK: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "balance_check_success",
+			Help:      "Whether the last balance check found the ledger balanced (1) or not (0).",
+		}, []string{"tenant"}),
+		balanceCheckTime: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "balance_check_duration_seconds",
+			Help:      "Duration of the last balance check.",
+		}, []string{"tenant"}),
+		balanceCheckLastAt: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "balance_check_timestamp_seconds",
+			Help:      "Unix time the last balance check finished.",
+		}, []string{"tenant"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests, m.httpDuration,
+		m.transactions, m.lockWait, m.accountCount, m.transactionCount,
+		m.balanceCheckOK, m.balanceCheckTime, m.balanceCheckLastAt,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest counts a served HTTP request. Route is the route's path
+// template, so that requests for different accounts share a series.
+func (m *Metrics) ObserveRequest(route, method string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	m.httpRequests.WithLabelValues(route, method, code).Inc()
+	m.httpDuration.WithLabelValues(route, method, code).Observe(duration.Seconds())
+}
+
+// Ledger returns the measurements of a tenant's ledger.
+func (m *Metrics) Ledger(tenant string) *LedgerMetrics {
+	return &LedgerMetrics{metrics: m, tenant: tenant}
+}
+
+// LedgerMetrics records the measurements of one tenant's ledger.
+type LedgerMetrics struct {
+	metrics *Metrics
+	tenant  string
+}
+
+// TransactionAttempted counts a transaction by its outcome.
+func (l *LedgerMetrics) TransactionAttempted(outcome string) {
+	l.metrics.transactions.WithLabelValues(l.tenant, outcome).Inc()
+}
+
+// LockWaited observes the time spent waiting for the ledger lock.
+func (l *LedgerMetrics) LockWaited(mode string, wait time.Duration) {
+	l.metrics.lockWait.WithLabelValues(l.tenant, mode).Observe(wait.Seconds())
+}
+
+// BookSize records the number of accounts and transactions in the ledger.
+func (l *LedgerMetrics) BookSize(accounts, transactions int) {
+	l.metrics.accountCount.WithLabelValues(l.tenant).Set(float64(accounts))
+	l.metrics.transactionCount.WithLabelValues(l.tenant).Set(float64(transactions))
+}
+
+// BalanceVerified records the result and duration of a balance check.
+func (l *LedgerMetrics) BalanceVerified(duration time.Duration, err error) {
+	ok := 1.0
+	if err != nil {
+		ok = 0
+	}
+	l.metrics.balanceCheckOK.WithLabelValues(l.tenant).Set(ok)
+	l.metrics.balanceCheckTime.WithLabelValues(l.tenant).Set(duration.Seconds())
+	l.metrics.balanceCheckLastAt.WithLabelValues(l.tenant).SetToCurrentTime()
+}
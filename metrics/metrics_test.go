@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"errors"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestObserveRequest(t *testing.T) {
+	m := New()
+	m.ObserveRequest("/accounts/{accountId}/balance", "GET", http.StatusOK, 20*time.Millisecond)
+	m.ObserveRequest("/accounts/{accountId}/balance", "GET", http.StatusOK, 30*time.Millisecond)
+	m.ObserveRequest("/accounts/{accountId}/balance", "GET", http.StatusNotFound, time.Millisecond)
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("/accounts/{accountId}/balance", "GET", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("/accounts/{accountId}/balance", "GET", "404")))
+	assert.Equal(t, 2, testutil.CollectAndCount(m.httpDuration))
+}
+
+func TestLedgerMetrics(t *testing.T) {
+	m := New()
+	retail := m.Ledger("retail")
+	cards := m.Ledger("cards")
+
+	retail.TransactionAttempted("recorded")
+	retail.TransactionAttempted("insufficient_funds")
+	cards.TransactionAttempted("recorded")
+	retail.BookSize(3, 7)
+	retail.LockWaited("write", time.Millisecond)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.transactions.WithLabelValues("retail", "insufficient_funds")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.transactions.WithLabelValues("cards", "recorded")))
+	assert.Equal(t, 3.0, testutil.ToFloat64(m.accountCount.WithLabelValues("retail")))
+	assert.Equal(t, 7.0, testutil.ToFloat64(m.transactionCount.WithLabelValues("retail")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.lockWait))
+
+	retail.BalanceVerified(5*time.Millisecond, nil)
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.balanceCheckOK.WithLabelValues("retail")))
+	assert.Equal(t, 0.005, testutil.ToFloat64(m.balanceCheckTime.WithLabelValues("retail")))
+	assert.Greater(t, testutil.ToFloat64(m.balanceCheckLastAt.WithLabelValues("retail")), 0.0)
+
+	retail.BalanceVerified(time.Millisecond, errors.New("ledger is unbalanced"))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.balanceCheckOK.WithLabelValues("retail")))
+}
+
+func TestHandler(t *testing.T) {
+	m := New()
+	m.Ledger("default").TransactionAttempted("currency_mismatch")
+
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	body := rr.Body.String()
+	assert.Contains(t, body, `ledger_transaction_attempts_total{outcome="currency_mismatch",tenant="default"} 1`)
+	assert.True(t, strings.Contains(body, "go_goroutines"), "runtime metrics are exported")
+}
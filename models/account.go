@@ -3,10 +3,13 @@ package models
 import "time"
 
 type Account struct {
-    ID             string    `json:"id"`
-    Name           string    `json:"name"`
-    Balance        Money     `json:"balance"`
-    Type           string    `json:"type"`
-    Currency       string    `json:"currency"`
-    CreateDateTime time.Time `json:"datetime"`
+    ID             string            `json:"id"`
+    Name           string            `json:"name"`
+    Balance        Money             `json:"balance"`
+    Type           string            `json:"type"`
+    Currency       string            `json:"currency"`
+    CreateDateTime time.Time         `json:"datetime"`
+    Frozen         bool              `json:"frozen,omitempty"`
+    Metadata       map[string]string `json:"metadata,omitempty"`
+    Tags           []string          `json:"tags,omitempty"`
 }
@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// Ledger event types.
+const (
+	EventAccountCreated      = "account.created"
+	EventAccountFrozen       = "account.frozen"
+	EventAccountUnfrozen     = "account.unfrozen"
+	EventTransactionRecorded = "transaction.recorded"
+	EventBalanceCheckFailed  = "balance.check_failed"
+)
+
+// KnownEventType reports whether t is one of the ledger event types.
+func KnownEventType(t string) bool {
+	switch t {
+	case EventAccountCreated, EventAccountFrozen, EventAccountUnfrozen,
+		EventTransactionRecorded, EventBalanceCheckFailed:
+		return true
+	}
+	return false
+}
+
+// Event is a change published by the ledger. Sequence numbers are assigned
+// in commit order, start at 1 and have no gaps. Accounts lists the accounts
+// the event concerns; Data holds the account, the transaction or, for
+// balance.check_failed, a BalanceCheckFailure.
+type Event struct {
+	Sequence uint64      `json:"sequence"`
+	ID       string      `json:"id"`
+	Type     string      `json:"type"`
+	Time     time.Time   `json:"time"`
+	Accounts []string    `json:"accounts,omitempty"`
+	Data     interface{} `json:"data"`
+}
+
+// BalanceCheckFailure is the payload of a balance.check_failed event.
+type BalanceCheckFailure struct {
+	Error string `json:"error"`
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Limits on the metadata and tags attached to accounts and transactions.
+const (
+	MaxMetadataEntries     = 50
+	MaxMetadataKeyLength   = 64
+	MaxMetadataValueLength = 512
+	MaxTags                = 20
+	MaxTagLength           = 64
+)
+
+// ValidateMetadata checks metadata and tags against the size limits.
+func ValidateMetadata(metadata map[string]string, tags []string) error {
+	if len(metadata) > MaxMetadataEntries {
+		return fmt.Errorf("metadata has %d entries, at most %d are allowed", len(metadata), MaxMetadataEntries)
+	}
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if key == "" || len(key) > MaxMetadataKeyLength {
+			return fmt.Errorf("metadata key %q must be 1 to %d bytes long", key, MaxMetadataKeyLength)
+		}
+		if len(metadata[key]) > MaxMetadataValueLength {
+			return fmt.Errorf("metadata value for %q exceeds %d bytes", key, MaxMetadataValueLength)
+		}
+	}
+
+	if len(tags) > MaxTags {
+		return fmt.Errorf("%d tags given, at most %d are allowed", len(tags), MaxTags)
+	}
+	for _, tag := range tags {
+		if tag == "" || len(tag) > MaxTagLength {
+			return fmt.Errorf("tag %q must be 1 to %d bytes long", tag, MaxTagLength)
+		}
+	}
+	return nil
+}
+
+// CloneMetadata copies metadata and tags so the caller's map and slice are
+// not shared with the ledger.
+func CloneMetadata(metadata map[string]string, tags []string) (map[string]string, []string) {
+	var clonedMetadata map[string]string
+	if metadata != nil {
+		clonedMetadata = make(map[string]string, len(metadata))
+		for key, value := range metadata {
+			clonedMetadata[key] = value
+		}
+	}
+	var clonedTags []string
+	if tags != nil {
+		clonedTags = append([]string{}, tags...)
+	}
+	return clonedMetadata, clonedTags
+}
+
+// MetadataFilter selects accounts or transactions by metadata and tags.
+// Empty fields match anything. Key alone requires the key to be present,
+// Value alone matches any key with that value, and both together require
+// the key to have that value.
+type MetadataFilter struct {
+	Key   string
+	Value string
+	Tag   string
+}
+
+// Matches reports whether the metadata and tags satisfy the filter.
+func (f MetadataFilter) Matches(metadata map[string]string, tags []string) bool {
+	switch {
+	case f.Key != "":
+		value, exists := metadata[f.Key]
+		if !exists || (f.Value != "" && value != f.Value) {
+			return false
+		}
+	case f.Value != "":
+		found := false
+		for _, value := range metadata {
+			if value == f.Value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if f.Tag != "" {
+		for _, tag := range tags {
+			if tag == f.Tag {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
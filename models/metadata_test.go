@@ -0,0 +1,91 @@
+package models
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestValidateMetadata(t *testing.T) {
+	tooMany := make(map[string]string)
+	for i := 0; i <= MaxMetadataEntries; i++ {
+		tooMany[fmt.Sprintf("key%d", i)] = "value"
+	}
+	manyTags := make([]string, MaxTags+1)
+	for i := range manyTags {
+		manyTags[i] = fmt.Sprintf("tag%d", i)
+	}
+
+	tests := []struct {
+		name     string
+		metadata map[string]string
+		tags     []string
+		wantErr  bool
+	}{
+		{"empty", nil, nil, false},
+		{"within limits", map[string]string{"order_id": "ORD-1", "note": ""}, []string{"vip"}, false},
+		{"too many entries", tooMany, nil, true},
+		{"empty key", map[string]string{"": "value"}, nil, true},
+		{"key too long", map[string]string{strings.Repeat("k", MaxMetadataKeyLength+1): "value"}, nil, true},
+		{"value too long", map[string]string{"key": strings.Repeat("v", MaxMetadataValueLength+1)}, nil, true},
+		{"too many tags", nil, manyTags, true},
+		{"empty tag", nil, []string{""}, true},
+		{"tag too long", nil, []string{strings.Repeat("t", MaxTagLength+1)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMetadata(tt.metadata, tt.tags)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestMetadataFilterMatches(t *testing.T) {
+	metadata := map[string]string{"order_id": "ORD-1", "cost_centre": "CC-42"}
+	tags := []string{"vip", "monthly"}
+
+	tests := []struct {
+		name   string
+		filter MetadataFilter
+		want   bool
+	}{
+		{"empty filter", MetadataFilter{}, true},
+		{"key present", MetadataFilter{Key: "order_id"}, true},
+		{"key missing", MetadataFilter{Key: "customer"}, false},
+		{"key and value", MetadataFilter{Key: "cost_centre", Value: "CC-42"}, true},
+		{"key with other value", MetadataFilter{Key: "cost_centre", Value: "CC-7"}, false},
+		{"value under any key", MetadataFilter{Value: "ORD-1"}, true},
+		{"unknown value", MetadataFilter{Value: "ORD-2"}, false},
+		{"tag", MetadataFilter{Tag: "monthly"}, true},
+		{"missing tag", MetadataFilter{Tag: "weekly"}, false},
+		{"key and tag", MetadataFilter{Key: "order_id", Tag: "weekly"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, tt.filter.Matches(metadata, tags))
+		})
+	}
+}
+
+func TestCloneMetadata(t *testing.T) {
+	metadata := map[string]string{"key": "value"}
+	tags := []string{"tag"}
+
+	clonedMetadata, clonedTags := CloneMetadata(metadata, tags)
+	metadata["key"] = "changed"
+	tags[0] = "changed"
+
+	assert.Equal(t, "value", clonedMetadata["key"])
+	assert.Equal(t, "tag", clonedTags[0])
+
+	clonedMetadata, clonedTags = CloneMetadata(nil, nil)
+	assert.Nil(t, clonedMetadata)
+	assert.Nil(t, clonedTags)
+}
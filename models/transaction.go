@@ -1,12 +1,63 @@
 package models
 
-import "time"
+import (
+	"github.com/shopspring/decimal"
+	"time"
+)
 
+// Transaction is a journal entry. Sequence is assigned by the ledger when the
+// entry is committed and orders it among all ledger changes.
 type Transaction struct {
 	ID            string    `json:"id"`
+	Sequence      uint64    `json:"sequence,omitempty"`
 	DateTime      time.Time `json:"datetime"`
 	Description   string    `json:"description"`
 	DebitAccount  string    `json:"debit_account"`
 	CreditAccount string    `json:"credit_account"`
 	Amount        Money     `json:"amount"`
+	Postings      []Posting `json:"postings,omitempty"`
+
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Tags     []string          `json:"tags,omitempty"`
+}
+
+// Posting is one leg of a multi-posting transaction. The amount is the signed
+// change applied to the account balance: negative legs debit the account and
+// positive legs credit it, so the legs of a balanced transaction sum to zero.
+type Posting struct {
+	Account string `json:"account"`
+	Amount  Money  `json:"amount"`
+}
+
+// Legs returns the postings of the transaction. A simple debit/credit
+// transaction is expanded into its two equivalent legs.
+func (t Transaction) Legs() []Posting {
+	if len(t.Postings) > 0 {
+		return t.Postings
+	}
+	return []Posting{
+		{Account: t.DebitAccount, Amount: Money{Amount: t.Amount.Amount.Neg(), Currency: t.Amount.Currency}},
+		{Account: t.CreditAccount, Amount: t.Amount},
+	}
+}
+
+// Involves reports whether any leg of the transaction touches the account.
+func (t Transaction) Involves(accountID string) bool {
+	for _, leg := range t.Legs() {
+		if leg.Account == accountID {
+			return true
+		}
+	}
+	return false
+}
+
+// Delta returns the net change the transaction applies to the account balance.
+func (t Transaction) Delta(accountID string) decimal.Decimal {
+	total := decimal.Zero
+	for _, leg := range t.Legs() {
+		if leg.Account == accountID {
+			total = total.Add(leg.Amount.Amount)
+		}
+	}
+	return total
 }
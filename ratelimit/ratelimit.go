@@ -0,0 +1,100 @@
+package ratelimit
+
+import (
+	"ledgerproject/config"
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often buckets that have refilled completely are
+// dropped. A full bucket behaves exactly like a new one.
+const sweepInterval = time.Minute
+
+// Decision is the outcome of a request against a client's bucket. Reset is
+// the time until the bucket is full again; RetryAfter is the time until the
+// next request is allowed and is zero for allowed requests.
+type Decision struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Limiter keeps one token bucket per client key. Each bucket holds up to
+// Burst tokens and refills at Rate tokens per second; every request takes
+// one token.
+type Limiter struct {
+	rate      float64
+	burst     float64
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+	mu        sync.Mutex
+}
+
+// New returns a limiter for the budget, or nil when the budget has no rate
+// and requests are unlimited.
+func New(limit config.RateLimit) *Limiter {
+	if limit.Rate <= 0 || limit.Burst <= 0 {
+		return nil
+	}
+	return &Limiter{
+		rate:      limit.Rate,
+		burst:     float64(limit.Burst),
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Allow takes a token from the key's bucket if one is left.
+func (l *Limiter) Allow(key string) Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &bucket{tokens: l.burst, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	d := Decision{Limit: int(l.burst)}
+	if b.tokens >= 1 {
+		b.tokens--
+		d.Allowed = true
+	} else {
+		d.RetryAfter = l.duration(1 - b.tokens)
+	}
+	d.Remaining = int(b.tokens)
+	d.Reset = l.duration(l.burst - b.tokens)
+	return d
+}
+
+// duration returns the time it takes to refill the given number of tokens.
+func (l *Limiter) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
+
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*l.rate >= l.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
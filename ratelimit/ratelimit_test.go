@@ -0,0 +1,76 @@
+package ratelimit
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ledgerproject/config"
+	"testing"
+	"time"
+)
+
+func newTestLimiter(limit config.RateLimit) (*Limiter, *time.Time) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	l := New(limit)
+	l.now = func() time.Time { return now }
+	l.lastSweep = now
+	return l, &now
+}
+
+func TestNewDisabled(t *testing.T) {
+	assert.Nil(t, New(config.RateLimit{}))
+	assert.Nil(t, New(config.RateLimit{Rate: 0, Burst: 10}))
+	assert.Nil(t, New(config.RateLimit{Rate: 10, Burst: 0}))
+}
+
+func TestAllow(t *testing.T) {
+	l, now := newTestLimiter(config.RateLimit{Rate: 2, Burst: 3})
+
+	// The burst is available at once
+	for remaining := 2; remaining >= 0; remaining-- {
+		d := l.Allow("alice")
+		require.True(t, d.Allowed)
+		assert.Equal(t, 3, d.Limit)
+		assert.Equal(t, remaining, d.Remaining)
+	}
+
+	d := l.Allow("alice")
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+	assert.Equal(t, 500*time.Millisecond, d.RetryAfter)
+	assert.Equal(t, 1500*time.Millisecond, d.Reset)
+
+	// Other clients have their own bucket
+	assert.True(t, l.Allow("bob").Allowed)
+
+	// Tokens refill at the rate
+	*now = now.Add(500 * time.Millisecond)
+	d = l.Allow("alice")
+	assert.True(t, d.Allowed)
+	assert.Zero(t, d.RetryAfter)
+	assert.False(t, l.Allow("alice").Allowed)
+
+	// but never beyond the burst
+	*now = now.Add(time.Hour)
+	for i := 0; i < 3; i++ {
+		assert.True(t, l.Allow("alice").Allowed)
+	}
+	assert.False(t, l.Allow("alice").Allowed)
+}
+
+func TestSweep(t *testing.T) {
+	l, now := newTestLimiter(config.RateLimit{Rate: 0.1, Burst: 10})
+
+	l.Allow("alice")
+	*now = now.Add(sweepInterval - 5*time.Second)
+	for i := 0; i < 10; i++ {
+		l.Allow("bob")
+	}
+	require.Len(t, l.buckets, 2)
+
+	// Alice's bucket has refilled by the next sweep, Bob's has not
+	*now = now.Add(5 * time.Second)
+	l.Allow("carol")
+	assert.Contains(t, l.buckets, "carol")
+	assert.NotContains(t, l.buckets, "alice")
+	assert.Len(t, l.buckets, 2)
+}
@@ -0,0 +1,320 @@
+package reconciliation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"go.uber.org/zap"
+	"ledgerproject/config"
+	"ledgerproject/ledger"
+	"ledgerproject/logger"
+	"ledgerproject/models"
+	"sync"
+	"time"
+)
+
+// Match kinds recorded on a statement line.
+const (
+	MatchAuto   = "auto"
+	MatchManual = "manual"
+)
+
+// Line is a single external statement line. The amount is signed from the
+// account's point of view: money in is positive, money out negative.
+type Line struct {
+	ID            string       `json:"id"`
+	Date          time.Time    `json:"date"`
+	Amount        models.Money `json:"amount"`
+	Reference     string       `json:"reference,omitempty"`
+	Description   string       `json:"description,omitempty"`
+	TransactionID string       `json:"transaction_id,omitempty"`
+	Match         string       `json:"match,omitempty"`
+}
+
+// Reconciliation is the persisted state of one uploaded statement.
+type Reconciliation struct {
+	ID        string    `json:"id"`
+	AccountID string    `json:"account_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	From      time.Time `json:"from"`
+	To        time.Time `json:"to"`
+	Lines     []Line    `json:"lines"`
+}
+
+// Report is a reconciliation together with the items still open on both
+// sides. Unmatched ledger transactions are computed against the live ledger
+// each time, so postings made after the upload show up.
+type Report struct {
+	Reconciliation
+	UnmatchedLines        []string             `json:"unmatched_lines"`
+	UnmatchedTransactions []models.Transaction `json:"unmatched_transactions"`
+}
+
+// Service matches statement lines against ledger transactions.
+type Service struct {
+	ledger ledger.LedgerService
+	store  Store
+	window time.Duration
+	mu     sync.Mutex
+}
+
+func NewService(l ledger.LedgerService, store Store, cfg *config.Config) *Service {
+	return &Service{
+		ledger: l,
+		store:  store,
+		window: cfg.ReconciliationWindow,
+	}
+}
+
+// Create stores a new reconciliation for the account and auto-matches its
+// lines.
+func (s *Service) Create(ctx context.Context, accountID string, lines []Line) (*Report, error) {
+	log := logger.FromContext(ctx)
+
+	balance, err := s.ledger.GetAccountBalance(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, fmt.Errorf("statement has no lines")
+	}
+
+	rec := &Reconciliation{
+		ID:        newID(),
+		AccountID: accountID,
+		CreatedAt: time.Now().UTC(),
+		Lines:     make([]Line, len(lines)),
+	}
+	rec.UpdatedAt = rec.CreatedAt
+
+	for i, line := range lines {
+		if line.Amount.Currency == "" {
+			line.Amount.Currency = balance.Currency
+		}
+		if line.Amount.Currency != balance.Currency {
+			return nil, fmt.Errorf("statement line %d currency %s does not match account currency %s",
+				i+1, line.Amount.Currency, balance.Currency)
+		}
+		line.ID = fmt.Sprintf("L%d", i+1)
+		line.TransactionID, line.Match = "", ""
+		rec.Lines[i] = line
+
+		if i == 0 || line.Date.Before(rec.From) {
+			rec.From = line.Date
+		}
+		if i == 0 || line.Date.After(rec.To) {
+			rec.To = line.Date
+		}
+	}
+	rec.From = rec.From.Add(-s.window)
+	rec.To = rec.To.Add(s.window)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.autoMatch(ctx, rec)
+	if err := s.store.Save(rec); err != nil {
+		return nil, err
+	}
+
+	log.Info("Reconciliation created",
+		zap.String("reconciliation_id", rec.ID),
+		zap.String("account_id", accountID),
+		zap.Int("lines", len(rec.Lines)))
+	return s.report(ctx, rec), nil
+}
+
+// Get returns the reconciliation with its open items.
+func (s *Service) Get(ctx context.Context, id string) (*Report, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, err := s.store.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	return s.report(ctx, rec), nil
+}
+
+// Match links a statement line to a ledger transaction by hand.
+func (s *Service) Match(ctx context.Context, id, lineID, txID string) (*Report, error) {
+	log := logger.FromContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, err := s.store.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	line := findLine(rec, lineID)
+	if line == nil {
+		return nil, fmt.Errorf("statement line %s does not exist", lineID)
+	}
+	if line.TransactionID != "" {
+		return nil, fmt.Errorf("statement line %s is already matched to %s", lineID, line.TransactionID)
+	}
+
+	found := false
+	for _, tx := range s.ledger.GetTransactionHistory(ctx, rec.AccountID) {
+		if tx.ID == txID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("transaction %s does not exist for account %s", txID, rec.AccountID)
+	}
+	for _, other := range rec.Lines {
+		if other.TransactionID == txID {
+			return nil, fmt.Errorf("transaction %s is already matched to line %s", txID, other.ID)
+		}
+	}
+
+	line.TransactionID, line.Match = txID, MatchManual
+	rec.UpdatedAt = time.Now().UTC()
+	if err := s.store.Save(rec); err != nil {
+		return nil, err
+	}
+
+	log.Info("Statement line matched",
+		zap.String("reconciliation_id", id),
+		zap.String("line_id", lineID),
+		zap.String("tx_id", txID))
+	return s.report(ctx, rec), nil
+}
+
+// Unmatch clears the match on a statement line.
+func (s *Service) Unmatch(ctx context.Context, id, lineID string) (*Report, error) {
+	log := logger.FromContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rec, err := s.store.Load(id)
+	if err != nil {
+		return nil, err
+	}
+	line := findLine(rec, lineID)
+	if line == nil {
+		return nil, fmt.Errorf("statement line %s does not exist", lineID)
+	}
+	if line.TransactionID == "" {
+		return nil, fmt.Errorf("statement line %s is not matched", lineID)
+	}
+
+	line.TransactionID, line.Match = "", ""
+	rec.UpdatedAt = time.Now().UTC()
+	if err := s.store.Save(rec); err != nil {
+		return nil, err
+	}
+
+	log.Info("Statement line unmatched",
+		zap.String("reconciliation_id", id),
+		zap.String("line_id", lineID))
+	return s.report(ctx, rec), nil
+}
+
+// autoMatch pairs each open line with an unclaimed transaction of the same
+// signed amount inside the date window. A reference equal to the transaction
+// ID wins; otherwise the transaction closest in time is chosen.
+func (s *Service) autoMatch(ctx context.Context, rec *Reconciliation) {
+	candidates := s.candidates(ctx, rec)
+	claimed := make(map[string]bool)
+	for _, line := range rec.Lines {
+		if line.TransactionID != "" {
+			claimed[line.TransactionID] = true
+		}
+	}
+
+	for i := range rec.Lines {
+		line := &rec.Lines[i]
+		if line.TransactionID != "" {
+			continue
+		}
+
+		var best *models.Transaction
+		var bestGap time.Duration
+		for j := range candidates {
+			tx := &candidates[j]
+			if claimed[tx.ID] || !tx.Delta(rec.AccountID).Equal(line.Amount.Amount) {
+				continue
+			}
+			gap := absDuration(tx.DateTime.Sub(line.Date))
+			if gap > s.window {
+				continue
+			}
+			if line.Reference != "" && line.Reference == tx.ID {
+				best = tx
+				break
+			}
+			if best == nil || gap < bestGap {
+				best, bestGap = tx, gap
+			}
+		}
+
+		if best != nil {
+			line.TransactionID, line.Match = best.ID, MatchAuto
+			claimed[best.ID] = true
+		}
+	}
+}
+
+// candidates returns the account's transactions within the statement window.
+func (s *Service) candidates(ctx context.Context, rec *Reconciliation) []models.Transaction {
+	var txs []models.Transaction
+	for _, tx := range s.ledger.GetTransactionHistory(ctx, rec.AccountID) {
+		if tx.DateTime.Before(rec.From) || tx.DateTime.After(rec.To) {
+			continue
+		}
+		txs = append(txs, tx)
+	}
+	return txs
+}
+
+func (s *Service) report(ctx context.Context, rec *Reconciliation) *Report {
+	r := &Report{
+		Reconciliation:        *rec,
+		UnmatchedLines:        []string{},
+		UnmatchedTransactions: []models.Transaction{},
+	}
+
+	matched := make(map[string]bool)
+	for _, line := range rec.Lines {
+		if line.TransactionID == "" {
+			r.UnmatchedLines = append(r.UnmatchedLines, line.ID)
+			continue
+		}
+		matched[line.TransactionID] = true
+	}
+	for _, tx := range s.candidates(ctx, rec) {
+		if !matched[tx.ID] {
+			r.UnmatchedTransactions = append(r.UnmatchedTransactions, tx)
+		}
+	}
+	return r
+}
+
+func findLine(rec *Reconciliation, lineID string) *Line {
+	for i := range rec.Lines {
+		if rec.Lines[i].ID == lineID {
+			return &rec.Lines[i]
+		}
+	}
+	return nil
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("rec-%d", time.Now().UnixNano())
+	}
+	return "rec-" + hex.EncodeToString(b)
+}
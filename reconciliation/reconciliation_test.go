@@ -0,0 +1,160 @@
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"ledgerproject/config"
+	"ledgerproject/ledger"
+	"ledgerproject/logger"
+	"ledgerproject/models"
+	"ledgerproject/services"
+	"testing"
+	"time"
+)
+
+// setupTestLogger initializes a test logger
+func setupTestLogger(t *testing.T) *zap.Logger {
+	testLogger := zaptest.NewLogger(t)
+	// Initialize the package-level logger
+	if err := logger.Init(true); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	return testLogger
+}
+
+func usd(amount int64) models.Money {
+	return models.Money{Amount: decimal.NewFromInt(amount), Currency: "USD"}
+}
+
+// setupTest returns a service over a ledger where BANK paid 100 twice (TX1,
+// TX2) and received 40 (TX3).
+func setupTest(t *testing.T) (*Service, ledger.LedgerService) {
+	setupTestLogger(t)
+
+	cfg := &config.Config{
+		CurrencyFile:         "../data/iso4217_currency_test.json",
+		ReconciliationWindow: 3 * 24 * time.Hour,
+	}
+	validator, err := services.NewCurrencyValidator(cfg)
+	require.NoError(t, err)
+
+	l := ledger.NewDetachedLedger(validator)
+	for _, acc := range []models.Account{
+		{ID: "BANK", Name: "Bank", Currency: "USD", Balance: usd(1000)},
+		{ID: "VENDOR", Name: "Vendor", Currency: "USD", Balance: usd(500)},
+	} {
+		require.NoError(t, l.CreateAccount(context.Background(), acc))
+	}
+	for _, tx := range []models.Transaction{
+		{ID: "TX1", DebitAccount: "BANK", CreditAccount: "VENDOR", Amount: usd(100)},
+		{ID: "TX2", DebitAccount: "BANK", CreditAccount: "VENDOR", Amount: usd(100)},
+		{ID: "TX3", DebitAccount: "VENDOR", CreditAccount: "BANK", Amount: usd(40)},
+	} {
+		require.NoError(t, l.RecordTransaction(context.Background(), tx))
+	}
+
+	return NewService(l, NewMemoryStore(), cfg), l
+}
+
+func TestCreateAutoMatches(t *testing.T) {
+	svc, _ := setupTest(t)
+	now := time.Now().UTC()
+
+	report, err := svc.Create(context.Background(), "BANK", []Line{
+		{Date: now, Amount: usd(-100), Reference: "TX2"},
+		{Date: now, Amount: usd(-100)},
+		{Date: now, Amount: models.Money{Amount: decimal.NewFromInt(40)}},
+		{Date: now, Amount: usd(-7), Description: "bank fee"},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, report.Lines, 4)
+	assert.Equal(t, "L1", report.Lines[0].ID)
+	assert.Equal(t, "TX2", report.Lines[0].TransactionID, "reference wins over time proximity")
+	assert.Equal(t, MatchAuto, report.Lines[0].Match)
+	assert.Equal(t, "TX1", report.Lines[1].TransactionID)
+	assert.Equal(t, "TX3", report.Lines[2].TransactionID)
+	assert.Equal(t, "USD", report.Lines[2].Amount.Currency, "currency defaults to the account's")
+	assert.Empty(t, report.Lines[3].TransactionID)
+
+	assert.Equal(t, []string{"L4"}, report.UnmatchedLines)
+	assert.Empty(t, report.UnmatchedTransactions)
+}
+
+func TestCreateRespectsDateWindow(t *testing.T) {
+	svc, _ := setupTest(t)
+
+	report, err := svc.Create(context.Background(), "BANK", []Line{
+		{Date: time.Now().UTC().AddDate(0, 0, -10), Amount: usd(40)},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"L1"}, report.UnmatchedLines)
+	assert.Empty(t, report.UnmatchedTransactions, "ledger transactions outside the window are not listed")
+}
+
+func TestCreateErrors(t *testing.T) {
+	svc, _ := setupTest(t)
+
+	_, err := svc.Create(context.Background(), "MISSING", []Line{{Date: time.Now(), Amount: usd(1)}})
+	assert.Error(t, err)
+
+	_, err = svc.Create(context.Background(), "BANK", nil)
+	assert.EqualError(t, err, "statement has no lines")
+
+	_, err = svc.Create(context.Background(), "BANK", []Line{{Date: time.Now(), Amount: models.Money{Amount: decimal.NewFromInt(1), Currency: "EUR"}}})
+	assert.EqualError(t, err, "statement line 1 currency EUR does not match account currency USD")
+}
+
+func TestManualMatchAndUnmatch(t *testing.T) {
+	svc, l := setupTest(t)
+	now := time.Now().UTC()
+
+	created, err := svc.Create(context.Background(), "BANK", []Line{
+		{Date: now, Amount: usd(-99), Reference: "wire 1"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{"L1"}, created.UnmatchedLines)
+	require.Len(t, created.UnmatchedTransactions, 3)
+
+	_, err = svc.Match(context.Background(), created.ID, "L9", "TX1")
+	assert.EqualError(t, err, "statement line L9 does not exist")
+
+	_, err = svc.Match(context.Background(), created.ID, "L1", "TX404")
+	assert.EqualError(t, err, "transaction TX404 does not exist for account BANK")
+
+	matched, err := svc.Match(context.Background(), created.ID, "L1", "TX1")
+	require.NoError(t, err)
+	assert.Equal(t, "TX1", matched.Lines[0].TransactionID)
+	assert.Equal(t, MatchManual, matched.Lines[0].Match)
+	assert.Empty(t, matched.UnmatchedLines)
+	assert.Len(t, matched.UnmatchedTransactions, 2)
+
+	_, err = svc.Match(context.Background(), created.ID, "L1", "TX2")
+	assert.EqualError(t, err, "statement line L1 is already matched to TX1")
+
+	// State survives a reload and picks up new ledger postings
+	require.NoError(t, l.RecordTransaction(context.Background(), models.Transaction{
+		ID: "TX4", DebitAccount: "VENDOR", CreditAccount: "BANK", Amount: usd(5),
+	}))
+	loaded, err := svc.Get(context.Background(), created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "TX1", loaded.Lines[0].TransactionID)
+	assert.Len(t, loaded.UnmatchedTransactions, 3)
+
+	unmatched, err := svc.Unmatch(context.Background(), created.ID, "L1")
+	require.NoError(t, err)
+	assert.Empty(t, unmatched.Lines[0].TransactionID)
+	assert.Equal(t, []string{"L1"}, unmatched.UnmatchedLines)
+
+	_, err = svc.Unmatch(context.Background(), created.ID, "L1")
+	assert.EqualError(t, err, "statement line L1 is not matched")
+
+	_, err = svc.Get(context.Background(), "rec-missing")
+	assert.True(t, errors.Is(err, ErrNotFound))
+}
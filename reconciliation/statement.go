@@ -0,0 +1,128 @@
+package reconciliation
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"io"
+	"ledgerproject/models"
+	"strings"
+	"time"
+)
+
+// ParseCSV reads statement lines from CSV with a header row. The date and
+// amount columns are required; currency, reference and description are
+// optional. Dates are YYYY-MM-DD or RFC 3339, amounts are signed with money
+// in positive.
+func ParseCSV(r io.Reader) ([]Line, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("statement is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading statement header: %v", err)
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"date", "amount"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("statement is missing the %s column", required)
+		}
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	var lines []Line
+	for row := 2; ; row++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", row, err)
+		}
+
+		line, err := StatementLine{
+			Date:        field(record, "date"),
+			Amount:      field(record, "amount"),
+			Currency:    field(record, "currency"),
+			Reference:   field(record, "reference"),
+			Description: field(record, "description"),
+		}.line()
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %v", row, err)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+// StatementLine is the upload format of a statement line, shared by the CSV
+// columns and the JSON body. Statements in camt.053 or MT940 are expected to
+// be converted into this shape before upload.
+type StatementLine struct {
+	Date        string `json:"date"`
+	Amount      string `json:"amount"`
+	Currency    string `json:"currency,omitempty"`
+	Reference   string `json:"reference,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// ParseJSON reads statement lines from a JSON array of StatementLine.
+func ParseJSON(r io.Reader) ([]Line, error) {
+	var uploaded []StatementLine
+	if err := json.NewDecoder(r).Decode(&uploaded); err != nil {
+		return nil, fmt.Errorf("error decoding statement: %v", err)
+	}
+
+	lines := make([]Line, 0, len(uploaded))
+	for i, u := range uploaded {
+		line, err := u.line()
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+		lines = append(lines, line)
+	}
+	return lines, nil
+}
+
+func (u StatementLine) line() (Line, error) {
+	date, err := parseDate(u.Date)
+	if err != nil {
+		return Line{}, err
+	}
+	amount, err := decimal.NewFromString(strings.ReplaceAll(u.Amount, ",", ""))
+	if err != nil {
+		return Line{}, fmt.Errorf("invalid amount %q", u.Amount)
+	}
+
+	return Line{
+		Date:        date,
+		Amount:      models.Money{Amount: amount, Currency: u.Currency},
+		Reference:   u.Reference,
+		Description: u.Description,
+	}, nil
+}
+
+func parseDate(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return t, nil
+}
@@ -0,0 +1,58 @@
+package reconciliation
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseCSV(t *testing.T) {
+	t.Run("valid statement", func(t *testing.T) {
+		input := "Date,Amount,Currency,Reference,Description\n" +
+			"2024-03-01,-1,250.00,USD,TX1,Rent\n"
+		_, err := ParseCSV(strings.NewReader(input))
+		assert.Error(t, err, "unquoted thousands separator shifts the columns")
+
+		input = "Date,Amount,Currency,Reference,Description\n" +
+			"2024-03-01,\"-1,250.00\",USD,TX1,Rent\n" +
+			"2024-03-02T10:00:00Z,40,,,\n"
+		lines, err := ParseCSV(strings.NewReader(input))
+		require.NoError(t, err)
+		require.Len(t, lines, 2)
+		assert.Equal(t, "-1250", lines[0].Amount.Amount.String())
+		assert.Equal(t, "USD", lines[0].Amount.Currency)
+		assert.Equal(t, "TX1", lines[0].Reference)
+		assert.Equal(t, "Rent", lines[0].Description)
+		assert.Equal(t, time.Date(2024, 3, 2, 10, 0, 0, 0, time.UTC), lines[1].Date)
+		assert.Empty(t, lines[1].Amount.Currency)
+	})
+
+	errorCases := map[string]string{
+		"empty":          "",
+		"missing column": "date,reference\n2024-03-01,TX1\n",
+		"bad date":       "date,amount\n01/03/2024,10\n",
+		"bad amount":     "date,amount\n2024-03-01,ten\n",
+	}
+	for name, input := range errorCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseCSV(strings.NewReader(input))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseJSON(t *testing.T) {
+	lines, err := ParseJSON(strings.NewReader(`[{"date":"2024-03-01","amount":"-10.5","reference":"TX9"}]`))
+	require.NoError(t, err)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "-10.5", lines[0].Amount.Amount.String())
+	assert.Equal(t, "TX9", lines[0].Reference)
+
+	_, err = ParseJSON(strings.NewReader(`[{"date":"2024-03-01","amount":"x"}]`))
+	assert.EqualError(t, err, `line 1: invalid amount "x"`)
+
+	_, err = ParseJSON(strings.NewReader(`{}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,137 @@
+package reconciliation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"ledgerproject/config"
+	"ledgerproject/logger"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+// ErrNotFound is returned when a reconciliation does not exist.
+var ErrNotFound = errors.New("reconciliation not found")
+
+// Store persists reconciliation state.
+type Store interface {
+	Save(rec *Reconciliation) error
+	Load(id string) (*Reconciliation, error)
+}
+
+// NewStore returns a file-backed store rooted at cfg.ReconciliationDir, or an
+// in-memory store when no directory is configured.
+func NewStore(cfg *config.Config) (Store, error) {
+	if cfg.ReconciliationDir == "" {
+		return NewMemoryStore(), nil
+	}
+	return NewFileStore(cfg.ReconciliationDir)
+}
+
+type memoryStore struct {
+	recs map[string]Reconciliation
+	mu   sync.RWMutex
+}
+
+func NewMemoryStore() Store {
+	return &memoryStore{recs: make(map[string]Reconciliation)}
+}
+
+func (m *memoryStore) Save(rec *Reconciliation) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	saved := *rec
+	saved.Lines = append([]Line(nil), rec.Lines...)
+	m.recs[rec.ID] = saved
+	return nil
+}
+
+func (m *memoryStore) Load(id string) (*Reconciliation, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	rec, exists := m.recs[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	rec.Lines = append([]Line(nil), rec.Lines...)
+	return &rec, nil
+}
+
+// validID guards file names built from reconciliation IDs.
+var validID = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// fileStore keeps one JSON document per reconciliation. Writes go to a
+// temporary file that is renamed into place so a crash never leaves a
+// half-written state behind.
+type fileStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+func NewFileStore(dir string) (Store, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("error creating reconciliation directory: %v", err)
+	}
+	return &fileStore{dir: dir}, nil
+}
+
+func (f *fileStore) path(id string) (string, error) {
+	if !validID.MatchString(id) {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return filepath.Join(f.dir, id+".json"), nil
+}
+
+func (f *fileStore) Save(rec *Reconciliation) error {
+	log := logger.Get()
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path, err := f.path(rec.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding reconciliation: %v", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		log.Error("Failed to write reconciliation", zap.Error(err), zap.String("file", tmp))
+		return fmt.Errorf("error writing reconciliation: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		log.Error("Failed to store reconciliation", zap.Error(err), zap.String("file", path))
+		return fmt.Errorf("error writing reconciliation: %v", err)
+	}
+	return nil
+}
+
+func (f *fileStore) Load(id string) (*Reconciliation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path, err := f.path(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading reconciliation: %v", err)
+	}
+
+	var rec Reconciliation
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, fmt.Errorf("error decoding reconciliation: %v", err)
+	}
+	return &rec, nil
+}
@@ -0,0 +1,75 @@
+package reconciliation
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ledgerproject/config"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestStores(t *testing.T) {
+	setupTestLogger(t)
+
+	fileStore, err := NewFileStore(filepath.Join(t.TempDir(), "reconciliations"))
+	require.NoError(t, err)
+
+	stores := map[string]Store{
+		"memory": NewMemoryStore(),
+		"file":   fileStore,
+	}
+
+	for name, store := range stores {
+		t.Run(name, func(t *testing.T) {
+			rec := &Reconciliation{
+				ID:        "rec-1",
+				AccountID: "BANK",
+				CreatedAt: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+				Lines: []Line{
+					{ID: "L1", Date: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Amount: usd(-100), TransactionID: "TX1", Match: MatchAuto},
+				},
+			}
+			require.NoError(t, store.Save(rec))
+
+			// Mutating the caller's copy must not change stored state
+			rec.Lines[0].TransactionID = ""
+
+			loaded, err := store.Load("rec-1")
+			require.NoError(t, err)
+			assert.Equal(t, "BANK", loaded.AccountID)
+			assert.Equal(t, "TX1", loaded.Lines[0].TransactionID)
+			assert.True(t, loaded.Lines[0].Amount.Amount.Equal(usd(-100).Amount))
+
+			_, err = store.Load("rec-2")
+			assert.True(t, errors.Is(err, ErrNotFound))
+		})
+	}
+}
+
+func TestFileStoreRejectsUnsafeIDs(t *testing.T) {
+	setupTestLogger(t)
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	require.NoError(t, err)
+
+	_, err = store.Load("../secret")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.Error(t, store.Save(&Reconciliation{ID: "a/b"}))
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestNewStore(t *testing.T) {
+	store, err := NewStore(&config.Config{})
+	require.NoError(t, err)
+	assert.IsType(t, &memoryStore{}, store)
+
+	store, err = NewStore(&config.Config{ReconciliationDir: t.TempDir()})
+	require.NoError(t, err)
+	assert.IsType(t, &fileStore{}, store)
+}
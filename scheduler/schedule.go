@@ -0,0 +1,366 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule yields the occurrence times of a standing order.
+type Schedule interface {
+	// Next returns the first occurrence strictly after the given time, or
+	// the zero time when the schedule has no further occurrences.
+	Next(after time.Time) time.Time
+}
+
+// ParseSchedule parses either a five-field cron expression (minute, hour,
+// day of month, month, day of week) or an RRULE such as
+// "FREQ=MONTHLY;BYMONTHDAY=1". RRULE occurrences are anchored at start;
+// all times are in UTC.
+func ParseSchedule(spec string, start time.Time) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	upper := strings.ToUpper(spec)
+	if strings.HasPrefix(upper, "RRULE:") || strings.Contains(upper, "FREQ=") {
+		return parseRRule(strings.TrimPrefix(upper, "RRULE:"), start)
+	}
+	return parseCron(spec)
+}
+
+// cronSchedule is a parsed cron expression. Each field holds the permitted
+// values indexed by value.
+type cronSchedule struct {
+	minute, hour, dom, month, dow []bool
+	domAny, dowAny                bool
+}
+
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"JAN": 1, "FEB": 2, "MAR": 3, "APR": 4, "MAY": 5, "JUN": 6,
+	"JUL": 7, "AUG": 8, "SEP": 9, "OCT": 10, "NOV": 11, "DEC": 12,
+}
+
+var dayNames = map[string]int{
+	"SUN": 0, "MON": 1, "TUE": 2, "WED": 3, "THU": 4, "FRI": 5, "SAT": 6,
+}
+
+func parseCron(spec string) (*cronSchedule, error) {
+	if macro, ok := cronMacros[strings.ToLower(spec)]; ok {
+		spec = macro
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", spec)
+	}
+
+	var c cronSchedule
+	var err error
+	if c.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, err
+	}
+	if c.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, err
+	}
+	if c.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, err
+	}
+	if c.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, err
+	}
+	if c.dow, err = parseCronField(fields[4], 0, 7, dayNames); err != nil {
+		return nil, err
+	}
+	// Sunday may be written as 0 or 7
+	c.dow[0] = c.dow[0] || c.dow[7]
+	c.domAny = fields[2] == "*"
+	c.dowAny = fields[4] == "*"
+	return &c, nil
+}
+
+func parseCronField(field string, min, max int, names map[string]int) ([]bool, error) {
+	allowed := make([]bool, max+1)
+	for _, part := range strings.Split(field, ",") {
+		rangeSpec, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid step in cron field %q", field)
+			}
+			rangeSpec, step = part[:i], n
+		}
+
+		lo, hi := min, max
+		switch {
+		case rangeSpec == "*":
+		case strings.Contains(rangeSpec, "-"):
+			bounds := strings.SplitN(rangeSpec, "-", 2)
+			var err error
+			if lo, err = cronValue(bounds[0], names); err != nil {
+				return nil, fmt.Errorf("invalid cron field %q: %v", field, err)
+			}
+			if hi, err = cronValue(bounds[1], names); err != nil {
+				return nil, fmt.Errorf("invalid cron field %q: %v", field, err)
+			}
+		default:
+			v, err := cronValue(rangeSpec, names)
+			if err != nil {
+				return nil, fmt.Errorf("invalid cron field %q: %v", field, err)
+			}
+			lo = v
+			if step == 1 {
+				hi = v
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return nil, fmt.Errorf("cron field %q is out of range %d-%d", field, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			allowed[v] = true
+		}
+	}
+	return allowed, nil
+}
+
+func cronValue(s string, names map[string]int) (int, error) {
+	if v, ok := names[strings.ToUpper(s)]; ok {
+		return v, nil
+	}
+	return strconv.Atoi(s)
+}
+
+func (c *cronSchedule) matchesDay(t time.Time) bool {
+	if !c.month[int(t.Month())] {
+		return false
+	}
+	dom, dow := c.dom[t.Day()], c.dow[int(t.Weekday())]
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	default:
+		// Standard cron semantics: either restricted day field may match
+		return dom || dow
+	}
+}
+
+// cronHorizon bounds the search for expressions that never fire, such as
+// "0 0 30 2 *".
+const cronHorizon = 5 * 366
+
+func (c *cronSchedule) Next(after time.Time) time.Time {
+	t := after.UTC().Truncate(time.Minute).Add(time.Minute)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < cronHorizon; i++ {
+		if c.matchesDay(day) {
+			for h := 0; h < 24; h++ {
+				if !c.hour[h] {
+					continue
+				}
+				for m := 0; m < 60; m++ {
+					if !c.minute[m] {
+						continue
+					}
+					if candidate := day.Add(time.Duration(h)*time.Hour + time.Duration(m)*time.Minute); !candidate.Before(t) {
+						return candidate
+					}
+				}
+			}
+		}
+		day = day.AddDate(0, 0, 1)
+	}
+	return time.Time{}
+}
+
+// rruleSchedule supports the subset of RFC 5545 recurrence rules needed for
+// standing orders: FREQ, INTERVAL, BYDAY (weekly), BYMONTHDAY (monthly),
+// COUNT and UNTIL.
+type rruleSchedule struct {
+	start    time.Time
+	freq     string
+	interval int
+	byDay    []time.Weekday
+	byMonth  []int
+	count    int
+	until    time.Time
+}
+
+var rruleDays = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+func parseRRule(spec string, start time.Time) (*rruleSchedule, error) {
+	r := &rruleSchedule{start: start.UTC(), interval: 1}
+	for _, part := range strings.Split(spec, ";") {
+		if part == "" {
+			continue
+		}
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid RRULE part %q", part)
+		}
+		key, value := kv[0], kv[1]
+
+		switch key {
+		case "FREQ":
+			switch value {
+			case "DAILY", "WEEKLY", "MONTHLY", "YEARLY":
+				r.freq = value
+			default:
+				return nil, fmt.Errorf("unsupported RRULE frequency %q", value)
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid RRULE interval %q", value)
+			}
+			r.interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("invalid RRULE count %q", value)
+			}
+			r.count = n
+		case "UNTIL":
+			until, err := parseRRuleTime(value)
+			if err != nil {
+				return nil, err
+			}
+			r.until = until
+		case "BYDAY":
+			for _, d := range strings.Split(value, ",") {
+				weekday, ok := rruleDays[d]
+				if !ok {
+					return nil, fmt.Errorf("unsupported RRULE day %q", d)
+				}
+				r.byDay = append(r.byDay, weekday)
+			}
+		case "BYMONTHDAY":
+			for _, d := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil || n == 0 || n < -31 || n > 31 {
+					return nil, fmt.Errorf("invalid RRULE month day %q", d)
+				}
+				r.byMonth = append(r.byMonth, n)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported RRULE part %q", key)
+		}
+	}
+
+	if r.freq == "" {
+		return nil, fmt.Errorf("RRULE %q has no FREQ", spec)
+	}
+	if len(r.byDay) > 0 && r.freq != "WEEKLY" {
+		return nil, fmt.Errorf("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if len(r.byMonth) > 0 && r.freq != "MONTHLY" {
+		return nil, fmt.Errorf("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	return r, nil
+}
+
+func parseRRuleTime(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid RRULE until %q", value)
+}
+
+// rruleHorizon bounds the number of periods walked when looking for the next
+// occurrence.
+const rruleHorizon = 100000
+
+func (r *rruleSchedule) Next(after time.Time) time.Time {
+	n := 0
+	for period := 0; period < rruleHorizon; period++ {
+		for _, occurrence := range r.period(period) {
+			if occurrence.Before(r.start) {
+				continue
+			}
+			n++
+			if r.count > 0 && n > r.count {
+				return time.Time{}
+			}
+			if !r.until.IsZero() && occurrence.After(r.until) {
+				return time.Time{}
+			}
+			if occurrence.After(after) {
+				return occurrence
+			}
+		}
+	}
+	return time.Time{}
+}
+
+// period returns the occurrences in the given period counted from the start,
+// in chronological order.
+func (r *rruleSchedule) period(p int) []time.Time {
+	s := r.start
+	clock := time.Duration(s.Hour())*time.Hour + time.Duration(s.Minute())*time.Minute +
+		time.Duration(s.Second())*time.Second
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Add(clock)
+	}
+
+	switch r.freq {
+	case "DAILY":
+		return []time.Time{s.AddDate(0, 0, p*r.interval)}
+
+	case "WEEKLY":
+		days := r.byDay
+		if len(days) == 0 {
+			days = []time.Weekday{s.Weekday()}
+		}
+		// Weeks start on Monday, the RFC 5545 default
+		monday := at(s.Year(), s.Month(), s.Day()-(int(s.Weekday())+6)%7).AddDate(0, 0, 7*p*r.interval)
+		var out []time.Time
+		for _, d := range days {
+			out = append(out, monday.AddDate(0, 0, (int(d)+6)%7))
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+		return out
+
+	case "MONTHLY":
+		first := time.Date(s.Year(), s.Month()+time.Month(p*r.interval), 1, 0, 0, 0, 0, time.UTC)
+		last := first.AddDate(0, 1, -1).Day()
+		days := r.byMonth
+		if len(days) == 0 {
+			days = []int{s.Day()}
+		}
+		var out []time.Time
+		for _, d := range days {
+			if d < 0 {
+				d = last + d + 1
+			}
+			// Months without the requested day are skipped, as in RFC 5545
+			if d >= 1 && d <= last {
+				out = append(out, at(first.Year(), first.Month(), d))
+			}
+		}
+		sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+		return out
+
+	default: // YEARLY
+		occurrence := at(s.Year()+p*r.interval, s.Month(), s.Day())
+		if occurrence.Day() != s.Day() {
+			return nil
+		}
+		return []time.Time{occurrence}
+	}
+}
@@ -0,0 +1,174 @@
+package scheduler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func at(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// occurrences returns the first n occurrences after the given time.
+func occurrences(s Schedule, after time.Time, n int) []string {
+	var out []string
+	for i := 0; i < n; i++ {
+		after = s.Next(after)
+		if after.IsZero() {
+			break
+		}
+		out = append(out, after.Format(time.RFC3339))
+	}
+	return out
+}
+
+func TestCronSchedule(t *testing.T) {
+	start := at("2024-01-30T12:00:00Z")
+
+	tests := []struct {
+		name string
+		spec string
+		want []string
+	}{
+		{
+			name: "first of the month",
+			spec: "0 9 1 * *",
+			want: []string{"2024-02-01T09:00:00Z", "2024-03-01T09:00:00Z", "2024-04-01T09:00:00Z"},
+		},
+		{
+			name: "steps and ranges",
+			spec: "*/30 12-13 * * *",
+			want: []string{"2024-01-30T12:30:00Z", "2024-01-30T13:00:00Z", "2024-01-30T13:30:00Z"},
+		},
+		{
+			name: "weekdays by name",
+			spec: "0 8 * * MON-FRI",
+			want: []string{"2024-01-31T08:00:00Z", "2024-02-01T08:00:00Z", "2024-02-02T08:00:00Z"},
+		},
+		{
+			name: "day of month or day of week",
+			spec: "0 0 15 * SUN",
+			want: []string{"2024-02-04T00:00:00Z", "2024-02-11T00:00:00Z", "2024-02-15T00:00:00Z"},
+		},
+		{
+			name: "sunday as seven",
+			spec: "0 0 * * 7",
+			want: []string{"2024-02-04T00:00:00Z"},
+		},
+		{
+			name: "macro",
+			spec: "@yearly",
+			want: []string{"2025-01-01T00:00:00Z", "2026-01-01T00:00:00Z"},
+		},
+		{
+			name: "never fires",
+			spec: "0 0 30 2 *",
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := ParseSchedule(tt.spec, start)
+			require.NoError(t, err)
+			if tt.want == nil {
+				assert.True(t, s.Next(start).IsZero())
+				return
+			}
+			assert.Equal(t, tt.want, occurrences(s, start, len(tt.want)))
+		})
+	}
+}
+
+func TestRRuleSchedule(t *testing.T) {
+	tests := []struct {
+		name      string
+		spec      string
+		start     string
+		want      []string
+		exhausted bool
+	}{
+		{
+			name:  "daily with interval",
+			spec:  "FREQ=DAILY;INTERVAL=2",
+			start: "2024-01-30T06:00:00Z",
+			want:  []string{"2024-01-30T06:00:00Z", "2024-02-01T06:00:00Z", "2024-02-03T06:00:00Z"},
+		},
+		{
+			name:  "weekly on several days",
+			spec:  "RRULE:FREQ=WEEKLY;BYDAY=MO,FR",
+			start: "2024-01-31T10:00:00Z",
+			want:  []string{"2024-02-02T10:00:00Z", "2024-02-05T10:00:00Z", "2024-02-09T10:00:00Z"},
+		},
+		{
+			name:  "monthly skips short months",
+			spec:  "FREQ=MONTHLY",
+			start: "2024-01-31T00:00:00Z",
+			want:  []string{"2024-01-31T00:00:00Z", "2024-03-31T00:00:00Z", "2024-05-31T00:00:00Z"},
+		},
+		{
+			name:  "last day of the month",
+			spec:  "FREQ=MONTHLY;BYMONTHDAY=-1",
+			start: "2024-01-15T00:00:00Z",
+			want:  []string{"2024-01-31T00:00:00Z", "2024-02-29T00:00:00Z", "2024-03-31T00:00:00Z"},
+		},
+		{
+			name:  "yearly on leap day",
+			spec:  "FREQ=YEARLY",
+			start: "2024-02-29T00:00:00Z",
+			want:  []string{"2024-02-29T00:00:00Z", "2028-02-29T00:00:00Z"},
+		},
+		{
+			name:      "count",
+			spec:      "FREQ=DAILY;COUNT=2",
+			start:     "2024-01-01T00:00:00Z",
+			want:      []string{"2024-01-01T00:00:00Z", "2024-01-02T00:00:00Z"},
+			exhausted: true,
+		},
+		{
+			name:      "until",
+			spec:      "FREQ=WEEKLY;UNTIL=20240110",
+			start:     "2024-01-01T00:00:00Z",
+			want:      []string{"2024-01-01T00:00:00Z", "2024-01-08T00:00:00Z"},
+			exhausted: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := at(tt.start)
+			s, err := ParseSchedule(tt.spec, start)
+			require.NoError(t, err)
+			got := occurrences(s, start.Add(-time.Nanosecond), len(tt.want))
+			assert.Equal(t, tt.want, got)
+			if tt.exhausted {
+				assert.True(t, s.Next(at(got[len(got)-1])).IsZero())
+			}
+		})
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"0 9 * *",
+		"60 * * * *",
+		"* * 0 * *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"FREQ=HOURLY",
+		"INTERVAL=2",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=DAILY;BYHOUR=9",
+	} {
+		_, err := ParseSchedule(spec, at("2024-01-01T00:00:00Z"))
+		assert.Error(t, err, spec)
+	}
+}
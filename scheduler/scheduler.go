@@ -0,0 +1,326 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"ledgerproject/config"
+	"ledgerproject/ledger"
+	"ledgerproject/logger"
+	"ledgerproject/models"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when a standing order does not exist.
+var ErrNotFound = errors.New("standing order not found")
+
+// Standing order states.
+const (
+	StatusActive    = "active"
+	StatusCompleted = "completed"
+	StatusCancelled = "cancelled"
+)
+
+// Occurrence states.
+const (
+	OccurrencePosted   = "posted"
+	OccurrenceRetrying = "retrying"
+	OccurrenceFailed   = "failed"
+)
+
+// StandingOrder is a recurring transaction template. End and MaxCount are
+// optional; MaxCount limits the number of occurrences, failed ones included.
+type StandingOrder struct {
+	ID            string       `json:"id"`
+	Description   string       `json:"description"`
+	DebitAccount  string       `json:"debit_account"`
+	CreditAccount string       `json:"credit_account"`
+	Amount        models.Money `json:"amount"`
+	Schedule      string       `json:"schedule"`
+	Start         time.Time    `json:"start"`
+	End           *time.Time   `json:"end,omitempty"`
+	MaxCount      int          `json:"max_count,omitempty"`
+
+	Status      string       `json:"status"`
+	NextRun     *time.Time   `json:"next_run,omitempty"`
+	Occurrences []Occurrence `json:"occurrences"`
+
+	schedule Schedule
+}
+
+// Occurrence records the outcome of one due date of a standing order. The
+// transaction ID is derived from the order ID and the due time, and the
+// ledger rejects IDs it already holds, so an occurrence is never posted
+// twice.
+type Occurrence struct {
+	TransactionID string    `json:"transaction_id"`
+	Due           time.Time `json:"due"`
+	Status        string    `json:"status"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"last_error,omitempty"`
+}
+
+// Scheduler stores standing orders and posts their due occurrences.
+type Scheduler struct {
+	ledger      ledger.LedgerService
+	interval    time.Duration
+	maxAttempts int
+	maxCatchUp  int
+	orders      map[string]*StandingOrder
+	now         func() time.Time
+	mu          sync.Mutex
+}
+
+func NewScheduler(l ledger.LedgerService, cfg *config.Config) *Scheduler {
+	return &Scheduler{
+		ledger:      l,
+		interval:    cfg.SchedulerInterval,
+		maxAttempts: cfg.SchedulerMaxAttempts,
+		maxCatchUp:  cfg.SchedulerMaxCatchUp,
+		orders:      make(map[string]*StandingOrder),
+		now:         time.Now,
+	}
+}
+
+// Create validates and stores a standing order. A missing start defaults to
+// now.
+func (s *Scheduler) Create(ctx context.Context, order StandingOrder) (*StandingOrder, error) {
+	log := logger.FromContext(ctx)
+
+	if order.ID == "" {
+		return nil, fmt.Errorf("standing order id is required")
+	}
+	if !order.Amount.Amount.IsPositive() {
+		return nil, fmt.Errorf("standing order amount must be positive")
+	}
+	if order.DebitAccount == order.CreditAccount {
+		return nil, fmt.Errorf("debit and credit accounts must differ")
+	}
+	for _, id := range []string{order.DebitAccount, order.CreditAccount} {
+		balance, err := s.ledger.GetAccountBalance(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if balance.Currency != order.Amount.Currency {
+			return nil, fmt.Errorf("account %s currency %s does not match amount currency %s",
+				id, balance.Currency, order.Amount.Currency)
+		}
+	}
+	if order.MaxCount < 0 {
+		return nil, fmt.Errorf("max count must not be negative")
+	}
+	if order.Start.IsZero() {
+		order.Start = s.now()
+	}
+	order.Start = order.Start.UTC()
+	if order.End != nil && !order.End.After(order.Start) {
+		return nil, fmt.Errorf("end %s must be after start %s", order.End.Format(time.RFC3339), order.Start.Format(time.RFC3339))
+	}
+
+	schedule, err := ParseSchedule(order.Schedule, order.Start)
+	if err != nil {
+		return nil, err
+	}
+	order.schedule = schedule
+	order.Status = StatusActive
+	order.Occurrences = []Occurrence{}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.orders[order.ID]; exists {
+		return nil, fmt.Errorf("standing order %s already exists", order.ID)
+	}
+	s.advance(&order)
+	s.orders[order.ID] = &order
+
+	log.Info("Standing order created",
+		zap.String("order_id", order.ID),
+		zap.String("schedule", order.Schedule))
+	return copyOrder(&order), nil
+}
+
+// Get returns a standing order with its occurrence log.
+func (s *Scheduler) Get(id string) (*StandingOrder, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, exists := s.orders[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return copyOrder(order), nil
+}
+
+// List returns all standing orders ordered by ID.
+func (s *Scheduler) List() []StandingOrder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orders := make([]StandingOrder, 0, len(s.orders))
+	for _, order := range s.orders {
+		orders = append(orders, *copyOrder(order))
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].ID < orders[j].ID })
+	return orders
+}
+
+// Cancel stops a standing order. Its occurrence log is kept.
+func (s *Scheduler) Cancel(id string) (*StandingOrder, error) {
+	log := logger.Get()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	order, exists := s.orders[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	order.Status = StatusCancelled
+	order.NextRun = nil
+
+	log.Info("Standing order cancelled", zap.String("order_id", id))
+	return copyOrder(order), nil
+}
+
+// Run posts due occurrences on every tick until the context is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.RunDue(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue retries occurrences that failed on an earlier run and posts the
+// occurrences that have fallen due since, at most maxCatchUp per order, so
+// that an order that missed many due dates does not hold the scheduler for
+// long; the rest are posted on the next runs. It returns the number of
+// transactions recorded.
+func (s *Scheduler) RunDue(ctx context.Context) int {
+	log := logger.FromContext(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	ids := make([]string, 0, len(s.orders))
+	for id := range s.orders {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	posted := 0
+	for _, id := range ids {
+		order := s.orders[id]
+		if order.Status == StatusCancelled {
+			continue
+		}
+
+		for i := range order.Occurrences {
+			if order.Occurrences[i].Status == OccurrenceRetrying && s.post(ctx, order, &order.Occurrences[i]) {
+				posted++
+			}
+		}
+
+		for caughtUp := 0; order.NextRun != nil && !order.NextRun.After(now); caughtUp++ {
+			if caughtUp == s.maxCatchUp {
+				log.Warn("Standing order is behind schedule, posting the remaining occurrences on the next runs",
+					zap.String("order_id", order.ID),
+					zap.Time("next_run", *order.NextRun))
+				break
+			}
+			due := *order.NextRun
+			order.Occurrences = append(order.Occurrences, Occurrence{
+				TransactionID: fmt.Sprintf("%s-%s", order.ID, due.Format("20060102T150405Z")),
+				Due:           due,
+			})
+			if s.post(ctx, order, &order.Occurrences[len(order.Occurrences)-1]) {
+				posted++
+			}
+			s.advance(order)
+		}
+	}
+	return posted
+}
+
+// post attempts to record an occurrence and updates its state. It reports
+// whether the transaction was recorded. An occurrence whose transaction ID
+// the ledger already holds counts as posted.
+func (s *Scheduler) post(ctx context.Context, order *StandingOrder, occurrence *Occurrence) bool {
+	log := logger.FromContext(ctx)
+
+	occurrence.Attempts++
+	err := s.ledger.RecordSystemTransaction(ctx, models.Transaction{
+		ID:            occurrence.TransactionID,
+		Description:   order.Description,
+		DebitAccount:  order.DebitAccount,
+		CreditAccount: order.CreditAccount,
+		Amount:        order.Amount,
+	})
+	if err == nil {
+		occurrence.Status, occurrence.LastError = OccurrencePosted, ""
+		log.Info("Standing order occurrence posted",
+			zap.String("order_id", order.ID),
+			zap.String("tx_id", occurrence.TransactionID))
+		return true
+	}
+	if errors.Is(err, ledger.ErrDuplicate) {
+		// An earlier attempt was recorded although it was not seen to succeed
+		occurrence.Status, occurrence.LastError = OccurrencePosted, ""
+		log.Warn("Standing order occurrence was already posted",
+			zap.String("order_id", order.ID),
+			zap.String("tx_id", occurrence.TransactionID))
+		return false
+	}
+
+	occurrence.LastError = err.Error()
+	occurrence.Status = OccurrenceRetrying
+	if occurrence.Attempts >= s.maxAttempts {
+		occurrence.Status = OccurrenceFailed
+	}
+	log.Error("Standing order occurrence failed",
+		zap.Error(err),
+		zap.String("order_id", order.ID),
+		zap.String("tx_id", occurrence.TransactionID),
+		zap.Int("attempts", occurrence.Attempts),
+		zap.String("status", occurrence.Status))
+	return false
+}
+
+// advance moves NextRun to the occurrence after the last one recorded and
+// completes the order when the schedule, end date or max count is exhausted.
+func (s *Scheduler) advance(order *StandingOrder) {
+	after := order.Start.Add(-time.Nanosecond)
+	if n := len(order.Occurrences); n > 0 {
+		after = order.Occurrences[n-1].Due
+	}
+
+	next := order.schedule.Next(after)
+	switch {
+	case next.IsZero(),
+		order.End != nil && next.After(*order.End),
+		order.MaxCount > 0 && len(order.Occurrences) >= order.MaxCount:
+		order.Status = StatusCompleted
+		order.NextRun = nil
+	default:
+		order.NextRun = &next
+	}
+}
+
+func copyOrder(order *StandingOrder) *StandingOrder {
+	copied := *order
+	copied.Occurrences = append([]Occurrence{}, order.Occurrences...)
+	if order.NextRun != nil {
+		next := *order.NextRun
+		copied.NextRun = &next
+	}
+	return &copied
+}
@@ -0,0 +1,257 @@
+package scheduler
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"ledgerproject/config"
+	"ledgerproject/ledger"
+	"ledgerproject/logger"
+	"ledgerproject/models"
+	"ledgerproject/services"
+	"testing"
+	"time"
+)
+
+// setupTestLogger initializes a test logger
+func setupTestLogger(t *testing.T) *zap.Logger {
+	testLogger := zaptest.NewLogger(t)
+	// Initialize the package-level logger
+	if err := logger.Init(true); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	return testLogger
+}
+
+func usd(amount int64) models.Money {
+	return models.Money{Amount: decimal.NewFromInt(amount), Currency: "USD"}
+}
+
+// setupTest returns a scheduler whose clock reads *now, over a ledger where
+// TENANT holds 250 USD and pays LANDLORD.
+func setupTest(t *testing.T, now *time.Time) (*Scheduler, ledger.LedgerService) {
+	setupTestLogger(t)
+
+	validator, err := services.NewCurrencyValidator(&config.Config{
+		CurrencyFile: "../data/iso4217_currency_test.json",
+	})
+	require.NoError(t, err)
+
+	l := ledger.NewDetachedLedger(validator)
+	for _, acc := range []models.Account{
+		{ID: "TENANT", Name: "Tenant", Currency: "USD", Balance: usd(250)},
+		{ID: "LANDLORD", Name: "Landlord", Currency: "USD"},
+		{ID: "EURO", Name: "Euro account", Currency: "EUR"},
+	} {
+		require.NoError(t, l.CreateAccount(context.Background(), acc))
+	}
+
+	s := NewScheduler(l, &config.Config{SchedulerInterval: time.Minute, SchedulerMaxAttempts: 2, SchedulerMaxCatchUp: 12})
+	s.now = func() time.Time { return *now }
+	return s, l
+}
+
+func rent(schedule string) StandingOrder {
+	return StandingOrder{
+		ID:            "RENT",
+		Description:   "Monthly rent",
+		DebitAccount:  "TENANT",
+		CreditAccount: "LANDLORD",
+		Amount:        usd(100),
+		Schedule:      schedule,
+		Start:         at("2024-01-01T00:00:00Z"),
+	}
+}
+
+func TestCreate(t *testing.T) {
+	now := at("2024-01-01T00:00:00Z")
+	s, _ := setupTest(t, &now)
+
+	end := at("2023-12-01T00:00:00Z")
+	tests := []struct {
+		name    string
+		modify  func(o *StandingOrder)
+		wantErr bool
+	}{
+		{"valid", func(o *StandingOrder) {}, false},
+		{"duplicate id", func(o *StandingOrder) {}, true},
+		{"missing id", func(o *StandingOrder) { o.ID = "" }, true},
+		{"bad schedule", func(o *StandingOrder) { o.ID, o.Schedule = "BAD", "every day" }, true},
+		{"zero amount", func(o *StandingOrder) { o.ID, o.Amount = "ZERO", usd(0) }, true},
+		{"unknown account", func(o *StandingOrder) { o.ID, o.CreditAccount = "UNKNOWN", "MISSING" }, true},
+		{"currency mismatch", func(o *StandingOrder) { o.ID, o.CreditAccount = "EUR", "EURO" }, true},
+		{"end before start", func(o *StandingOrder) { o.ID, o.End = "END", &end }, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order := rent("0 0 1 * *")
+			tt.modify(&order)
+			created, err := s.Create(context.Background(), order)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, StatusActive, created.Status)
+			assert.Equal(t, at("2024-01-01T00:00:00Z"), *created.NextRun)
+		})
+	}
+
+	assert.Len(t, s.List(), 1)
+}
+
+func TestRunDue(t *testing.T) {
+	t.Run("posts each occurrence once", func(t *testing.T) {
+		now := at("2024-01-15T00:00:00Z")
+		s, l := setupTest(t, &now)
+		_, err := s.Create(context.Background(), rent("FREQ=MONTHLY"))
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, s.RunDue(context.Background()))
+		assert.Equal(t, 0, s.RunDue(context.Background()))
+
+		now = at("2024-02-01T00:00:00Z")
+		assert.Equal(t, 1, s.RunDue(context.Background()))
+
+		history := l.GetTransactionHistory(context.Background(), "TENANT")
+		require.Len(t, history, 2)
+		assert.Equal(t, "RENT-20240101T000000Z", history[0].ID)
+		assert.Equal(t, "RENT-20240201T000000Z", history[1].ID)
+
+		order, err := s.Get("RENT")
+		require.NoError(t, err)
+		assert.Equal(t, at("2024-03-01T00:00:00Z"), *order.NextRun)
+	})
+
+	t.Run("retries and records failures", func(t *testing.T) {
+		now := at("2024-03-15T00:00:00Z")
+		s, l := setupTest(t, &now)
+		_, err := s.Create(context.Background(), rent("0 0 1 * *"))
+		require.NoError(t, err)
+
+		// Only two of the three rents are covered by the tenant's balance
+		assert.Equal(t, 2, s.RunDue(context.Background()))
+		order, err := s.Get("RENT")
+		require.NoError(t, err)
+		require.Len(t, order.Occurrences, 3)
+		failed := order.Occurrences[2]
+		assert.Equal(t, OccurrenceRetrying, failed.Status)
+		assert.Equal(t, 1, failed.Attempts)
+		assert.Contains(t, failed.LastError, "insufficient funds")
+
+		// The second attempt fails too and the occurrence is given up
+		assert.Equal(t, 0, s.RunDue(context.Background()))
+		order, err = s.Get("RENT")
+		require.NoError(t, err)
+		assert.Equal(t, OccurrenceFailed, order.Occurrences[2].Status)
+		assert.Equal(t, 2, order.Occurrences[2].Attempts)
+
+		require.NoError(t, l.RecordTransaction(context.Background(), models.Transaction{
+			ID: "TOPUP", DebitAccount: "LANDLORD", CreditAccount: "TENANT", Amount: usd(200),
+		}))
+		assert.Equal(t, 0, s.RunDue(context.Background()))
+	})
+
+	t.Run("retry succeeds once funds arrive", func(t *testing.T) {
+		now := at("2024-03-15T00:00:00Z")
+		s, l := setupTest(t, &now)
+		_, err := s.Create(context.Background(), rent("0 0 1 * *"))
+		require.NoError(t, err)
+		assert.Equal(t, 2, s.RunDue(context.Background()))
+
+		require.NoError(t, l.RecordTransaction(context.Background(), models.Transaction{
+			ID: "TOPUP", DebitAccount: "LANDLORD", CreditAccount: "TENANT", Amount: usd(50),
+		}))
+		assert.Equal(t, 1, s.RunDue(context.Background()))
+		order, err := s.Get("RENT")
+		require.NoError(t, err)
+		assert.Equal(t, OccurrencePosted, order.Occurrences[2].Status)
+		assert.Equal(t, 2, order.Occurrences[2].Attempts)
+	})
+
+	t.Run("stops at max count", func(t *testing.T) {
+		now := at("2024-12-31T00:00:00Z")
+		s, _ := setupTest(t, &now)
+		order := rent("@monthly")
+		order.MaxCount = 2
+		_, err := s.Create(context.Background(), order)
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, s.RunDue(context.Background()))
+		got, err := s.Get("RENT")
+		require.NoError(t, err)
+		assert.Equal(t, StatusCompleted, got.Status)
+		assert.Nil(t, got.NextRun)
+	})
+
+	t.Run("stops at end date", func(t *testing.T) {
+		now := at("2024-12-31T00:00:00Z")
+		s, _ := setupTest(t, &now)
+		order := rent("@monthly")
+		order.Amount = usd(1)
+		end := at("2024-03-01T00:00:00Z")
+		order.End = &end
+		_, err := s.Create(context.Background(), order)
+		require.NoError(t, err)
+
+		assert.Equal(t, 3, s.RunDue(context.Background()))
+		got, err := s.Get("RENT")
+		require.NoError(t, err)
+		assert.Equal(t, StatusCompleted, got.Status)
+	})
+
+	t.Run("catches up a few occurrences per run", func(t *testing.T) {
+		now := at("2024-01-31T00:00:00Z")
+		s, l := setupTest(t, &now)
+		order := rent("@daily")
+		order.Amount = usd(1)
+		_, err := s.Create(context.Background(), order)
+		require.NoError(t, err)
+
+		assert.Equal(t, 12, s.RunDue(context.Background()))
+		got, err := s.Get("RENT")
+		require.NoError(t, err)
+		assert.Equal(t, at("2024-01-13T00:00:00Z"), *got.NextRun)
+
+		assert.Equal(t, 12, s.RunDue(context.Background()))
+		assert.Equal(t, 7, s.RunDue(context.Background()))
+		assert.Len(t, l.GetTransactionHistory(context.Background(), "TENANT"), 31)
+	})
+
+	t.Run("occurrences already in the ledger count as posted", func(t *testing.T) {
+		now := at("2024-01-15T00:00:00Z")
+		s, l := setupTest(t, &now)
+		require.NoError(t, l.RecordTransaction(context.Background(), models.Transaction{
+			ID: "RENT-20240101T000000Z", DebitAccount: "TENANT", CreditAccount: "LANDLORD", Amount: usd(100),
+		}))
+		_, err := s.Create(context.Background(), rent("0 0 1 * *"))
+		require.NoError(t, err)
+
+		assert.Equal(t, 0, s.RunDue(context.Background()))
+		order, err := s.Get("RENT")
+		require.NoError(t, err)
+		require.Len(t, order.Occurrences, 1)
+		assert.Equal(t, OccurrencePosted, order.Occurrences[0].Status)
+		assert.Empty(t, order.Occurrences[0].LastError)
+		assert.Len(t, l.GetTransactionHistory(context.Background(), "TENANT"), 1)
+	})
+
+	t.Run("cancelled orders do not run", func(t *testing.T) {
+		now := at("2024-02-15T00:00:00Z")
+		s, _ := setupTest(t, &now)
+		_, err := s.Create(context.Background(), rent("@monthly"))
+		require.NoError(t, err)
+
+		cancelled, err := s.Cancel("RENT")
+		require.NoError(t, err)
+		assert.Equal(t, StatusCancelled, cancelled.Status)
+		assert.Equal(t, 0, s.RunDue(context.Background()))
+
+		_, err = s.Cancel("MISSING")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+}
@@ -10,21 +10,27 @@ import (
 	"sync"
 )
 
+// defaultMinorUnits is used for currencies whose entry has no minor_unit.
+const defaultMinorUnits int32 = 2
+
 type CurrencyValidator struct {
 	validCurrencies map[string]struct{}
+	minorUnits      map[string]int32
 	mu              sync.RWMutex
 	config          *config.Config
 }
 
 type currencyData struct {
-	Code   string `json:"code"`
-	Name   string `json:"name"`
-	Number string `json:"number"`
+	Code      string `json:"code"`
+	Name      string `json:"name"`
+	Number    string `json:"number"`
+	MinorUnit *int32 `json:"minor_unit"`
 }
 
 func NewCurrencyValidator(config *config.Config) (*CurrencyValidator, error) {
 	cv := &CurrencyValidator{
 		validCurrencies: make(map[string]struct{}),
+		minorUnits:      make(map[string]int32),
 		config:          config,
 	}
 
@@ -61,6 +67,10 @@ func (cv *CurrencyValidator) loadCurrencies() error {
 
 	for _, currency := range data.Currencies {
 		cv.validCurrencies[currency.Code] = struct{}{}
+		cv.minorUnits[currency.Code] = defaultMinorUnits
+		if currency.MinorUnit != nil {
+			cv.minorUnits[currency.Code] = *currency.MinorUnit
+		}
 	}
 
 	log.Info("Currency data loaded successfully",
@@ -68,6 +78,13 @@ func (cv *CurrencyValidator) loadCurrencies() error {
 	return nil
 }
 
+// Len returns the number of currencies loaded.
+func (cv *CurrencyValidator) Len() int {
+	cv.mu.RLock()
+	defer cv.mu.RUnlock()
+	return len(cv.validCurrencies)
+}
+
 func (cv *CurrencyValidator) IsValid(code string) bool {
 	log := logger.Get()
 	cv.mu.RLock()
@@ -78,3 +95,36 @@ func (cv *CurrencyValidator) IsValid(code string) bool {
 	log.Info("Currency code is valid", zap.String("currency_code", code))
 	return exists
 }
+
+// MinorUnits returns the number of decimal places used by the currency, as
+// given by the ISO 4217 minor unit. Unknown currencies default to two.
+func (cv *CurrencyValidator) MinorUnits(code string) int32 {
+	cv.mu.RLock()
+	defer cv.mu.RUnlock()
+
+	if units, exists := cv.minorUnits[code]; exists {
+		return units
+	}
+	return defaultMinorUnits
+}
+
+// Subset returns a validator that accepts only the given codes, keeping their
+// minor units. Every code must be valid in cv.
+func (cv *CurrencyValidator) Subset(codes []string) (*CurrencyValidator, error) {
+	cv.mu.RLock()
+	defer cv.mu.RUnlock()
+
+	subset := &CurrencyValidator{
+		validCurrencies: make(map[string]struct{}, len(codes)),
+		minorUnits:      make(map[string]int32, len(codes)),
+		config:          cv.config,
+	}
+	for _, code := range codes {
+		if _, exists := cv.validCurrencies[code]; !exists {
+			return nil, fmt.Errorf("invalid currency code: %s", code)
+		}
+		subset.validCurrencies[code] = struct{}{}
+		subset.minorUnits[code] = cv.minorUnits[code]
+	}
+	return subset, nil
+}
@@ -26,7 +26,9 @@ const TestData = `{
     "currencies": [
         {"code": "USD", "name": "US Dollar", "number": "840"},
         {"code": "EUR", "name": "Euro", "number": "978"},
-        {"code": "GBP", "name": "British Pound", "number": "826"}
+        {"code": "GBP", "name": "British Pound", "number": "826"},
+        {"code": "JPY", "name": "Yen", "number": "392", "minor_unit": 0},
+        {"code": "KWD", "name": "Kuwaiti Dinar", "number": "414", "minor_unit": 3}
     ]
 }`
 
@@ -178,6 +180,65 @@ func TestCurrencyValidator_IsValid(t *testing.T) {
 	}
 }
 
+func TestCurrencyValidator_MinorUnits(t *testing.T) {
+	_, cfg, cleanup := setupTestData(t)
+	defer cleanup()
+
+	cv, err := NewCurrencyValidator(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		code string
+		want int32
+	}{
+		{name: "Explicit zero", code: "JPY", want: 0},
+		{name: "Explicit three", code: "KWD", want: 3},
+		{name: "Missing minor unit defaults to two", code: "USD", want: 2},
+		{name: "Unknown currency defaults to two", code: "XXX", want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cv.MinorUnits(tt.code); got != tt.want {
+				t.Errorf("CurrencyValidator.MinorUnits() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCurrencyValidator_Subset(t *testing.T) {
+	_, cfg, cleanup := setupTestData(t)
+	defer cleanup()
+
+	cv, err := NewCurrencyValidator(cfg)
+	if err != nil {
+		t.Fatalf("Failed to create validator: %v", err)
+	}
+
+	subset, err := cv.Subset([]string{"USD", "JPY"})
+	if err != nil {
+		t.Fatalf("Failed to create subset: %v", err)
+	}
+	for code, want := range map[string]bool{"USD": true, "JPY": true, "EUR": false, "XXX": false} {
+		if got := subset.IsValid(code); got != want {
+			t.Errorf("Subset.IsValid(%s) = %v, want %v", code, got, want)
+		}
+	}
+	if got := subset.MinorUnits("JPY"); got != 0 {
+		t.Errorf("Subset.MinorUnits(JPY) = %v, want 0", got)
+	}
+	if got := subset.Len(); got != 2 {
+		t.Errorf("Subset.Len() = %v, want 2", got)
+	}
+
+	if _, err := cv.Subset([]string{"USD", "XXX"}); err == nil {
+		t.Error("Expected error for a currency outside the validator")
+	}
+}
+
 func TestCurrencyValidator_Concurrent(t *testing.T) {
 	// Setup test data
 	tmpDir, cfg, cleanup := setupTestData(t)
@@ -0,0 +1,195 @@
+package statements
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"time"
+)
+
+// Camt053Namespace is the XML namespace of the ISO 20022 BankToCustomerStatement
+// message version produced by the generator.
+const Camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+// ISO 20022 code values used in the statement.
+const (
+	Credit = "CRDT"
+	Debit  = "DBIT"
+
+	BalanceOpeningBooked = "OPBD"
+	BalanceClosingBooked = "CLBD"
+
+	EntryStatusBooked = "BOOK"
+)
+
+// The types below mirror the camt.053.001.02 schema. Only the elements the
+// ledger can fill are modelled; element order follows the XSD sequence.
+
+type Document struct {
+	XMLName xml.Name                `xml:"Document"`
+	Xmlns   string                  `xml:"xmlns,attr"`
+	Stmt    BankToCustomerStatement `xml:"BkToCstmrStmt"`
+}
+
+type BankToCustomerStatement struct {
+	GroupHeader GroupHeader `xml:"GrpHdr"`
+	Statements  []Statement `xml:"Stmt"`
+}
+
+type GroupHeader struct {
+	MessageID        string `xml:"MsgId"`
+	CreationDateTime string `xml:"CreDtTm"`
+}
+
+type Statement struct {
+	ID               string               `xml:"Id"`
+	CreationDateTime string               `xml:"CreDtTm"`
+	FromToDate       FromToDate           `xml:"FrToDt"`
+	Account          CashAccount          `xml:"Acct"`
+	Balances         []Balance            `xml:"Bal"`
+	Summary          *TransactionsSummary `xml:"TxsSummry,omitempty"`
+	Entries          []Entry              `xml:"Ntry"`
+}
+
+type FromToDate struct {
+	FromDateTime string `xml:"FrDtTm"`
+	ToDateTime   string `xml:"ToDtTm"`
+}
+
+type CashAccount struct {
+	ID       AccountID `xml:"Id"`
+	Currency string    `xml:"Ccy"`
+}
+
+type AccountID struct {
+	Other GenericAccountID `xml:"Othr"`
+}
+
+type GenericAccountID struct {
+	ID string `xml:"Id"`
+}
+
+type Balance struct {
+	Type                 BalanceType     `xml:"Tp"`
+	Amount               Amount          `xml:"Amt"`
+	CreditDebitIndicator string          `xml:"CdtDbtInd"`
+	Date                 DateAndDateTime `xml:"Dt"`
+}
+
+type BalanceType struct {
+	CodeOrProprietary CodeOrProprietary `xml:"CdOrPrtry"`
+}
+
+type CodeOrProprietary struct {
+	Code string `xml:"Cd"`
+}
+
+type Amount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type DateAndDateTime struct {
+	Date     string `xml:"Dt,omitempty"`
+	DateTime string `xml:"DtTm,omitempty"`
+}
+
+type TransactionsSummary struct {
+	TotalEntries       NumberAndSum       `xml:"TtlNtries"`
+	TotalCreditEntries NumberAndSumSimple `xml:"TtlCdtNtries"`
+	TotalDebitEntries  NumberAndSumSimple `xml:"TtlDbtNtries"`
+}
+
+type NumberAndSum struct {
+	NumberOfEntries      int    `xml:"NbOfNtries"`
+	Sum                  string `xml:"Sum"`
+	TotalNetEntryAmount  string `xml:"TtlNetNtryAmt"`
+	CreditDebitIndicator string `xml:"CdtDbtInd"`
+}
+
+type NumberAndSumSimple struct {
+	NumberOfEntries int    `xml:"NbOfNtries"`
+	Sum             string `xml:"Sum"`
+}
+
+type Entry struct {
+	Reference                string              `xml:"NtryRef"`
+	Amount                   Amount              `xml:"Amt"`
+	CreditDebitIndicator     string              `xml:"CdtDbtInd"`
+	Status                   string              `xml:"Sts"`
+	BookingDate              DateAndDateTime     `xml:"BookgDt"`
+	ValueDate                DateAndDateTime     `xml:"ValDt"`
+	AccountServicerReference string              `xml:"AcctSvcrRef"`
+	BankTransactionCode      BankTransactionCode `xml:"BkTxCd"`
+	Details                  EntryDetails        `xml:"NtryDtls"`
+}
+
+type BankTransactionCode struct {
+	Proprietary ProprietaryCode `xml:"Prtry"`
+}
+
+type ProprietaryCode struct {
+	Code   string `xml:"Cd"`
+	Issuer string `xml:"Issr,omitempty"`
+}
+
+type EntryDetails struct {
+	TransactionDetails []TransactionDetails `xml:"TxDtls"`
+}
+
+type TransactionDetails struct {
+	References     References      `xml:"Refs"`
+	RelatedParties *RelatedParties `xml:"RltdPties,omitempty"`
+	RemittanceInfo *RemittanceInfo `xml:"RmtInf,omitempty"`
+}
+
+type References struct {
+	AccountServicerReference string `xml:"AcctSvcrRef"`
+	EndToEndID               string `xml:"EndToEndId"`
+}
+
+type RelatedParties struct {
+	DebtorAccount   *CashAccountRef `xml:"DbtrAcct,omitempty"`
+	CreditorAccount *CashAccountRef `xml:"CdtrAcct,omitempty"`
+}
+
+type CashAccountRef struct {
+	ID AccountID `xml:"Id"`
+}
+
+type RemittanceInfo struct {
+	Unstructured string `xml:"Ustrd"`
+}
+
+// indicator splits a signed amount into the unsigned value and CRDT/DBIT
+// indicator that ISO 20022 expects. Zero is reported as a credit.
+func indicator(amount decimal.Decimal) (string, string) {
+	if amount.IsNegative() {
+		return amount.Neg().String(), Debit
+	}
+	return amount.String(), Credit
+}
+
+func isoDate(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+func isoDateTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+func accountRef(id string) *CashAccountRef {
+	if id == "" {
+		return nil
+	}
+	return &CashAccountRef{ID: AccountID{Other: GenericAccountID{ID: fit(id, maxAccountIDLength)}}}
+}
+
+// Marshal renders the document with an XML declaration.
+func (d *Document) Marshal() ([]byte, error) {
+	out, err := xml.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("error encoding camt.053 document: %v", err)
+	}
+	return append([]byte(xml.Header), out...), nil
+}
@@ -0,0 +1,201 @@
+package statements
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"ledgerproject/ledger"
+	"ledgerproject/logger"
+	"ledgerproject/models"
+	"strings"
+	"time"
+)
+
+// Generator builds account statements from the ledger's transaction history.
+type Generator struct {
+	ledger ledger.LedgerService
+	now    func() time.Time
+}
+
+func NewGenerator(l ledger.LedgerService) *Generator {
+	return &Generator{
+		ledger: l,
+		now:    time.Now,
+	}
+}
+
+// Camt053 builds an ISO 20022 camt.053 statement for the account covering
+// transactions booked in [from, to). Opening and closing balances are derived
+// by unwinding the current balance through the history.
+func (g *Generator) Camt053(ctx context.Context, accountID string, from, to time.Time) (*Document, error) {
+	log := logger.FromContext(ctx)
+
+	if !from.Before(to) {
+		return nil, fmt.Errorf("statement period start %s must be before end %s", isoDate(from), isoDate(to))
+	}
+
+	balance, err := g.ledger.GetAccountBalance(ctx, accountID)
+	if err != nil {
+		return nil, err
+	}
+	history := g.ledger.GetTransactionHistory(ctx, accountID)
+
+	// Walk back from the current balance to the end of the period
+	closing := balance.Amount
+	var inPeriod []models.Transaction
+	for _, tx := range history {
+		switch {
+		case !tx.DateTime.Before(to):
+			closing = closing.Sub(tx.Delta(accountID))
+		case !tx.DateTime.Before(from):
+			inPeriod = append(inPeriod, tx)
+		}
+	}
+
+	opening := closing
+	var credits, debits, creditSum, debitSum = 0, 0, decimal.Zero, decimal.Zero
+	entries := make([]Entry, 0, len(inPeriod))
+	for _, tx := range inPeriod {
+		change := tx.Delta(accountID)
+		opening = opening.Sub(change)
+		if change.IsNegative() {
+			debits++
+			debitSum = debitSum.Add(change.Neg())
+		} else {
+			credits++
+			creditSum = creditSum.Add(change)
+		}
+		entries = append(entries, g.entry(tx, accountID, change, balance.Currency))
+	}
+
+	created := g.now().UTC()
+	net, netIndicator := indicator(creditSum.Sub(debitSum))
+	openingAmount, openingIndicator := indicator(opening)
+	closingAmount, closingIndicator := indicator(closing)
+
+	doc := &Document{
+		Xmlns: Camt053Namespace,
+		Stmt: BankToCustomerStatement{
+			GroupHeader: GroupHeader{
+				MessageID:        messageID(accountID, created),
+				CreationDateTime: isoDateTime(created),
+			},
+			Statements: []Statement{{
+				ID:               statementID(accountID, from, to),
+				CreationDateTime: isoDateTime(created),
+				FromToDate: FromToDate{
+					FromDateTime: isoDateTime(from),
+					ToDateTime:   isoDateTime(to),
+				},
+				Account: CashAccount{
+					ID:       AccountID{Other: GenericAccountID{ID: fit(accountID, maxAccountIDLength)}},
+					Currency: balance.Currency,
+				},
+				Balances: []Balance{
+					{
+						Type:                 BalanceType{CodeOrProprietary: CodeOrProprietary{Code: BalanceOpeningBooked}},
+						Amount:               Amount{Currency: balance.Currency, Value: openingAmount},
+						CreditDebitIndicator: openingIndicator,
+						Date:                 DateAndDateTime{Date: isoDate(from)},
+					},
+					{
+						Type:                 BalanceType{CodeOrProprietary: CodeOrProprietary{Code: BalanceClosingBooked}},
+						Amount:               Amount{Currency: balance.Currency, Value: closingAmount},
+						CreditDebitIndicator: closingIndicator,
+						Date:                 DateAndDateTime{Date: isoDate(to.Add(-time.Nanosecond))},
+					},
+				},
+				Summary: &TransactionsSummary{
+					TotalEntries: NumberAndSum{
+						NumberOfEntries:      len(entries),
+						Sum:                  creditSum.Add(debitSum).String(),
+						TotalNetEntryAmount:  net,
+						CreditDebitIndicator: netIndicator,
+					},
+					TotalCreditEntries: NumberAndSumSimple{NumberOfEntries: credits, Sum: creditSum.String()},
+					TotalDebitEntries:  NumberAndSumSimple{NumberOfEntries: debits, Sum: debitSum.String()},
+				},
+				Entries: entries,
+			}},
+		},
+	}
+
+	log.Info("camt.053 statement generated",
+		zap.String("account_id", accountID),
+		zap.Int("entries", len(entries)))
+	return doc, nil
+}
+
+// Lengths of the ISO 20022 Max35Text type used for identifiers and
+// references, and of the Max34Text type used for account identifiers.
+const (
+	maxIDLength        = 35
+	maxAccountIDLength = 34
+)
+
+// messageID identifies one generated message: the creation time to the second
+// followed by a digest of the account and the exact creation time, so that
+// long account IDs still fit in Max35Text.
+func messageID(accountID string, created time.Time) string {
+	id := "STMT-" + created.Format("20060102150405") + "-"
+	return id + digest(accountID, fmt.Sprint(created.UnixNano()))[:maxIDLength-len(id)]
+}
+
+// statementID identifies the statement of an account for a period. It is
+// stable, so regenerating the same statement yields the same ID.
+func statementID(accountID string, from, to time.Time) string {
+	return digest(accountID)[:16] + "-" + from.UTC().Format("20060102") + "-" + to.UTC().Format("20060102")
+}
+
+func digest(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// fit returns id unchanged when it has at most max characters. Longer IDs,
+// such as those of standing order occurrences and interest accruals, keep
+// their start followed by a digest of the whole ID, so they stay
+// recognizable and distinct.
+func fit(id string, max int) string {
+	runes := []rune(id)
+	if len(runes) <= max {
+		return id
+	}
+	const digestLength = 16
+	return string(runes[:max-digestLength-1]) + "-" + digest(id)[:digestLength]
+}
+
+func (g *Generator) entry(tx models.Transaction, accountID string, change decimal.Decimal, currency string) Entry {
+	value, cdtDbt := indicator(change)
+	ref := fit(tx.ID, maxIDLength)
+
+	details := TransactionDetails{
+		References: References{AccountServicerReference: ref, EndToEndID: ref},
+	}
+	if len(tx.Postings) == 0 {
+		details.RelatedParties = &RelatedParties{
+			DebtorAccount:   accountRef(tx.DebitAccount),
+			CreditorAccount: accountRef(tx.CreditAccount),
+		}
+	}
+	if tx.Description != "" {
+		details.RemittanceInfo = &RemittanceInfo{Unstructured: tx.Description}
+	}
+
+	return Entry{
+		Reference:                ref,
+		Amount:                   Amount{Currency: currency, Value: value},
+		CreditDebitIndicator:     cdtDbt,
+		Status:                   EntryStatusBooked,
+		BookingDate:              DateAndDateTime{DateTime: isoDateTime(tx.DateTime)},
+		ValueDate:                DateAndDateTime{DateTime: isoDateTime(tx.DateTime)},
+		AccountServicerReference: ref,
+		BankTransactionCode: BankTransactionCode{
+			Proprietary: ProprietaryCode{Code: "TRANSFER", Issuer: "LEDGER"},
+		},
+		Details: EntryDetails{TransactionDetails: []TransactionDetails{details}},
+	}
+}
@@ -0,0 +1,318 @@
+package statements
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"io"
+	"ledgerproject/config"
+	"ledgerproject/ledger"
+	"ledgerproject/logger"
+	"ledgerproject/models"
+	"ledgerproject/services"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupTestLogger initializes a test logger
+func setupTestLogger(t *testing.T) *zap.Logger {
+	testLogger := zaptest.NewLogger(t)
+	// Initialize the package-level logger
+	if err := logger.Init(true); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	return testLogger
+}
+
+// setupTest returns a generator over a ledger where CASH started with 1000
+// USD, paid 250 of rent and received 100 of sales.
+func setupTest(t *testing.T) *Generator {
+	setupTestLogger(t)
+
+	validator, err := services.NewCurrencyValidator(&config.Config{
+		CurrencyFile: "../data/iso4217_currency_test.json",
+	})
+	require.NoError(t, err)
+
+	l := ledger.NewDetachedLedger(validator)
+	usd := func(amount int64) models.Money {
+		return models.Money{Amount: decimal.NewFromInt(amount), Currency: "USD"}
+	}
+	for _, acc := range []models.Account{
+		{ID: "CASH", Name: "Cash", Currency: "USD", Balance: usd(1000)},
+		{ID: "RENT", Name: "Rent", Currency: "USD"},
+		{ID: "SALES", Name: "Sales", Currency: "USD", Balance: usd(500)},
+	} {
+		require.NoError(t, l.CreateAccount(context.Background(), acc))
+	}
+	require.NoError(t, l.RecordTransaction(context.Background(), models.Transaction{
+		ID: "TX1", Description: "March rent", DebitAccount: "CASH", CreditAccount: "RENT", Amount: usd(250),
+	}))
+	require.NoError(t, l.RecordTransaction(context.Background(), models.Transaction{
+		ID: "TX2", Description: "Card sales", DebitAccount: "SALES", CreditAccount: "CASH", Amount: usd(100),
+	}))
+
+	g := NewGenerator(l)
+	g.now = func() time.Time { return time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC) }
+	return g
+}
+
+func TestCamt053(t *testing.T) {
+	g := setupTest(t)
+	now := time.Now().UTC()
+
+	t.Run("period covering all transactions", func(t *testing.T) {
+		doc, err := g.Camt053(context.Background(), "CASH", now.Add(-time.Hour), now.Add(time.Hour))
+		require.NoError(t, err)
+
+		assert.Equal(t, Camt053Namespace, doc.Xmlns)
+		stmt := doc.Stmt.Statements[0]
+		assert.Equal(t, "CASH", stmt.Account.ID.Other.ID)
+		assert.Equal(t, "USD", stmt.Account.Currency)
+
+		require.Len(t, stmt.Balances, 2)
+		assert.Equal(t, BalanceOpeningBooked, stmt.Balances[0].Type.CodeOrProprietary.Code)
+		assert.Equal(t, "1000", stmt.Balances[0].Amount.Value)
+		assert.Equal(t, Credit, stmt.Balances[0].CreditDebitIndicator)
+		assert.Equal(t, BalanceClosingBooked, stmt.Balances[1].Type.CodeOrProprietary.Code)
+		assert.Equal(t, "850", stmt.Balances[1].Amount.Value)
+
+		require.Len(t, stmt.Entries, 2)
+		rent := stmt.Entries[0]
+		assert.Equal(t, "TX1", rent.Reference)
+		assert.Equal(t, "250", rent.Amount.Value)
+		assert.Equal(t, "USD", rent.Amount.Currency)
+		assert.Equal(t, Debit, rent.CreditDebitIndicator)
+		assert.Equal(t, EntryStatusBooked, rent.Status)
+		details := rent.Details.TransactionDetails[0]
+		assert.Equal(t, "TX1", details.References.EndToEndID)
+		assert.Equal(t, "RENT", details.RelatedParties.CreditorAccount.ID.Other.ID)
+		assert.Equal(t, "March rent", details.RemittanceInfo.Unstructured)
+		assert.Equal(t, Credit, stmt.Entries[1].CreditDebitIndicator)
+
+		summary := stmt.Summary
+		assert.Equal(t, 2, summary.TotalEntries.NumberOfEntries)
+		assert.Equal(t, "350", summary.TotalEntries.Sum)
+		assert.Equal(t, "150", summary.TotalEntries.TotalNetEntryAmount)
+		assert.Equal(t, Debit, summary.TotalEntries.CreditDebitIndicator)
+		assert.Equal(t, 1, summary.TotalCreditEntries.NumberOfEntries)
+		assert.Equal(t, "250", summary.TotalDebitEntries.Sum)
+	})
+
+	t.Run("identifiers fit Max35Text", func(t *testing.T) {
+		doc, err := g.Camt053(context.Background(), "CASH", now.Add(-time.Hour), now.Add(time.Hour))
+		require.NoError(t, err)
+
+		assert.LessOrEqual(t, len(doc.Stmt.GroupHeader.MessageID), maxIDLength)
+		assert.Regexp(t, `^STMT-20300101000000-[0-9a-f]{15}$`, doc.Stmt.GroupHeader.MessageID)
+		stmt := doc.Stmt.Statements[0]
+		assert.LessOrEqual(t, len(stmt.ID), maxIDLength)
+		assert.Regexp(t, `^[0-9a-f]{16}-\d{8}-\d{8}$`, stmt.ID)
+
+		again, err := g.Camt053(context.Background(), "CASH", now.Add(-time.Hour), now.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, stmt.ID, again.Stmt.Statements[0].ID)
+
+		long := strings.Repeat("ACCOUNT", 10)
+		assert.LessOrEqual(t, len(messageID(long, now)), maxIDLength)
+		assert.LessOrEqual(t, len(statementID(long, now, now.AddDate(0, 1, 0))), maxIDLength)
+	})
+
+	t.Run("long transaction and account IDs", func(t *testing.T) {
+		ctx := context.Background()
+		account := strings.Repeat("A", 40)
+		txID := "interest-SAVINGS-ACCOUNT-000001-20300101"
+		require.Len(t, txID, 40)
+		require.NoError(t, g.ledger.CreateAccount(ctx, models.Account{ID: account, Name: "Long", Currency: "USD"}))
+		require.NoError(t, g.ledger.RecordTransaction(ctx, models.Transaction{
+			ID: txID, DebitAccount: "CASH", CreditAccount: account,
+			Amount: models.Money{Amount: decimal.NewFromInt(5), Currency: "USD"},
+		}))
+
+		doc, err := g.Camt053(ctx, account, now.Add(-time.Hour), now.Add(time.Hour))
+		require.NoError(t, err)
+		stmt := doc.Stmt.Statements[0]
+		assert.LessOrEqual(t, len(stmt.Account.ID.Other.ID), maxAccountIDLength)
+		require.Len(t, stmt.Entries, 1)
+		entry := stmt.Entries[0]
+		assert.LessOrEqual(t, len(entry.Reference), maxIDLength)
+		assert.True(t, strings.HasPrefix(entry.Reference, "interest-SAVINGS"), entry.Reference)
+		assert.Equal(t, entry.Reference, entry.AccountServicerReference)
+		refs := entry.Details.TransactionDetails[0].References
+		assert.Equal(t, entry.Reference, refs.EndToEndID)
+		assert.Equal(t, entry.Reference, refs.AccountServicerReference)
+		parties := entry.Details.TransactionDetails[0].RelatedParties
+		assert.LessOrEqual(t, len(parties.CreditorAccount.ID.Other.ID), maxAccountIDLength)
+		assert.Equal(t, "CASH", parties.DebtorAccount.ID.Other.ID)
+
+		// Distinct long IDs stay distinct
+		assert.NotEqual(t, fit(txID, maxIDLength), fit("interest-SAVINGS-ACCOUNT-000001-20300102", maxIDLength))
+	})
+
+	t.Run("period before any transaction", func(t *testing.T) {
+		doc, err := g.Camt053(context.Background(), "CASH", now.AddDate(0, -1, 0), now.AddDate(0, 0, -1))
+		require.NoError(t, err)
+
+		stmt := doc.Stmt.Statements[0]
+		assert.Empty(t, stmt.Entries)
+		assert.Equal(t, "1000", stmt.Balances[0].Amount.Value)
+		assert.Equal(t, "1000", stmt.Balances[1].Amount.Value)
+	})
+
+	t.Run("unknown account", func(t *testing.T) {
+		_, err := g.Camt053(context.Background(), "MISSING", now.Add(-time.Hour), now)
+		assert.Error(t, err)
+	})
+
+	t.Run("empty period", func(t *testing.T) {
+		_, err := g.Camt053(context.Background(), "CASH", now, now)
+		assert.Error(t, err)
+	})
+}
+
+// camt053Schema lists, for each element we emit, the child elements allowed by
+// the camt.053.001.02 XSD in sequence order. Required children are marked
+// with a leading "!".
+var camt053Schema = map[string][]string{
+	"Document":      {"!BkToCstmrStmt"},
+	"BkToCstmrStmt": {"!GrpHdr", "!Stmt"},
+	"GrpHdr":        {"!MsgId", "!CreDtTm", "MsgRcpt", "MsgPgntn", "AddtlInf"},
+	"Stmt": {"!Id", "ElctrncSeqNb", "LglSeqNb", "!CreDtTm", "FrToDt", "CpyDplctInd", "RptgSrc",
+		"!Acct", "RltdAcct", "Intrst", "!Bal", "TxsSummry", "Ntry", "AddtlStmtInf"},
+	"FrToDt":       {"!FrDtTm", "!ToDtTm"},
+	"Acct":         {"!Id", "Tp", "Ccy", "Nm", "Ownr", "Svcr"},
+	"Id":           {"IBAN", "Othr"},
+	"Othr":         {"!Id", "SchmeNm", "Issr"},
+	"Bal":          {"!Tp", "CdtLine", "!Amt", "!CdtDbtInd", "!Dt", "Avlbty"},
+	"Tp":           {"!CdOrPrtry", "SubTp"},
+	"CdOrPrtry":    {"Cd", "Prtry"},
+	"Dt":           {"Dt", "DtTm"},
+	"TxsSummry":    {"TtlNtries", "TtlCdtNtries", "TtlDbtNtries", "TtlNtriesPerBkTxCd"},
+	"TtlNtries":    {"NbOfNtries", "Sum", "TtlNetNtryAmt", "CdtDbtInd"},
+	"TtlCdtNtries": {"NbOfNtries", "Sum"},
+	"TtlDbtNtries": {"NbOfNtries", "Sum"},
+	"Ntry": {"NtryRef", "!Amt", "!CdtDbtInd", "RvslInd", "!Sts", "BookgDt", "ValDt", "AcctSvcrRef",
+		"Avlbty", "!BkTxCd", "ComssnWvrInd", "AddtlInfInd", "AmtDtls", "Chrgs", "TechInptChanl",
+		"Intrst", "NtryDtls", "AddtlNtryInf"},
+	"BookgDt":   {"Dt", "DtTm"},
+	"ValDt":     {"Dt", "DtTm"},
+	"BkTxCd":    {"Domn", "Prtry"},
+	"Prtry":     {"!Cd", "Issr"},
+	"NtryDtls":  {"Btch", "TxDtls"},
+	"TxDtls":    {"Refs", "AmtDtls", "Avlbty", "BkTxCd", "Chrgs", "Intrst", "RltdPties", "RltdAgts", "Purp", "RltdRmtInf", "RmtInf"},
+	"Refs":      {"MsgId", "AcctSvcrRef", "PmtInfId", "InstrId", "EndToEndId", "TxId", "MndtId", "ChqNb", "ClrSysRef", "Prtry"},
+	"RltdPties": {"InitgPty", "Dbtr", "DbtrAcct", "UltmtDbtr", "Cdtr", "CdtrAcct", "UltmtCdtr", "TradgPty", "Prtry"},
+	"DbtrAcct":  {"!Id", "Tp", "Ccy", "Nm"},
+	"CdtrAcct":  {"!Id", "Tp", "Ccy", "Nm"},
+	"RmtInf":    {"Ustrd", "Strd"},
+}
+
+type xmlNode struct {
+	name     string
+	children []*xmlNode
+}
+
+func parseTree(t *testing.T, data []byte) *xmlNode {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var stack []*xmlNode
+	var root *xmlNode
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+
+		switch el := token.(type) {
+		case xml.StartElement:
+			if root == nil {
+				assert.Equal(t, Camt053Namespace, el.Name.Space)
+			}
+			node := &xmlNode{name: el.Name.Local}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.children = append(parent.children, node)
+			} else {
+				root = node
+			}
+			stack = append(stack, node)
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+		}
+	}
+	return root
+}
+
+// checkStructure verifies that every element's children follow the XSD
+// sequence and that required children are present.
+func checkStructure(t *testing.T, node *xmlNode, path string) {
+	path = path + "/" + node.name
+	allowed, known := camt053Schema[node.name]
+	if !known {
+		assert.Empty(t, node.children, "%s: unexpected children for leaf element", path)
+		return
+	}
+
+	position := 0
+	for _, child := range node.children {
+		found := false
+		for ; position < len(allowed); position++ {
+			if trimRequired(allowed[position]) == child.name {
+				found = true
+				break
+			}
+		}
+		if !assert.True(t, found, "%s: element %s is not allowed here or out of order", path, child.name) {
+			return
+		}
+		checkStructure(t, child, path)
+	}
+
+	for _, name := range allowed {
+		if name[0] != '!' {
+			continue
+		}
+		present := false
+		for _, child := range node.children {
+			if child.name == name[1:] {
+				present = true
+			}
+		}
+		assert.True(t, present, "%s: required element %s is missing", path, name[1:])
+	}
+}
+
+func trimRequired(name string) string {
+	if name[0] == '!' {
+		return name[1:]
+	}
+	return name
+}
+
+func TestCamt053SchemaStructure(t *testing.T) {
+	g := setupTest(t)
+	now := time.Now().UTC()
+
+	doc, err := g.Camt053(context.Background(), "CASH", now.Add(-time.Hour), now.Add(time.Hour))
+	require.NoError(t, err)
+
+	data, err := doc.Marshal()
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(data, []byte(xml.Header)))
+
+	root := parseTree(t, data)
+	require.NotNil(t, root)
+	assert.Equal(t, "Document", root.name)
+	checkStructure(t, root, "")
+
+	// The document must round-trip through the same types
+	var decoded Document
+	require.NoError(t, xml.Unmarshal(data, &decoded))
+	assert.Len(t, decoded.Stmt.Statements[0].Entries, 2)
+}
@@ -0,0 +1,282 @@
+package tenants
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.uber.org/fx"
+	"go.uber.org/zap"
+	"ledgerproject/audit"
+	"ledgerproject/config"
+	"ledgerproject/fees"
+	"ledgerproject/importer"
+	"ledgerproject/interest"
+	"ledgerproject/ledger"
+	"ledgerproject/logger"
+	"ledgerproject/metrics"
+	"ledgerproject/reconciliation"
+	"ledgerproject/scheduler"
+	"ledgerproject/services"
+	"ledgerproject/statements"
+	"ledgerproject/webhooks"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultID is the tenant served to platform principals that do not name a
+// tenant. It is backed by the services the application is wired with.
+const DefaultID = "default"
+
+var (
+	ErrNotFound = errors.New("tenant not found")
+	ErrExists   = errors.New("tenant already exists")
+)
+
+// validID guards tenant IDs, which appear in paths and directory names.
+var validID = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+// Tenant is one business unit's book. Every tenant has its own ledger and
+// services, so accounts, transactions, locks, reports and stored data never
+// cross tenants. Currencies is empty when all platform currencies are
+// allowed.
+type Tenant struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Currencies []string  `json:"currencies,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+
+	Config     *config.Config          `json:"-"`
+	Ledger     ledger.LedgerService    `json:"-"`
+	Importer   *importer.Importer      `json:"-"`
+	Statements *statements.Generator   `json:"-"`
+	Reconciler *reconciliation.Service `json:"-"`
+	Interest   *interest.Engine        `json:"-"`
+	Scheduler  *scheduler.Scheduler    `json:"-"`
+	Fees       *fees.Engine            `json:"-"`
+	Webhooks   *webhooks.Dispatcher    `json:"-"`
+	Audit      *audit.Log              `json:"-"`
+}
+
+// Params lists the services of the default tenant.
+type Params struct {
+	fx.In
+
+	Config     *config.Config
+	Currencies *services.CurrencyValidator
+	Ledger     ledger.LedgerService
+	Importer   *importer.Importer
+	Reconciler *reconciliation.Service
+	Interest   *interest.Engine
+	Scheduler  *scheduler.Scheduler
+	Fees       *fees.Engine
+	Webhooks   *webhooks.Dispatcher
+	Audit      *audit.Log
+	Metrics    *metrics.Metrics
+}
+
+// Registry holds the tenants and builds the services of new ones. Tenants
+// created through the API live until the process stops; permanent tenants
+// belong in the configuration.
+type Registry struct {
+	base       *config.Config
+	currencies *services.CurrencyValidator
+	metrics    *metrics.Metrics
+	tenants    map[string]*Tenant
+	jobs       context.Context
+	mu         sync.RWMutex
+}
+
+// NewRegistry returns a registry holding the default tenant and the tenants
+// listed in the configuration.
+func NewRegistry(p Params) (*Registry, error) {
+	r := &Registry{
+		base:       p.Config,
+		currencies: p.Currencies,
+		metrics:    p.Metrics,
+		tenants:    make(map[string]*Tenant),
+	}
+	r.tenants[DefaultID] = &Tenant{
+		ID:         DefaultID,
+		Name:       "Default",
+		CreatedAt:  time.Now().UTC(),
+		Config:     p.Config,
+		Ledger:     p.Ledger,
+		Importer:   p.Importer,
+		Statements: statements.NewGenerator(p.Ledger),
+		Reconciler: p.Reconciler,
+		Interest:   p.Interest,
+		Scheduler:  p.Scheduler,
+		Fees:       p.Fees,
+		Webhooks:   p.Webhooks,
+		Audit:      p.Audit,
+	}
+
+	for _, tc := range p.Config.Tenants {
+		if _, err := r.Create(tc); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Create builds a tenant with its own ledger and services. Its background
+// jobs start right away when the registry is running.
+func (r *Registry) Create(tc config.TenantConfig) (*Tenant, error) {
+	log := logger.Get()
+	if !validID.MatchString(tc.ID) {
+		return nil, fmt.Errorf("invalid tenant id %q: use lowercase letters, digits and dashes", tc.ID)
+	}
+	if tc.Name == "" {
+		tc.Name = tc.ID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.tenants[tc.ID]; exists {
+		return nil, fmt.Errorf("%w: %s", ErrExists, tc.ID)
+	}
+
+	t, err := r.build(tc)
+	if err != nil {
+		log.Error("Failed to create tenant", zap.Error(err), zap.String("tenant", tc.ID))
+		return nil, err
+	}
+	r.tenants[t.ID] = t
+	if r.jobs != nil {
+		t.start(r.jobs)
+	}
+
+	log.Info("Tenant created", zap.String("tenant", t.ID), zap.Strings("currencies", t.Currencies))
+	return t, nil
+}
+
+// build wires a tenant's services from a copy of the platform configuration.
+// Stored data goes to the tenant's own directory.
+func (r *Registry) build(tc config.TenantConfig) (*Tenant, error) {
+	cfg := *r.base
+	cfg.Tenants = nil
+	cfg.FeeRulesFile = tc.FeeRulesFile
+	cfg.ReconciliationDir = ""
+	cfg.AuditLogFile = ""
+	if r.base.TenantDataDir != "" {
+		dir := filepath.Join(r.base.TenantDataDir, tc.ID)
+		if err := os.MkdirAll(dir, 0o750); err != nil {
+			return nil, fmt.Errorf("error creating tenant directory: %v", err)
+		}
+		cfg.ReconciliationDir = filepath.Join(dir, "reconciliations")
+		cfg.AuditLogFile = filepath.Join(dir, "audit.log")
+	}
+
+	currencies := r.currencies
+	if len(tc.Currencies) > 0 {
+		subset, err := r.currencies.Subset(tc.Currencies)
+		if err != nil {
+			return nil, err
+		}
+		currencies = subset
+	}
+
+	feeEngine, err := fees.NewEngine(currencies, &cfg)
+	if err != nil {
+		return nil, err
+	}
+	store, err := reconciliation.NewStore(&cfg)
+	if err != nil {
+		return nil, err
+	}
+	trail, err := audit.NewLog(&cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var measured ledger.Metrics
+	if r.metrics != nil {
+		measured = r.metrics.Ledger(tc.ID)
+	}
+	l := ledger.NewLedger(currencies, feeEngine, measured, cfg.BalanceCheckInterval)
+	return &Tenant{
+		ID:         tc.ID,
+		Name:       tc.Name,
+		Currencies: append([]string(nil), tc.Currencies...),
+		CreatedAt:  time.Now().UTC(),
+		Config:     &cfg,
+		Ledger:     l,
+		Importer:   importer.NewImporter(l, currencies),
+		Statements: statements.NewGenerator(l),
+		Reconciler: reconciliation.NewService(l, store, &cfg),
+		Interest:   interest.NewEngine(l, currencies, &cfg),
+		Scheduler:  scheduler.NewScheduler(l, &cfg),
+		Fees:       feeEngine,
+		Webhooks:   webhooks.NewDispatcher(l, &cfg),
+		Audit:      trail,
+	}, nil
+}
+
+// Get returns the tenant with the given ID.
+func (r *Registry) Get(id string) (*Tenant, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	t, exists := r.tenants[id]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return t, nil
+}
+
+// List returns all tenants ordered by ID.
+func (r *Registry) List() []*Tenant {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]*Tenant, 0, len(r.tenants))
+	for _, t := range r.tenants {
+		list = append(list, t)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// Run starts the background jobs of every tenant but the default one, whose
+// jobs the application runs itself, and of tenants created later. It returns
+// when ctx is done.
+func (r *Registry) Run(ctx context.Context) {
+	r.mu.Lock()
+	r.jobs = ctx
+	for id, t := range r.tenants {
+		if id != DefaultID {
+			t.start(ctx)
+		}
+	}
+	r.mu.Unlock()
+
+	<-ctx.Done()
+}
+
+// Close closes the audit trails of the tenants the registry created.
+func (r *Registry) Close() error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var errs []error
+	for id, t := range r.tenants {
+		if id == DefaultID {
+			continue
+		}
+		if err := t.Audit.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %s: %v", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (t *Tenant) start(ctx context.Context) {
+	go t.Interest.Run(ctx)
+	go t.Scheduler.Run(ctx)
+	go t.Webhooks.Run(ctx)
+}
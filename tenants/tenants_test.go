@@ -0,0 +1,165 @@
+package tenants
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"ledgerproject/config"
+	"ledgerproject/logger"
+	"ledgerproject/models"
+	"ledgerproject/services"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// setupTestLogger initializes a test logger
+func setupTestLogger(t *testing.T) *zap.Logger {
+	testLogger := zaptest.NewLogger(t)
+	// Initialize the package-level logger
+	if err := logger.Init(true); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	return testLogger
+}
+
+func setupRegistry(t *testing.T, cfg *config.Config) *Registry {
+	setupTestLogger(t)
+	cfg.CurrencyFile = "../data/iso4217_currency_test.json"
+	cv, err := services.NewCurrencyValidator(cfg)
+	require.NoError(t, err)
+
+	r, err := NewRegistry(Params{Config: cfg, Currencies: cv})
+	require.NoError(t, err)
+	return r
+}
+
+func usd(amount int64) models.Money {
+	return models.Money{Amount: decimal.NewFromInt(amount), Currency: "USD"}
+}
+
+func TestNewRegistry(t *testing.T) {
+	r := setupRegistry(t, &config.Config{
+		Tenants: []config.TenantConfig{
+			{ID: "retail", Name: "Retail Banking", Currencies: []string{"USD"}},
+			{ID: "cards"},
+		},
+	})
+
+	var ids []string
+	for _, tenant := range r.List() {
+		ids = append(ids, tenant.ID)
+	}
+	assert.Equal(t, []string{"cards", DefaultID, "retail"}, ids)
+
+	retail, err := r.Get("retail")
+	require.NoError(t, err)
+	assert.Equal(t, "Retail Banking", retail.Name)
+	assert.Equal(t, []string{"USD"}, retail.Currencies)
+
+	cards, err := r.Get("cards")
+	require.NoError(t, err)
+	assert.Equal(t, "cards", cards.Name)
+
+	_, err = r.Get("wholesale")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCreateErrors(t *testing.T) {
+	r := setupRegistry(t, &config.Config{})
+
+	tests := []struct {
+		name    string
+		tenant  config.TenantConfig
+		wantErr string
+	}{
+		{name: "empty id", tenant: config.TenantConfig{}, wantErr: "invalid tenant id"},
+		{name: "uppercase id", tenant: config.TenantConfig{ID: "Retail"}, wantErr: "invalid tenant id"},
+		{name: "path in id", tenant: config.TenantConfig{ID: "../retail"}, wantErr: "invalid tenant id"},
+		{name: "default tenant", tenant: config.TenantConfig{ID: DefaultID}, wantErr: "already exists"},
+		{name: "unknown currency", tenant: config.TenantConfig{ID: "retail", Currencies: []string{"XXX"}}, wantErr: "invalid currency code"},
+		{name: "missing fee rules", tenant: config.TenantConfig{ID: "retail", FeeRulesFile: "missing.json"}, wantErr: "fee rules file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := r.Create(tt.tenant)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestTenantIsolation(t *testing.T) {
+	dir := t.TempDir()
+	r := setupRegistry(t, &config.Config{TenantDataDir: dir})
+
+	retail, err := r.Create(config.TenantConfig{ID: "retail", Currencies: []string{"USD"}})
+	require.NoError(t, err)
+	cards, err := r.Create(config.TenantConfig{ID: "cards"})
+	require.NoError(t, err)
+
+	// The same account IDs live independently in each tenant
+	for _, tenant := range []*Tenant{retail, cards} {
+		require.NoError(t, tenant.Ledger.CreateAccount(context.Background(), models.Account{ID: "ACC001", Name: "Cash", Currency: "USD", Balance: usd(100)}))
+		require.NoError(t, tenant.Ledger.CreateAccount(context.Background(), models.Account{ID: "ACC002", Name: "Revenue", Currency: "USD"}))
+	}
+	require.NoError(t, retail.Ledger.RecordTransaction(context.Background(), models.Transaction{
+		ID: "TX001", DebitAccount: "ACC001", CreditAccount: "ACC002", Amount: usd(40),
+	}))
+
+	balance, err := retail.Ledger.GetAccountBalance(context.Background(), "ACC001")
+	require.NoError(t, err)
+	assert.Equal(t, "60", balance.Amount.String())
+	balance, err = cards.Ledger.GetAccountBalance(context.Background(), "ACC001")
+	require.NoError(t, err)
+	assert.Equal(t, "100", balance.Amount.String())
+	assert.Empty(t, cards.Ledger.GetTransactionHistory(context.Background(), "ACC001"))
+
+	// Currencies are restricted per tenant
+	assert.Error(t, retail.Ledger.CreateAccount(context.Background(), models.Account{ID: "ACC003", Name: "Euro", Currency: "EUR"}))
+	assert.NoError(t, cards.Ledger.CreateAccount(context.Background(), models.Account{ID: "ACC003", Name: "Euro", Currency: "EUR"}))
+
+	// Stored data is kept per tenant
+	for _, tenant := range []*Tenant{retail, cards} {
+		assert.Equal(t, filepath.Join(dir, tenant.ID, "audit.log"), tenant.Config.AuditLogFile)
+		assert.Equal(t, filepath.Join(dir, tenant.ID, "reconciliations"), tenant.Config.ReconciliationDir)
+		_, err := os.Stat(filepath.Join(dir, tenant.ID, "audit.log"))
+		assert.NoError(t, err)
+	}
+	require.NoError(t, r.Close())
+}
+
+func TestRunStartsTenantJobs(t *testing.T) {
+	r := setupRegistry(t, &config.Config{
+		InterestAccrualInterval: time.Hour,
+		SchedulerInterval:       time.Hour,
+		WebhookPollInterval:     time.Hour,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan struct{})
+	go func() {
+		r.Run(ctx)
+		close(done)
+	}()
+
+	// Tenants created while running are started too
+	require.Eventually(t, func() bool {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return r.jobs != nil
+	}, time.Second, 10*time.Millisecond)
+	_, err := r.Create(config.TenantConfig{ID: "retail"})
+	require.NoError(t, err)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after the context was cancelled")
+	}
+}
@@ -0,0 +1,173 @@
+// Package tlsconfig serves the API over TLS with the certificate, key and
+// client CAs in the configuration, and picks up new files when they are
+// replaced on disk.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"go.uber.org/zap"
+	"ledgerproject/config"
+	"ledgerproject/logger"
+	"os"
+	"sync"
+	"time"
+)
+
+// nextProtos offers HTTP/2 before HTTP/1.1 in the ALPN negotiation.
+var nextProtos = []string{"h2", "http/1.1"}
+
+// stamp identifies a version of a file.
+type stamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// Reloader holds the TLS configuration of the API server. Handshakes use the
+// files as last loaded; once every reload interval a handshake checks whether
+// they changed and reloads them. Files that fail to load, such as a
+// certificate replaced before its key, are logged and the previous
+// configuration stays in use until the next check.
+type Reloader struct {
+	cfg     config.TLS
+	now     func() time.Time
+	mu      sync.Mutex
+	checked time.Time
+	stamps  []stamp
+	current *tls.Config
+}
+
+// New loads the files of the TLS configuration, or returns nil when TLS is
+// disabled.
+func New(cfg *config.Config) (*Reloader, error) {
+	if !cfg.TLS.Enabled() {
+		return nil, nil
+	}
+	r := &Reloader{cfg: cfg.TLS, now: time.Now}
+	stamps, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	current, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+	r.stamps, r.current, r.checked = stamps, current, r.now()
+	return r, nil
+}
+
+// ServerConfig returns the TLS configuration to serve with. Each handshake
+// gets the latest certificate and client CAs.
+func (r *Reloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		NextProtos: nextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return r.config(), nil
+		},
+	}
+}
+
+// config returns the current configuration, reloading the files first if a
+// check is due and they changed.
+func (r *Reloader) config() *tls.Config {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if now.Sub(r.checked) < r.cfg.ReloadInterval {
+		return r.current
+	}
+	r.checked = now
+
+	log := logger.Get()
+	stamps, err := r.stat()
+	if err != nil {
+		log.Warn("Failed to check TLS files", zap.Error(err))
+		return r.current
+	}
+	if equal(stamps, r.stamps) {
+		return r.current
+	}
+	current, err := r.load()
+	if err != nil {
+		log.Warn("Failed to reload TLS files, keeping the previous certificate", zap.Error(err))
+		return r.current
+	}
+
+	r.stamps, r.current = stamps, current
+	log.Info("Reloaded TLS certificate",
+		zap.String("cert_file", r.cfg.CertFile),
+		zap.Time("not_after", current.Certificates[0].Leaf.NotAfter))
+	return r.current
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.cfg.CertFile, r.cfg.KeyFile}
+	if r.cfg.ClientCAFile != "" {
+		files = append(files, r.cfg.ClientCAFile)
+	}
+	return files
+}
+
+func (r *Reloader) stat() ([]stamp, error) {
+	var stamps []stamp
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, fmt.Errorf("error reading TLS file: %v", err)
+		}
+		stamps = append(stamps, stamp{modTime: info.ModTime(), size: info.Size()})
+	}
+	return stamps, nil
+}
+
+// load reads the certificate, its key and the client CAs. Clients are asked
+// for a certificate only when there are client CAs to verify it with.
+func (r *Reloader) load() (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("error loading TLS certificate: %v", err)
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, fmt.Errorf("error parsing TLS certificate: %v", err)
+		}
+	}
+
+	c := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   nextProtos,
+		Certificates: []tls.Certificate{cert},
+	}
+	if r.cfg.ClientCAFile == "" {
+		return c, nil
+	}
+
+	data, err := os.ReadFile(r.cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading client CA file: %v", err)
+	}
+	c.ClientCAs = x509.NewCertPool()
+	if !c.ClientCAs.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("client CA file %s holds no PEM certificates", r.cfg.ClientCAFile)
+	}
+	c.ClientAuth = tls.VerifyClientCertIfGiven
+	if r.cfg.RequireClientCert {
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return c, nil
+}
+
+func equal(a, b []stamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,209 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"ledgerproject/config"
+	"ledgerproject/logger"
+	"ledgerproject/tlsconfig/tlstest"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// env is a CA with a server certificate and a client certificate named
+// "payments", in dir.
+type env struct {
+	dir      string
+	ca       *tlstest.CA
+	server   *x509.Certificate
+	cfg      *config.Config
+	certFile string
+	keyFile  string
+}
+
+func setupTest(t *testing.T) *env {
+	require.NoError(t, logger.Init(true))
+	dir := t.TempDir()
+	ca := tlstest.NewCA(t, dir, "ca")
+	server, certFile, keyFile := ca.Issue(t, dir, "localhost")
+	_, clientCert, clientKey := ca.Issue(t, dir, "payments")
+	return &env{
+		dir:    dir,
+		ca:     ca,
+		server: server,
+		cfg: &config.Config{TLS: config.TLS{
+			CertFile: certFile, KeyFile: keyFile, ClientCAFile: ca.CertFile, ReloadInterval: time.Minute,
+		}},
+		certFile: clientCert,
+		keyFile:  clientKey,
+	}
+}
+
+// serve serves the reloader's configuration and answers with the subject of
+// the verified client certificate, if any.
+func serve(t *testing.T, r *Reloader) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	srv := &http.Server{
+		TLSConfig: r.ServerConfig(),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if len(req.TLS.VerifiedChains) > 0 {
+				w.Write([]byte(req.TLS.VerifiedChains[0][0].Subject.CommonName))
+			}
+		}),
+	}
+	go srv.ServeTLS(ln, "", "")
+	t.Cleanup(func() { srv.Close() })
+	return "https://" + ln.Addr().String()
+}
+
+// client trusts the CA and presents the certificate, if given. It opens a
+// connection per request so that every request is a new handshake.
+func (e *env) client(t *testing.T, withCert bool) *http.Client {
+	tlsConfig := &tls.Config{RootCAs: x509.NewCertPool()}
+	tlsConfig.RootCAs.AddCert(e.ca.Cert)
+	if withCert {
+		cert, err := tls.LoadX509KeyPair(e.certFile, e.keyFile)
+		require.NoError(t, err)
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   tlsConfig,
+		ForceAttemptHTTP2: true,
+		DisableKeepAlives: true,
+	}}
+}
+
+func get(t *testing.T, client *http.Client, url string) (*http.Response, string) {
+	resp, err := client.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body := make([]byte, 64)
+	n, _ := resp.Body.Read(body)
+	return resp, string(body[:n])
+}
+
+func TestDisabled(t *testing.T) {
+	r, err := New(&config.Config{})
+	require.NoError(t, err)
+	assert.Nil(t, r)
+}
+
+func TestClientCertificates(t *testing.T) {
+	tests := []struct {
+		name        string
+		require     bool
+		noClientCA  bool
+		withCert    bool
+		wantSubject string
+		wantErr     bool
+	}{
+		{name: "verified client", withCert: true, wantSubject: "payments"},
+		{name: "optional without certificate"},
+		{name: "required without certificate", require: true, wantErr: true},
+		{name: "required with certificate", require: true, withCert: true, wantSubject: "payments"},
+		{name: "no client CA", noClientCA: true, withCert: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := setupTest(t)
+			e.cfg.TLS.RequireClientCert = tt.require
+			if tt.noClientCA {
+				e.cfg.TLS.ClientCAFile = ""
+			}
+			r, err := New(e.cfg)
+			require.NoError(t, err)
+			url := serve(t, r)
+
+			resp, err := e.client(t, tt.withCert).Get(url)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			defer resp.Body.Close()
+			assert.Equal(t, 2, resp.ProtoMajor, "HTTP/2 is negotiated")
+			body := make([]byte, 64)
+			n, _ := resp.Body.Read(body)
+			assert.Equal(t, tt.wantSubject, string(body[:n]))
+		})
+	}
+}
+
+func TestReload(t *testing.T) {
+	e := setupTest(t)
+	r, err := New(e.cfg)
+	require.NoError(t, err)
+	now := time.Now()
+	r.now = func() time.Time { return now }
+	url := serve(t, r)
+	client := e.client(t, true)
+
+	resp, _ := get(t, client, url)
+	assert.Equal(t, e.server.SerialNumber, resp.TLS.PeerCertificates[0].SerialNumber)
+
+	// Rotate the certificate; it is picked up at the next check
+	rotated, _, _ := e.ca.Issue(t, e.dir, "localhost")
+	touch(t, e.cfg.TLS.CertFile, e.cfg.TLS.KeyFile)
+	resp, _ = get(t, client, url)
+	assert.Equal(t, e.server.SerialNumber, resp.TLS.PeerCertificates[0].SerialNumber, "reloaded before the interval")
+
+	now = now.Add(time.Minute)
+	resp, body := get(t, client, url)
+	assert.Equal(t, rotated.SerialNumber, resp.TLS.PeerCertificates[0].SerialNumber)
+	assert.Equal(t, "payments", body)
+
+	// A broken certificate is not loaded
+	require.NoError(t, os.WriteFile(e.cfg.TLS.CertFile, []byte("not a certificate"), 0o600))
+	touch(t, e.cfg.TLS.CertFile)
+	now = now.Add(time.Minute)
+	resp, _ = get(t, client, url)
+	assert.Equal(t, rotated.SerialNumber, resp.TLS.PeerCertificates[0].SerialNumber)
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		modify  func(e *env)
+		wantErr string
+	}{
+		{
+			name:    "missing certificate",
+			modify:  func(e *env) { e.cfg.TLS.CertFile = filepath.Join(e.dir, "missing.crt") },
+			wantErr: "error reading TLS file",
+		},
+		{
+			name:    "mismatched key",
+			modify:  func(e *env) { e.cfg.TLS.KeyFile = e.keyFile },
+			wantErr: "error loading TLS certificate",
+		},
+		{
+			name:    "client CA without certificates",
+			modify:  func(e *env) { e.cfg.TLS.ClientCAFile = e.keyFile },
+			wantErr: "holds no PEM certificates",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := setupTest(t)
+			tt.modify(e)
+			_, err := New(e.cfg)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+// touch moves the modification time of the files forward, so that changes
+// are seen on file systems with coarse timestamps.
+func touch(t *testing.T, files ...string) {
+	later := time.Now().Add(time.Hour)
+	for _, file := range files {
+		require.NoError(t, os.Chtimes(file, later, later))
+	}
+}
@@ -0,0 +1,128 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	"ledgerproject/config"
+	"os"
+	"path/filepath"
+)
+
+// instrumentation names the tracer of the service's own spans.
+const instrumentation = "ledgerproject"
+
+// serviceName identifies the service in the exported spans.
+const serviceName = "ledger"
+
+// Provider exports the spans of the process. A provider without an exporter
+// leaves the default no-op tracer in place, so spans cost next to nothing.
+type Provider struct {
+	provider *sdktrace.TracerProvider
+	file     *os.File
+}
+
+// NewProvider installs the tracer provider selected by the configuration and
+// the W3C trace context propagator. Trace context is propagated even when
+// nothing is exported, so that callers' traces are not cut at this service.
+func NewProvider(cfg *config.Config) (*Provider, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{}))
+
+	p := &Provider{}
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Tracing.Exporter {
+	case "", "none":
+		return p, nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "file":
+		if err := os.MkdirAll(filepath.Dir(cfg.Tracing.File), 0o750); err != nil {
+			return nil, fmt.Errorf("error creating trace directory: %v", err)
+		}
+		p.file, err = os.OpenFile(cfg.Tracing.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o640)
+		if err != nil {
+			return nil, fmt.Errorf("error opening trace file: %v", err)
+		}
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(p.file))
+	case "otlp":
+		exporter, err = otlptracehttp.New(context.Background(),
+			otlptracehttp.WithEndpoint(cfg.Tracing.Endpoint), otlptracehttp.WithInsecure())
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Tracing.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error creating trace exporter: %v", err)
+	}
+
+	p.provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.Tracing.SampleRatio))),
+	)
+	otel.SetTracerProvider(p.provider)
+	return p, nil
+}
+
+// Shutdown exports the spans still buffered and closes the exporter.
+func (p *Provider) Shutdown(ctx context.Context) error {
+	if p.provider == nil {
+		return nil
+	}
+	err := p.provider.Shutdown(ctx)
+	if p.file != nil {
+		if closeErr := p.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Start starts a span as a child of the span in ctx, if any.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentation).Start(ctx, name, opts...)
+}
+
+// End ends the span, marking it failed when err is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Stages traces the consecutive stages of an operation as child spans of the
+// operation's span. Starting a stage ends the previous one.
+type Stages struct {
+	ctx     context.Context
+	current trace.Span
+}
+
+// NewStages returns the stages of the operation whose span is in ctx.
+func NewStages(ctx context.Context) *Stages {
+	return &Stages{ctx: ctx}
+}
+
+// Start ends the current stage and starts the named one.
+func (s *Stages) Start(name string) {
+	s.End(nil)
+	_, s.current = Start(s.ctx, name)
+}
+
+// End ends the current stage, marking it failed when err is not nil.
+func (s *Stages) End(err error) {
+	if s.current != nil {
+		End(s.current, err)
+		s.current = nil
+	}
+}
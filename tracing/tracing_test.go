@@ -0,0 +1,72 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	"ledgerproject/config"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileExporter(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "traces", "spans.jsonl")
+	p, err := NewProvider(&config.Config{Tracing: config.Tracing{Exporter: "file", File: file, SampleRatio: 1}})
+	require.NoError(t, err)
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child")
+	End(child, errors.New("insufficient funds"))
+	parent.End()
+	require.NoError(t, p.Shutdown(context.Background()))
+
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	spans := string(data)
+	assert.Equal(t, 2, strings.Count(spans, `"SpanKind":`))
+	assert.Contains(t, spans, `"Name":"child"`)
+	assert.Contains(t, spans, "insufficient funds")
+	assert.Contains(t, spans, parent.SpanContext().TraceID().String())
+}
+
+func TestNewProviderErrors(t *testing.T) {
+	_, err := NewProvider(&config.Config{Tracing: config.Tracing{Exporter: "jaeger"}})
+	assert.ErrorContains(t, err, "unknown trace exporter")
+
+	p, err := NewProvider(&config.Config{Tracing: config.Tracing{Exporter: "none"}})
+	require.NoError(t, err)
+	assert.NoError(t, p.Shutdown(context.Background()))
+}
+
+func TestStages(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	ctx, span := Start(context.Background(), "operation")
+	stages := NewStages(ctx)
+	stages.Start("first")
+	stages.Start("second")
+	stages.End(errors.New("rejected"))
+	stages.End(nil)
+	span.End()
+
+	ended := recorder.Ended()
+	require.Len(t, ended, 3)
+	assert.Equal(t, "first", ended[0].Name())
+	assert.Equal(t, codes.Unset, ended[0].Status().Code)
+	assert.Equal(t, "second", ended[1].Name())
+	assert.Equal(t, codes.Error, ended[1].Status().Code)
+	for _, stage := range ended[:2] {
+		assert.Equal(t, span.SpanContext().SpanID(), stage.Parent().SpanID())
+	}
+}
@@ -0,0 +1,42 @@
+package webhooks
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	sent := time.Unix(1700000000, 0)
+	payload := []byte(`{"type":"account.created"}`)
+	header := Sign("secret", sent, payload)
+
+	assert.Regexp(t, `^t=1700000000,v1=[0-9a-f]{64}$`, header)
+
+	tests := []struct {
+		name    string
+		secret  string
+		header  string
+		payload []byte
+		now     time.Time
+		wantErr string
+	}{
+		{name: "valid", secret: "secret", header: header, payload: payload, now: sent.Add(time.Minute)},
+		{name: "wrong secret", secret: "other", header: header, payload: payload, now: sent, wantErr: "signature mismatch"},
+		{name: "tampered payload", secret: "secret", header: header, payload: []byte(`{}`), now: sent, wantErr: "signature mismatch"},
+		{name: "too old", secret: "secret", header: header, payload: payload, now: sent.Add(10 * time.Minute), wantErr: "outside tolerance"},
+		{name: "malformed", secret: "secret", header: "v1=abc", payload: payload, now: sent, wantErr: "malformed signature header"},
+		{name: "bad hex", secret: "secret", header: "t=1700000000,v1=zz", payload: payload, now: sent, wantErr: "signature mismatch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Verify(tt.secret, tt.header, tt.payload, tt.now, 5*time.Minute)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,358 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go.uber.org/zap"
+	"ledgerproject/config"
+	"ledgerproject/ledger"
+	"ledgerproject/logger"
+	"ledgerproject/models"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned for unknown endpoints and dead letters.
+var ErrNotFound = errors.New("not found")
+
+// outboxBatch is the number of events read from the ledger outbox per run.
+const outboxBatch = 500
+
+// Endpoint is a registered webhook receiver. An empty EventTypes list
+// subscribes to every event type.
+type Endpoint struct {
+	ID         string    `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+func (e Endpoint) subscribed(eventType string) bool {
+	if len(e.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range e.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// Delivery is one event on its way to one endpoint.
+type Delivery struct {
+	ID          string       `json:"id"`
+	EndpointID  string       `json:"endpoint_id"`
+	Event       models.Event `json:"event"`
+	Attempts    int          `json:"attempts"`
+	NextAttempt time.Time    `json:"next_attempt"`
+	LastError   string       `json:"last_error,omitempty"`
+}
+
+// Dispatcher relays events from the ledger outbox to the registered
+// endpoints. Events are read in sequence order and the outbox cursor only
+// advances once their deliveries are queued, so every committed event is
+// delivered at least once. Failed deliveries are retried with exponential
+// backoff and moved to the dead-letter list after the last attempt.
+type Dispatcher struct {
+	ledger      ledger.LedgerService
+	client      *http.Client
+	interval    time.Duration
+	maxAttempts int
+	retryBase   time.Duration
+	retryMax    time.Duration
+
+	endpoints   map[string]*Endpoint
+	queue       []*Delivery
+	deadLetters []*Delivery
+	cursor      uint64
+	now         func() time.Time
+	mu          sync.Mutex
+}
+
+func NewDispatcher(l ledger.LedgerService, cfg *config.Config) *Dispatcher {
+	return &Dispatcher{
+		ledger:      l,
+		client:      &http.Client{Timeout: cfg.WebhookTimeout},
+		interval:    cfg.WebhookPollInterval,
+		maxAttempts: cfg.WebhookMaxAttempts,
+		retryBase:   cfg.WebhookRetryBase,
+		retryMax:    cfg.WebhookRetryMax,
+		endpoints:   make(map[string]*Endpoint),
+		now:         time.Now,
+	}
+}
+
+// Register adds an endpoint. A secret is generated when none is given. The
+// endpoint receives events committed after its registration.
+func (d *Dispatcher) Register(endpoint Endpoint) (*Endpoint, error) {
+	log := logger.Get()
+
+	u, err := url.Parse(endpoint.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("webhook url %q must be an absolute http or https url", endpoint.URL)
+	}
+	for _, t := range endpoint.EventTypes {
+		if !models.KnownEventType(t) {
+			return nil, fmt.Errorf("unknown event type %q", t)
+		}
+	}
+	if endpoint.Secret == "" {
+		endpoint.Secret = randomHex(32)
+	}
+	endpoint.ID = "wh-" + randomHex(8)
+	endpoint.CreatedAt = d.now().UTC()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// Queue what is pending for the existing endpoints so the new one starts
+	// at the current end of the outbox instead of receiving history
+	d.pull()
+	d.endpoints[endpoint.ID] = &endpoint
+
+	log.Info("Webhook endpoint registered",
+		zap.String("endpoint_id", endpoint.ID),
+		zap.String("url", endpoint.URL))
+	registered := endpoint
+	return &registered, nil
+}
+
+// Endpoints returns the registered endpoints without their secrets.
+func (d *Dispatcher) Endpoints() []Endpoint {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	endpoints := make([]Endpoint, 0, len(d.endpoints))
+	for _, endpoint := range d.endpoints {
+		e := *endpoint
+		e.Secret = ""
+		endpoints = append(endpoints, e)
+	}
+	sort.Slice(endpoints, func(i, j int) bool { return endpoints[i].CreatedAt.Before(endpoints[j].CreatedAt) })
+	return endpoints
+}
+
+// Unregister removes an endpoint and drops its pending deliveries.
+func (d *Dispatcher) Unregister(id string) error {
+	log := logger.Get()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, exists := d.endpoints[id]; !exists {
+		return fmt.Errorf("webhook endpoint %s: %w", id, ErrNotFound)
+	}
+	delete(d.endpoints, id)
+
+	pending := d.queue[:0]
+	for _, delivery := range d.queue {
+		if delivery.EndpointID != id {
+			pending = append(pending, delivery)
+		}
+	}
+	d.queue = pending
+
+	log.Info("Webhook endpoint removed", zap.String("endpoint_id", id))
+	return nil
+}
+
+// DeadLetters returns the deliveries that exhausted their attempts.
+func (d *Dispatcher) DeadLetters() []Delivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	letters := make([]Delivery, len(d.deadLetters))
+	for i, delivery := range d.deadLetters {
+		letters[i] = *delivery
+	}
+	return letters
+}
+
+// Redeliver moves a dead letter back to the queue with a fresh set of
+// attempts.
+func (d *Dispatcher) Redeliver(id string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for i, delivery := range d.deadLetters {
+		if delivery.ID != id {
+			continue
+		}
+		if _, exists := d.endpoints[delivery.EndpointID]; !exists {
+			return fmt.Errorf("webhook endpoint %s: %w", delivery.EndpointID, ErrNotFound)
+		}
+		d.deadLetters = append(d.deadLetters[:i], d.deadLetters[i+1:]...)
+		delivery.Attempts = 0
+		delivery.NextAttempt = d.now()
+		d.queue = append(d.queue, delivery)
+		return nil
+	}
+	return fmt.Errorf("dead letter %s: %w", id, ErrNotFound)
+}
+
+// Run relays events on every tick until the context is cancelled.
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		d.Dispatch(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch queues deliveries for new outbox events and attempts every
+// delivery that is due. It returns the number of successful deliveries.
+func (d *Dispatcher) Dispatch(ctx context.Context) int {
+	d.mu.Lock()
+	d.pull()
+	now := d.now()
+	var due []*Delivery
+	for _, delivery := range d.queue {
+		if !delivery.NextAttempt.After(now) {
+			due = append(due, delivery)
+		}
+	}
+	endpoints := make(map[string]Endpoint, len(d.endpoints))
+	for id, endpoint := range d.endpoints {
+		endpoints[id] = *endpoint
+	}
+	d.mu.Unlock()
+
+	// Deliveries are sent without holding the lock
+	results := make(map[*Delivery]error, len(due))
+	for _, delivery := range due {
+		results[delivery] = d.send(ctx, endpoints[delivery.EndpointID], delivery)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.settle(results)
+}
+
+// pull reads new events from the outbox and queues a delivery per
+// subscribed endpoint. The caller must hold d.mu.
+func (d *Dispatcher) pull() {
+	for {
+		events := d.ledger.Events(d.cursor, outboxBatch)
+		for _, event := range events {
+			for _, endpoint := range d.endpoints {
+				if !endpoint.subscribed(event.Type) {
+					continue
+				}
+				d.queue = append(d.queue, &Delivery{
+					ID:          fmt.Sprintf("%s-%d", endpoint.ID, event.Sequence),
+					EndpointID:  endpoint.ID,
+					Event:       event,
+					NextAttempt: d.now(),
+				})
+			}
+			d.cursor = event.Sequence
+		}
+		if len(events) < outboxBatch {
+			return
+		}
+	}
+}
+
+// settle records the outcome of the attempted deliveries. The caller must
+// hold d.mu.
+func (d *Dispatcher) settle(results map[*Delivery]error) int {
+	log := logger.Get()
+
+	delivered := 0
+	pending := d.queue[:0]
+	for _, delivery := range d.queue {
+		err, attempted := results[delivery]
+		if !attempted {
+			pending = append(pending, delivery)
+			continue
+		}
+
+		delivery.Attempts++
+		if err == nil {
+			delivered++
+			continue
+		}
+
+		delivery.LastError = err.Error()
+		if delivery.Attempts >= d.maxAttempts {
+			d.deadLetters = append(d.deadLetters, delivery)
+			log.Error("Webhook delivery moved to dead letters",
+				zap.Error(err),
+				zap.String("delivery_id", delivery.ID),
+				zap.Int("attempts", delivery.Attempts))
+			continue
+		}
+		delivery.NextAttempt = d.now().Add(d.backoff(delivery.Attempts))
+		pending = append(pending, delivery)
+		log.Warn("Webhook delivery failed, will retry",
+			zap.Error(err),
+			zap.String("delivery_id", delivery.ID),
+			zap.Int("attempts", delivery.Attempts),
+			zap.Time("next_attempt", delivery.NextAttempt))
+	}
+	d.queue = pending
+	return delivered
+}
+
+// backoff returns the wait after the given number of failed attempts:
+// retryBase doubled per attempt, capped at retryMax.
+func (d *Dispatcher) backoff(attempts int) time.Duration {
+	wait := d.retryBase
+	for i := 1; i < attempts && wait < d.retryMax; i++ {
+		wait *= 2
+	}
+	if wait > d.retryMax {
+		wait = d.retryMax
+	}
+	return wait
+}
+
+func (d *Dispatcher) send(ctx context.Context, endpoint Endpoint, delivery *Delivery) error {
+	payload, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return fmt.Errorf("error encoding event: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, delivery.Event.Type)
+	req.Header.Set(DeliveryHeader, delivery.ID)
+	req.Header.Set(SignatureHeader, Sign(endpoint.Secret, d.now(), payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,202 @@
+package webhooks
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest"
+	"io"
+	"ledgerproject/config"
+	"ledgerproject/ledger"
+	"ledgerproject/logger"
+	"ledgerproject/models"
+	"ledgerproject/services"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// setupTestLogger initializes a test logger
+func setupTestLogger(t *testing.T) *zap.Logger {
+	testLogger := zaptest.NewLogger(t)
+	// Initialize the package-level logger
+	if err := logger.Init(true); err != nil {
+		t.Fatalf("Failed to initialize logger: %v", err)
+	}
+	return testLogger
+}
+
+// receiver is a webhook endpoint that answers with status and records the
+// deliveries it verified.
+type receiver struct {
+	status   int
+	secret   string
+	received []models.Event
+	mu       sync.Mutex
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	payload, _ := io.ReadAll(r.Body)
+	if err := Verify(rc.secret, r.Header.Get(SignatureHeader), payload, time.Now(), time.Hour); err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	var event models.Event
+	if err := json.Unmarshal(payload, &event); err != nil || event.Type != r.Header.Get(EventHeader) {
+		http.Error(w, "bad event", http.StatusBadRequest)
+		return
+	}
+	if rc.status != http.StatusOK {
+		http.Error(w, "unavailable", rc.status)
+		return
+	}
+	rc.received = append(rc.received, event)
+}
+
+func (rc *receiver) set(status int) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.status = status
+}
+
+func setupTest(t *testing.T, now *time.Time) (*Dispatcher, ledger.LedgerService) {
+	setupTestLogger(t)
+
+	validator, err := services.NewCurrencyValidator(&config.Config{
+		CurrencyFile: "../data/iso4217_currency_test.json",
+	})
+	require.NoError(t, err)
+
+	d := NewDispatcher(ledger.NewDetachedLedger(validator), &config.Config{
+		WebhookPollInterval: time.Second,
+		WebhookTimeout:      time.Second,
+		WebhookMaxAttempts:  3,
+		WebhookRetryBase:    time.Second,
+		WebhookRetryMax:     3 * time.Second,
+	})
+	d.now = func() time.Time { return *now }
+	return d, d.ledger
+}
+
+func TestRegister(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	d, _ := setupTest(t, &now)
+
+	tests := []struct {
+		name     string
+		endpoint Endpoint
+		wantErr  string
+	}{
+		{name: "valid", endpoint: Endpoint{URL: "https://example.com/hook"}},
+		{name: "filtered", endpoint: Endpoint{URL: "http://example.com/hook", EventTypes: []string{models.EventAccountFrozen}}},
+		{name: "relative url", endpoint: Endpoint{URL: "/hook"}, wantErr: "absolute http or https"},
+		{name: "other scheme", endpoint: Endpoint{URL: "ftp://example.com"}, wantErr: "absolute http or https"},
+		{name: "unknown type", endpoint: Endpoint{URL: "https://example.com", EventTypes: []string{"account.deleted"}}, wantErr: "unknown event type"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registered, err := d.Register(tt.endpoint)
+			if tt.wantErr != "" {
+				assert.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.NotEmpty(t, registered.ID)
+			assert.Len(t, registered.Secret, 64)
+		})
+	}
+
+	endpoints := d.Endpoints()
+	require.Len(t, endpoints, 2)
+	for _, endpoint := range endpoints {
+		assert.Empty(t, endpoint.Secret)
+	}
+
+	require.NoError(t, d.Unregister(endpoints[0].ID))
+	assert.ErrorIs(t, d.Unregister(endpoints[0].ID), ErrNotFound)
+	assert.Len(t, d.Endpoints(), 1)
+}
+
+func TestDispatch(t *testing.T) {
+	// The receiver checks signature timestamps against the wall clock
+	now := time.Now().UTC()
+	d, l := setupTest(t, &now)
+	ctx := context.Background()
+
+	// Events committed before registration are not delivered
+	require.NoError(t, l.CreateAccount(context.Background(), models.Account{ID: "OLD", Name: "Old", Currency: "USD"}))
+
+	rc := &receiver{status: http.StatusOK, secret: "s3cret"}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	all, err := d.Register(Endpoint{URL: srv.URL, Secret: "s3cret"})
+	require.NoError(t, err)
+	_, err = d.Register(Endpoint{URL: srv.URL, Secret: "s3cret", EventTypes: []string{models.EventAccountFrozen}})
+	require.NoError(t, err)
+
+	require.NoError(t, l.CreateAccount(context.Background(), models.Account{
+		ID: "ACC001", Name: "Account 1", Currency: "USD",
+		Balance: models.Money{Amount: decimal.NewFromInt(100), Currency: "USD"},
+	}))
+	require.NoError(t, l.FreezeAccount(context.Background(), "ACC001"))
+
+	// account.created once, account.frozen for both endpoints
+	assert.Equal(t, 3, d.Dispatch(ctx))
+	assert.Equal(t, 0, d.Dispatch(ctx))
+	require.Len(t, rc.received, 3)
+	assert.Equal(t, models.EventAccountCreated, rc.received[0].Type)
+	assert.Equal(t, []string{"ACC001"}, rc.received[0].Accounts)
+
+	// Failures back off and end up in the dead letters
+	rc.set(http.StatusServiceUnavailable)
+	require.NoError(t, l.UnfreezeAccount(context.Background(), "ACC001"))
+
+	assert.Equal(t, 0, d.Dispatch(ctx))
+	assert.Equal(t, 0, d.Dispatch(ctx), "not due before the backoff elapses")
+	now = now.Add(time.Second)
+	assert.Equal(t, 0, d.Dispatch(ctx))
+	now = now.Add(time.Second)
+	assert.Equal(t, 0, d.Dispatch(ctx), "second retry waits twice as long")
+	now = now.Add(time.Second)
+	assert.Equal(t, 0, d.Dispatch(ctx))
+
+	letters := d.DeadLetters()
+	require.Len(t, letters, 1)
+	assert.Equal(t, all.ID, letters[0].EndpointID)
+	assert.Equal(t, 3, letters[0].Attempts)
+	assert.Contains(t, letters[0].LastError, "503")
+	assert.Equal(t, models.EventAccountUnfrozen, letters[0].Event.Type)
+
+	// Redelivery sends it once the endpoint recovers
+	rc.set(http.StatusOK)
+	assert.ErrorIs(t, d.Redeliver("missing"), ErrNotFound)
+	require.NoError(t, d.Redeliver(letters[0].ID))
+	assert.Empty(t, d.DeadLetters())
+	assert.Equal(t, 1, d.Dispatch(ctx))
+	assert.Equal(t, models.EventAccountUnfrozen, rc.received[3].Type)
+}
+
+func TestBackoff(t *testing.T) {
+	d := &Dispatcher{retryBase: time.Second, retryMax: 10 * time.Second}
+
+	for attempts, want := range map[int]time.Duration{
+		1: time.Second,
+		2: 2 * time.Second,
+		3: 4 * time.Second,
+		4: 8 * time.Second,
+		5: 10 * time.Second,
+		9: 10 * time.Second,
+	} {
+		assert.Equal(t, want, d.backoff(attempts), "attempts %d", attempts)
+	}
+}